@@ -8,12 +8,16 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // 运行镜像是 alpine，不带时区库
 
+	"github.com/chiwei-platform/paas-engine/internal/adapter/github"
 	httpadapter "github.com/chiwei-platform/paas-engine/internal/adapter/http"
 	"github.com/chiwei-platform/paas-engine/internal/adapter/kubernetes"
 	"github.com/chiwei-platform/paas-engine/internal/adapter/loki"
+	"github.com/chiwei-platform/paas-engine/internal/adapter/registry"
 	"github.com/chiwei-platform/paas-engine/internal/adapter/repository"
 	"github.com/chiwei-platform/paas-engine/internal/config"
+	"github.com/chiwei-platform/paas-engine/internal/domain"
 	"github.com/chiwei-platform/paas-engine/internal/port"
 	"github.com/chiwei-platform/paas-engine/internal/service"
 	"gorm.io/gorm"
@@ -34,11 +38,18 @@ func main() {
 	imageRepoRepo := repository.NewImageRepoRepo(db)
 	buildRepo := repository.NewBuildRepo(db)
 	releaseRepo := repository.NewReleaseRepo(db)
+	releaseRevisionRepo := repository.NewReleaseRevisionRepo(db)
+	releaseCanaryRepo := repository.NewReleaseCanaryRepo(db)
+	releaseOperationRepo := repository.NewReleaseOperationRepo(db)
 	ciConfigRepo := repository.NewCIConfigRepo(db)
 	pipelineRunRepo := repository.NewPipelineRunRepo(db)
 	configBundleRepo := repository.NewConfigBundleRepo(db)
 	dynamicConfigRepo := repository.NewDynamicConfigRepo(db)
 	gatewayRuleRepo := repository.NewGatewayRuleRepo(db)
+	releaseDriftRepo := repository.NewReleaseDriftRepo(db)
+	driftEventRepo := repository.NewDriftEventRepo(db)
+	laneRepo := repository.NewLaneRepo(db)
+	laneSleepPolicyRepo := repository.NewLaneSleepPolicyRepo(db)
 
 	// K8s 客户端（可选，无集群时降级运行）
 	cs, _, k8sErr := kubernetes.NewClientset(cfg.KubeconfigPath)
@@ -49,18 +60,45 @@ func main() {
 	var deployer port.Deployer
 	var buildExecutor port.BuildExecutor
 	var testExecutor port.TestExecutor
+	var imageScanner port.ImageScanner
+
+	// GitHub API（token 为空走匿名额度）：构建前把 GitRef 解析成 commit（构建去重、构建 Job），CI 变更检测和镜像晋升用 compare，CI 读取 pipeline.yml
+	githubClient := github.NewClient(cfg.GitHubToken, cfg.BuildHttpProxy)
 
 	if cs != nil {
 		deployer = kubernetes.NewK8sDeployer(cs, cfg.DeployNamespace, cfg.SidecarImage)
-		buildExecutor = kubernetes.NewKanikoBuildExecutor(cs, kubernetes.KanikoBuildConfig{
+		// 构建后端按 ImageRepo.builder 选择，两者共用 namespace 和 Job 标签约定
+		buildExecutor = kubernetes.NewBuildExecutorRouter(map[string]port.BuildExecutor{
+			domain.BuilderKaniko: kubernetes.NewKanikoBuildExecutor(cs, kubernetes.KanikoBuildConfig{
+				Namespace:          cfg.KanikoNamespace,
+				KanikoImage:        cfg.KanikoImage,
+				RegistrySecret:     cfg.RegistrySecret,
+				RegistryMirrors:    cfg.RegistryMirrors,
+				InsecureRegistries: cfg.InsecureRegistries,
+				CacheRepo:          cfg.KanikoCacheRepo,
+				HttpProxy:          cfg.BuildHttpProxy,
+				NoProxy:            cfg.BuildNoProxy,
+				RefResolver:        githubClient,
+			}),
+			domain.BuilderBuildKit: kubernetes.NewBuildKitBuildExecutor(cs, kubernetes.BuildKitBuildConfig{
+				Namespace:          cfg.KanikoNamespace,
+				BuildKitImage:      cfg.BuildKitImage,
+				RegistrySecret:     cfg.RegistrySecret,
+				InsecureRegistries: cfg.InsecureRegistries,
+				CacheRepo:          cfg.BuildKitCacheRepo,
+				HttpProxy:          cfg.BuildHttpProxy,
+				NoProxy:            cfg.BuildNoProxy,
+				RefResolver:        githubClient,
+			}),
+		})
+		imageScanner = kubernetes.NewTrivyScanner(cs, kubernetes.TrivyScanConfig{
 			Namespace:          cfg.KanikoNamespace,
-			KanikoImage:        cfg.KanikoImage,
+			TrivyImage:         cfg.TrivyImage,
 			RegistrySecret:     cfg.RegistrySecret,
-			RegistryMirrors:    cfg.RegistryMirrors,
 			InsecureRegistries: cfg.InsecureRegistries,
-			CacheRepo:          cfg.KanikoCacheRepo,
 			HttpProxy:          cfg.BuildHttpProxy,
 			NoProxy:            cfg.BuildNoProxy,
+			ReportURL:          cfg.ScanReportURL,
 		})
 		testExecutor = kubernetes.NewK8sTestExecutor(cs, kubernetes.TestExecutorConfig{
 			Namespace: cfg.CINamespace,
@@ -73,20 +111,55 @@ func main() {
 	// Loki 日志查询
 	lokiClient := loki.NewClient(cfg.LokiURL)
 
+	// registry API：镜像晋升打 tag、按保留策略删除旧镜像
+	registryClient := registry.NewClient(registry.Config{
+		Username:           cfg.RegistryUsername,
+		Password:           cfg.RegistryPassword,
+		InsecureRegistries: cfg.InsecureRegistries,
+	})
+
 	// 服务层
 	appSvc := service.NewAppService(appRepo, imageRepoRepo, releaseRepo, configBundleRepo)
 	imageRepoSvc := service.NewImageRepoService(imageRepoRepo, appRepo)
-	buildSvc := service.NewBuildService(imageRepoRepo, buildRepo, buildExecutor, lokiClient)
+	buildSvc := service.NewBuildService(imageRepoRepo, buildRepo, buildExecutor, lokiClient, service.BuildServiceConfig{
+		MaxConcurrent:        cfg.BuildMaxConcurrent,
+		MaxConcurrentPerRepo: cfg.BuildMaxConcurrentPerRepo,
+		QueueInterval:        cfg.BuildQueueCheckInterval,
+		RefResolver:          githubClient,
+		ConfigBundles:        configBundleRepo,
+		Registry:             registryClient,
+		Ancestry:             githubClient,
+		Releases:             releaseRepo,
+		Revisions:            releaseRevisionRepo,
+	})
 	configBundleSvc := service.NewConfigBundleService(configBundleRepo, appRepo, releaseRepo, service.ConfigBundleServiceConfig{
 		LegacyLaneWhitelist: cfg.LegacyLaneWhitelist,
 	})
 	dynamicConfigSvc := service.NewDynamicConfigService(dynamicConfigRepo)
 	gatewayRuleSvc := service.NewGatewayRuleService(gatewayRuleRepo)
-	releaseSvc := service.NewReleaseService(appRepo, imageRepoRepo, buildRepo, releaseRepo, deployer, configBundleSvc, service.ReleaseServiceConfig{
+	releaseSvc := service.NewReleaseService(appRepo, imageRepoRepo, buildRepo, releaseRepo, releaseRevisionRepo, releaseOperationRepo, deployer, configBundleSvc, service.ReleaseServiceConfig{
+		LegacyLaneWhitelist: cfg.LegacyLaneWhitelist,
+	})
+	driftSvc := service.NewDriftService(appRepo, releaseRepo, releaseDriftRepo, driftEventRepo, deployer, configBundleSvc, cfg.DriftReconcileInterval)
+	imageGCSvc := service.NewImageGCService(imageRepoRepo, buildRepo, releaseRepo, releaseRevisionRepo, pipelineRunRepo, registryClient, cfg.ImageGCInterval)
+	imageScanSvc := service.NewImageScanService(imageRepoRepo, buildRepo, imageScanner, cfg.ImageScanInterval, cfg.ImageScanTimeout)
+	laneSleepSvc := service.NewLaneSleepService(releaseSvc, laneSleepPolicyRepo, service.LaneSleepServiceConfig{
+		DefaultIdleHours:    cfg.LaneSleepIdleHours,
+		Location:            cfg.LaneSleepLocation,
+		Interval:            cfg.LaneSleepCheckInterval,
 		LegacyLaneWhitelist: cfg.LegacyLaneWhitelist,
 	})
+	laneSvc := service.NewLaneService(laneRepo, releaseSvc, configBundleSvc, dynamicConfigSvc, gatewayRuleSvc, ciConfigRepo, laneSleepPolicyRepo, service.LaneServiceConfig{
+		ExpiryInterval:      cfg.LaneExpiryCheckInterval,
+		LegacyLaneWhitelist: cfg.LegacyLaneWhitelist,
+	})
+	canarySvc := service.NewCanaryService(releaseSvc, gatewayRuleSvc, releaseCanaryRepo)
 	logSvc := service.NewLogService(appRepo, lokiClient, cfg.DeployNamespace)
-	pipelineSvc := service.NewPipelineService(ciConfigRepo, pipelineRunRepo, testExecutor, buildSvc, releaseSvc, appRepo, imageRepoRepo, lokiClient, cfg.CINamespace)
+	pipelineSvc := service.NewPipelineService(ciConfigRepo, pipelineRunRepo, testExecutor, buildSvc, releaseSvc, appRepo, imageRepoRepo, lokiClient, githubClient, githubClient, service.PipelineServiceConfig{
+		CINamespace: cfg.CINamespace,
+		GatewayURL:  cfg.CIGatewayURL,
+		MaxParallel: cfg.CIMaxParallelJobs,
+	})
 
 	// 启动 Build Informer
 	ctx, cancel := context.WithCancel(context.Background())
@@ -99,6 +172,35 @@ func main() {
 	} else {
 		slog.Info("baseline gateway rules ensured")
 	}
+	// 回收持有者已退出（租约过期）的后台流程：启动时一次，之后每个租约周期一次，
+	// 蓝绿切换时停掉的旧实例留下的流程也能被接管
+	recoverOrphans := func() {
+		// 没人驱动的金丝雀恢复放量前的权重
+		if err := canarySvc.AbortInterrupted(ctx); err != nil {
+			slog.Warn("failed to abort interrupted canaries", "error", err)
+		}
+		// 接管没等完 rollout 的部署（放在金丝雀回收之后：被删掉的金丝雀 release 不再接管）
+		if err := releaseSvc.ResumeInterrupted(ctx); err != nil {
+			slog.Warn("failed to resume interrupted release operations", "error", err)
+		}
+		// 接管没跑完的 pipeline run；停机期间结束的测试 Job 由下面的 Test Informer 首次同步补上
+		if err := pipelineSvc.ResumeRuns(ctx); err != nil {
+			slog.Warn("failed to resume unfinished pipeline runs", "error", err)
+		}
+	}
+	recoverOrphans()
+	go func() {
+		ticker := time.NewTicker(domain.LeaseTTL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				recoverOrphans()
+			}
+		}
+	}()
 	if buildExecutor != nil {
 		go func() {
 			if err := buildExecutor.Watch(ctx, buildSvc.OnBuildStatusChange); err != nil {
@@ -107,6 +209,28 @@ func main() {
 		}()
 	}
 
+	// 启动构建队列兜底出队（无 K8s 时没有 executor，构建只排队不提交）
+	if buildExecutor != nil {
+		go buildSvc.Start(ctx)
+	}
+
+	// 启动漂移对账（无 K8s 或 DRIFT_RECONCILE_INTERVAL<=0 时 Start 直接返回）
+	go driftSvc.Start(ctx)
+
+	// 按 ImageRepo retention 策略清理旧镜像（IMAGE_GC_INTERVAL<=0 时 Start 直接返回）
+	go imageGCSvc.Start(ctx)
+
+	// 扫描开启了 scan_policy 的构建产物（无集群或 IMAGE_SCAN_INTERVAL<=0 时 Start 直接返回）
+	go imageScanSvc.Start(ctx)
+
+	// 启动 coe/ppe lane 自动休眠（LANE_SLEEP_CHECK_INTERVAL<=0 时 Start 直接返回）
+	if deployer != nil {
+		go laneSleepSvc.Start(ctx)
+	}
+
+	// 启动登记 lane 的到期回收（LANE_EXPIRY_CHECK_INTERVAL<=0 时 Start 直接返回）
+	go laneSvc.Start(ctx)
+
 	// 启动 Test Informer
 	if testExecutor != nil {
 		go func() {
@@ -116,7 +240,7 @@ func main() {
 		}()
 	}
 
-	// 启动 Git Poller（需要 GITHUB_TOKEN 和 CI_GIT_REPO）
+	// 启动 Git Poller（需要 GITHUB_TOKEN 和 CI_GIT_REPO；配置了 webhook 时作兜底，GIT_POLL_INTERVAL<=0 时 Start 直接返回）
 	if cfg.GitHubToken != "" && cfg.CIGitRepo != "" {
 		poller := service.NewGitPoller(ciConfigRepo, pipelineSvc, cfg.CIGitRepo,
 			cfg.GitHubToken, cfg.GitPollInterval, cfg.BuildHttpProxy)
@@ -167,15 +291,18 @@ func main() {
 
 	// HTTP 路由
 	handler := httpadapter.NewRouter(
-		httpadapter.NewAppHandler(appSvc, buildSvc),
-		httpadapter.NewReleaseHandler(releaseSvc),
+		httpadapter.NewAppHandler(appSvc, buildSvc, imageScanSvc),
+		httpadapter.NewReleaseHandler(releaseSvc, canarySvc),
 		httpadapter.NewLogHandler(logSvc),
-		httpadapter.NewImageRepoHandler(imageRepoSvc),
+		httpadapter.NewImageRepoHandler(imageRepoSvc, imageGCSvc),
 		httpadapter.NewOpsHandler(opsDbs, writeDbs, mutationRepo),
 		httpadapter.NewPipelineHandler(pipelineSvc),
 		httpadapter.NewConfigBundleHandler(configBundleSvc),
 		httpadapter.NewDynamicConfigHandler(dynamicConfigSvc),
 		httpadapter.NewGatewayRuleHandler(gatewayRuleSvc),
+		httpadapter.NewDriftHandler(driftSvc),
+		httpadapter.NewLaneHandler(laneSvc, laneSleepSvc),
+		httpadapter.NewGitHubWebhookHandler(pipelineSvc, cfg.GitHubWebhookSecret, cfg.CIGitRepo),
 		cfg.APIToken,
 	)
 
@@ -0,0 +1,226 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	_ port.GitRefResolver       = (*Client)(nil)
+	_ port.GitComparer          = (*Client)(nil)
+	_ port.GitAncestry          = (*Client)(nil)
+	_ port.PipelineConfigLoader = (*Client)(nil)
+)
+
+// compareFilesLimit 是 compare API 最多返回的文件数，达到上限说明列表被截断。
+const compareFilesLimit = 300
+
+// Client 通过 GitHub REST API 把 git ref 解析为 commit SHA、对比两个 commit 的改动和祖先关系、读取仓库文件。
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient 创建 GitHub 客户端。token 为空时走匿名限流额度，proxyURL 为空则直连。
+func NewClient(token, proxyURL string) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		if u, err := url.Parse(proxyURL); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+	return &Client{
+		baseURL:    "https://api.github.com",
+		token:      token,
+		httpClient: &http.Client{Transport: transport, Timeout: 15 * time.Second},
+	}
+}
+
+// splitRepo 把 "owner/repo(.git)" 规整为 "owner/repo"。
+func splitRepo(gitRepo string) (string, error) {
+	ownerRepo := strings.TrimSuffix(gitRepo, ".git")
+	if strings.Count(ownerRepo, "/") != 1 {
+		return "", fmt.Errorf("github: invalid repo %q, want owner/repo", gitRepo)
+	}
+	return ownerRepo, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, apiURL, accept string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("github: build request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// ResolveCommit 返回 ref（branch / tag / 短 SHA）当前指向的完整 commit SHA。
+// gitRepo 格式如 "bezhai/chiwei-platform" 或 "bezhai/chiwei-platform.git"。
+func (c *Client) ResolveCommit(ctx context.Context, gitRepo, ref string) (string, error) {
+	ownerRepo, err := splitRepo(gitRepo)
+	if err != nil {
+		return "", err
+	}
+	ref = strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/tags/")
+	apiURL := fmt.Sprintf("%s/repos/%s/commits/%s", c.baseURL, ownerRepo, url.PathEscape(ref))
+
+	// sha media type 直接返回纯文本的 commit SHA
+	req, err := c.newRequest(ctx, apiURL, "application/vnd.github.sha")
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("github: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("github: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("github: resolve %s@%s returned %d", ownerRepo, ref, resp.StatusCode)
+	}
+	sha := strings.TrimSpace(string(body))
+	if len(sha) != 40 {
+		return "", fmt.Errorf("github: unexpected commit sha %q for %s@%s", sha, ownerRepo, ref)
+	}
+	return sha, nil
+}
+
+// compareResponse 是 compare API 的响应（仅取所需字段）。
+type compareResponse struct {
+	Status string `json:"status"` // ahead / behind / diverged / identical
+	Files  []struct {
+		Filename         string `json:"filename"`
+		PreviousFilename string `json:"previous_filename"`
+	} `json:"files"`
+}
+
+// compare 调用 compare API 对比 base...head。
+func (c *Client) compare(ctx context.Context, gitRepo, base, head string) (*compareResponse, error) {
+	ownerRepo, err := splitRepo(gitRepo)
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("%s/repos/%s/compare/%s...%s", c.baseURL, ownerRepo, url.PathEscape(base), url.PathEscape(head))
+	req, err := c.newRequest(ctx, apiURL, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github: compare %s %s...%s returned %d", ownerRepo, base, head, resp.StatusCode)
+	}
+	var result compareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("github: decode compare response: %w", err)
+	}
+	return &result, nil
+}
+
+// ChangedFiles 通过 compare API 列出 base..head 改动的文件。
+func (c *Client) ChangedFiles(ctx context.Context, gitRepo, base, head string) ([]string, error) {
+	result, err := c.compare(ctx, gitRepo, base, head)
+	if err != nil {
+		return nil, err
+	}
+	// behind / diverged：base 上有 head 不包含的提交（force push、回退），只看 head 侧的改动会漏
+	if result.Status != "ahead" && result.Status != "identical" {
+		return nil, fmt.Errorf("github: %s is %s of %s, cannot diff incrementally", head, result.Status, base)
+	}
+	if len(result.Files) >= compareFilesLimit {
+		return nil, fmt.Errorf("github: %s...%s changes %d+ files, list is truncated", base, head, compareFilesLimit)
+	}
+
+	files := make([]string, 0, len(result.Files))
+	for _, f := range result.Files {
+		files = append(files, f.Filename)
+		if f.PreviousFilename != "" {
+			files = append(files, f.PreviousFilename)
+		}
+	}
+	return files, nil
+}
+
+// IsAncestor 判断 commit 是否已包含在 ref 的历史里：compare commit...ref 为 ahead 或 identical。
+func (c *Client) IsAncestor(ctx context.Context, gitRepo, commit, ref string) (bool, error) {
+	result, err := c.compare(ctx, gitRepo, commit, ref)
+	if err != nil {
+		return false, err
+	}
+	return result.Status == "ahead" || result.Status == "identical", nil
+}
+
+// maxFileSize 是读取仓库文件的大小上限。
+const maxFileSize = 1 << 20
+
+// LoadPipelineConfig 读取 ref 上仓库根目录的 pipeline.yml 并解析。未知字段视为错误，避免拼错的配置被静默忽略。
+func (c *Client) LoadPipelineConfig(ctx context.Context, gitRepo, ref string) (*domain.PipelineConfig, error) {
+	data, err := c.readFile(ctx, gitRepo, ref, domain.PipelineConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	var cfg domain.PipelineConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: parse %s: %v", domain.ErrInvalidInput, domain.PipelineConfigFile, err)
+	}
+	return &cfg, nil
+}
+
+// readFile 通过 contents API 读取 ref 上的文件原文，文件不存在时返回 domain.ErrNotFound。
+func (c *Client) readFile(ctx context.Context, gitRepo, ref, path string) ([]byte, error) {
+	ownerRepo, err := splitRepo(gitRepo)
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", c.baseURL, ownerRepo, path, url.QueryEscape(ref))
+	// raw media type 直接返回文件内容，不用 base64 解码
+	req, err := c.newRequest(ctx, apiURL, "application/vnd.github.raw")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s not found in %s@%s", domain.ErrNotFound, path, ownerRepo, ref)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github: read %s from %s@%s returned %d", path, ownerRepo, ref, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("github: read response: %w", err)
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("github: %s in %s@%s exceeds %d bytes", path, ownerRepo, ref, maxFileSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+func TestResolveCommit(t *testing.T) {
+	const sha = "3f2a9c1d4b5e6f708192a3b4c5d6e7f809162738"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.github.sha" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.EscapedPath() {
+		case "/repos/bezhai/chiwei-platform/commits/feat%2Flane-clone":
+			w.Write([]byte(sha))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient("tok", "")
+	c.baseURL = srv.URL
+
+	got, err := c.ResolveCommit(context.Background(), "bezhai/chiwei-platform.git", "feat/lane-clone")
+	if err != nil {
+		t.Fatalf("ResolveCommit() error = %v", err)
+	}
+	if got != sha {
+		t.Errorf("sha = %q, want %q", got, sha)
+	}
+
+	if _, err := c.ResolveCommit(context.Background(), "bezhai/chiwei-platform", "no-such-branch"); err == nil {
+		t.Error("expected error for unknown ref")
+	}
+	if _, err := c.ResolveCommit(context.Background(), "chiwei-platform", "main"); err == nil {
+		t.Error("expected error for repo without owner")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	const base, head = "1111111111111111111111111111111111111111", "2222222222222222222222222222222222222222"
+	status := "ahead"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/bezhai/chiwei-platform/compare/"+base+"..."+head {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"status":%q,"files":[
+			{"filename":"apps/lark-server/src/index.ts"},
+			{"filename":"packages/ts-shared/log.ts","previous_filename":"packages/ts-shared/logger.ts"}
+		]}`, status)
+	}))
+	defer srv.Close()
+
+	c := NewClient("", "")
+	c.baseURL = srv.URL
+
+	got, err := c.ChangedFiles(context.Background(), "bezhai/chiwei-platform.git", base, head)
+	if err != nil {
+		t.Fatalf("ChangedFiles() error = %v", err)
+	}
+	want := []string{"apps/lark-server/src/index.ts", "packages/ts-shared/log.ts", "packages/ts-shared/logger.ts"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	// force push 后 head 不再是 base 的后继，不能增量
+	status = "diverged"
+	if _, err := c.ChangedFiles(context.Background(), "bezhai/chiwei-platform", base, head); err == nil {
+		t.Error("expected error for diverged history")
+	}
+	if _, err := c.ChangedFiles(context.Background(), "bezhai/chiwei-platform", head, base); err == nil {
+		t.Error("expected error for unknown compare range")
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	const commit = "1111111111111111111111111111111111111111"
+	status := "ahead"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/bezhai/chiwei-platform/compare/"+commit+"...main" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"status":%q}`, status)
+	}))
+	defer srv.Close()
+
+	c := NewClient("", "")
+	c.baseURL = srv.URL
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		status string
+		want   bool
+	}{{"ahead", true}, {"identical", true}, {"diverged", false}, {"behind", false}} {
+		status = tt.status
+		got, err := c.IsAncestor(ctx, "bezhai/chiwei-platform", commit, "main")
+		if err != nil {
+			t.Fatalf("IsAncestor() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("status %s: IsAncestor() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+	if _, err := c.IsAncestor(ctx, "bezhai/chiwei-platform", commit, "dev"); err == nil {
+		t.Error("expected error for unknown ref")
+	}
+}
+
+func TestLoadPipelineConfig(t *testing.T) {
+	const sha = "3f2a9c1d4b5e6f708192a3b4c5d6e7f809162738"
+	files := map[string]string{
+		sha: `services:
+  paas-engine:
+    runtime: go
+    unit_test: cd apps/paas-engine && go test ./...
+lark_flow:
+  runtime: python
+  cmd: python tests/lark_flow.py
+  timeout: 10m
+`,
+		"typo": "services:\n  paas-engine:\n    runtime: go\n    unittest: go test ./...\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.github.raw" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		content, ok := files[r.URL.Query().Get("ref")]
+		if r.URL.Path != "/repos/bezhai/chiwei-platform/contents/pipeline.yml" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	c := NewClient("", "")
+	c.baseURL = srv.URL
+	ctx := context.Background()
+
+	cfg, err := c.LoadPipelineConfig(ctx, "bezhai/chiwei-platform.git", sha)
+	if err != nil {
+		t.Fatalf("LoadPipelineConfig() error = %v", err)
+	}
+	if svc, ok := cfg.Service("paas-engine"); !ok || svc.Runtime != "go" || svc.UnitTest == "" {
+		t.Errorf("paas-engine = %+v, %v", svc, ok)
+	}
+	if cfg.LarkFlow == nil || cfg.LarkFlow.Timeout != "10m" {
+		t.Errorf("lark_flow = %+v", cfg.LarkFlow)
+	}
+
+	if _, err := c.LoadPipelineConfig(ctx, "bezhai/chiwei-platform", "main"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("missing file error = %v, want ErrNotFound", err)
+	}
+	// 拼错的字段不能被静默忽略
+	if _, err := c.LoadPipelineConfig(ctx, "bezhai/chiwei-platform", "typo"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("unknown field error = %v, want ErrInvalidInput", err)
+	}
+}
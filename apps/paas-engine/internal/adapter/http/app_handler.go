@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -12,10 +13,11 @@ import (
 type AppHandler struct {
 	svc      *service.AppService
 	buildSvc *service.BuildService
+	scanSvc  *service.ImageScanService
 }
 
-func NewAppHandler(svc *service.AppService, buildSvc *service.BuildService) *AppHandler {
-	return &AppHandler{svc: svc, buildSvc: buildSvc}
+func NewAppHandler(svc *service.AppService, buildSvc *service.BuildService, scanSvc *service.ImageScanService) *AppHandler {
+	return &AppHandler{svc: svc, buildSvc: buildSvc, scanSvc: scanSvc}
 }
 
 func (h *AppHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -101,7 +103,12 @@ func (h *AppHandler) CreateBuild(w http.ResponseWriter, r *http.Request) {
 		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, build)
+	// 命中已有构建时没有新建资源
+	status := http.StatusCreated
+	if build.Reused {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, build)
 }
 
 func (h *AppHandler) ListBuilds(w http.ResponseWriter, r *http.Request) {
@@ -157,12 +164,57 @@ func (h *AppHandler) CancelBuild(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"cancelled": id})
 }
 
+// PromoteBuild 把 test channel 构建按原 digest 晋升为 stable 版本。
+func (h *AppHandler) PromoteBuild(w http.ResponseWriter, r *http.Request) {
+	repoName, ok := h.getImageRepoName(w, r)
+	if !ok {
+		return
+	}
+	var req service.PromoteBuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, err)
+		return
+	}
+	build, err := h.buildSvc.PromoteBuild(r.Context(), repoName, chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// 已晋升过时返回已有结果
+	status := http.StatusCreated
+	if build.Reused {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, build)
+}
+
+// ScanBuild 把构建产物重新排入漏洞扫描队列。
+func (h *AppHandler) ScanBuild(w http.ResponseWriter, r *http.Request) {
+	repoName, ok := h.getImageRepoName(w, r)
+	if !ok {
+		return
+	}
+	build, err := h.scanSvc.RequestScan(r.Context(), repoName, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, build)
+}
+
+// GetBuildLogs 返回构建日志；follow=true 时以 SSE 逐行推送直到构建结束。
 func (h *AppHandler) GetBuildLogs(w http.ResponseWriter, r *http.Request) {
 	repoName, ok := h.getImageRepoName(w, r)
 	if !ok {
 		return
 	}
 	id := chi.URLParam(r, "id")
+	if r.URL.Query().Get("follow") == "true" {
+		followLogs(w, r, func(ctx context.Context, offset int, emit func(service.LogLine) error) error {
+			return h.buildSvc.FollowBuildLogs(ctx, repoName, id, offset, emit)
+		})
+		return
+	}
 	logs, err := h.buildSvc.GetBuildLogs(r.Context(), repoName, id)
 	if err != nil {
 		writeError(w, err)
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/chiwei-platform/paas-engine/internal/service"
+)
+
+type DriftHandler struct {
+	svc *service.DriftService
+}
+
+func NewDriftHandler(svc *service.DriftService) *DriftHandler {
+	return &DriftHandler{svc: svc}
+}
+
+// List 返回最近一轮对账中存在漂移的 release。
+func (h *DriftHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.writeDrift(w, r)
+}
+
+// ListEvents 返回最近的漂移事件，支持 ?app= / ?lane= 过滤。
+// limit 来自查询参数 ?limit=，缺省/非法时默认 20、上限 200。
+func (h *DriftHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	events, err := h.svc.ListEvents(r.Context(), r.URL.Query().Get("app"), r.URL.Query().Get("lane"), limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// Reconcile 立即执行一轮对账，返回对账后的漂移列表。
+func (h *DriftHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.ReconcileOnce(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeDrift(w, r)
+}
+
+func (h *DriftHandler) writeDrift(w http.ResponseWriter, r *http.Request) {
+	drifts, err := h.svc.ListDrift(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, drifts)
+}
@@ -18,7 +18,7 @@ const testAPIToken = "test-token"
 func buildFullGatewayRouter() http.Handler {
 	svc := service.NewGatewayRuleService(newGwStubRepo())
 	gwH := NewGatewayRuleHandler(svc)
-	return NewRouter(nil, nil, nil, nil, nil, nil, nil, nil, gwH, testAPIToken)
+	return NewRouter(nil, nil, nil, nil, nil, nil, nil, nil, gwH, nil, nil, nil, testAPIToken)
 }
 
 func reqWithAuth(t *testing.T, r http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
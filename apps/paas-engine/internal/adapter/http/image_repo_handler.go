@@ -10,11 +10,12 @@ import (
 )
 
 type ImageRepoHandler struct {
-	svc *service.ImageRepoService
+	svc   *service.ImageRepoService
+	gcSvc *service.ImageGCService
 }
 
-func NewImageRepoHandler(svc *service.ImageRepoService) *ImageRepoHandler {
-	return &ImageRepoHandler{svc: svc}
+func NewImageRepoHandler(svc *service.ImageRepoService, gcSvc *service.ImageGCService) *ImageRepoHandler {
+	return &ImageRepoHandler{svc: svc, gcSvc: gcSvc}
 }
 
 func (h *ImageRepoHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -73,3 +74,14 @@ func (h *ImageRepoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"deleted": name})
 }
+
+// CollectGarbage 立即按 retention 策略清理该仓库的旧镜像，?dry_run=true 只返回将删除的镜像。
+func (h *ImageRepoHandler) CollectGarbage(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	report, err := h.gcSvc.CollectImageRepo(r.Context(), chi.URLParam(r, "repo"), dryRun)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type LaneHandler struct {
+	svc      *service.LaneService
+	sleepSvc *service.LaneSleepService
+}
+
+func NewLaneHandler(svc *service.LaneService, sleepSvc *service.LaneSleepService) *LaneHandler {
+	return &LaneHandler{svc: svc, sleepSvc: sleepSvc}
+}
+
+func (h *LaneHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateLaneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, domain.ErrInvalidInput)
+		return
+	}
+	lane, err := h.svc.CreateLane(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, lane)
+}
+
+func (h *LaneHandler) List(w http.ResponseWriter, r *http.Request) {
+	lanes, err := h.svc.ListLanes(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, lanes)
+}
+
+func (h *LaneHandler) Get(w http.ResponseWriter, r *http.Request) {
+	lane, err := h.svc.GetLane(r.Context(), chi.URLParam(r, "lane"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, lane)
+}
+
+func (h *LaneHandler) Update(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	lane, err := h.svc.UpdateLane(r.Context(), chi.URLParam(r, "lane"), body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, lane)
+}
+
+// Delete 回收整条 lane。部分步骤失败时仍返回 200，报告里 completed=false 并列出 errors，
+// lane 记录保留，可以重试。
+func (h *LaneHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svc.TeardownLane(r.Context(), chi.URLParam(r, "lane"), "manual")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Clone 把 ?from= 源 lane 的 release 复制到目标 lane，?apps=a,b 限定 app（默认全部），
+// ?dynamic_config=true / ?config_bundles=true 同时复制对应的 lane 覆盖。
+// 部署在后台进行，返回 202 与逐个 app 的结果（成功的带 operation_id）。
+func (h *LaneHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := service.CloneLaneRequest{From: q.Get("from")}
+	if v := q.Get("apps"); v != "" {
+		for _, app := range strings.Split(v, ",") {
+			if app = strings.TrimSpace(app); app != "" {
+				req.Apps = append(req.Apps, app)
+			}
+		}
+	}
+	for key, target := range map[string]*bool{"dynamic_config": &req.DynamicConfig, "config_bundles": &req.ConfigBundles} {
+		if v := q.Get(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeError(w, domain.ErrInvalidInput)
+				return
+			}
+			*target = b
+		}
+	}
+	report, err := h.svc.CloneLane(r.Context(), chi.URLParam(r, "lane"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, report)
+}
+
+// GetSleepPolicy 返回 lane 的休眠策略（单独配置或默认）和当前是否休眠。
+func (h *LaneHandler) GetSleepPolicy(w http.ResponseWriter, r *http.Request) {
+	status, err := h.sleepSvc.GetStatus(r.Context(), chi.URLParam(r, "lane"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *LaneHandler) SetSleepPolicy(w http.ResponseWriter, r *http.Request) {
+	var req service.SetLaneSleepPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, domain.ErrInvalidInput)
+		return
+	}
+	policy, err := h.sleepSvc.SetPolicy(r.Context(), chi.URLParam(r, "lane"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// DeleteSleepPolicy 删除 lane 的单独配置，回到默认策略。
+func (h *LaneHandler) DeleteSleepPolicy(w http.ResponseWriter, r *http.Request) {
+	lane := chi.URLParam(r, "lane")
+	if err := h.sleepSvc.DeletePolicy(r.Context(), lane); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"deleted": lane})
+}
+
+// Sleep 立即把 lane 缩到 0，返回被休眠的 release。
+func (h *LaneHandler) Sleep(w http.ResponseWriter, r *http.Request) {
+	releases, err := h.sleepSvc.Sleep(r.Context(), chi.URLParam(r, "lane"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, releases)
+}
+
+// Wake 为 lane 上每个休眠的 release 启动唤醒操作后返回 202，
+// 每项带 operation_id 供轮询 GET /releases/{id}/operations/{op}。
+func (h *LaneHandler) Wake(w http.ResponseWriter, r *http.Request) {
+	releases, ops, err := h.sleepSvc.Wake(r.Context(), chi.URLParam(r, "lane"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	out := make([]releaseAccepted, 0, len(releases))
+	for i, release := range releases {
+		out = append(out, releaseAccepted{Release: release, OperationID: ops[i].ID})
+	}
+	writeJSON(w, http.StatusAccepted, out)
+}
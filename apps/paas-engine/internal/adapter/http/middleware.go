@@ -59,6 +59,11 @@ func (rw *responseWriter) WriteHeader(status int) {
 	rw.ResponseWriter.WriteHeader(status)
 }
 
+// Unwrap 让 http.ResponseController 能找到底层 writer 做 Flush（SSE 日志流需要）。
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 type ctxHeadersKey struct{}
 
 func contextPropagationMiddleware(next http.Handler) http.Handler {
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -96,14 +97,20 @@ func (h *PipelineHandler) CancelRun(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"cancelled": id})
 }
 
-// GetLogs 获取 job 日志。
-// GET /api/paas/ci/runs/{id}/logs
+// GetLogs 获取 job 日志；follow=true 时以 SSE 逐行推送直到 job 结束。
+// GET /api/paas/ci/runs/{id}/logs?job=xxx[&follow=true]
 func (h *PipelineHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
 	jobID := r.URL.Query().Get("job")
 	if jobID == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "job query param required"})
 		return
 	}
+	if r.URL.Query().Get("follow") == "true" {
+		followLogs(w, r, func(ctx context.Context, offset int, emit func(service.LogLine) error) error {
+			return h.svc.FollowJobLogs(ctx, jobID, offset, emit)
+		})
+		return
+	}
 	logs, err := h.svc.GetJobLogs(r.Context(), jobID)
 	if err != nil {
 		writeError(w, err)
@@ -2,33 +2,50 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/chiwei-platform/paas-engine/internal/domain"
 	"github.com/chiwei-platform/paas-engine/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
 type ReleaseHandler struct {
-	svc *service.ReleaseService
+	svc       *service.ReleaseService
+	canarySvc *service.CanaryService
 }
 
-func NewReleaseHandler(svc *service.ReleaseService) *ReleaseHandler {
-	return &ReleaseHandler{svc: svc}
+func NewReleaseHandler(svc *service.ReleaseService, canarySvc *service.CanaryService) *ReleaseHandler {
+	return &ReleaseHandler{svc: svc, canarySvc: canarySvc}
 }
 
+// releaseAccepted 是异步部署入口的响应：release 字段平铺（兼容旧的同步响应），
+// 外加 operation_id 供轮询 GET /releases/{id}/operations/{op}。
+type releaseAccepted struct {
+	*domain.Release
+	OperationID string `json:"operation_id"`
+}
+
+func writeAccepted(w http.ResponseWriter, release *domain.Release, op *domain.ReleaseOperation) {
+	w.Header().Set("Location", "/api/paas/releases/"+release.ID+"/operations/"+op.ID)
+	writeJSON(w, http.StatusAccepted, releaseAccepted{Release: release, OperationID: op.ID})
+}
+
+// Create 校验并落库 pending 状态的 release 后立即返回 202，下发和 rollout 在后台执行。
 func (h *ReleaseHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateReleaseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, err)
 		return
 	}
-	release, err := h.svc.CreateOrUpdateRelease(r.Context(), req)
+	release, op, err := h.svc.StartCreateOrUpdateRelease(r.Context(), req)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, release)
+	writeAccepted(w, release, op)
 }
 
 func (h *ReleaseHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -42,6 +59,16 @@ func (h *ReleaseHandler) List(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, releases)
 }
 
+// LaneCommits 返回 lane 上各 app 正在运行的 commit 和镜像 digest。
+func (h *ReleaseHandler) LaneCommits(w http.ResponseWriter, r *http.Request) {
+	commits, err := h.svc.ListLaneCommits(r.Context(), chi.URLParam(r, "lane"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, commits)
+}
+
 func (h *ReleaseHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	release, err := h.svc.GetRelease(r.Context(), id)
@@ -59,12 +86,12 @@ func (h *ReleaseHandler) Update(w http.ResponseWriter, r *http.Request) {
 		writeError(w, err)
 		return
 	}
-	release, err := h.svc.UpdateRelease(r.Context(), id, body)
+	release, op, err := h.svc.StartUpdateRelease(r.Context(), id, body)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, release)
+	writeAccepted(w, release, op)
 }
 
 func (h *ReleaseHandler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -104,6 +131,93 @@ func (h *ReleaseHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, status)
 }
 
+// ListRevisions 返回 release 最近 N 条部署历史（revision 倒序）。
+// limit 来自查询参数 ?limit=，缺省/非法时默认 20、上限 200。
+func (h *ReleaseHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	revs, err := h.svc.ListReleaseRevisions(r.Context(), id, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, revs)
+}
+
+// Rollback 把 release 重新部署为 ?revision=N 对应的历史版本，和 Create 一样异步执行。
+func (h *ReleaseHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	revision, err := strconv.ParseInt(r.URL.Query().Get("revision"), 10, 64)
+	if err != nil || revision <= 0 {
+		writeError(w, fmt.Errorf("%w: revision query param is required and must be positive", domain.ErrInvalidInput))
+		return
+	}
+	release, op, err := h.svc.StartRollbackRelease(r.Context(), id, revision)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeAccepted(w, release, op)
+}
+
+// GetOperation 返回一次后台部署操作的状态和进度事件。
+func (h *ReleaseHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	opID := chi.URLParam(r, "op")
+	op, err := h.svc.GetReleaseOperation(r.Context(), id, opID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, op)
+}
+
+// StartCanary 启动金丝雀发布。步进流程在后台执行，立即返回 202 和初始状态。
+func (h *ReleaseHandler) StartCanary(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req service.StartCanaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, domain.ErrInvalidInput)
+		return
+	}
+	canary, err := h.canarySvc.StartCanary(r.Context(), id, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, canary)
+}
+
+// GetCanary 返回 release 最近一次金丝雀及每一步的观测结果。
+func (h *ReleaseHandler) GetCanary(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	canary, err := h.canarySvc.GetCanary(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, canary)
+}
+
+// AbortCanary 中止运行中的金丝雀并恢复放量前的权重。
+func (h *ReleaseHandler) AbortCanary(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	canary, err := h.canarySvc.AbortCanary(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, canary)
+}
+
 func (h *ReleaseHandler) DeleteByAppAndLane(w http.ResponseWriter, r *http.Request) {
 	appName := r.URL.Query().Get("app")
 	lane := r.URL.Query().Get("lane")
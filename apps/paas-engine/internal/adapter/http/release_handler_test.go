@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// newReleaseTestRouter 按 router.go 的方式注册 /releases 下的 rollback / revisions 路由，
+// 用 nil service 的 handler 只验证路由匹配与参数校验。
+func newReleaseTestRouter() *chi.Mux {
+	h := NewReleaseHandler(nil, nil)
+	r := chi.NewRouter()
+	r.Route("/api/paas/releases", func(r chi.Router) {
+		r.Post("/{id}:rollback", h.Rollback)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/status", h.GetStatus)
+			r.Get("/revisions", h.ListRevisions)
+		})
+	})
+	return r
+}
+
+func TestReleaseRollbackRequiresRevision(t *testing.T) {
+	r := newReleaseTestRouter()
+	for _, path := range []string{
+		"/api/paas/releases/rel-1:rollback",
+		"/api/paas/releases/rel-1:rollback?revision=abc",
+		"/api/paas/releases/rel-1:rollback?revision=0",
+	} {
+		rec := doReq(t, r, http.MethodPost, path, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestWriteAcceptedFlattensRelease 验证异步响应保持 release 字段平铺，并带上 operation_id 和 Location。
+func TestWriteAcceptedFlattensRelease(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeAccepted(rec,
+		&domain.Release{ID: "rel-1", AppName: "myapp", Lane: "prod", Status: domain.ReleaseStatusPending},
+		&domain.ReleaseOperation{ID: "op-1"})
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/paas/releases/rel-1/operations/op-1" {
+		t.Errorf("Location = %q", loc)
+	}
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Data["id"] != "rel-1" || body.Data["status"] != "pending" || body.Data["operation_id"] != "op-1" {
+		t.Errorf("unexpected body: %v", body.Data)
+	}
+}
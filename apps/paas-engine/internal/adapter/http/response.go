@@ -28,7 +28,8 @@ func writeError(w http.ResponseWriter, err error) {
 	case errors.Is(err, domain.ErrNotFound):
 		status = http.StatusNotFound
 		msg = err.Error()
-	case errors.Is(err, domain.ErrAlreadyExists):
+	case errors.Is(err, domain.ErrAlreadyExists),
+		errors.Is(err, domain.ErrOperationSuperseded):
 		status = http.StatusConflict
 		msg = err.Error()
 	case errors.Is(err, domain.ErrInvalidInput):
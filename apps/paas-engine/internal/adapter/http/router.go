@@ -18,6 +18,9 @@ func NewRouter(
 	configBundleH *ConfigBundleHandler,
 	dynamicConfigH *DynamicConfigHandler,
 	gatewayRuleH *GatewayRuleHandler,
+	driftH *DriftHandler,
+	laneH *LaneHandler,
+	githubWebhookH *GitHubWebhookHandler,
 	apiToken string,
 ) http.Handler {
 	r := chi.NewRouter()
@@ -38,6 +41,9 @@ func NewRouter(
 	// Gateway Rules — internal read endpoint (no auth, for api-gateway polling)
 	r.Get("/internal/gateway-rules", gatewayRuleH.Snapshot)
 
+	// GitHub webhook — no API key, authenticated by X-Hub-Signature-256
+	r.Post("/hooks/github", githubWebhookH.Handle)
+
 	r.Route("/api/paas", func(r chi.Router) {
 		r.Use(authMiddleware(apiToken))
 
@@ -60,6 +66,8 @@ func NewRouter(
 					r.Post("/", appH.CreateBuild)
 					r.Get("/", appH.ListBuilds)
 					r.Get("/latest", appH.GetLatestBuild)
+					r.Post("/{id}:promote", appH.PromoteBuild)
+					r.Post("/{id}:scan", appH.ScanBuild)
 					r.Route("/{id}", func(r chi.Router) {
 						r.Get("/", appH.GetBuild)
 						r.Post("/cancel", appH.CancelBuild)
@@ -76,14 +84,39 @@ func NewRouter(
 			r.Delete("/", releaseH.DeleteByAppAndLane)
 			r.Get("/orphans", releaseH.GetOrphans)
 			r.Delete("/orphans", releaseH.CleanupOrphans)
+			r.Get("/drift", driftH.List)
+			r.Get("/drift/events", driftH.ListEvents)
+			r.Post("/drift:reconcile", driftH.Reconcile)
+			r.Post("/{id}:rollback", releaseH.Rollback)
 			r.Route("/{id}", func(r chi.Router) {
 				r.Get("/", releaseH.Get)
 				r.Put("/", releaseH.Update)
 				r.Delete("/", releaseH.Delete)
 				r.Get("/status", releaseH.GetStatus)
+				r.Get("/revisions", releaseH.ListRevisions)
+				r.Get("/operations/{op}", releaseH.GetOperation)
+				r.Post("/canary", releaseH.StartCanary)
+				r.Get("/canary", releaseH.GetCanary)
+				r.Post("/canary:abort", releaseH.AbortCanary)
 			})
 		})
 
+		// Lanes（登记 / 回收 / 克隆 / 休眠 / 唤醒）
+		r.Route("/lanes", func(r chi.Router) {
+			r.Post("/", laneH.Create)
+			r.Get("/", laneH.List)
+			r.Get("/{lane}", laneH.Get)
+			r.Put("/{lane}", laneH.Update)
+			r.Delete("/{lane}", laneH.Delete)
+			r.Get("/{lane}/commits", releaseH.LaneCommits)
+			r.Post("/{lane}:clone", laneH.Clone)
+			r.Post("/{lane}:sleep", laneH.Sleep)
+			r.Post("/{lane}:wake", laneH.Wake)
+			r.Get("/{lane}/sleep-policy", laneH.GetSleepPolicy)
+			r.Put("/{lane}/sleep-policy", laneH.SetSleepPolicy)
+			r.Delete("/{lane}/sleep-policy", laneH.DeleteSleepPolicy)
+		})
+
 		// Ops
 		r.Route("/ops", func(r chi.Router) {
 			r.Post("/query", opsH.Query)
@@ -100,6 +133,7 @@ func NewRouter(
 		r.Route("/image-repos", func(r chi.Router) {
 			r.Post("/", imageRepoH.Create)
 			r.Get("/", imageRepoH.List)
+			r.Post("/{repo}:gc", imageRepoH.CollectGarbage)
 			r.Route("/{repo}", func(r chi.Router) {
 				r.Get("/", imageRepoH.Get)
 				r.Put("/", imageRepoH.Update)
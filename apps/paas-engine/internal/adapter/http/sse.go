@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/service"
+)
+
+// sseHeartbeat 是没有日志输出时发送 SSE 注释的间隔，防止代理按空闲超时断开连接。
+const sseHeartbeat = 15 * time.Second
+
+// followLogs 以 Server-Sent Events 推送日志：每行一个事件，id 为行号，结束时发送 end 事件。
+// 断线重连时浏览器自动带上 Last-Event-ID，curl 等客户端可传 ?offset=已收到的行数，从断点续传。
+func followLogs(w http.ResponseWriter, r *http.Request, follow func(ctx context.Context, offset int, emit func(service.LogLine) error) error) {
+	offset, err := parseLogOffset(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sse := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		sse.heartbeat(ctx)
+	}()
+
+	err = follow(ctx, offset, func(line service.LogLine) error {
+		return sse.send("", strconv.Itoa(line.Offset), line.Text)
+	})
+	cancel()
+	<-heartbeatDone // handler 返回后不能再写 w
+
+	switch {
+	case err == nil:
+		_ = sse.send("end", "", "")
+	case !sse.header:
+		// 还没开始推送（如构建不存在），按普通接口返回错误
+		writeError(w, err)
+	case r.Context().Err() == nil:
+		_ = sse.send("error", "", err.Error())
+	}
+}
+
+// parseLogOffset 解析续传位置：Last-Event-ID 是最后收到的行号，offset 是已收到的行数。
+func parseLogOffset(r *http.Request) (int, error) {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%w: invalid Last-Event-ID %q", domain.ErrInvalidInput, id)
+		}
+		return n + 1, nil
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%w: invalid offset %q", domain.ErrInvalidInput, raw)
+		}
+		return n, nil
+	}
+	return 0, nil
+}
+
+// sseWriter 把事件写成 text/event-stream 并立即 flush。第一次写入时才发送响应头，
+// 在此之前出错仍可返回普通的错误响应。日志行和心跳并发写入，由 mu 串行化。
+type sseWriter struct {
+	mu     sync.Mutex
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	header bool // 已发送响应头
+}
+
+func (s *sseWriter) send(event, id, data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(s.event(event, id, data))
+}
+
+func (s *sseWriter) event(event, id, data string) string {
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	// SSE 把 \r 也当作换行，进度条等带 \r 的输出拆成多个 data 行，客户端按 \n 拼回
+	for _, part := range strings.FieldsFunc(data, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		fmt.Fprintf(&b, "data: %s\n", part)
+	}
+	if !strings.Contains(b.String(), "data:") {
+		b.WriteString("data:\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func (s *sseWriter) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			_ = s.write(": keepalive\n\n")
+			s.mu.Unlock()
+		}
+	}
+}
+
+// write 在持有 mu 时调用。
+func (s *sseWriter) write(chunk string) error {
+	if !s.header {
+		h := s.w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+		s.w.WriteHeader(http.StatusOK)
+		s.header = true
+	}
+	if _, err := fmt.Fprint(s.w, chunk); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/service"
+)
+
+func fakeFollow(lines []string, gotOffset *int) func(context.Context, int, func(service.LogLine) error) error {
+	return func(_ context.Context, offset int, emit func(service.LogLine) error) error {
+		*gotOffset = offset
+		for i := offset; i < len(lines); i++ {
+			if err := emit(service.LogLine{Offset: i, Text: lines[i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestFollowLogs_SSE(t *testing.T) {
+	var offset int
+	follow := fakeFollow([]string{"step 1", "progress 10%\rprogress 100%", "done"}, &offset)
+
+	// 在中间件包装后的 writer 上也能 flush
+	handler := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followLogs(w, r, follow)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/logs?follow=true", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if offset != 1 {
+		t.Errorf("resume offset = %d, want 1 (Last-Event-ID + 1)", offset)
+	}
+	want := "id: 1\ndata: progress 10%\ndata: progress 100%\n\n" +
+		"id: 2\ndata: done\n\n" +
+		"event: end\ndata:\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestFollowLogs_Errors(t *testing.T) {
+	var offset int
+	rec := httptest.NewRecorder()
+	followLogs(rec, httptest.NewRequest(http.MethodGet, "/logs?follow=true&offset=-1", nil), fakeFollow(nil, &offset))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid offset: status = %d, want 400", rec.Code)
+	}
+
+	// 推送开始前出错返回普通错误响应
+	rec = httptest.NewRecorder()
+	followLogs(rec, httptest.NewRequest(http.MethodGet, "/logs?follow=true", nil),
+		func(context.Context, int, func(service.LogLine) error) error { return domain.ErrBuildNotFound })
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Header().Get("Content-Type"), "json") {
+		t.Errorf("not found: status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
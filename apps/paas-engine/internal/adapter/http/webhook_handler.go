@@ -0,0 +1,163 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/service"
+)
+
+// maxGitHubWebhookBody 限制 webhook body 大小。签名要读完整个 body 才能校验，
+// 不限制的话未经认证的请求就能让服务读入任意大的数据。
+const maxGitHubWebhookBody = 5 << 20
+
+// GitHubWebhookHandler 接收 GitHub webhook，推送到注册了 CI 的分支时触发 pipeline。
+type GitHubWebhookHandler struct {
+	svc     *service.PipelineService
+	secret  string // X-Hub-Signature-256 的 HMAC 密钥，为空时不接收 webhook
+	gitRepo string // CI 跑的 monorepo（owner/repo），其他仓库的事件忽略
+}
+
+func NewGitHubWebhookHandler(svc *service.PipelineService, secret, gitRepo string) *GitHubWebhookHandler {
+	return &GitHubWebhookHandler{svc: svc, secret: secret, gitRepo: gitRepo}
+}
+
+// Handle 校验签名后处理 push / pull_request 事件，其余事件忽略。
+// POST /hooks/github
+func (h *GitHubWebhookHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if h.secret == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "github webhook not configured"})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGitHubWebhookBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "body too large"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+		return
+	}
+	if !validGitHubSignature(h.secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "ping" {
+		writeJSON(w, http.StatusOK, map[string]string{"pong": r.Header.Get("X-GitHub-Delivery")})
+		return
+	}
+	branch, sha, err := parseGitHubPush(event, body, h.gitRepo)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if branch == "" {
+		writeJSON(w, http.StatusAccepted, map[string]string{"ignored": event})
+		return
+	}
+
+	run, err := h.svc.TriggerBranch(r.Context(), branch, sha)
+	switch {
+	case errors.Is(err, domain.ErrAlreadyExists):
+		// GitHub 重投、push 与 pull_request 同一个 commit：已经触发过
+		writeJSON(w, http.StatusOK, map[string]string{"duplicate": sha})
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrInvalidInput):
+		writeJSON(w, http.StatusAccepted, map[string]string{"ignored": err.Error()})
+	case err != nil:
+		writeError(w, err)
+	default:
+		slog.Info("github webhook: triggered pipeline", "event", event, "branch", branch, "sha", sha,
+			"lane", run.Lane, "delivery", r.Header.Get("X-GitHub-Delivery"))
+		writeJSON(w, http.StatusCreated, run)
+	}
+}
+
+// validGitHubSignature 校验 X-Hub-Signature-256（"sha256=" + body 的 HMAC-SHA256 十六进制）。
+func validGitHubSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// gitHubPushEvent 是 push / pull_request 事件中用到的字段。
+type gitHubPushEvent struct {
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	// push
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+	// pull_request
+	Action      string `json:"action"`
+	PullRequest struct {
+		Head struct {
+			Ref  string `json:"ref"`
+			SHA  string `json:"sha"`
+			Repo struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
+		} `json:"head"`
+		Base struct {
+			Repo struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
+		} `json:"base"`
+	} `json:"pull_request"`
+}
+
+// parseGitHubPush 从事件中取出被推送的分支和 head commit。不需要触发的事件（其他仓库的事件、
+// 删分支、推 tag、与新 commit 无关的 PR 动作、来自 fork 的 PR）返回空分支。
+func parseGitHubPush(event string, body []byte, gitRepo string) (branch, sha string, err error) {
+	if event != "push" && event != "pull_request" {
+		return "", "", nil
+	}
+	var payload gitHubPushEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", "", fmt.Errorf("invalid %s payload: %w", event, err)
+	}
+	// 同一个 secret 可能配在多个仓库上；CI 总是 checkout gitRepo，别的仓库的同名分支不能触发
+	if !strings.EqualFold(payload.Repository.FullName, strings.TrimSuffix(gitRepo, ".git")) {
+		return "", "", nil
+	}
+
+	if event == "push" {
+		branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/")
+		if !ok || payload.Deleted {
+			return "", "", nil
+		}
+		return branch, payload.After, nil
+	}
+
+	switch payload.Action {
+	case "opened", "reopened", "synchronize":
+	default:
+		return "", "", nil
+	}
+	pr := payload.PullRequest
+	// fork 的分支名可能和本仓库注册了 CI 的分支相同，不能拿它的代码跑到 lane 上
+	if pr.Head.Repo.FullName != pr.Base.Repo.FullName {
+		return "", "", nil
+	}
+	return pr.Head.Ref, pr.Head.SHA, nil
+}
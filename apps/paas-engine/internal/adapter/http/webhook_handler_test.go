@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testWebhookSecret = "s3cret"
+	testWebhookRepo   = "bezhai/chiwei-platform.git"
+)
+
+func signGitHubBody(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func doWebhook(h *GitHubWebhookHandler, event, body, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/github", bytes.NewReader([]byte(body)))
+	req.Header.Set("X-GitHub-Event", event)
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", signature)
+	}
+	rec := httptest.NewRecorder()
+	h.Handle(rec, req)
+	return rec
+}
+
+// TestGitHubWebhook_Signature 用 nil service 的 handler 验证签名校验，以及不需要触发 pipeline 的事件。
+func TestGitHubWebhook_Signature(t *testing.T) {
+	h := NewGitHubWebhookHandler(nil, testWebhookSecret, testWebhookRepo)
+	push := `{"ref":"refs/heads/feat/x","after":"0123456789abcdef0123456789abcdef01234567"}`
+	otherRepo := `{"repository":{"full_name":"someone/other"},"ref":"refs/heads/feat/x","after":"0123456789abcdef0123456789abcdef01234567"}`
+	big := `{"ref":"` + strings.Repeat("x", maxGitHubWebhookBody) + `"}`
+
+	cases := []struct {
+		name      string
+		handler   *GitHubWebhookHandler
+		event     string
+		body      string
+		signature string
+		want      int
+	}{
+		{"not configured", NewGitHubWebhookHandler(nil, "", testWebhookRepo), "push", push, signGitHubBody("", push), http.StatusNotFound},
+		{"missing signature", h, "push", push, "", http.StatusUnauthorized},
+		{"wrong secret", h, "push", push, signGitHubBody("other", push), http.StatusUnauthorized},
+		{"tampered body", h, "push", push, signGitHubBody(testWebhookSecret, push+" "), http.StatusUnauthorized},
+		{"not hex", h, "push", push, "sha256=zz", http.StatusUnauthorized},
+		{"ping", h, "ping", `{"zen":"hi"}`, signGitHubBody(testWebhookSecret, `{"zen":"hi"}`), http.StatusOK},
+		{"other event", h, "issues", `{}`, signGitHubBody(testWebhookSecret, `{}`), http.StatusAccepted},
+		{"tag push", h, "push", `{"ref":"refs/tags/v1"}`, signGitHubBody(testWebhookSecret, `{"ref":"refs/tags/v1"}`), http.StatusAccepted},
+		{"other repo", h, "push", otherRepo, signGitHubBody(testWebhookSecret, otherRepo), http.StatusAccepted},
+		{"bad payload", h, "push", `{`, signGitHubBody(testWebhookSecret, `{`), http.StatusBadRequest},
+		{"too large", h, "push", big, signGitHubBody(testWebhookSecret, big), http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := doWebhook(tc.handler, tc.event, tc.body, tc.signature)
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseGitHubPush(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	pr := func(action, headRepo string) string {
+		return `{"repository":{"full_name":"bezhai/chiwei-platform"},"action":"` + action + `","pull_request":{"head":{"ref":"feat/x","sha":"` + sha +
+			`","repo":{"full_name":"` + headRepo + `"}},"base":{"repo":{"full_name":"bezhai/chiwei-platform"}}}}`
+	}
+
+	cases := []struct {
+		name       string
+		event      string
+		body       string
+		wantBranch string
+	}{
+		{"push", "push", `{"repository":{"full_name":"bezhai/chiwei-platform"},"ref":"refs/heads/feat/x","after":"` + sha + `"}`, "feat/x"},
+		{"push to other repo", "push", `{"repository":{"full_name":"someone/chiwei-platform"},"ref":"refs/heads/feat/x","after":"` + sha + `"}`, ""},
+		{"push without repository", "push", `{"ref":"refs/heads/feat/x","after":"` + sha + `"}`, ""},
+		{"branch deleted", "push", `{"repository":{"full_name":"bezhai/chiwei-platform"},"ref":"refs/heads/feat/x","after":"0000000000000000000000000000000000000000","deleted":true}`, ""},
+		{"tag push", "push", `{"ref":"refs/tags/v1","after":"` + sha + `"}`, ""},
+		{"pr synchronize", "pull_request", pr("synchronize", "bezhai/chiwei-platform"), "feat/x"},
+		{"pr closed", "pull_request", pr("closed", "bezhai/chiwei-platform"), ""},
+		{"pr from fork", "pull_request", pr("opened", "someone/chiwei-platform"), ""},
+		{"other event", "workflow_run", `{}`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			branch, gotSHA, err := parseGitHubPush(tc.event, []byte(tc.body), testWebhookRepo)
+			if err != nil {
+				t.Fatalf("parseGitHubPush() error = %v", err)
+			}
+			if branch != tc.wantBranch {
+				t.Errorf("branch = %q, want %q", branch, tc.wantBranch)
+			}
+			if branch != "" && gotSHA != sha {
+				t.Errorf("sha = %q, want %q", gotSHA, sha)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const labelBuildID = "paas.chiwei/build-id"
+
+// labelBuilder 区分同一 namespace 里不同后端的构建 Job。早于该标签创建的 Job 没有它，按 kaniko 处理。
+const labelBuilder = "paas.chiwei/builder"
+
+// 构建容器把推送后的镜像 digest 写进 termination message，
+// Job 完成后从 Pod 状态里读回，不需要额外的存储或 sidecar。
+const digestFilePath = "/dev/termination-log"
+
+// buildJobs 是各构建后端共用的 Job 生命周期：Job / Pod 都打 build-id 和 builder 标签，
+// Watch / GetLogs / Cancel 的语义与后端无关，只有构建容器名和 digest 的写法不同。
+type buildJobs struct {
+	client    kubernetes.Interface
+	namespace string
+	builder   string                      // labelBuilder 的值，也是 Job 名前缀
+	container string                      // 执行构建的容器，读日志和 digest 用
+	digestOf  func(message string) string // 从容器 termination message 解析 digest
+}
+
+func (j *buildJobs) jobName(buildID string) string {
+	return fmt.Sprintf("%s-%s", j.builder, strings.ReplaceAll(buildID, "-", ""))
+}
+
+func (j *buildJobs) labels(buildID string) map[string]string {
+	return map[string]string{labelBuildID: buildID, labelBuilder: j.builder}
+}
+
+// newJob 按统一的 TTL / 重试 / 期限包装构建 Pod。
+func (j *buildJobs) newJob(buildID string, spec corev1.PodSpec, annotations map[string]string) *batchv1.Job {
+	ttl := int32(3600)
+	backoff := int32(0)
+	// 从 Job 创建时刻起算，排不上队的时间也算在内 —— 这正是要的：
+	// 没有期限时，调度不上的构建会永远停在 running（build 记录在 Submit 成功即写 running），
+	// Makefile 的轮询只认终态，于是卡死。观测到最慢的真实构建 425s，给 4 倍余量。
+	deadline := int64(1800)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      j.jobName(buildID),
+			Namespace: j.namespace,
+			Labels:    j.labels(buildID),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoff,
+			TTLSecondsAfterFinished: &ttl,
+			ActiveDeadlineSeconds:   &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      j.labels(buildID),
+					Annotations: annotations,
+				},
+				Spec: spec,
+			},
+		},
+	}
+}
+
+// create 创建 Job；有 secret 构建参数时先放进构建专用 Secret，以同名环境变量注入构建容器。
+func (j *buildJobs) create(ctx context.Context, job *batchv1.Job, sub *port.BuildSubmission) error {
+	var argsSecret *corev1.Secret
+	if len(sub.SecretBuildArgs) > 0 {
+		var err error
+		argsSecret, err = j.createBuildArgsSecret(ctx, job.Name, sub)
+		if err != nil {
+			return err
+		}
+		container := &job.Spec.Template.Spec.Containers[0]
+		for _, name := range slices.Sorted(maps.Keys(sub.SecretBuildArgs)) {
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name: name,
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: argsSecret.Name},
+					Key:                  name,
+				}},
+			})
+		}
+	}
+
+	created, err := j.client.BatchV1().Jobs(j.namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		if argsSecret != nil {
+			_ = j.client.CoreV1().Secrets(j.namespace).Delete(ctx, argsSecret.Name, metav1.DeleteOptions{})
+		}
+		return err
+	}
+	if argsSecret != nil {
+		j.adoptBuildArgsSecret(ctx, argsSecret, created)
+	}
+	return nil
+}
+
+// createBuildArgsSecret 把 secret 构建参数放进一个构建专用的 Secret。
+func (j *buildJobs) createBuildArgsSecret(ctx context.Context, jobName string, sub *port.BuildSubmission) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName + "-build-args",
+			Namespace: j.namespace,
+			Labels:    j.labels(sub.BuildID),
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: sub.SecretBuildArgs,
+	}
+	created, err := j.client.CoreV1().Secrets(j.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("create build args secret: %w", err)
+	}
+	return created, nil
+}
+
+// adoptBuildArgsSecret 让 Secret 归属 Job，Job 被 TTL 清理或取消删除时一并回收。
+func (j *buildJobs) adoptBuildArgsSecret(ctx context.Context, secret *corev1.Secret, job *batchv1.Job) {
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Name:       job.Name,
+		UID:        job.UID,
+	}}
+	if _, err := j.client.CoreV1().Secrets(j.namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		slog.Warn("failed to set owner of build args secret, it will not be garbage collected",
+			"secret", secret.Name, "job", job.Name, "error", err)
+	}
+}
+
+func (j *buildJobs) cancel(ctx context.Context, jobName string) error {
+	propagation := metav1.DeletePropagationForeground
+	return j.client.BatchV1().Jobs(j.namespace).Delete(ctx, jobName, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+}
+
+// owns 按 Job / Pod 的标签判断是否由本后端创建。
+func (j *buildJobs) owns(labels map[string]string) bool {
+	builder := labels[labelBuilder]
+	if builder == "" {
+		builder = domain.BuilderKaniko
+	}
+	return builder == j.builder
+}
+
+// watch 启动 Job Informer，监听本后端构建 Job 的状态变化。
+func (j *buildJobs) watch(ctx context.Context, callback port.BuildStatusCallback) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		j.client,
+		0,
+		informers.WithNamespace(j.namespace),
+	)
+	jobInformer := factory.Batch().V1().Jobs().Informer()
+
+	jobInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			job, ok := newObj.(*batchv1.Job)
+			if !ok || !j.owns(job.Labels) {
+				return
+			}
+			buildID, ok := job.Labels[labelBuildID]
+			if !ok {
+				return
+			}
+
+			status, log := jobToStatus(job)
+			if status == "" {
+				return
+			}
+			result := port.BuildResult{Status: status, Log: log}
+			if status == domain.BuildStatusSucceeded {
+				result.Digest = j.readDigest(ctx, buildID)
+			}
+			callback(buildID, result)
+		},
+	})
+
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// getLogs 通过 buildID label 找到 Pod，读取构建容器日志；没有本后端的 Pod 时返回空。
+func (j *buildJobs) getLogs(ctx context.Context, buildID string) (string, error) {
+	stream, err := j.streamLogs(ctx, buildID, false)
+	if err != nil || stream == nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("read pod logs for build %s: %w", buildID, err)
+	}
+	return string(data), nil
+}
+
+// streamLogs 打开构建容器的日志流，follow 时持续输出直到容器退出；没有本后端的 Pod 时返回 nil。
+func (j *buildJobs) streamLogs(ctx context.Context, buildID string, follow bool) (io.ReadCloser, error) {
+	pods, err := j.client.CoreV1().Pods(j.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", labelBuildID, buildID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pods for build %s: %w", buildID, err)
+	}
+	for _, pod := range pods.Items {
+		if !j.owns(pod.Labels) {
+			continue
+		}
+		stream, err := j.client.CoreV1().Pods(j.namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: j.container,
+			Follow:    follow,
+		}).Stream(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get pod logs %s: %w", pod.Name, err)
+		}
+		return stream, nil
+	}
+	return nil, nil
+}
+
+// readDigest 从构建容器的 termination message 读回推送的镜像 digest，读不到返回空。
+func (j *buildJobs) readDigest(ctx context.Context, buildID string) string {
+	pods, err := j.client.CoreV1().Pods(j.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", labelBuildID, buildID),
+	})
+	if err != nil {
+		slog.Warn("failed to list build pods for digest", "build_id", buildID, "error", err)
+		return ""
+	}
+	for _, pod := range pods.Items {
+		if !j.owns(pod.Labels) {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != j.container || cs.State.Terminated == nil || cs.State.Terminated.ExitCode != 0 {
+				continue
+			}
+			if digest := j.digestOf(cs.State.Terminated.Message); strings.HasPrefix(digest, "sha256:") {
+				return digest
+			}
+		}
+	}
+	return ""
+}
+
+// resolveCommit 返回本次构建固定的 commit：GitRef 已是完整 SHA 直接用，
+// 否则交给 resolver 解析；没有配置 resolver 时返回空，按原 ref 构建。
+func resolveCommit(ctx context.Context, resolver port.GitRefResolver, sub *port.BuildSubmission) (string, error) {
+	if len(sub.GitRef) == 40 && isCommitHash(sub.GitRef) {
+		return sub.GitRef, nil
+	}
+	if resolver == nil {
+		return "", nil
+	}
+	sha, err := resolver.ResolveCommit(ctx, sub.GitRepo, sub.GitRef)
+	if err != nil {
+		return "", fmt.Errorf("resolve git ref %s: %w", sub.GitRef, err)
+	}
+	return sha, nil
+}
+
+// contextGitRef 返回 git 构建上下文使用的 ref：已解析出 commit 用 commit，
+// 否则把 branch / tag 补全成 refs/heads/、refs/tags/。
+func contextGitRef(gitRef, commitSHA string) string {
+	if commitSHA != "" {
+		return commitSHA
+	}
+	if gitRef == "" || strings.HasPrefix(gitRef, "refs/") || isCommitHash(gitRef) {
+		return gitRef
+	}
+	if looksLikeTag(gitRef) {
+		return "refs/tags/" + gitRef
+	}
+	return "refs/heads/" + gitRef
+}
+
+func proxyEnv(httpProxy, noProxy string) []corev1.EnvVar {
+	if httpProxy == "" {
+		return nil
+	}
+	env := []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: httpProxy},
+		{Name: "HTTPS_PROXY", Value: httpProxy},
+		{Name: "http_proxy", Value: httpProxy},
+		{Name: "https_proxy", Value: httpProxy},
+	}
+	if noProxy != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "NO_PROXY", Value: noProxy},
+			corev1.EnvVar{Name: "no_proxy", Value: noProxy},
+		)
+	}
+	return env
+}
+
+// registryConfigVolume 把镜像仓库凭证 Secret 挂成 docker config.json。
+func registryConfigVolume(secretName string) corev1.Volume {
+	return corev1.Volume{
+		Name: "docker-config",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+				Items: []corev1.KeyToPath{
+					{Key: ".dockerconfigjson", Path: "config.json"},
+				},
+			},
+		},
+	}
+}
+
+func isCommitHash(ref string) bool {
+	if len(ref) < 7 || len(ref) > 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
+
+func looksLikeTag(ref string) bool {
+	return strings.HasPrefix(ref, "v") && len(ref) > 1 && ref[1] >= '0' && ref[1] <= '9'
+}
+
+func jobToStatus(job *batchv1.Job) (domain.BuildStatus, string) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobComplete && cond.Status == "True" {
+			return domain.BuildStatusSucceeded, ""
+		}
+		if cond.Type == batchv1.JobFailed && cond.Status == "True" {
+			return domain.BuildStatusFailed, cond.Message
+		}
+	}
+	if job.Status.Active > 0 {
+		return domain.BuildStatusRunning, ""
+	}
+	return "", ""
+}
@@ -0,0 +1,93 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+var _ port.BuildExecutor = (*BuildExecutorRouter)(nil)
+
+// BuildExecutorRouter 按 BuildSubmission.Builder 把构建分派给对应后端，对上层仍是单个 BuildExecutor。
+// Job 名以后端名开头（kaniko-xxx / buildkit-xxx），Cancel 据此找回后端。
+type BuildExecutorRouter struct {
+	executors map[string]port.BuildExecutor
+}
+
+// NewBuildExecutorRouter 以后端名（domain.BuilderKaniko 等）注册执行器，必须包含 kaniko 作为默认后端。
+func NewBuildExecutorRouter(executors map[string]port.BuildExecutor) *BuildExecutorRouter {
+	return &BuildExecutorRouter{executors: executors}
+}
+
+func (r *BuildExecutorRouter) executor(builder string) (port.BuildExecutor, error) {
+	if builder == "" {
+		builder = domain.BuilderKaniko
+	}
+	executor, ok := r.executors[builder]
+	if !ok {
+		return nil, fmt.Errorf("build backend %q is not enabled", builder)
+	}
+	return executor, nil
+}
+
+func (r *BuildExecutorRouter) Submit(ctx context.Context, sub *port.BuildSubmission) (*port.SubmittedBuild, error) {
+	executor, err := r.executor(sub.Builder)
+	if err != nil {
+		return nil, err
+	}
+	return executor.Submit(ctx, sub)
+}
+
+func (r *BuildExecutorRouter) Cancel(ctx context.Context, jobName string) error {
+	builder, _, _ := strings.Cut(jobName, "-")
+	executor, err := r.executor(builder)
+	if err != nil {
+		return err
+	}
+	return executor.Cancel(ctx, jobName)
+}
+
+// Watch 同时启动各后端的 Informer，任一返回即取消其余的并返回它的结果。
+func (r *BuildExecutorRouter) Watch(ctx context.Context, callback port.BuildStatusCallback) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(r.executors))
+	for _, executor := range r.executors {
+		go func() { errs <- executor.Watch(ctx, callback) }()
+	}
+	err := <-errs
+	cancel()
+	for range len(r.executors) - 1 {
+		<-errs
+	}
+	return err
+}
+
+// GetLogs 依次问各后端，构建 Pod 只属于其中一个，其余返回空。
+func (r *BuildExecutorRouter) GetLogs(ctx context.Context, buildID string) (string, error) {
+	for _, builder := range slices.Sorted(maps.Keys(r.executors)) {
+		logs, err := r.executors[builder].GetLogs(ctx, buildID)
+		if err != nil || logs != "" {
+			return logs, err
+		}
+	}
+	return "", nil
+}
+
+// FollowLogs 同 GetLogs，返回构建 Pod 所属后端的日志流。
+func (r *BuildExecutorRouter) FollowLogs(ctx context.Context, buildID string) (io.ReadCloser, error) {
+	for _, builder := range slices.Sorted(maps.Keys(r.executors)) {
+		stream, err := r.executors[builder].FollowLogs(ctx, buildID)
+		if err != nil || stream != nil {
+			return stream, err
+		}
+	}
+	return nil, nil
+}
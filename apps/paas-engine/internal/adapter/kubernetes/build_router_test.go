@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBuildExecutorRouter(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	router := NewBuildExecutorRouter(map[string]port.BuildExecutor{
+		"kaniko":   NewKanikoBuildExecutor(client, KanikoBuildConfig{Namespace: "paas-builds"}),
+		"buildkit": NewBuildKitBuildExecutor(client, BuildKitBuildConfig{Namespace: "paas-builds"}),
+	})
+	ctx := context.Background()
+
+	cases := []struct {
+		buildID string
+		builder string
+		wantJob string
+	}{
+		{"b1", "", "kaniko-b1"},
+		{"b2", "kaniko", "kaniko-b2"},
+		{"b3", "buildkit", "buildkit-b3"},
+	}
+	for _, c := range cases {
+		submitted, err := router.Submit(ctx, &port.BuildSubmission{
+			BuildID:  c.buildID,
+			GitRepo:  "example/repo",
+			GitRef:   "main",
+			ImageTag: "registry.example.com/app:1.0.0",
+			Builder:  c.builder,
+		})
+		if err != nil {
+			t.Fatalf("builder %q: Submit() error = %v", c.builder, err)
+		}
+		if submitted.JobName != c.wantJob {
+			t.Errorf("builder %q: job = %q, want %q", c.builder, submitted.JobName, c.wantJob)
+		}
+	}
+
+	if _, err := router.Submit(ctx, &port.BuildSubmission{BuildID: "b4", Builder: "docker"}); err == nil {
+		t.Error("expected error for unknown builder")
+	}
+
+	// Cancel 按 Job 名前缀找回后端
+	if err := router.Cancel(ctx, "buildkit-b3"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if _, err := client.BatchV1().Jobs("paas-builds").Get(ctx, "buildkit-b3", metav1.GetOptions{}); err == nil {
+		t.Error("buildkit job should be deleted")
+	}
+	if err := router.Cancel(ctx, "unknown-b9"); err == nil {
+		t.Error("expected error for job of unknown backend")
+	}
+}
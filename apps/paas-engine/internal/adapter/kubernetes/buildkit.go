@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var _ port.BuildExecutor = (*BuildKitBuildExecutor)(nil)
+
+// rootless buildkitd 跑在普通用户下，state 目录和凭证都在 /home/user 里
+const buildkitUserHome = "/home/user"
+
+// BuildKitBuildExecutor 用 rootless BuildKit（buildctl-daemonless.sh）在 Job 里构建，
+// Job / Pod 标签、TTL、期限、secret 参数注入与 Kaniko 一致，只是换了构建容器。
+type BuildKitBuildExecutor struct {
+	jobs               buildJobs
+	buildkitImage      string
+	registrySecret     string
+	insecureRegistries []string
+	cacheRepo          string
+	httpProxy          string
+	noProxy            string
+	refResolver        port.GitRefResolver
+}
+
+type BuildKitBuildConfig struct {
+	Namespace          string
+	BuildKitImage      string // 需为 rootless 变体，如 moby/buildkit:rootless
+	RegistrySecret     string
+	InsecureRegistries []string
+	CacheRepo          string // registry 缓存仓库，每个镜像仓库一个 tag
+	HttpProxy          string
+	NoProxy            string
+	RefResolver        port.GitRefResolver // 提交前把 GitRef 解析为 commit，nil 则按原 ref 构建
+}
+
+func NewBuildKitBuildExecutor(client kubernetes.Interface, cfg BuildKitBuildConfig) *BuildKitBuildExecutor {
+	return &BuildKitBuildExecutor{
+		jobs: buildJobs{
+			client:    client,
+			namespace: cfg.Namespace,
+			builder:   domain.BuilderBuildKit,
+			container: "buildkit",
+			digestOf:  buildkitDigest,
+		},
+		buildkitImage:      cfg.BuildKitImage,
+		registrySecret:     cfg.RegistrySecret,
+		insecureRegistries: cfg.InsecureRegistries,
+		cacheRepo:          cfg.CacheRepo,
+		httpProxy:          cfg.HttpProxy,
+		noProxy:            cfg.NoProxy,
+		refResolver:        cfg.RefResolver,
+	}
+}
+
+func (e *BuildKitBuildExecutor) Submit(ctx context.Context, sub *port.BuildSubmission) (*port.SubmittedBuild, error) {
+	commitSHA, err := resolveCommit(ctx, e.refResolver, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	// dockerfile 前端直接拉 git context：<url>#<ref>:<子目录>
+	gitContext := "https://github.com/" + sub.GitRepo
+	if !strings.HasSuffix(gitContext, ".git") {
+		gitContext += ".git"
+	}
+	gitContext += "#" + contextGitRef(sub.GitRef, commitSHA)
+	if sub.ContextDir != "" && sub.ContextDir != "." {
+		gitContext += ":" + sub.ContextDir
+	}
+
+	args := []string{
+		"build",
+		"--frontend=dockerfile.v0",
+		"--opt=context=" + gitContext,
+	}
+	if sub.Dockerfile != "" {
+		args = append(args, "--opt=filename="+sub.Dockerfile)
+	}
+	for _, name := range slices.Sorted(maps.Keys(sub.BuildArgs)) {
+		args = append(args, fmt.Sprintf("--opt=build-arg:%s=%s", name, sub.BuildArgs[name]))
+	}
+	// buildctl 不从环境变量取 build-arg，用 $(NAME) 让 kubelet 在启动容器时展开，值不进 Job spec
+	for _, name := range slices.Sorted(maps.Keys(sub.SecretBuildArgs)) {
+		args = append(args, fmt.Sprintf("--opt=build-arg:%s=$(%s)", name, name))
+	}
+	if sub.Target != "" {
+		args = append(args, "--opt=target="+sub.Target)
+	}
+	if sub.Platform != "" {
+		args = append(args, "--opt=platform="+sub.Platform)
+	}
+	for _, key := range slices.Sorted(maps.Keys(sub.Labels)) {
+		args = append(args, fmt.Sprintf("--opt=label:%s=%s", key, sub.Labels[key]))
+	}
+
+	insecure := ""
+	if slices.Contains(e.insecureRegistries, registryHost(sub.ImageTag)) {
+		insecure = ",registry.insecure=true"
+	}
+	if sub.NoCache {
+		args = append(args, "--no-cache")
+	} else if e.cacheRepo != "" {
+		cacheRef := e.cacheRepo + ":" + cacheTag(sub.ImageTag)
+		cacheInsecure := ""
+		if slices.Contains(e.insecureRegistries, registryHost(e.cacheRepo)) {
+			cacheInsecure = ",registry.insecure=true"
+		}
+		args = append(args,
+			fmt.Sprintf("--import-cache=type=registry,ref=%s%s", cacheRef, cacheInsecure),
+			fmt.Sprintf("--export-cache=type=registry,ref=%s,mode=max%s", cacheRef, cacheInsecure),
+		)
+	}
+	args = append(args,
+		fmt.Sprintf("--output=type=image,name=%s,push=true%s", sub.ImageTag, insecure),
+		"--metadata-file="+digestFilePath,
+	)
+
+	// rootless buildkitd 需要放开 seccomp / AppArmor，见 buildkit 仓库 examples/kubernetes
+	annotations := map[string]string{
+		"container.apparmor.security.beta.kubernetes.io/buildkit": "unconfined",
+	}
+	job := e.jobs.newJob(sub.BuildID, e.podSpec(args), annotations)
+	if err := e.jobs.create(ctx, job, sub); err != nil {
+		return nil, err
+	}
+	return &port.SubmittedBuild{JobName: job.Name, CommitSHA: commitSHA}, nil
+}
+
+func (e *BuildKitBuildExecutor) podSpec(args []string) corev1.PodSpec {
+	uid := int64(1000)
+	spec := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		// 与 kaniko 相同：构建需要外网，只有 app 节点有可用出口
+		NodeSelector: map[string]string{"node-role": "app"},
+		Containers: []corev1.Container{
+			{
+				Name:    "buildkit",
+				Image:   e.buildkitImage,
+				Command: []string{"buildctl-daemonless.sh"},
+				Args:    args,
+				Env: append(proxyEnv(e.httpProxy, e.noProxy),
+					// 非特权 Pod 里没有 user namespace 可用于构建步骤的进程隔离
+					corev1.EnvVar{Name: "BUILDKITD_FLAGS", Value: "--oci-worker-no-process-sandbox"},
+				),
+				SecurityContext: &corev1.SecurityContext{
+					RunAsUser:      &uid,
+					RunAsGroup:     &uid,
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "buildkitd", MountPath: buildkitUserHome + "/.local/share/buildkit"},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{
+			{Name: "buildkitd", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		},
+	}
+	if e.registrySecret != "" {
+		volume := registryConfigVolume(e.registrySecret)
+		spec.Volumes = append(spec.Volumes, volume)
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts,
+			corev1.VolumeMount{Name: volume.Name, MountPath: buildkitUserHome + "/.docker", ReadOnly: true},
+		)
+	}
+	return spec
+}
+
+func (e *BuildKitBuildExecutor) Cancel(ctx context.Context, jobName string) error {
+	return e.jobs.cancel(ctx, jobName)
+}
+
+// Watch 启动 Job Informer，监听 BuildKit Job 状态变化。
+func (e *BuildKitBuildExecutor) Watch(ctx context.Context, callback port.BuildStatusCallback) error {
+	return e.jobs.watch(ctx, callback)
+}
+
+// GetLogs 通过 buildID label 找到 Pod，读取 buildkit 容器日志。
+func (e *BuildKitBuildExecutor) GetLogs(ctx context.Context, buildID string) (string, error) {
+	return e.jobs.getLogs(ctx, buildID)
+}
+
+// FollowLogs 跟随 buildkit 容器日志直到容器退出。
+func (e *BuildKitBuildExecutor) FollowLogs(ctx context.Context, buildID string) (io.ReadCloser, error) {
+	return e.jobs.streamLogs(ctx, buildID, true)
+}
+
+// buildkitDigest 从 buildctl --metadata-file 写下的 JSON 里取镜像 digest。
+func buildkitDigest(message string) string {
+	var metadata struct {
+		Digest string `json:"containerimage.digest"`
+	}
+	if err := json.Unmarshal([]byte(message), &metadata); err != nil {
+		return ""
+	}
+	return metadata.Digest
+}
+
+// registryHost 取镜像引用的 registry 部分（第一段路径）。
+func registryHost(ref string) string {
+	host, _, _ := strings.Cut(ref, "/")
+	return host
+}
+
+// cacheTag 用镜像名（去掉 registry 路径和 tag）作为缓存 tag，同一镜像仓库的构建共用一份缓存。
+func cacheTag(imageTag string) string {
+	name := imageTag[strings.LastIndex(imageTag, "/")+1:]
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return "buildkit-" + name
+}
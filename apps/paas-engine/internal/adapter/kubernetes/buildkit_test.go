@@ -0,0 +1,138 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBuildKitSubmit(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	executor := NewBuildKitBuildExecutor(client, BuildKitBuildConfig{
+		Namespace:          "paas-builds",
+		BuildKitImage:      "moby/buildkit:rootless",
+		RegistrySecret:     "harbor-secret",
+		InsecureRegistries: []string{"harbor.local:30002"},
+		CacheRepo:          "harbor.local:30002/cache/build",
+		RefResolver:        &stubRefResolver{sha: "0123456789abcdef0123456789abcdef01234567"},
+	})
+
+	submitted, err := executor.Submit(context.Background(), &port.BuildSubmission{
+		BuildID:         "test-build-id",
+		GitRepo:         "example/repo",
+		GitRef:          "main",
+		ContextDir:      "apps/agent-service",
+		Dockerfile:      "Dockerfile.prod",
+		ImageTag:        "harbor.local:30002/inner-bot/agent-service:1.0.0",
+		BuildArgs:       map[string]string{"PYTHON_VERSION": "3.12"},
+		SecretBuildArgs: map[string]string{"PIP_TOKEN": "s3cret"},
+		Target:          "runtime",
+		Platform:        "linux/arm64",
+		Labels:          map[string]string{"org.opencontainers.image.version": "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if submitted.JobName != "buildkit-testbuildid" {
+		t.Errorf("JobName = %q, want buildkit-testbuildid", submitted.JobName)
+	}
+
+	job, err := client.BatchV1().Jobs("paas-builds").Get(context.Background(), submitted.JobName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if job.Labels[labelBuildID] != "test-build-id" || job.Spec.Template.Labels[labelBuilder] != "buildkit" {
+		t.Errorf("job labels = %v, pod labels = %v", job.Labels, job.Spec.Template.Labels)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Name != "buildkit" || container.Command[0] != "buildctl-daemonless.sh" {
+		t.Errorf("container = %s %v", container.Name, container.Command)
+	}
+	for _, want := range []string{
+		"--opt=context=https://github.com/example/repo.git#0123456789abcdef0123456789abcdef01234567:apps/agent-service",
+		"--opt=filename=Dockerfile.prod",
+		"--opt=build-arg:PYTHON_VERSION=3.12",
+		"--opt=build-arg:PIP_TOKEN=$(PIP_TOKEN)",
+		"--opt=target=runtime",
+		"--opt=platform=linux/arm64",
+		"--opt=label:org.opencontainers.image.version=1.0.0",
+		"--import-cache=type=registry,ref=harbor.local:30002/cache/build:buildkit-agent-service,registry.insecure=true",
+		"--output=type=image,name=harbor.local:30002/inner-bot/agent-service:1.0.0,push=true,registry.insecure=true",
+		"--metadata-file=/dev/termination-log",
+	} {
+		if !containsArg(container.Args, want) {
+			t.Errorf("expected arg %q, got %v", want, container.Args)
+		}
+	}
+	for _, a := range container.Args {
+		if strings.Contains(a, "s3cret") {
+			t.Fatalf("secret value leaked into job args: %v", container.Args)
+		}
+	}
+	// secret 值与 kaniko 一样经构建专用 Secret 注入
+	if _, err := client.CoreV1().Secrets("paas-builds").Get(context.Background(), "buildkit-testbuildid-build-args", metav1.GetOptions{}); err != nil {
+		t.Errorf("get build args secret: %v", err)
+	}
+}
+
+func TestBuildKitSubmit_NoCache(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	executor := NewBuildKitBuildExecutor(client, BuildKitBuildConfig{Namespace: "paas-builds", CacheRepo: "registry.example.com/cache"})
+
+	if _, err := executor.Submit(context.Background(), &port.BuildSubmission{
+		BuildID:  "b1",
+		GitRepo:  "example/repo.git",
+		GitRef:   "v1.2.0",
+		ImageTag: "registry.example.com/app:1.0.0",
+		NoCache:  true,
+	}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	jobs, _ := client.BatchV1().Jobs("paas-builds").List(context.Background(), metav1.ListOptions{})
+	args := jobs.Items[0].Spec.Template.Spec.Containers[0].Args
+	if !containsArg(args, "--opt=context=https://github.com/example/repo.git#refs/tags/v1.2.0") {
+		t.Errorf("expected tag context, got %v", args)
+	}
+	if !containsArg(args, "--no-cache") || containsArgPrefix(args, "--import-cache=") {
+		t.Errorf("no_cache build should not use registry cache: %v", args)
+	}
+}
+
+func TestBuildKitReadDigest(t *testing.T) {
+	pods := []corev1.Pod{
+		{
+			// 同一 namespace 里的 kaniko Pod（旧 Pod 没有 builder 标签）不归 BuildKit 管
+			ObjectMeta: metav1.ObjectMeta{Name: "kaniko-b1-abc", Namespace: "paas-builds", Labels: map[string]string{labelBuildID: "b1"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "buildkit-b1-xyz",
+				Namespace: "paas-builds",
+				Labels:    map[string]string{labelBuildID: "b1", labelBuilder: "buildkit"},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "buildkit",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: 0,
+						Message:  `{"containerimage.digest":"sha256:0123abcd","image.name":"registry.example.com/app:1.0.0"}`,
+					}},
+				}},
+			},
+		},
+	}
+	executor := NewBuildKitBuildExecutor(fake.NewSimpleClientset(&pods[0], &pods[1]), BuildKitBuildConfig{Namespace: "paas-builds"})
+
+	if got := executor.jobs.readDigest(context.Background(), "b1"); got != "sha256:0123abcd" {
+		t.Errorf("readDigest() = %q, want sha256:0123abcd", got)
+	}
+	if got := buildkitDigest("not json"); got != "" {
+		t.Errorf("buildkitDigest(invalid) = %q, want empty", got)
+	}
+}
@@ -38,10 +38,21 @@ func NewK8sDeployer(client kubernetes.Interface, namespace, sidecarImage string)
 	return &K8sDeployer{client: client, namespace: namespace, sidecarImage: sidecarImage}
 }
 
-func (d *K8sDeployer) Deploy(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) error {
+func (d *K8sDeployer) Apply(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) error {
+	// 关闭自动伸缩时先删 HPA，避免它在 Deployment 改回固定副本数之后又把副本改掉
+	if release.Autoscaling == nil {
+		if err := d.deleteHPA(ctx, release.ResourceName()); err != nil {
+			return err
+		}
+	}
 	if err := d.applyDeployment(ctx, release, app, bundleEnvs); err != nil {
 		return fmt.Errorf("apply deployment: %w", err)
 	}
+	if release.Autoscaling != nil {
+		if err := d.applyHPA(ctx, release); err != nil {
+			return fmt.Errorf("apply hpa: %w", err)
+		}
+	}
 	if app.Port > 0 { // Worker 无端口，跳过 Service
 		if err := d.applyService(ctx, release, app); err != nil {
 			return fmt.Errorf("apply service: %w", err)
@@ -50,14 +61,48 @@ func (d *K8sDeployer) Deploy(ctx context.Context, release *domain.Release, app *
 			return fmt.Errorf("apply base service: %w", err)
 		}
 	}
-	if err := d.waitForRollout(ctx, release.ResourceName()); err != nil {
+	return nil
+}
+
+func (d *K8sDeployer) WaitForRollout(ctx context.Context, name string, onEvent func(domain.ReleaseOperationEvent)) error {
+	if err := d.waitForRollout(ctx, name, onEvent); err != nil {
 		return fmt.Errorf("wait for rollout: %w", err)
 	}
 	return nil
 }
 
+func (d *K8sDeployer) Scale(ctx context.Context, release *domain.Release, replicas int32) (int32, error) {
+	name := release.ResourceName()
+	if replicas == 0 {
+		if err := d.deleteHPA(ctx, name); err != nil {
+			return 0, err
+		}
+	}
+	deploy, err := d.client.AppsV1().Deployments(d.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("get deployment %s: %w", name, err)
+	}
+	var previous int32 = 1
+	if deploy.Spec.Replicas != nil {
+		previous = *deploy.Spec.Replicas
+	}
+	deploy.Spec.Replicas = &replicas
+	if _, err := d.client.AppsV1().Deployments(d.namespace).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+		return 0, fmt.Errorf("scale deployment %s: %w", name, err)
+	}
+	if replicas > 0 && release.Autoscaling != nil {
+		if err := d.applyHPA(ctx, release); err != nil {
+			return 0, fmt.Errorf("apply hpa: %w", err)
+		}
+	}
+	return previous, nil
+}
+
 func (d *K8sDeployer) Delete(ctx context.Context, release *domain.Release, hasOtherReleases bool) error {
 	name := release.ResourceName()
+	if err := d.deleteHPA(ctx, name); err != nil {
+		return err
+	}
 	if err := d.client.AppsV1().Deployments(d.namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("delete deployment %s: %w", name, err)
 	}
@@ -84,6 +129,10 @@ func (d *K8sDeployer) DeleteResource(ctx context.Context, kind, name string) err
 		if err := d.client.CoreV1().Services(d.namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 			return fmt.Errorf("delete service %s: %w", name, err)
 		}
+	case "HorizontalPodAutoscaler":
+		if err := d.deleteHPA(ctx, name); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unsupported resource kind: %s", kind)
 	}
@@ -123,6 +172,21 @@ func (d *K8sDeployer) ListManagedResources(ctx context.Context) ([]port.ManagedR
 		})
 	}
 
+	hpas, err := d.client.AutoscalingV2().HorizontalPodAutoscalers(d.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list hpas: %w", err)
+	}
+	for _, hpa := range hpas.Items {
+		resources = append(resources, port.ManagedResource{
+			Kind:    "HorizontalPodAutoscaler",
+			Name:    hpa.Name,
+			AppName: hpa.Labels["app"],
+			Lane:    hpa.Labels["lane"],
+		})
+	}
+
 	return resources, nil
 }
 
@@ -143,6 +207,9 @@ func (d *K8sDeployer) GetDeploymentStatus(ctx context.Context, name string) (*do
 		Ready:      deploy.Status.ReadyReplicas,
 		Available:  deploy.Status.AvailableReplicas,
 	}
+	if status.Autoscaling, err = d.getHPAStatus(ctx, name); err != nil {
+		return nil, err
+	}
 
 	// 通过 Deployment selector 查找 Pods
 	selector := deploy.Spec.Selector.MatchLabels
@@ -188,20 +255,56 @@ func (d *K8sDeployer) GetDeploymentStatus(ctx context.Context, name string) (*do
 
 func (d *K8sDeployer) applyDeployment(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) error {
 	name := release.ResourceName()
-	labels := map[string]string{
-		"app":  release.AppName,
-		"lane": release.Lane,
-	}
 
 	// Bundle envs → auto-managed K8s Secret
-	var bundleSecretName string
 	if len(bundleEnvs) > 0 {
-		bundleSecretName = name + "-config"
-		if err := d.applySecret(ctx, bundleSecretName, bundleEnvs); err != nil {
+		if err := d.applySecret(ctx, bundleSecretName(release), bundleEnvs); err != nil {
 			return fmt.Errorf("apply config secret: %w", err)
 		}
 	}
 
+	deploy, err := d.buildDeployment(release, app, len(bundleEnvs) > 0)
+	if err != nil {
+		return err
+	}
+
+	existing, err := d.client.AppsV1().Deployments(d.namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = d.client.AppsV1().Deployments(d.namespace).Create(ctx, deploy, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	// HPA 接管副本数时保留线上值，否则每次下发都会把 HPA 扩出来的副本缩回去；
+	// 线上为 0（lane 休眠中）时不保留，HPA 不会把 0 副本的 Deployment 扩起来
+	if release.Autoscaling != nil && existing.Spec.Replicas != nil && *existing.Spec.Replicas > 0 {
+		deploy.Spec.Replicas = existing.Spec.Replicas
+	}
+	existing.Spec = deploy.Spec
+	_, err = d.client.AppsV1().Deployments(d.namespace).Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// bundleSecretName 是 ConfigBundle 解析结果落地的 Secret 名。
+func bundleSecretName(release *domain.Release) string {
+	return release.ResourceName() + "-config"
+}
+
+// buildDeployment 按 App + Release 渲染期望的 Deployment，不访问集群。
+// hasBundle 表示需要挂载 ConfigBundle Secret。
+func (d *K8sDeployer) buildDeployment(release *domain.Release, app *domain.App, hasBundle bool) (*appsv1.Deployment, error) {
+	name := release.ResourceName()
+	labels := map[string]string{
+		"app":  release.AppName,
+		"lane": release.Lane,
+	}
+
+	var bundleSecret string
+	if hasBundle {
+		bundleSecret = bundleSecretName(release)
+	}
+
 	mergedEnvs := mergeEnvs(app.Envs, release.Envs)
 	if release.Version != "" {
 		mergedEnvs["VERSION"] = release.Version
@@ -212,19 +315,23 @@ func (d *K8sDeployer) applyDeployment(ctx context.Context, release *domain.Relea
 
 	// EnvFrom: legacy sources first, then bundle secret (bundle overrides legacy for duplicate keys)
 	envFrom := buildEnvFrom(app.EnvFromSecrets, app.EnvFromConfigMaps)
-	if bundleSecretName != "" {
+	if bundleSecret != "" {
 		envFrom = append(envFrom, corev1.EnvFromSource{
 			SecretRef: &corev1.SecretEnvSource{
-				LocalObjectReference: corev1.LocalObjectReference{Name: bundleSecretName},
+				LocalObjectReference: corev1.LocalObjectReference{Name: bundleSecret},
 			},
 		})
 	}
 
 	replicas := release.Replicas
+	if release.Autoscaling != nil {
+		// 首次创建从 HPA 下限起步，之后由 HPA 调整
+		replicas = release.Autoscaling.MinReplicas
+	}
 
 	container := corev1.Container{
 		Name:    app.Name,
-		Image:   release.Image,
+		Image:   release.DeployImage(),
 		EnvFrom: envFrom,
 		Env:     envVars,
 	}
@@ -237,12 +344,42 @@ func (d *K8sDeployer) applyDeployment(ctx context.Context, release *domain.Relea
 		}
 	}
 
+	// Resources / Probes：App 默认值叠加 Release 覆盖。readiness 决定 AvailableReplicas，
+	// 配了之后 waitForRollout 等到的是"服务健康"而不只是"容器已启动"。
+	resources, err := buildResources(domain.EffectiveResources(app, release))
+	if err != nil {
+		return nil, err
+	}
+	container.Resources = resources
+	if probes := domain.EffectiveProbes(app, release); probes != nil {
+		container.ReadinessProbe = buildProbe(probes.Readiness, app.Port)
+		container.LivenessProbe = buildProbe(probes.Liveness, app.Port)
+		container.StartupProbe = buildProbe(probes.Startup, app.Port)
+	}
+
 	// Volume mounts from App.Volumes
 	pvcVolumes, pvcMounts := buildPVCVolumes(app.Volumes)
 	container.VolumeMounts = pvcMounts
 
+	// App 声明的附加容器：用户 init 容器排在 lane-sidecar-init 之前，
+	// 避免 iptables 劫持后迁移等任务连不上外部服务；sidecar 排在主容器之后。
 	var initContainers []corev1.Container
 	var sidecarContainers []corev1.Container
+	for _, ec := range app.InitContainers {
+		c, err := buildExtraContainer(ec, release.DeployImage(), mergedEnvs, envFrom)
+		if err != nil {
+			return nil, fmt.Errorf("init container %s: %w", ec.Name, err)
+		}
+		initContainers = append(initContainers, c)
+	}
+	for _, ec := range app.Sidecars {
+		c, err := buildExtraContainer(ec, release.DeployImage(), mergedEnvs, envFrom)
+		if err != nil {
+			return nil, fmt.Errorf("sidecar %s: %w", ec.Name, err)
+		}
+		sidecarContainers = append(sidecarContainers, c)
+	}
+	pvcVolumes = mergePodVolumes(pvcVolumes, app.InitContainers, app.Sidecars)
 
 	if app.SidecarEnabled {
 		sidecarImage := d.sidecarImage
@@ -262,7 +399,7 @@ func (d *K8sDeployer) applyDeployment(ctx context.Context, release *domain.Relea
 		})
 
 		sidecarContainers = append(sidecarContainers, corev1.Container{
-			Name:            "lane-sidecar",
+			Name:            sidecarContainerName,
 			Image:           sidecarImage,
 			ImagePullPolicy: corev1.PullAlways,
 			Env: []corev1.EnvVar{
@@ -312,18 +449,7 @@ func (d *K8sDeployer) applyDeployment(ctx context.Context, release *domain.Relea
 			},
 		},
 	}
-
-	existing, err := d.client.AppsV1().Deployments(d.namespace).Get(ctx, name, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		_, err = d.client.AppsV1().Deployments(d.namespace).Create(ctx, deploy, metav1.CreateOptions{})
-		return err
-	}
-	if err != nil {
-		return err
-	}
-	existing.Spec = deploy.Spec
-	_, err = d.client.AppsV1().Deployments(d.namespace).Update(ctx, existing, metav1.UpdateOptions{})
-	return err
+	return deploy, nil
 }
 
 func (d *K8sDeployer) applyService(ctx context.Context, release *domain.Release, app *domain.App) error {
@@ -497,6 +623,56 @@ func buildPVCVolumes(appVolumes []domain.VolumeMount) ([]corev1.Volume, []corev1
 	return volumes, mounts
 }
 
+// buildExtraContainer 渲染 App 声明的 init 容器 / sidecar。Image 为空时沿用 release 镜像；
+// InheritEnvs 时带上主容器的 env 和 envFrom（含 ConfigBundle Secret），自身 Envs 优先。
+func buildExtraContainer(ec domain.ExtraContainer, releaseImage string, mainEnvs map[string]string, mainEnvFrom []corev1.EnvFromSource) (corev1.Container, error) {
+	image := ec.Image
+	if image == "" {
+		image = releaseImage
+	}
+	envs := ec.Envs
+	c := corev1.Container{
+		Name:    ec.Name,
+		Image:   image,
+		Command: ec.Command,
+		Args:    ec.Args,
+	}
+	if ec.InheritEnvs {
+		envs = mergeEnvs(mainEnvs, ec.Envs)
+		c.EnvFrom = mainEnvFrom
+	}
+	if len(envs) > 0 {
+		c.Env = envsToK8s(envs)
+	}
+	resources, err := buildResources(ec.Resources)
+	if err != nil {
+		return corev1.Container{}, err
+	}
+	c.Resources = resources
+	_, c.VolumeMounts = buildPVCVolumes(ec.Volumes)
+	return c, nil
+}
+
+// mergePodVolumes 把附加容器用到的 PVC 补进 Pod 的 Volume 列表，与主容器共用同名 Volume。
+func mergePodVolumes(volumes []corev1.Volume, containerGroups ...[]domain.ExtraContainer) []corev1.Volume {
+	seen := make(map[string]bool, len(volumes))
+	for _, v := range volumes {
+		seen[v.Name] = true
+	}
+	for _, group := range containerGroups {
+		for _, ec := range group {
+			extra, _ := buildPVCVolumes(ec.Volumes)
+			for _, v := range extra {
+				if !seen[v.Name] {
+					seen[v.Name] = true
+					volumes = append(volumes, v)
+				}
+			}
+		}
+	}
+	return volumes
+}
+
 func envsToK8s(envs map[string]string) []corev1.EnvVar {
 	result := make([]corev1.EnvVar, 0, len(envs))
 	for k, v := range envs {
@@ -512,47 +688,83 @@ const (
 
 // waitForRollout 轮询 Deployment 直到所有副本就绪或超时。
 // 除了检查 Deployment 级别状态，还会检测 Pod 级别的 CrashLoopBackOff 以快速失败。
-func (d *K8sDeployer) waitForRollout(ctx context.Context, name string) error {
+// 第一次检查不等 ticker：进程重启后接管的操作往往早已 rollout 完。
+func (d *K8sDeployer) waitForRollout(ctx context.Context, name string, onEvent func(domain.ReleaseOperationEvent)) error {
 	ctx, cancel := context.WithTimeout(ctx, rolloutTimeout)
 	defer cancel()
 
 	ticker := time.NewTicker(rolloutInterval)
 	defer ticker.Stop()
 
+	report := func(eventType, msg string) {
+		if onEvent != nil {
+			onEvent(domain.ReleaseOperationEvent{Type: eventType, Message: msg})
+		}
+	}
+	var lastRS string
+	lastReady := int32(-1)
+
 	for {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("deployment %s rollout timed out after %s", name, rolloutTimeout)
-		case <-ticker.C:
-			deploy, err := d.client.AppsV1().Deployments(d.namespace).Get(ctx, name, metav1.GetOptions{})
-			if err != nil {
-				return fmt.Errorf("get deployment %s: %w", name, err)
+		deploy, err := d.client.AppsV1().Deployments(d.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return d.rolloutCtxErr(ctx, name)
 			}
+			return fmt.Errorf("get deployment %s: %w", name, err)
+		}
 
-			// Progressing condition 为 False 表示部署卡住
-			for _, cond := range deploy.Status.Conditions {
-				if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse {
-					return fmt.Errorf("deployment %s is not progressing: %s", name, cond.Message)
-				}
+		// Progressing condition 为 False 表示部署卡住
+		for _, cond := range deploy.Status.Conditions {
+			if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse {
+				return fmt.Errorf("deployment %s is not progressing: %s", name, cond.Message)
 			}
+		}
 
-			// 检测 Pod 级别的 CrashLoopBackOff，快速失败而非等待超时
-			if reason, ok := d.detectPodFailure(ctx, deploy); ok {
-				return fmt.Errorf("deployment %s failed: %s", name, reason)
-			}
+		if rs := d.getLatestRS(ctx, deploy); rs != nil && rs.Name != lastRS {
+			lastRS = rs.Name
+			report(domain.OperationEventReplicaSetCreated, fmt.Sprintf("replicaset %s created (revision %s)",
+				rs.Name, rs.Annotations["deployment.kubernetes.io/revision"]))
+		}
 
-			spec := deploy.Spec
-			status := deploy.Status
-			if status.ObservedGeneration >= deploy.Generation &&
-				status.UpdatedReplicas == *spec.Replicas &&
-				status.AvailableReplicas == *spec.Replicas {
-				slog.Info("deployment rollout complete", "name", name)
-				return nil
-			}
+		// 检测 Pod 级别的 CrashLoopBackOff，快速失败而非等待超时
+		if reason, ok := d.detectPodFailure(ctx, deploy); ok {
+			return fmt.Errorf("deployment %s failed: %s", name, reason)
+		}
+
+		spec := deploy.Spec
+		status := deploy.Status
+		// 新旧 RS 交替期间只统计新版本的就绪数
+		ready := status.UpdatedReplicas
+		if status.AvailableReplicas < ready {
+			ready = status.AvailableReplicas
+		}
+		if ready != lastReady {
+			lastReady = ready
+			report(domain.OperationEventPodsReady, fmt.Sprintf("pods ready %d/%d", ready, *spec.Replicas))
+		}
+		if status.ObservedGeneration >= deploy.Generation &&
+			status.UpdatedReplicas == *spec.Replicas &&
+			status.AvailableReplicas == *spec.Replicas {
+			slog.Info("deployment rollout complete", "name", name)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return d.rolloutCtxErr(ctx, name)
+		case <-ticker.C:
 		}
 	}
 }
 
+// rolloutCtxErr 区分超时和被调用方取消（新操作顶替了旧操作）。
+func (d *K8sDeployer) rolloutCtxErr(ctx context.Context, name string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("deployment %s rollout timed out after %s", name, rolloutTimeout)
+	}
+	return ctx.Err()
+}
+
 // detectPodFailure 检查 Deployment 最新 ReplicaSet 的 Pod 是否存在不可恢复的失败状态。
 // 返回失败原因和是否检测到失败。
 func (d *K8sDeployer) detectPodFailure(ctx context.Context, deploy *appsv1.Deployment) (string, bool) {
@@ -595,8 +807,12 @@ func (d *K8sDeployer) detectPodFailure(ctx context.Context, deploy *appsv1.Deplo
 
 		for _, cs := range pod.Status.InitContainerStatuses {
 			if cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff" {
-				return fmt.Sprintf("pod %s init container is in CrashLoopBackOff: %s",
-					pod.Name, cs.State.Waiting.Message), true
+				return fmt.Sprintf("pod %s init container %s is in CrashLoopBackOff: %s",
+					pod.Name, cs.Name, cs.State.Waiting.Message), true
+			}
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason == "ImagePullBackOff" {
+				return fmt.Sprintf("pod %s init container %s failed to pull image: %s",
+					pod.Name, cs.Name, cs.State.Waiting.Message), true
 			}
 		}
 	}
@@ -605,8 +821,16 @@ func (d *K8sDeployer) detectPodFailure(ctx context.Context, deploy *appsv1.Deplo
 }
 
 // getLatestRSHash 获取 Deployment 最新 ReplicaSet 的 pod-template-hash。
-// 最新 RS 通过 revision annotation 判断。
 func (d *K8sDeployer) getLatestRSHash(ctx context.Context, deploy *appsv1.Deployment) string {
+	rs := d.getLatestRS(ctx, deploy)
+	if rs == nil {
+		return ""
+	}
+	return rs.Labels["pod-template-hash"]
+}
+
+// getLatestRS 获取 Deployment 最新的 ReplicaSet，最新 RS 通过 revision annotation 判断。
+func (d *K8sDeployer) getLatestRS(ctx context.Context, deploy *appsv1.Deployment) *appsv1.ReplicaSet {
 	selector := deploy.Spec.Selector.MatchLabels
 	labelSelector := ""
 	for k, v := range selector {
@@ -621,12 +845,13 @@ func (d *K8sDeployer) getLatestRSHash(ctx context.Context, deploy *appsv1.Deploy
 	})
 	if err != nil {
 		slog.Warn("failed to list replicasets for hash lookup", "error", err)
-		return ""
+		return nil
 	}
 
 	var latestRevision int64
-	var latestHash string
-	for _, rs := range rsList.Items {
+	var latest *appsv1.ReplicaSet
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
 		revStr := rs.Annotations["deployment.kubernetes.io/revision"]
 		if revStr == "" {
 			continue
@@ -637,8 +862,8 @@ func (d *K8sDeployer) getLatestRSHash(ctx context.Context, deploy *appsv1.Deploy
 		}
 		if rev > latestRevision {
 			latestRevision = rev
-			latestHash = rs.Labels["pod-template-hash"]
+			latest = rs
 		}
 	}
-	return latestHash
+	return latest
 }
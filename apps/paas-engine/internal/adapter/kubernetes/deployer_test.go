@@ -83,6 +83,21 @@ func TestDetectPodFailure(t *testing.T) {
 			wantFail:   true,
 			wantReason: "init container",
 		},
+		{
+			name: "init container ImagePullBackOff on latest RS",
+			objects: []runtime.Object{latestRS, oldRS, func() *corev1.Pod {
+				pod := makePod("myapp-prod-abc", labels, latestHash, nil)
+				pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+					Name: "migrate",
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "manifest unknown"},
+					},
+				}}
+				return pod
+			}()},
+			wantFail:   true,
+			wantReason: "init container migrate failed to pull image",
+		},
 		{
 			name:     "no pods",
 			objects:  []runtime.Object{latestRS},
@@ -323,9 +338,7 @@ func TestDeployWorkerSkipsService(t *testing.T) {
 		Replicas: 1,
 	}
 
-	// 使用 Deploy（而非 applyDeployment）来验证 Service 逻辑
-	// 注意: Deploy 会调用 waitForRollout，fake client 的 Deployment 没有 Status，
-	// 所以这里直接测试 applyDeployment + 检查 Service 不存在
+	// 直接测试 applyDeployment + 检查 Service 不存在
 	if err := deployer.applyDeployment(context.Background(), release, app, nil); err != nil {
 		t.Fatalf("applyDeployment() error = %v", err)
 	}
@@ -336,7 +349,7 @@ func TestDeployWorkerSkipsService(t *testing.T) {
 		t.Fatalf("Deployment should exist: %v", err)
 	}
 
-	// 验证 Service 不存在（Deploy 在 Port=0 时跳过 applyService）
+	// 验证 Service 不存在（Apply 在 Port=0 时跳过 applyService）
 	svcs, err := client.CoreV1().Services("default").List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		t.Fatalf("List Services error = %v", err)
@@ -705,3 +718,191 @@ func TestBuildEnvFrom(t *testing.T) {
 		})
 	}
 }
+
+// TestApplyDeploymentResourcesAndProbes 验证 App 的 resources/probes 叠加 Release 覆盖后下发到主容器。
+func TestApplyDeploymentResourcesAndProbes(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	deployer := NewK8sDeployer(client, "default", "")
+
+	app := &domain.App{
+		Name:      "web-service",
+		Port:      8080,
+		Resources: &domain.ResourceSpec{CPURequest: "100m", CPULimit: "1", MemoryRequest: "128Mi", MemoryLimit: "512Mi"},
+		Probes: &domain.HealthProbes{
+			Readiness: &domain.ProbeSpec{Type: domain.ProbeTypeHTTP, Path: "/healthz", PeriodSeconds: 5},
+			Liveness:  &domain.ProbeSpec{Type: domain.ProbeTypeTCP, Port: 9000},
+		},
+	}
+	release := &domain.Release{
+		ID:        "r-res",
+		AppName:   "web-service",
+		Lane:      "prod",
+		Image:     "harbor.local/inner-bot/web-service:abc123",
+		Replicas:  1,
+		Resources: &domain.ResourceSpec{MemoryLimit: "1Gi"},
+		Probes: &domain.HealthProbes{
+			Startup: &domain.ProbeSpec{Type: domain.ProbeTypeExec, Command: []string{"cat", "/tmp/started"}, FailureThreshold: 30},
+		},
+	}
+
+	if err := deployer.applyDeployment(context.Background(), release, app, nil); err != nil {
+		t.Fatalf("applyDeployment() error = %v", err)
+	}
+	deploy, err := client.AppsV1().Deployments("default").Get(context.Background(), "web-service-prod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get Deployment error = %v", err)
+	}
+	container := deploy.Spec.Template.Spec.Containers[0]
+
+	if got := container.Resources.Requests.Cpu().String(); got != "100m" {
+		t.Errorf("cpu request = %s, want 100m", got)
+	}
+	if got := container.Resources.Limits.Memory().String(); got != "1Gi" {
+		t.Errorf("memory limit = %s, want release override 1Gi", got)
+	}
+
+	rp := container.ReadinessProbe
+	if rp == nil || rp.HTTPGet == nil || rp.HTTPGet.Path != "/healthz" || rp.HTTPGet.Port.IntValue() != 8080 || rp.PeriodSeconds != 5 {
+		t.Errorf("readiness probe = %+v", rp)
+	}
+	lp := container.LivenessProbe
+	if lp == nil || lp.TCPSocket == nil || lp.TCPSocket.Port.IntValue() != 9000 {
+		t.Errorf("liveness probe = %+v", lp)
+	}
+	sp := container.StartupProbe
+	if sp == nil || sp.Exec == nil || len(sp.Exec.Command) != 2 || sp.FailureThreshold != 30 {
+		t.Errorf("startup probe = %+v", sp)
+	}
+}
+
+// TestApplyDeploymentExtraContainers 验证 App 声明的 init 容器和 sidecar：
+// 用户 init 容器排在 lane-sidecar-init 之前，sidecar 排在主容器之后，
+// 空镜像沿用 release 镜像，InheritEnvs 继承主容器 env / envFrom，PVC Volume 与主容器共用。
+func TestApplyDeploymentExtraContainers(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	deployer := NewK8sDeployer(client, "default", "harbor.local/lane-sidecar:v1")
+
+	app := &domain.App{
+		Name:           "agent-service",
+		Port:           8000,
+		SidecarEnabled: true,
+		Envs:           map[string]string{"DB_HOST": "pg", "LOG_LEVEL": "info"},
+		Volumes:        []domain.VolumeMount{{PVCName: "shared-data", MountPath: "/data"}},
+		InitContainers: []domain.ExtraContainer{{
+			Name:        "migrate",
+			Command:     []string{"alembic", "upgrade", "head"},
+			InheritEnvs: true,
+			Envs:        map[string]string{"LOG_LEVEL": "debug"},
+		}},
+		Sidecars: []domain.ExtraContainer{{
+			Name:      "log-shipper",
+			Image:     "harbor.local/vector:0.40",
+			Args:      []string{"--config", "/etc/vector.toml"},
+			Volumes:   []domain.VolumeMount{{PVCName: "shared-data", MountPath: "/logs", ReadOnly: true}, {PVCName: "vector-state", MountPath: "/state"}},
+			Resources: &domain.ResourceSpec{MemoryLimit: "128Mi"},
+		}},
+	}
+	release := &domain.Release{
+		ID:       "r-extra",
+		AppName:  "agent-service",
+		Lane:     "prod",
+		Image:    "harbor.local/inner-bot/agent-service:abc123",
+		Replicas: 1,
+	}
+
+	if err := deployer.applyDeployment(context.Background(), release, app, map[string]string{"API_KEY": "x"}); err != nil {
+		t.Fatalf("applyDeployment() error = %v", err)
+	}
+	deploy, err := client.AppsV1().Deployments("default").Get(context.Background(), "agent-service-prod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get Deployment error = %v", err)
+	}
+	spec := deploy.Spec.Template.Spec
+
+	if len(spec.InitContainers) != 2 || spec.InitContainers[0].Name != "migrate" || spec.InitContainers[1].Name != "lane-sidecar-init" {
+		t.Fatalf("init containers = %+v, want [migrate lane-sidecar-init]", spec.InitContainers)
+	}
+	migrate := spec.InitContainers[0]
+	if migrate.Image != release.Image {
+		t.Errorf("migrate image = %q, want release image", migrate.Image)
+	}
+	envs := map[string]string{}
+	for _, e := range migrate.Env {
+		envs[e.Name] = e.Value
+	}
+	if envs["DB_HOST"] != "pg" || envs["LOG_LEVEL"] != "debug" || envs["LANE"] != "prod" {
+		t.Errorf("migrate envs = %v, want inherited envs with own override", envs)
+	}
+	if len(migrate.EnvFrom) != 1 || migrate.EnvFrom[0].SecretRef == nil || migrate.EnvFrom[0].SecretRef.Name != "agent-service-prod-config" {
+		t.Errorf("migrate envFrom = %+v, want bundle secret", migrate.EnvFrom)
+	}
+
+	if len(spec.Containers) != 3 || spec.Containers[0].Name != "agent-service" || spec.Containers[1].Name != "log-shipper" || spec.Containers[2].Name != "lane-sidecar" {
+		t.Fatalf("containers = %+v, want [agent-service log-shipper lane-sidecar]", spec.Containers)
+	}
+	shipper := spec.Containers[1]
+	if shipper.Image != "harbor.local/vector:0.40" || len(shipper.Args) != 2 {
+		t.Errorf("log-shipper = %+v", shipper)
+	}
+	if len(shipper.Env) != 0 || len(shipper.EnvFrom) != 0 {
+		t.Errorf("log-shipper should not inherit envs, got env=%v envFrom=%v", shipper.Env, shipper.EnvFrom)
+	}
+	if got := shipper.Resources.Limits.Memory().String(); got != "128Mi" {
+		t.Errorf("log-shipper memory limit = %s, want 128Mi", got)
+	}
+	if len(shipper.VolumeMounts) != 2 || !shipper.VolumeMounts[0].ReadOnly {
+		t.Errorf("log-shipper mounts = %+v", shipper.VolumeMounts)
+	}
+
+	// shared-data 与主容器共用一个 Volume，vector-state 补进 Pod
+	if len(spec.Volumes) != 2 || spec.Volumes[0].Name != "shared-data" || spec.Volumes[1].Name != "vector-state" {
+		t.Errorf("volumes = %+v, want [shared-data vector-state]", spec.Volumes)
+	}
+}
+
+// TestWaitForRolloutReportsProgress 验证已完成 rollout 的 Deployment 第一次检查就返回，
+// 并上报新 ReplicaSet 和就绪副本数事件。
+func TestWaitForRolloutReportsProgress(t *testing.T) {
+	labels := map[string]string{"app": "myapp", "lane": "prod"}
+	replicas := int32(2)
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp-prod", Namespace: "default", Generation: 3},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 3,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      2,
+			AvailableReplicas:  2,
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "myapp-prod-abc123",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "myapp", "lane": "prod", "pod-template-hash": "abc123"},
+			Annotations: map[string]string{"deployment.kubernetes.io/revision": "5"},
+		},
+	}
+	client := fakeclient.NewSimpleClientset(deploy, rs)
+	deployer := NewK8sDeployer(client, "default", "")
+
+	var events []domain.ReleaseOperationEvent
+	err := deployer.WaitForRollout(context.Background(), "myapp-prod", func(ev domain.ReleaseOperationEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatalf("WaitForRollout() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Type != domain.OperationEventReplicaSetCreated || events[0].Message != "replicaset myapp-prod-abc123 created (revision 5)" {
+		t.Errorf("unexpected replicaset event: %+v", events[0])
+	}
+	if events[1].Type != domain.OperationEventPodsReady || events[1].Message != "pods ready 2/2" {
+		t.Errorf("unexpected pods event: %+v", events[1])
+	}
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const sidecarContainerName = "lane-sidecar"
+
+func (d *K8sDeployer) DiffDeployment(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) ([]domain.DriftItem, error) {
+	desired, err := d.buildDeployment(release, app, len(bundleEnvs) > 0)
+	if err != nil {
+		return nil, fmt.Errorf("build desired deployment: %w", err)
+	}
+	live, err := d.client.AppsV1().Deployments(d.namespace).Get(ctx, desired.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []domain.DriftItem{{Field: domain.DriftFieldDeployment, Expected: "present", Actual: "missing"}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get deployment: %w", err)
+	}
+	// HPA 管理副本数时副本差异是正常伸缩，不算漂移
+	return diffDeployment(desired, live, app.Name, release.Autoscaling == nil), nil
+}
+
+// diffDeployment 只比较平台负责下发、人工最常改动的几项：主容器镜像、副本数、
+// env（逐 key）、envFrom 来源和 lane-sidecar 是否存在。其他字段（资源、探针等）
+// 会被 API Server 补默认值，直接比较噪音太大。checkReplicas 为 false 时跳过副本数。
+func diffDeployment(desired, live *appsv1.Deployment, mainContainer string, checkReplicas bool) []domain.DriftItem {
+	var items []domain.DriftItem
+
+	want := findContainer(desired.Spec.Template.Spec.Containers, mainContainer)
+	got := findContainer(live.Spec.Template.Spec.Containers, mainContainer)
+	if got == nil {
+		items = append(items, domain.DriftItem{Field: domain.DriftFieldImage, Expected: want.Image, Actual: ""})
+	} else {
+		if want.Image != got.Image {
+			items = append(items, domain.DriftItem{Field: domain.DriftFieldImage, Expected: want.Image, Actual: got.Image})
+		}
+		items = append(items, diffEnv(want.Env, got.Env)...)
+		if w, g := envFromString(want.EnvFrom), envFromString(got.EnvFrom); w != g {
+			items = append(items, domain.DriftItem{Field: domain.DriftFieldEnvFrom, Expected: w, Actual: g})
+		}
+	}
+
+	if w, g := replicasString(desired.Spec.Replicas), replicasString(live.Spec.Replicas); checkReplicas && w != g {
+		items = append(items, domain.DriftItem{Field: domain.DriftFieldReplicas, Expected: w, Actual: g})
+	}
+
+	wantSidecar := findContainer(desired.Spec.Template.Spec.Containers, sidecarContainerName) != nil
+	gotSidecar := findContainer(live.Spec.Template.Spec.Containers, sidecarContainerName) != nil
+	if wantSidecar != gotSidecar {
+		items = append(items, domain.DriftItem{Field: domain.DriftFieldSidecar, Expected: presence(wantSidecar), Actual: presence(gotSidecar)})
+	}
+	return items
+}
+
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// diffEnv 按变量名比较，结果按 key 排序保证输出稳定。ValueFrom 引用的变量只比较是否存在。
+func diffEnv(want, got []corev1.EnvVar) []domain.DriftItem {
+	wantMap := envValues(want)
+	gotMap := envValues(got)
+	keys := make([]string, 0, len(wantMap)+len(gotMap))
+	for k := range wantMap {
+		keys = append(keys, k)
+	}
+	for k := range gotMap {
+		if _, ok := wantMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var items []domain.DriftItem
+	for _, k := range keys {
+		w, g := wantMap[k], gotMap[k]
+		if w != g {
+			items = append(items, domain.DriftItem{Field: domain.DriftFieldEnv, Key: k, Expected: w, Actual: g})
+		}
+	}
+	return items
+}
+
+func envValues(envs []corev1.EnvVar) map[string]string {
+	m := make(map[string]string, len(envs))
+	for _, e := range envs {
+		if e.ValueFrom != nil {
+			m[e.Name] = "<valueFrom>"
+			continue
+		}
+		m[e.Name] = e.Value
+	}
+	return m
+}
+
+// envFromString 把 envFrom 来源渲染成 "secret/a,configmap/b"，保留顺序（后者覆盖前者）。
+func envFromString(sources []corev1.EnvFromSource) string {
+	parts := make([]string, 0, len(sources))
+	for _, s := range sources {
+		switch {
+		case s.SecretRef != nil:
+			parts = append(parts, "secret/"+s.SecretRef.Name)
+		case s.ConfigMapRef != nil:
+			parts = append(parts, "configmap/"+s.ConfigMapRef.Name)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func replicasString(r *int32) string {
+	if r == nil {
+		return "1" // API Server 默认值
+	}
+	return strconv.Itoa(int(*r))
+}
+
+func presence(ok bool) string {
+	if ok {
+		return "present"
+	}
+	return "absent"
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiffDeploymentNoDriftAfterApply(t *testing.T) {
+	client := fakeclient.NewSimpleClientset()
+	deployer := NewK8sDeployer(client, "default", "")
+	app := &domain.App{Name: "myapp", Port: 8080, EnvFromSecrets: []string{"app-env"}, SidecarEnabled: true}
+	release := &domain.Release{ID: "r1", AppName: "myapp", Lane: "prod", Image: "harbor.local/inner-bot/myapp:v1", Replicas: 2}
+	bundle := map[string]string{"DB_URL": "postgres://x"}
+
+	if err := deployer.applyDeployment(context.Background(), release, app, bundle); err != nil {
+		t.Fatalf("applyDeployment() error = %v", err)
+	}
+	items, err := deployer.DiffDeployment(context.Background(), release, app, bundle)
+	if err != nil {
+		t.Fatalf("DiffDeployment() error = %v", err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("expected no drift right after apply, got %+v", items)
+	}
+}
+
+// TestDiffDeploymentDetectsManualEdits 模拟 kubectl edit / scale 后的各类漂移。
+func TestDiffDeploymentDetectsManualEdits(t *testing.T) {
+	ctx := context.Background()
+	client := fakeclient.NewSimpleClientset()
+	deployer := NewK8sDeployer(client, "default", "")
+	app := &domain.App{Name: "myapp", Port: 8080, EnvFromSecrets: []string{"app-env"}, SidecarEnabled: true}
+	release := &domain.Release{ID: "r1", AppName: "myapp", Lane: "prod", Image: "harbor.local/inner-bot/myapp:v1", Replicas: 2}
+
+	if err := deployer.applyDeployment(ctx, release, app, nil); err != nil {
+		t.Fatalf("applyDeployment() error = %v", err)
+	}
+	live, _ := client.AppsV1().Deployments("default").Get(ctx, "myapp-prod", metav1.GetOptions{})
+	replicas := int32(5)
+	live.Spec.Replicas = &replicas
+	spec := &live.Spec.Template.Spec
+	spec.Containers = spec.Containers[:1] // 删掉 lane-sidecar
+	main := &spec.Containers[0]
+	main.Image = "harbor.local/inner-bot/myapp:hotfix"
+	main.Env = append(main.Env, corev1.EnvVar{Name: "DEBUG", Value: "1"})
+	main.EnvFrom = append(main.EnvFrom, corev1.EnvFromSource{
+		ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "extra"}},
+	})
+	if _, err := client.AppsV1().Deployments("default").Update(ctx, live, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update live deployment: %v", err)
+	}
+
+	items, err := deployer.DiffDeployment(ctx, release, app, nil)
+	if err != nil {
+		t.Fatalf("DiffDeployment() error = %v", err)
+	}
+	got := make(map[domain.DriftField]domain.DriftItem)
+	for _, it := range items {
+		got[it.Field] = it
+	}
+	if len(items) != 5 {
+		t.Fatalf("expected 5 drift items, got %d: %+v", len(items), items)
+	}
+	if it := got[domain.DriftFieldImage]; it.Actual != "harbor.local/inner-bot/myapp:hotfix" {
+		t.Errorf("image drift = %+v", it)
+	}
+	if it := got[domain.DriftFieldReplicas]; it.Expected != "2" || it.Actual != "5" {
+		t.Errorf("replicas drift = %+v", it)
+	}
+	if it := got[domain.DriftFieldEnv]; it.Key != "DEBUG" || it.Expected != "" || it.Actual != "1" {
+		t.Errorf("env drift = %+v", it)
+	}
+	if it := got[domain.DriftFieldEnvFrom]; it.Expected != "secret/app-env" || it.Actual != "secret/app-env,configmap/extra" {
+		t.Errorf("envFrom drift = %+v", it)
+	}
+	if it := got[domain.DriftFieldSidecar]; it.Expected != "present" || it.Actual != "absent" {
+		t.Errorf("sidecar drift = %+v", it)
+	}
+}
+
+func TestDiffDeploymentMissing(t *testing.T) {
+	deployer := NewK8sDeployer(fakeclient.NewSimpleClientset(), "default", "")
+	app := &domain.App{Name: "myapp"}
+	release := &domain.Release{ID: "r1", AppName: "myapp", Lane: "prod", Image: "img:v1", Replicas: 1}
+
+	items, err := deployer.DiffDeployment(context.Background(), release, app, nil)
+	if err != nil {
+		t.Fatalf("DiffDeployment() error = %v", err)
+	}
+	if len(items) != 1 || items[0].Field != domain.DriftFieldDeployment || items[0].Actual != "missing" {
+		t.Fatalf("expected missing deployment drift, got %+v", items)
+	}
+}
@@ -0,0 +1,107 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// buildHPA 按 Release.Autoscaling 渲染 autoscaling/v2 HPA，名称与 Deployment 相同。
+func (d *K8sDeployer) buildHPA(release *domain.Release) *autoscalingv2.HorizontalPodAutoscaler {
+	spec := release.Autoscaling
+	name := release.ResourceName()
+	minReplicas := spec.MinReplicas
+
+	var metrics []autoscalingv2.MetricSpec
+	if spec.TargetCPUUtilization > 0 {
+		metrics = append(metrics, utilizationMetric(corev1.ResourceCPU, spec.TargetCPUUtilization))
+	}
+	if spec.TargetMemoryUtilization > 0 {
+		metrics = append(metrics, utilizationMetric(corev1.ResourceMemory, spec.TargetMemoryUtilization))
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: d.namespace,
+			Labels: map[string]string{
+				"app":  release.AppName,
+				"lane": release.Lane,
+			},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: spec.MaxReplicas,
+			Metrics:     metrics,
+		},
+	}
+}
+
+func utilizationMetric(resource corev1.ResourceName, target int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: resource,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &target,
+			},
+		},
+	}
+}
+
+func (d *K8sDeployer) applyHPA(ctx context.Context, release *domain.Release) error {
+	hpa := d.buildHPA(release)
+	client := d.client.AutoscalingV2().HorizontalPodAutoscalers(d.namespace)
+	existing, err := client.Get(ctx, hpa.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(ctx, hpa, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	existing.Labels = hpa.Labels
+	existing.Spec = hpa.Spec
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteHPA 删除 release 的 HPA，不存在视为成功。
+func (d *K8sDeployer) deleteHPA(ctx context.Context, name string) error {
+	err := d.client.AutoscalingV2().HorizontalPodAutoscalers(d.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("delete hpa %s: %w", name, err)
+	}
+	return nil
+}
+
+// getHPAStatus 查询 Deployment 同名 HPA 的副本状态，没有 HPA 返回 nil。
+func (d *K8sDeployer) getHPAStatus(ctx context.Context, name string) (*domain.AutoscalingStatus, error) {
+	hpa, err := d.client.AutoscalingV2().HorizontalPodAutoscalers(d.namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get hpa %s: %w", name, err)
+	}
+	status := &domain.AutoscalingStatus{
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		status.MinReplicas = *hpa.Spec.MinReplicas
+	}
+	return status, nil
+}
@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// TestApplyWithAutoscaling 验证 HPA 的创建、副本数不被覆盖，以及关闭自动伸缩后删除 HPA。
+func TestApplyWithAutoscaling(t *testing.T) {
+	ctx := context.Background()
+	client := fakeclient.NewSimpleClientset()
+	deployer := NewK8sDeployer(client, "default", "")
+	app := &domain.App{Name: "myapp", Resources: &domain.ResourceSpec{CPURequest: "100m"}}
+	release := &domain.Release{
+		ID: "r1", AppName: "myapp", Lane: "prod", Image: "img:v1", Replicas: 1,
+		Autoscaling: &domain.AutoscalingSpec{MinReplicas: 2, MaxReplicas: 6, TargetCPUUtilization: 70},
+	}
+
+	if err := deployer.Apply(ctx, release, app, nil); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	hpa, err := client.AutoscalingV2().HorizontalPodAutoscalers("default").Get(ctx, "myapp-prod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get HPA error = %v", err)
+	}
+	if hpa.Spec.ScaleTargetRef.Kind != "Deployment" || hpa.Spec.ScaleTargetRef.Name != "myapp-prod" {
+		t.Errorf("unexpected scale target: %+v", hpa.Spec.ScaleTargetRef)
+	}
+	if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 6 {
+		t.Errorf("replica range = %d..%d, want 2..6", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if len(hpa.Spec.Metrics) != 1 || hpa.Spec.Metrics[0].Resource.Name != corev1.ResourceCPU ||
+		*hpa.Spec.Metrics[0].Resource.Target.AverageUtilization != 70 {
+		t.Errorf("unexpected metrics: %+v", hpa.Spec.Metrics)
+	}
+	deploy, _ := client.AppsV1().Deployments("default").Get(ctx, "myapp-prod", metav1.GetOptions{})
+	if *deploy.Spec.Replicas != 2 {
+		t.Errorf("new deployment should start at min_replicas, got %d", *deploy.Spec.Replicas)
+	}
+
+	// HPA 扩容后重新下发（如换镜像）不应把副本数改回去
+	scaled := int32(5)
+	deploy.Spec.Replicas = &scaled
+	if _, err := client.AppsV1().Deployments("default").Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("scale deployment: %v", err)
+	}
+	release.Image = "img:v2"
+	if err := deployer.Apply(ctx, release, app, nil); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	deploy, _ = client.AppsV1().Deployments("default").Get(ctx, "myapp-prod", metav1.GetOptions{})
+	if *deploy.Spec.Replicas != 5 {
+		t.Errorf("replicas managed by HPA were overwritten: got %d, want 5", *deploy.Spec.Replicas)
+	}
+
+	// 关闭自动伸缩：删 HPA，副本数回到 release.Replicas
+	release.Autoscaling = nil
+	release.Replicas = 3
+	if err := deployer.Apply(ctx, release, app, nil); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if _, err := client.AutoscalingV2().HorizontalPodAutoscalers("default").Get(ctx, "myapp-prod", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected HPA to be deleted, got err = %v", err)
+	}
+	deploy, _ = client.AppsV1().Deployments("default").Get(ctx, "myapp-prod", metav1.GetOptions{})
+	if *deploy.Spec.Replicas != 3 {
+		t.Errorf("replicas = %d, want 3", *deploy.Spec.Replicas)
+	}
+}
+
+func TestGetDeploymentStatusWithHPA(t *testing.T) {
+	ctx := context.Background()
+	client := fakeclient.NewSimpleClientset()
+	deployer := NewK8sDeployer(client, "default", "")
+	app := &domain.App{Name: "myapp", Resources: &domain.ResourceSpec{MemoryRequest: "128Mi"}}
+	release := &domain.Release{
+		ID: "r1", AppName: "myapp", Lane: "prod", Image: "img:v1",
+		Autoscaling: &domain.AutoscalingSpec{MinReplicas: 1, MaxReplicas: 4, TargetMemoryUtilization: 80},
+	}
+	if err := deployer.Apply(ctx, release, app, nil); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	hpa, _ := client.AutoscalingV2().HorizontalPodAutoscalers("default").Get(ctx, "myapp-prod", metav1.GetOptions{})
+	hpa.Status.CurrentReplicas = 2
+	hpa.Status.DesiredReplicas = 3
+	if _, err := client.AutoscalingV2().HorizontalPodAutoscalers("default").UpdateStatus(ctx, hpa, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update HPA status: %v", err)
+	}
+
+	status, err := deployer.GetDeploymentStatus(ctx, "myapp-prod")
+	if err != nil {
+		t.Fatalf("GetDeploymentStatus() error = %v", err)
+	}
+	as := status.Autoscaling
+	if as == nil {
+		t.Fatal("expected autoscaling status")
+	}
+	if as.MinReplicas != 1 || as.MaxReplicas != 4 || as.CurrentReplicas != 2 || as.DesiredReplicas != 3 {
+		t.Errorf("unexpected autoscaling status: %+v", as)
+	}
+
+	// 删除 release 时一并删除 HPA
+	if err := deployer.Delete(ctx, release, false); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := client.AutoscalingV2().HorizontalPodAutoscalers("default").Get(ctx, "myapp-prod", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected HPA to be deleted with release, got err = %v", err)
+	}
+}
@@ -4,25 +4,20 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 	"github.com/chiwei-platform/paas-engine/internal/port"
-	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
 )
 
 var _ port.BuildExecutor = (*KanikoBuildExecutor)(nil)
 
-const labelBuildID = "paas.chiwei/build-id"
-
 type KanikoBuildExecutor struct {
-	client             kubernetes.Interface
-	namespace          string
+	jobs               buildJobs
 	kanikoImage        string
 	registrySecret     string
 	registryMirrors    []string
@@ -30,6 +25,7 @@ type KanikoBuildExecutor struct {
 	cacheRepo          string
 	httpProxy          string
 	noProxy            string
+	refResolver        port.GitRefResolver
 }
 
 type KanikoBuildConfig struct {
@@ -41,12 +37,18 @@ type KanikoBuildConfig struct {
 	CacheRepo          string
 	HttpProxy          string
 	NoProxy            string
+	RefResolver        port.GitRefResolver // 提交前把 GitRef 解析为 commit，nil 则按原 ref 构建
 }
 
 func NewKanikoBuildExecutor(client kubernetes.Interface, cfg KanikoBuildConfig) *KanikoBuildExecutor {
 	return &KanikoBuildExecutor{
-		client:             client,
-		namespace:          cfg.Namespace,
+		jobs: buildJobs{
+			client:    client,
+			namespace: cfg.Namespace,
+			builder:   domain.BuilderKaniko,
+			container: "kaniko",
+			digestOf:  strings.TrimSpace,
+		},
 		kanikoImage:        cfg.KanikoImage,
 		registrySecret:     cfg.RegistrySecret,
 		registryMirrors:    cfg.RegistryMirrors,
@@ -54,33 +56,25 @@ func NewKanikoBuildExecutor(client kubernetes.Interface, cfg KanikoBuildConfig)
 		cacheRepo:          cfg.CacheRepo,
 		httpProxy:          cfg.HttpProxy,
 		noProxy:            cfg.NoProxy,
+		refResolver:        cfg.RefResolver,
 	}
 }
 
-func (e *KanikoBuildExecutor) Submit(ctx context.Context, sub *port.BuildSubmission) (string, error) {
-	jobName := fmt.Sprintf("kaniko-%s", strings.ReplaceAll(sub.BuildID, "-", ""))
-	ttl := int32(3600)
-	backoff := int32(0)
-	// 从 Job 创建时刻起算，排不上队的时间也算在内 —— 这正是要的：
-	// 没有期限时，调度不上的构建会永远停在 running（build 记录在 Submit 成功即写 running），
-	// Makefile 的轮询只认终态，于是卡死。观测到最慢的真实构建 425s，给 4 倍余量。
-	deadline := int64(1800)
-
-	gitContext := "git://github.com/" + sub.GitRepo
-	gitRef := sub.GitRef
-	if gitRef != "" && !strings.HasPrefix(gitRef, "refs/") {
-		if isCommitHash(gitRef) {
-			// kaniko git context 直接使用 commit hash
-		} else if looksLikeTag(gitRef) {
-			gitRef = "refs/tags/" + gitRef
-		} else {
-			gitRef = "refs/heads/" + gitRef
-		}
+func (e *KanikoBuildExecutor) Submit(ctx context.Context, sub *port.BuildSubmission) (*port.SubmittedBuild, error) {
+	// 先把 branch / tag 解析成 commit 再构建：构建期间分支再有新提交，
+	// 记录的 commit 和镜像内容也不会对不上
+	commitSHA, err := resolveCommit(ctx, e.refResolver, sub)
+	if err != nil {
+		return nil, err
 	}
+	// kaniko git context 直接支持 commit hash 和完整 ref
+	gitContext := "git://github.com/" + sub.GitRepo
+	gitRef := contextGitRef(sub.GitRef, commitSHA)
 
 	args := []string{
 		fmt.Sprintf("--context=%s#%s", gitContext, gitRef),
 		fmt.Sprintf("--destination=%s", sub.ImageTag),
+		fmt.Sprintf("--digest-file=%s", digestFilePath),
 	}
 	if !sub.NoCache && e.cacheRepo != "" {
 		args = append(args, "--cache=true", "--cache-repo="+e.cacheRepo)
@@ -97,6 +91,23 @@ func (e *KanikoBuildExecutor) Submit(ctx context.Context, sub *port.BuildSubmiss
 	if sub.Dockerfile != "" {
 		args = append(args, fmt.Sprintf("--dockerfile=%s", sub.Dockerfile))
 	}
+	// 构建参数按名字排序，同样的输入渲染出同样的 Job
+	for _, name := range slices.Sorted(maps.Keys(sub.BuildArgs)) {
+		args = append(args, fmt.Sprintf("--build-arg=%s=%s", name, sub.BuildArgs[name]))
+	}
+	// secret 参数只传名字，kaniko 从同名环境变量取值（jobs.create 从构建专用 Secret 注入），值不进 Job spec
+	for _, name := range slices.Sorted(maps.Keys(sub.SecretBuildArgs)) {
+		args = append(args, "--build-arg="+name)
+	}
+	if sub.Target != "" {
+		args = append(args, "--target="+sub.Target)
+	}
+	if sub.Platform != "" {
+		args = append(args, "--custom-platform="+sub.Platform)
+	}
+	for _, key := range slices.Sorted(maps.Keys(sub.Labels)) {
+		args = append(args, fmt.Sprintf("--label=%s=%s", key, sub.Labels[key]))
+	}
 	for _, mirror := range e.registryMirrors {
 		args = append(args, fmt.Sprintf("--registry-mirror=%s", mirror))
 	}
@@ -105,31 +116,11 @@ func (e *KanikoBuildExecutor) Submit(ctx context.Context, sub *port.BuildSubmiss
 		args = append(args, fmt.Sprintf("--skip-tls-verify-registry=%s", reg))
 	}
 
-	job := &batchv1.Job{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      jobName,
-			Namespace: e.namespace,
-			Labels: map[string]string{
-				labelBuildID: sub.BuildID,
-			},
-		},
-		Spec: batchv1.JobSpec{
-			BackoffLimit:            &backoff,
-			TTLSecondsAfterFinished: &ttl,
-			ActiveDeadlineSeconds:   &deadline,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{labelBuildID: sub.BuildID},
-				},
-				Spec: e.podSpec(args),
-			},
-		},
-	}
-
-	if _, err := e.client.BatchV1().Jobs(e.namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
-		return "", err
+	job := e.jobs.newJob(sub.BuildID, e.podSpec(args), nil)
+	if err := e.jobs.create(ctx, job, sub); err != nil {
+		return nil, err
 	}
-	return jobName, nil
+	return &port.SubmittedBuild{JobName: job.Name, CommitSHA: commitSHA}, nil
 }
 
 func (e *KanikoBuildExecutor) podSpec(args []string) corev1.PodSpec {
@@ -146,138 +137,37 @@ func (e *KanikoBuildExecutor) podSpec(args []string) corev1.PodSpec {
 			},
 		},
 	}
-	if e.httpProxy != "" {
-		spec.Containers[0].Env = append(spec.Containers[0].Env,
-			corev1.EnvVar{Name: "HTTP_PROXY", Value: e.httpProxy},
-			corev1.EnvVar{Name: "HTTPS_PROXY", Value: e.httpProxy},
-			corev1.EnvVar{Name: "http_proxy", Value: e.httpProxy},
-			corev1.EnvVar{Name: "https_proxy", Value: e.httpProxy},
-		)
-		if e.noProxy != "" {
-			spec.Containers[0].Env = append(spec.Containers[0].Env,
-				corev1.EnvVar{Name: "NO_PROXY", Value: e.noProxy},
-				corev1.EnvVar{Name: "no_proxy", Value: e.noProxy},
-			)
-		}
-	}
+	spec.Containers[0].Env = proxyEnv(e.httpProxy, e.noProxy)
 	if e.registrySecret != "" {
-		volumeName := "docker-config"
-		spec.Volumes = []corev1.Volume{
-			{
-				Name: volumeName,
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: e.registrySecret,
-						Items: []corev1.KeyToPath{
-							{Key: ".dockerconfigjson", Path: "config.json"},
-						},
-					},
-				},
-			},
-		}
+		volume := registryConfigVolume(e.registrySecret)
+		spec.Volumes = []corev1.Volume{volume}
 		spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
-			{Name: volumeName, MountPath: "/kaniko/.docker", ReadOnly: true},
+			{Name: volume.Name, MountPath: "/kaniko/.docker", ReadOnly: true},
 		}
 	}
 	return spec
 }
 
 func (e *KanikoBuildExecutor) Cancel(ctx context.Context, jobName string) error {
-	propagation := metav1.DeletePropagationForeground
-	return e.client.BatchV1().Jobs(e.namespace).Delete(ctx, jobName, metav1.DeleteOptions{
-		PropagationPolicy: &propagation,
-	})
+	return e.jobs.cancel(ctx, jobName)
 }
 
-// Watch 启动 Job Informer，监听标签匹配的 Kaniko Job 状态变化。
+// Watch 启动 Job Informer，监听 Kaniko Job 状态变化。
 func (e *KanikoBuildExecutor) Watch(ctx context.Context, callback port.BuildStatusCallback) error {
-	factory := informers.NewSharedInformerFactoryWithOptions(
-		e.client,
-		0,
-		informers.WithNamespace(e.namespace),
-	)
-	jobInformer := factory.Batch().V1().Jobs().Informer()
-
-	jobInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			job, ok := newObj.(*batchv1.Job)
-			if !ok {
-				return
-			}
-			buildID, ok := job.Labels[labelBuildID]
-			if !ok {
-				return
-			}
-
-			status, log := jobToStatus(job)
-			if status != "" {
-				callback(buildID, status, log)
-			}
-		},
-	})
-
-	factory.Start(ctx.Done())
-	factory.WaitForCacheSync(ctx.Done())
-
-	<-ctx.Done()
-	return ctx.Err()
+	return e.jobs.watch(ctx, callback)
 }
 
-// GetLogs 通过 buildID label 找到 Pod，读取容器日志。
+// GetLogs 通过 buildID label 找到 Pod，读取 kaniko 容器日志。
 func (e *KanikoBuildExecutor) GetLogs(ctx context.Context, buildID string) (string, error) {
-	pods, err := e.client.CoreV1().Pods(e.namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%s=%s", labelBuildID, buildID),
-	})
-	if err != nil {
-		return "", fmt.Errorf("list pods for build %s: %w", buildID, err)
-	}
-	if len(pods.Items) == 0 {
-		return "", nil
-	}
-
-	pod := pods.Items[0]
-	stream, err := e.client.CoreV1().Pods(e.namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
-		Container: "kaniko",
-	}).Stream(ctx)
-	if err != nil {
-		return "", fmt.Errorf("get pod logs %s: %w", pod.Name, err)
-	}
-	defer stream.Close()
-
-	data, err := io.ReadAll(stream)
-	if err != nil {
-		return "", fmt.Errorf("read pod logs %s: %w", pod.Name, err)
-	}
-	return string(data), nil
+	return e.jobs.getLogs(ctx, buildID)
 }
 
-func isCommitHash(ref string) bool {
-	if len(ref) < 7 || len(ref) > 40 {
-		return false
-	}
-	for _, c := range ref {
-		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
-			return false
-		}
-	}
-	return true
+// FollowLogs 跟随 kaniko 容器日志直到容器退出。
+func (e *KanikoBuildExecutor) FollowLogs(ctx context.Context, buildID string) (io.ReadCloser, error) {
+	return e.jobs.streamLogs(ctx, buildID, true)
 }
 
-func looksLikeTag(ref string) bool {
-	return strings.HasPrefix(ref, "v") && len(ref) > 1 && ref[1] >= '0' && ref[1] <= '9'
-}
-
-func jobToStatus(job *batchv1.Job) (domain.BuildStatus, string) {
-	for _, cond := range job.Status.Conditions {
-		if cond.Type == batchv1.JobComplete && cond.Status == "True" {
-			return domain.BuildStatusSucceeded, ""
-		}
-		if cond.Type == batchv1.JobFailed && cond.Status == "True" {
-			return domain.BuildStatusFailed, cond.Message
-		}
-	}
-	if job.Status.Active > 0 {
-		return domain.BuildStatusRunning, ""
-	}
-	return "", ""
+// readDigest 读回 kaniko --digest-file 写下的镜像 digest，读不到返回空。
+func (e *KanikoBuildExecutor) readDigest(ctx context.Context, buildID string) string {
+	return e.jobs.readDigest(ctx, buildID)
 }
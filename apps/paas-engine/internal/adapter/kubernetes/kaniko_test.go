@@ -2,11 +2,14 @@ package kubernetes
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 	"github.com/chiwei-platform/paas-engine/internal/port"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -208,6 +211,148 @@ func TestSubmit_HasExecutionDeadline(t *testing.T) {
 	}
 }
 
+type stubRefResolver struct {
+	sha string
+	err error
+}
+
+func (r *stubRefResolver) ResolveCommit(_ context.Context, _, _ string) (string, error) {
+	return r.sha, r.err
+}
+
+// 提交前把分支解析成 commit，构建上下文固定到这个 commit，并让 kaniko 把 digest 写进 termination message。
+func TestSubmit_PinsResolvedCommit(t *testing.T) {
+	const sha = "3f2a9c1d4b5e6f708192a3b4c5d6e7f809162738"
+	client := fake.NewSimpleClientset()
+	executor := NewKanikoBuildExecutor(client, KanikoBuildConfig{
+		Namespace:   "paas-builds",
+		KanikoImage: "gcr.io/kaniko-project/executor:latest",
+		RefResolver: &stubRefResolver{sha: sha},
+	})
+
+	submitted, err := executor.Submit(context.Background(), &port.BuildSubmission{
+		BuildID:  "test-build-id",
+		GitRepo:  "example/repo",
+		GitRef:   "feat/provenance",
+		ImageTag: "registry.example.com/app:1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if submitted.CommitSHA != sha || submitted.JobName != "kaniko-testbuildid" {
+		t.Errorf("submitted = %+v", submitted)
+	}
+
+	jobs, _ := client.BatchV1().Jobs("paas-builds").List(context.Background(), metav1.ListOptions{})
+	args := jobs.Items[0].Spec.Template.Spec.Containers[0].Args
+	if !containsArg(args, "--context=git://github.com/example/repo#"+sha) {
+		t.Errorf("context should be pinned to resolved commit, got %v", args)
+	}
+	if !containsArg(args, "--digest-file=/dev/termination-log") {
+		t.Errorf("expected --digest-file arg, got %v", args)
+	}
+
+	// 解析失败不提交 Job
+	failing := NewKanikoBuildExecutor(fake.NewSimpleClientset(), KanikoBuildConfig{
+		Namespace:   "paas-builds",
+		RefResolver: &stubRefResolver{err: errors.New("404")},
+	})
+	if _, err := failing.Submit(context.Background(), &port.BuildSubmission{BuildID: "b2", GitRepo: "example/repo", GitRef: "gone"}); err == nil {
+		t.Error("expected error when ref cannot be resolved")
+	}
+}
+
+func TestSubmit_BuildOptions(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	executor := NewKanikoBuildExecutor(client, KanikoBuildConfig{
+		Namespace:   "paas-builds",
+		KanikoImage: "gcr.io/kaniko-project/executor:latest",
+	})
+
+	_, err := executor.Submit(context.Background(), &port.BuildSubmission{
+		BuildID:         "test-build-id",
+		GitRepo:         "example/repo",
+		GitRef:          "main",
+		ImageTag:        "registry.example.com/app:1.0.0",
+		BuildArgs:       map[string]string{"NODE_ENV": "production", "APP_NAME": "lark-server"},
+		SecretBuildArgs: map[string]string{"NPM_TOKEN": "s3cret"},
+		Target:          "runtime",
+		Platform:        "linux/arm64",
+		Labels:          map[string]string{"org.opencontainers.image.revision": "abc"},
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	jobs, _ := client.BatchV1().Jobs("paas-builds").List(context.Background(), metav1.ListOptions{})
+	container := jobs.Items[0].Spec.Template.Spec.Containers[0]
+	for _, want := range []string{
+		"--build-arg=APP_NAME=lark-server",
+		"--build-arg=NODE_ENV=production",
+		"--build-arg=NPM_TOKEN",
+		"--target=runtime",
+		"--custom-platform=linux/arm64",
+		"--label=org.opencontainers.image.revision=abc",
+	} {
+		if !containsArg(container.Args, want) {
+			t.Errorf("expected arg %q, got %v", want, container.Args)
+		}
+	}
+	for _, a := range container.Args {
+		if strings.Contains(a, "s3cret") {
+			t.Fatalf("secret value leaked into job args: %v", container.Args)
+		}
+	}
+
+	// secret 值经构建专用 Secret 注入同名环境变量，Secret 归属 Job
+	secret, err := client.CoreV1().Secrets("paas-builds").Get(context.Background(), "kaniko-testbuildid-build-args", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get build args secret: %v", err)
+	}
+	if secret.StringData["NPM_TOKEN"] != "s3cret" {
+		t.Errorf("secret data = %v", secret.StringData)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "kaniko-testbuildid" {
+		t.Errorf("secret owner = %+v, want job", secret.OwnerReferences)
+	}
+	var env *corev1.EnvVar
+	for i := range container.Env {
+		if container.Env[i].Name == "NPM_TOKEN" {
+			env = &container.Env[i]
+		}
+	}
+	if env == nil || env.ValueFrom == nil || env.ValueFrom.SecretKeyRef.Name != secret.Name || env.ValueFrom.SecretKeyRef.Key != "NPM_TOKEN" {
+		t.Errorf("NPM_TOKEN env = %+v", env)
+	}
+}
+
+func TestReadDigest(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kaniko-b1-xyz",
+			Namespace: "paas-builds",
+			Labels:    map[string]string{labelBuildID: "b1"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "kaniko",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 0,
+					Message:  "sha256:0123abcd\n",
+				}},
+			}},
+		},
+	}
+	executor := NewKanikoBuildExecutor(fake.NewSimpleClientset(pod), KanikoBuildConfig{Namespace: "paas-builds"})
+
+	if got := executor.readDigest(context.Background(), "b1"); got != "sha256:0123abcd" {
+		t.Errorf("readDigest() = %q, want sha256:0123abcd", got)
+	}
+	if got := executor.readDigest(context.Background(), "other"); got != "" {
+		t.Errorf("readDigest() for unknown build = %q, want empty", got)
+	}
+}
+
 func containsArg(args []string, target string) bool {
 	for _, a := range args {
 		if a == target {
@@ -10,6 +10,7 @@ import (
 	"github.com/chiwei-platform/paas-engine/internal/port"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -68,15 +69,19 @@ func (e *K8sTestExecutor) Submit(ctx context.Context, sub *port.TestSubmission)
 	if gitRef == "" {
 		gitRef = "main"
 	}
+	// --branch 只接受分支 / tag，完整 commit 要单独 fetch
+	cloneCmd := fmt.Sprintf("git clone --depth 1 --branch '%s' '%s' /workspace", gitRef, gitURL)
+	if domain.IsFullCommitSHA(gitRef) {
+		cloneCmd = fmt.Sprintf("git init -q /workspace && cd /workspace && git remote add origin '%s' && "+
+			"git fetch --depth 1 origin '%s' && git checkout -q FETCH_HEAD", gitURL, gitRef)
+	}
 
 	// Init container: clone repo
 	initContainer := corev1.Container{
 		Name:    "git-clone",
 		Image:   "harbor.local:30002/library/alpine-git:latest",
 		Command: []string{"sh", "-c"},
-		Args: []string{
-			fmt.Sprintf("git clone --depth 1 --branch '%s' '%s' /workspace", gitRef, gitURL),
-		},
+		Args:    []string{cloneCmd},
 		VolumeMounts: []corev1.VolumeMount{
 			{Name: "workspace", MountPath: "/workspace"},
 		},
@@ -149,7 +154,8 @@ func (e *K8sTestExecutor) Submit(ctx context.Context, sub *port.TestSubmission)
 		},
 	}
 
-	if _, err := e.client.BatchV1().Jobs(e.namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
+	// Job 名由 JobRunID 决定，重启后重新提交同一个 job 时接着用已创建的 Job
+	if _, err := e.client.BatchV1().Jobs(e.namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
 		return "", err
 	}
 	return jobName, nil
@@ -171,21 +177,26 @@ func (e *K8sTestExecutor) Watch(ctx context.Context, callback port.TestStatusCal
 	)
 	jobInformer := factory.Batch().V1().Jobs().Informer()
 
+	handle := func(obj any) {
+		job, ok := obj.(*batchv1.Job)
+		if !ok {
+			return
+		}
+		jobRunID, ok := job.Labels[labelJobRunID]
+		if !ok {
+			return
+		}
+
+		status, log := testJobToStatus(job)
+		if status != "" {
+			callback(jobRunID, status, log)
+		}
+	}
 	jobInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: func(oldObj, newObj any) {
-			job, ok := newObj.(*batchv1.Job)
-			if !ok {
-				return
-			}
-			jobRunID, ok := job.Labels[labelJobRunID]
-			if !ok {
-				return
-			}
-
-			status, log := testJobToStatus(job)
-			if status != "" {
-				callback(jobRunID, status, log)
-			}
+		// 启动时对已有的 Job 各触发一次 Add，补上 paas-engine 停机期间结束的测试
+		AddFunc: handle,
+		UpdateFunc: func(_, newObj any) {
+			handle(newObj)
 		},
 	})
 
@@ -198,31 +209,44 @@ func (e *K8sTestExecutor) Watch(ctx context.Context, callback port.TestStatusCal
 
 // GetLogs 通过 jobRunID label 找到 Pod，读取 test 容器日志。
 func (e *K8sTestExecutor) GetLogs(ctx context.Context, jobRunID string) (string, error) {
+	stream, err := e.streamLogs(ctx, jobRunID, false)
+	if err != nil || stream == nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("read pod logs for job run %s: %w", jobRunID, err)
+	}
+	return string(data), nil
+}
+
+// FollowLogs 跟随 test 容器日志直到容器退出。
+func (e *K8sTestExecutor) FollowLogs(ctx context.Context, jobRunID string) (io.ReadCloser, error) {
+	return e.streamLogs(ctx, jobRunID, true)
+}
+
+func (e *K8sTestExecutor) streamLogs(ctx context.Context, jobRunID string, follow bool) (io.ReadCloser, error) {
 	pods, err := e.client.CoreV1().Pods(e.namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: fmt.Sprintf("%s=%s", labelJobRunID, jobRunID),
 	})
 	if err != nil {
-		return "", fmt.Errorf("list pods for job run %s: %w", jobRunID, err)
+		return nil, fmt.Errorf("list pods for job run %s: %w", jobRunID, err)
 	}
 	if len(pods.Items) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
 	pod := pods.Items[0]
-	containerName := "test"
 	stream, err := e.client.CoreV1().Pods(e.namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
-		Container: containerName,
+		Container: "test",
+		Follow:    follow,
 	}).Stream(ctx)
 	if err != nil {
-		return "", fmt.Errorf("get pod logs %s: %w", pod.Name, err)
+		return nil, fmt.Errorf("get pod logs %s: %w", pod.Name, err)
 	}
-	defer stream.Close()
-
-	data, err := io.ReadAll(stream)
-	if err != nil {
-		return "", fmt.Errorf("read pod logs %s: %w", pod.Name, err)
-	}
-	return string(data), nil
+	return stream, nil
 }
 
 func testJobToStatus(job *batchv1.Job) (domain.PipelineRunStatus, string) {
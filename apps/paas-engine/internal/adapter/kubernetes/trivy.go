@@ -0,0 +1,217 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var _ port.ImageScanner = (*TrivyScanner)(nil)
+
+// labelScanID 标记扫描 Job / Pod 对应的扫描任务。
+const labelScanID = "paas.chiwei/scan-id"
+
+// TrivyScanner 以 K8s Job 运行 Trivy 扫描镜像，从容器日志读回 JSON 报告并按严重级别计数。
+type TrivyScanner struct {
+	client             kubernetes.Interface
+	namespace          string
+	trivyImage         string
+	registrySecret     string
+	insecureRegistries []string
+	httpProxy          string
+	noProxy            string
+	reportURL          string
+	pollInterval       time.Duration
+}
+
+type TrivyScanConfig struct {
+	Namespace          string
+	TrivyImage         string
+	RegistrySecret     string // 拉取私有镜像的 docker config Secret
+	InsecureRegistries []string
+	HttpProxy          string
+	NoProxy            string
+	// ReportURL 是完整报告地址模板，{job} 替换为扫描 Job 名（如指向 Loki 的日志查询），空则不生成
+	ReportURL    string
+	PollInterval time.Duration // 轮询 Job 状态的间隔，默认 5s
+}
+
+func NewTrivyScanner(client kubernetes.Interface, cfg TrivyScanConfig) *TrivyScanner {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	return &TrivyScanner{
+		client:             client,
+		namespace:          cfg.Namespace,
+		trivyImage:         cfg.TrivyImage,
+		registrySecret:     cfg.RegistrySecret,
+		insecureRegistries: cfg.InsecureRegistries,
+		httpProxy:          cfg.HttpProxy,
+		noProxy:            cfg.NoProxy,
+		reportURL:          cfg.ReportURL,
+		pollInterval:       cfg.PollInterval,
+	}
+}
+
+// Scan 创建（或复用同名的）扫描 Job，等它结束后解析报告。Job 结束后删除，超时由 ctx 控制。
+func (s *TrivyScanner) Scan(ctx context.Context, scanID, image string) (*port.ScanReport, error) {
+	jobName := "trivy-" + strings.ReplaceAll(scanID, "-", "")
+	jobs := s.client.BatchV1().Jobs(s.namespace)
+	if _, err := jobs.Create(ctx, s.newJob(jobName, scanID, image), metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("create scan job: %w", err)
+	}
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		_ = jobs.Delete(context.Background(), jobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	}()
+
+	if err := s.wait(ctx, jobName); err != nil {
+		return nil, err
+	}
+	logs, err := s.readLogs(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := parseTrivyReport(strings.NewReader(logs))
+	if err != nil {
+		return nil, err
+	}
+	report := &port.ScanReport{Scanner: "trivy", Counts: counts}
+	if s.reportURL != "" {
+		report.ReportURL = strings.ReplaceAll(s.reportURL, "{job}", jobName)
+	}
+	return report, nil
+}
+
+func (s *TrivyScanner) newJob(jobName, scanID, image string) *batchv1.Job {
+	ttl := int32(3600)
+	backoff := int32(0)
+	labels := map[string]string{labelScanID: scanID}
+
+	container := corev1.Container{
+		Name:  "trivy",
+		Image: s.trivyImage,
+		Args: []string{
+			"image", "--format", "json", "--quiet", "--no-progress",
+			"--scanners", "vuln", "--timeout", "15m", image,
+		},
+		Env: proxyEnv(s.httpProxy, s.noProxy),
+	}
+	if host, _, _ := strings.Cut(image, "/"); slices.Contains(s.insecureRegistries, host) {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "TRIVY_INSECURE", Value: "true"})
+	}
+	spec := corev1.PodSpec{RestartPolicy: corev1.RestartPolicyNever}
+	if s.registrySecret != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "DOCKER_CONFIG", Value: "/docker-config"})
+		container.VolumeMounts = []corev1.VolumeMount{{Name: "docker-config", MountPath: "/docker-config"}}
+		spec.Volumes = []corev1.Volume{registryConfigVolume(s.registrySecret)}
+	}
+	spec.Containers = []corev1.Container{container}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: s.namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoff,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       spec,
+			},
+		},
+	}
+}
+
+// wait 轮询 Job 直到成功；失败或 ctx 结束时返回错误。
+func (s *TrivyScanner) wait(ctx context.Context, jobName string) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		job, err := s.client.BatchV1().Jobs(s.namespace).Get(ctx, jobName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get scan job %s: %w", jobName, err)
+		}
+		if job.Status.Succeeded > 0 {
+			return nil
+		}
+		if job.Status.Failed > 0 {
+			return fmt.Errorf("scan job %s failed", jobName)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("scan job %s: %w", jobName, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// readLogs 读取扫描 Pod 的容器日志，即 Trivy 的 JSON 报告。
+func (s *TrivyScanner) readLogs(ctx context.Context, scanID string) (string, error) {
+	pods, err := s.client.CoreV1().Pods(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", labelScanID, scanID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("list scan pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("no pod found for scan %s", scanID)
+	}
+	stream, err := s.client.CoreV1().Pods(s.namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{
+		Container: "trivy",
+	}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get scan pod logs: %w", err)
+	}
+	defer stream.Close()
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("read scan pod logs: %w", err)
+	}
+	return string(data), nil
+}
+
+// trivyReport 是 trivy --format json 的输出（仅取所需字段）。
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			PkgName         string `json:"PkgName"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// parseTrivyReport 按严重级别统计漏洞数。同一漏洞出现在同一个包的多个位置只计一次。
+func parseTrivyReport(r io.Reader) (map[string]int, error) {
+	var report trivyReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("decode trivy report: %w", err)
+	}
+	counts := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			key := v.VulnerabilityID + "/" + v.PkgName
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			counts[strings.ToUpper(v.Severity)]++
+		}
+	}
+	return counts, nil
+}
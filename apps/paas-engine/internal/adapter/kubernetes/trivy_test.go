@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseTrivyReport(t *testing.T) {
+	report := `{
+		"Results": [
+			{"Vulnerabilities": [
+				{"VulnerabilityID": "CVE-1", "PkgName": "openssl", "Severity": "HIGH"},
+				{"VulnerabilityID": "CVE-2", "PkgName": "zlib", "Severity": "critical"}
+			]},
+			{"Vulnerabilities": [
+				{"VulnerabilityID": "CVE-1", "PkgName": "openssl", "Severity": "HIGH"},
+				{"VulnerabilityID": "CVE-3", "PkgName": "busybox", "Severity": "LOW"}
+			]},
+			{"Vulnerabilities": null}
+		]
+	}`
+	counts, err := parseTrivyReport(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("parseTrivyReport() error = %v", err)
+	}
+	want := map[string]int{"HIGH": 1, "CRITICAL": 1, "LOW": 1}
+	if len(counts) != len(want) {
+		t.Fatalf("counts = %v, want %v", counts, want)
+	}
+	for sev, n := range want {
+		if counts[sev] != n {
+			t.Errorf("counts[%s] = %d, want %d", sev, counts[sev], n)
+		}
+	}
+
+	if _, err := parseTrivyReport(strings.NewReader("FATAL unable to pull image")); err == nil {
+		t.Error("expected error for non-JSON output")
+	}
+}
+
+func TestTrivyScanner_NewJob(t *testing.T) {
+	s := NewTrivyScanner(fake.NewSimpleClientset(), TrivyScanConfig{
+		Namespace:          "paas-builds",
+		TrivyImage:         "aquasec/trivy:0.50.1",
+		RegistrySecret:     "harbor-cred",
+		InsecureRegistries: []string{"harbor.local:30002"},
+	})
+	image := "harbor.local:30002/inner-bot/app:1.0.0.3@sha256:abc"
+	job := s.newJob("trivy-b1", "b-1", image)
+
+	if job.Labels[labelScanID] != "b-1" || job.Spec.Template.Labels[labelScanID] != "b-1" {
+		t.Errorf("scan id label missing: %v / %v", job.Labels, job.Spec.Template.Labels)
+	}
+	c := job.Spec.Template.Spec.Containers[0]
+	if c.Name != "trivy" || c.Args[len(c.Args)-1] != image {
+		t.Errorf("container = %s %v", c.Name, c.Args)
+	}
+	env := make(map[string]string)
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	if env["TRIVY_INSECURE"] != "true" || env["DOCKER_CONFIG"] != "/docker-config" {
+		t.Errorf("env = %v", env)
+	}
+	if !slices.ContainsFunc(job.Spec.Template.Spec.Volumes, func(v corev1.Volume) bool { return v.Name == "docker-config" }) {
+		t.Error("registry config volume not mounted")
+	}
+
+	// 非 insecure registry 不加 TRIVY_INSECURE
+	job = s.newJob("trivy-b2", "b-2", "harbor.example.com/inner-bot/app:1.0.0.3")
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "TRIVY_INSECURE" {
+			t.Error("TRIVY_INSECURE set for secure registry")
+		}
+	}
+}
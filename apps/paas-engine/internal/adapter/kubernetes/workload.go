@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// buildResources 把 domain.ResourceSpec 转成 K8s ResourceRequirements，nil 返回空值（不设限制）。
+func buildResources(spec *domain.ResourceSpec) (corev1.ResourceRequirements, error) {
+	var out corev1.ResourceRequirements
+	if spec == nil {
+		return out, nil
+	}
+	set := func(list *corev1.ResourceList, name corev1.ResourceName, field, v string) error {
+		if v == "" {
+			return nil
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return fmt.Errorf("parse %s %q: %w", field, v, err)
+		}
+		if *list == nil {
+			*list = corev1.ResourceList{}
+		}
+		(*list)[name] = q
+		return nil
+	}
+	if err := set(&out.Requests, corev1.ResourceCPU, "cpu_request", spec.CPURequest); err != nil {
+		return out, err
+	}
+	if err := set(&out.Limits, corev1.ResourceCPU, "cpu_limit", spec.CPULimit); err != nil {
+		return out, err
+	}
+	if err := set(&out.Requests, corev1.ResourceMemory, "memory_request", spec.MemoryRequest); err != nil {
+		return out, err
+	}
+	if err := set(&out.Limits, corev1.ResourceMemory, "memory_limit", spec.MemoryLimit); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// buildProbe 把 domain.ProbeSpec 转成 K8s Probe。http/tcp 未指定端口时用 appPort。
+func buildProbe(spec *domain.ProbeSpec, appPort int) *corev1.Probe {
+	if spec == nil {
+		return nil
+	}
+	port := spec.Port
+	if port == 0 {
+		port = appPort
+	}
+	probe := &corev1.Probe{
+		InitialDelaySeconds: spec.InitialDelaySeconds,
+		PeriodSeconds:       spec.PeriodSeconds,
+		TimeoutSeconds:      spec.TimeoutSeconds,
+		FailureThreshold:    spec.FailureThreshold,
+		SuccessThreshold:    spec.SuccessThreshold,
+	}
+	switch spec.Type {
+	case domain.ProbeTypeHTTP:
+		probe.HTTPGet = &corev1.HTTPGetAction{Path: spec.Path, Port: intstr.FromInt(port)}
+	case domain.ProbeTypeTCP:
+		probe.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt(port)}
+	case domain.ProbeTypeExec:
+		probe.Exec = &corev1.ExecAction{Command: spec.Command}
+	}
+	return probe
+}
@@ -0,0 +1,295 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+var _ port.ImageRegistry = (*Client)(nil)
+
+// manifestAccept 覆盖 OCI / Docker 的单平台和多平台 manifest，HEAD 时 registry 按它返回 digest。
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// Client 通过 OCI distribution API 操作镜像，认证按 registry 返回的 challenge 走 Basic 或 Bearer token。
+type Client struct {
+	username   string
+	password   string
+	insecure   []string // 走 http 的 registry host
+	httpClient *http.Client
+}
+
+type Config struct {
+	Username           string
+	Password           string
+	InsecureRegistries []string
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		username:   cfg.Username,
+		password:   cfg.Password,
+		insecure:   cfg.InsecureRegistries,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// DeleteImage 删除 image 的 tag。先按 tag 删除（OCI 1.1，只去掉这一个 tag）；registry 不支持时
+// 解析出 digest 按 digest 删除 manifest —— 这会连带删掉指向同一 digest 的其他 tag，调用方需自行避开。
+func (c *Client) DeleteImage(ctx context.Context, image string) error {
+	host, name, ref, err := parseImage(image)
+	if err != nil {
+		return err
+	}
+	manifestURL := c.manifestURL(host, name)
+
+	resp, err := c.do(ctx, http.MethodDelete, manifestURL+ref, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil
+	case resp.StatusCode/100 == 2:
+		return nil
+	case strings.HasPrefix(ref, "sha256:"):
+		return fmt.Errorf("registry: delete %s: unexpected status %d", image, resp.StatusCode)
+	}
+
+	resp, err = c.do(ctx, http.MethodHead, manifestURL+ref, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if resp.StatusCode != http.StatusOK || digest == "" {
+		return fmt.Errorf("registry: resolve digest of %s: status %d", image, resp.StatusCode)
+	}
+
+	resp, err = c.do(ctx, http.MethodDelete, manifestURL+digest, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("registry: delete %s@%s: unexpected status %d", image, digest, resp.StatusCode)
+	}
+	return nil
+}
+
+// TagImage 给 source（registry/name@sha256:...）指向的 manifest 打上 target（registry/name:tag）这个 tag：
+// 原样取回 manifest 再按新 tag PUT，层和 digest 都不变。只支持同一仓库内打 tag。
+func (c *Client) TagImage(ctx context.Context, source, target string) error {
+	srcHost, srcName, digest, err := parseImage(source)
+	if err != nil {
+		return err
+	}
+	host, name, tag, err := parseImage(target)
+	if err != nil {
+		return err
+	}
+	if srcHost != host || srcName != name {
+		return fmt.Errorf("registry: cannot tag %s as %s: different repository", source, target)
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("registry: source image %s must be pinned by digest", source)
+	}
+	manifestURL := c.manifestURL(host, name)
+
+	resp, err := c.do(ctx, http.MethodGet, manifestURL+digest, "", nil)
+	if err != nil {
+		return err
+	}
+	manifest, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("registry: read manifest of %s: %w", source, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry: get manifest of %s: status %d", source, resp.StatusCode)
+	}
+
+	resp, err = c.do(ctx, http.MethodPut, manifestURL+tag, resp.Header.Get("Content-Type"), manifest)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("registry: put %s: unexpected status %d", target, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Docker-Content-Digest"); got != "" && got != digest {
+		return fmt.Errorf("registry: tagged %s with digest %s, want %s", target, got, digest)
+	}
+	return nil
+}
+
+func (c *Client) manifestURL(host, name string) string {
+	scheme := "https"
+	if slices.Contains(c.insecure, host) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/manifests/", scheme, host, name)
+}
+
+// do 发请求，遇到 401 按 WWW-Authenticate 取得凭证后重试一次。body 非空时按 contentType 上传 manifest。
+func (c *Client) do(ctx context.Context, method, rawURL, contentType string, body []byte) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("registry: build request: %w", err)
+		}
+		req.Header.Set("Accept", manifestAccept)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req, nil
+	}
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %s %s: %w", method, rawURL, err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, err
+	}
+	if req, err = newRequest(); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+	resp, err = c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("registry: %s %s: %w", method, rawURL, err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry: %s %s: not authorized (status %d)", method, rawURL, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// authorize 按 challenge 生成 Authorization 头：Basic 直接用账号密码，Bearer 先去 realm 换 token。
+func (c *Client) authorize(ctx context.Context, challenge string) (string, error) {
+	if c.username == "" {
+		return "", fmt.Errorf("registry: authentication required but no credentials configured")
+	}
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)), nil
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("registry: invalid token realm %q", params["realm"])
+		}
+		q := realm.Query()
+		for _, key := range []string{"service", "scope"} {
+			if params[key] != "" {
+				q.Set(key, params[key])
+			}
+		}
+		realm.RawQuery = q.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", fmt.Errorf("registry: build token request: %w", err)
+		}
+		req.SetBasicAuth(c.username, c.password)
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("registry: fetch token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return "", fmt.Errorf("registry: fetch token: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("registry: decode token: %w", err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	}
+	return "", fmt.Errorf("registry: unsupported auth challenge %q", challenge)
+}
+
+// parseChallenge 解析 `Bearer realm="...",service="...",scope="repository:a/b:pull,delete"`。
+// 引号内可能有逗号（scope 的多个 action），不能简单按逗号切。
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.TrimSpace(key)
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			value = value[end+2:]
+		} else {
+			v, _, _ := strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(v)
+			value = value[len(v):]
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), ","))
+	}
+	return scheme, params
+}
+
+// parseImage 把 registry/name:tag（或 name[:tag]@sha256:...）拆成 registry host、仓库名和 reference。
+func parseImage(image string) (host, name, ref string, err error) {
+	host, rest, ok := strings.Cut(image, "/")
+	if !ok || rest == "" {
+		return "", "", "", fmt.Errorf("registry: image %q has no registry host", image)
+	}
+	if name, ref, ok = strings.Cut(rest, "@"); ok {
+		// tag@digest 以 digest 为准
+		if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+			name = name[:i]
+		}
+		return host, name, ref, nil
+	}
+	i := strings.LastIndex(rest, ":")
+	if i < 0 || strings.Contains(rest[i:], "/") {
+		return "", "", "", fmt.Errorf("registry: image %q has no tag", image)
+	}
+	return host, rest[:i], rest[i+1:], nil
+}
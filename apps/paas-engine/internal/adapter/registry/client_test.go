@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry 模拟 distribution registry：token 认证、不支持按 tag 删除、按 digest 删除 manifest。
+type fakeRegistry struct {
+	mu        sync.Mutex
+	manifests map[string]string // "name:tag" → digest
+	bodies    map[string]string // digest → manifest
+	deleted   []string
+}
+
+func (f *fakeRegistry) handler(t *testing.T, srvURL *string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "robot" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:inner-bot/app:pull,delete" {
+			t.Errorf("token scope = %q", r.URL.Query().Get("scope"))
+		}
+		fmt.Fprint(w, `{"token":"tok"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:inner-bot/app:pull,delete"`, *srvURL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name, ref, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch {
+		case r.Method == http.MethodHead:
+			digest, ok := f.manifests[name+":"+ref]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+		case r.Method == http.MethodGet && strings.HasPrefix(ref, "sha256:"):
+			body, ok := f.bodies[ref]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			fmt.Fprint(w, body)
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get("Content-Type") != "application/vnd.oci.image.manifest.v1+json" {
+				t.Errorf("put content type = %q", r.Header.Get("Content-Type"))
+			}
+			for digest, b := range f.bodies {
+				if b == string(body) {
+					f.manifests[name+":"+ref] = digest
+					w.Header().Set("Docker-Content-Digest", digest)
+					w.WriteHeader(http.StatusCreated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		case r.Method == http.MethodDelete && strings.HasPrefix(ref, "sha256:"):
+			found := false
+			for key, digest := range f.manifests {
+				if strings.HasPrefix(key, name+":") && digest == ref {
+					delete(f.manifests, key)
+					found = true
+				}
+			}
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			f.deleted = append(f.deleted, ref)
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusMethodNotAllowed) // UNSUPPORTED
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	return mux
+}
+
+func TestDeleteImage(t *testing.T) {
+	reg := &fakeRegistry{manifests: map[string]string{"inner-bot/app:1.0.0.1": "sha256:aaa", "inner-bot/app:1.0.0.2": "sha256:bbb"}}
+	var srvURL string
+	srv := httptest.NewServer(reg.handler(t, &srvURL))
+	defer srv.Close()
+	srvURL = srv.URL
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	c := NewClient(Config{Username: "robot", Password: "secret", InsecureRegistries: []string{host}})
+	ctx := context.Background()
+
+	if err := c.DeleteImage(ctx, host+"/inner-bot/app:1.0.0.1"); err != nil {
+		t.Fatalf("DeleteImage() error = %v", err)
+	}
+	if len(reg.deleted) != 1 || reg.deleted[0] != "sha256:aaa" {
+		t.Errorf("deleted = %v, want [sha256:aaa]", reg.deleted)
+	}
+	if _, ok := reg.manifests["inner-bot/app:1.0.0.2"]; !ok {
+		t.Error("other tag must be kept")
+	}
+
+	// 已经不存在的镜像视为删除成功
+	if err := c.DeleteImage(ctx, host+"/inner-bot/app:1.0.0.1"); err != nil {
+		t.Errorf("DeleteImage() of missing image error = %v", err)
+	}
+
+	bad := NewClient(Config{Username: "robot", Password: "wrong", InsecureRegistries: []string{host}})
+	if err := bad.DeleteImage(ctx, host+"/inner-bot/app:1.0.0.2"); err == nil {
+		t.Error("expected error with wrong credentials")
+	}
+}
+
+func TestTagImage(t *testing.T) {
+	reg := &fakeRegistry{
+		manifests: map[string]string{"inner-bot/app:1.0.0.1": "sha256:aaa"},
+		bodies:    map[string]string{"sha256:aaa": `{"schemaVersion":2}`},
+	}
+	var srvURL string
+	srv := httptest.NewServer(reg.handler(t, &srvURL))
+	defer srv.Close()
+	srvURL = srv.URL
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	c := NewClient(Config{Username: "robot", Password: "secret", InsecureRegistries: []string{host}})
+	ctx := context.Background()
+
+	if err := c.TagImage(ctx, host+"/inner-bot/app:1.0.0.1@sha256:aaa", host+"/inner-bot/app:1.0.0.2"); err != nil {
+		t.Fatalf("TagImage() error = %v", err)
+	}
+	if got := reg.manifests["inner-bot/app:1.0.0.2"]; got != "sha256:aaa" {
+		t.Errorf("new tag digest = %q, want sha256:aaa", got)
+	}
+
+	if err := c.TagImage(ctx, host+"/inner-bot/app@sha256:aaa", host+"/inner-bot/other:1.0.0.2"); err == nil {
+		t.Error("expected error when tagging into another repository")
+	}
+	if err := c.TagImage(ctx, host+"/inner-bot/app:1.0.0.1", host+"/inner-bot/app:1.0.0.3"); err == nil {
+		t.Error("expected error when source is not pinned by digest")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://harbor.local/service/token",service="harbor-registry",scope="repository:inner-bot/app:pull,delete"`)
+	if scheme != "Bearer" {
+		t.Errorf("scheme = %q", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://harbor.local/service/token",
+		"service": "harbor-registry",
+		"scope":   "repository:inner-bot/app:pull,delete",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("params[%s] = %q, want %q", k, params[k], v)
+		}
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		image, host, name, ref string
+		wantErr                bool
+	}{
+		{image: "harbor.local:30002/inner-bot/app:1.0.0.3", host: "harbor.local:30002", name: "inner-bot/app", ref: "1.0.0.3"},
+		{image: "harbor.local/app@sha256:abc", host: "harbor.local", name: "app", ref: "sha256:abc"},
+		{image: "harbor.local:30002/inner-bot/app:1.0.0.3@sha256:abc", host: "harbor.local:30002", name: "inner-bot/app", ref: "sha256:abc"},
+		{image: "harbor.local:30002/inner-bot/app", wantErr: true},
+		{image: "app:1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		host, name, ref, err := parseImage(tt.image)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseImage(%q) error = %v, wantErr %v", tt.image, err, tt.wantErr)
+			continue
+		}
+		if host != tt.host || name != tt.name || ref != tt.ref {
+			t.Errorf("parseImage(%q) = %q %q %q", tt.image, host, name, ref)
+		}
+	}
+}
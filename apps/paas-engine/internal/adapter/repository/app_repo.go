@@ -105,6 +105,22 @@ func appToModel(a *domain.App) (*AppModel, error) {
 	if err != nil {
 		return nil, err
 	}
+	resourcesJSON, err := json.Marshal(a.Resources)
+	if err != nil {
+		return nil, fmt.Errorf("marshal Resources: %w", err)
+	}
+	probesJSON, err := json.Marshal(a.Probes)
+	if err != nil {
+		return nil, fmt.Errorf("marshal Probes: %w", err)
+	}
+	initContainersJSON, err := json.Marshal(a.InitContainers)
+	if err != nil {
+		return nil, fmt.Errorf("marshal InitContainers: %w", err)
+	}
+	sidecarsJSON, err := json.Marshal(a.Sidecars)
+	if err != nil {
+		return nil, fmt.Errorf("marshal Sidecars: %w", err)
+	}
 	return &AppModel{
 		Name:              a.Name,
 		Description:       a.Description,
@@ -119,6 +135,11 @@ func appToModel(a *domain.App) (*AppModel, error) {
 		AllowedLaneClasses: string(allowedLaneClassesJSON),
 		SidecarEnabled:    a.SidecarEnabled,
 		Volumes:           string(volumesJSON),
+		Resources:         string(resourcesJSON),
+		Probes:            string(probesJSON),
+		DriftPolicy:       string(a.DriftPolicy),
+		InitContainers:    string(initContainersJSON),
+		Sidecars:          string(sidecarsJSON),
 		CreatedAt:         a.CreatedAt,
 		UpdatedAt:         a.UpdatedAt,
 	}, nil
@@ -167,6 +188,30 @@ func modelToApp(m *AppModel) (*domain.App, error) {
 			return nil, err
 		}
 	}
+	var resources *domain.ResourceSpec
+	if m.Resources != "" {
+		if err := json.Unmarshal([]byte(m.Resources), &resources); err != nil {
+			return nil, fmt.Errorf("unmarshal Resources: %w", err)
+		}
+	}
+	var probes *domain.HealthProbes
+	if m.Probes != "" {
+		if err := json.Unmarshal([]byte(m.Probes), &probes); err != nil {
+			return nil, fmt.Errorf("unmarshal Probes: %w", err)
+		}
+	}
+	var initContainers []domain.ExtraContainer
+	if m.InitContainers != "" && m.InitContainers != "null" {
+		if err := json.Unmarshal([]byte(m.InitContainers), &initContainers); err != nil {
+			return nil, fmt.Errorf("unmarshal InitContainers: %w", err)
+		}
+	}
+	var sidecars []domain.ExtraContainer
+	if m.Sidecars != "" && m.Sidecars != "null" {
+		if err := json.Unmarshal([]byte(m.Sidecars), &sidecars); err != nil {
+			return nil, fmt.Errorf("unmarshal Sidecars: %w", err)
+		}
+	}
 	return &domain.App{
 		Name:              m.Name,
 		Description:       m.Description,
@@ -181,6 +226,11 @@ func modelToApp(m *AppModel) (*domain.App, error) {
 		AllowedLaneClasses: allowedLaneClasses,
 		SidecarEnabled:    m.SidecarEnabled,
 		Volumes:           volumes,
+		Resources:         resources,
+		Probes:            probes,
+		DriftPolicy:       domain.DriftPolicy(m.DriftPolicy),
+		InitContainers:    initContainers,
+		Sidecars:          sidecars,
 		CreatedAt:         m.CreatedAt,
 		UpdatedAt:         m.UpdatedAt,
 	}, nil
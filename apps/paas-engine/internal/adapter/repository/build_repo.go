@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
@@ -51,7 +52,7 @@ func (r *BuildRepo) FindByImageRepo(ctx context.Context, imageRepoName string) (
 func (r *BuildRepo) FindLatestSuccessful(ctx context.Context, imageRepoName string) (*domain.Build, error) {
 	var m BuildModel
 	result := r.db.WithContext(ctx).
-		Where("image_repo_name = ? AND status = ?", imageRepoName, string(domain.BuildStatusSucceeded)).
+		Where("image_repo_name = ? AND status = ? AND pruned_at IS NULL", imageRepoName, string(domain.BuildStatusSucceeded)).
 		Order("created_at desc").
 		First(&m)
 	if result.Error != nil {
@@ -93,39 +94,120 @@ func (r *BuildRepo) FindLatestVersioned(ctx context.Context, imageRepoName strin
 	return modelToBuild(&m), nil
 }
 
+func (r *BuildRepo) FindByStatus(ctx context.Context, status domain.BuildStatus) ([]*domain.Build, error) {
+	var models []BuildModel
+	if err := r.db.WithContext(ctx).Where("status = ?", string(status)).Order("priority asc, created_at asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	builds := make([]*domain.Build, 0, len(models))
+	for i := range models {
+		builds = append(builds, modelToBuild(&models[i]))
+	}
+	return builds, nil
+}
+
+func (r *BuildRepo) FindByScanStatus(ctx context.Context, status domain.ScanStatus) ([]*domain.Build, error) {
+	var models []BuildModel
+	if err := r.db.WithContext(ctx).Where("scan_status = ?", string(status)).Order("updated_at asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	builds := make([]*domain.Build, 0, len(models))
+	for i := range models {
+		builds = append(builds, modelToBuild(&models[i]))
+	}
+	return builds, nil
+}
+
+func (r *BuildRepo) FindByFingerprint(ctx context.Context, imageRepoName, fingerprint string) ([]*domain.Build, error) {
+	var models []BuildModel
+	if err := r.db.WithContext(ctx).
+		Where("image_repo_name = ? AND fingerprint = ?", imageRepoName, fingerprint).
+		Order("created_at desc").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	builds := make([]*domain.Build, 0, len(models))
+	for i := range models {
+		builds = append(builds, modelToBuild(&models[i]))
+	}
+	return builds, nil
+}
+
 func (r *BuildRepo) Update(ctx context.Context, build *domain.Build) error {
 	m := buildToModel(build)
 	return r.db.WithContext(ctx).Save(m).Error
 }
 
+func (r *BuildRepo) UpdateFromStatus(ctx context.Context, build *domain.Build, from domain.BuildStatus) (bool, error) {
+	m := buildToModel(build)
+	result := r.db.WithContext(ctx).Model(m).Where("status = ?", string(from)).Select("*").Updates(m)
+	return result.RowsAffected == 1, result.Error
+}
+
 func buildToModel(b *domain.Build) *BuildModel {
+	var scanStatus, scan string
+	if b.Scan != nil {
+		scanStatus = string(b.Scan.Status)
+		data, _ := json.Marshal(b.Scan)
+		scan = string(data)
+	}
 	return &BuildModel{
-		ID:            b.ID,
-		ImageRepoName: b.ImageRepoName,
-		GitRef:        b.GitRef,
-		ImageTag:      b.ImageTag,
-		Version:       b.Version,
-		Channel:       b.Channel,
-		Status:        string(b.Status),
-		JobName:       b.JobName,
-		Log:           b.Log,
-		CreatedAt:     b.CreatedAt,
-		UpdatedAt:     b.UpdatedAt,
+		ID:                  b.ID,
+		ImageRepoName:       b.ImageRepoName,
+		GitRef:              b.GitRef,
+		ImageTag:            b.ImageTag,
+		Version:             b.Version,
+		Channel:             b.Channel,
+		Priority:            b.Priority,
+		Status:              string(b.Status),
+		JobName:             b.JobName,
+		Log:                 b.Log,
+		CommitSHA:           b.CommitSHA,
+		Digest:              b.Digest,
+		Dockerfile:          b.Dockerfile,
+		StartedAt:           b.StartedAt,
+		DurationSeconds:     b.DurationSeconds,
+		Fingerprint:         b.Fingerprint,
+		PrunedAt:            b.PrunedAt,
+		PromotedFrom:        b.PromotedFrom,
+		VerifiedLane:        b.VerifiedLane,
+		ScanStatus:          scanStatus,
+		Scan:                scan,
+		BuildOptionsColumns: buildOptionsToColumns(b.BuildOptions),
+		CreatedAt:           b.CreatedAt,
+		UpdatedAt:           b.UpdatedAt,
 	}
 }
 
 func modelToBuild(m *BuildModel) *domain.Build {
+	var scan *domain.ImageScan
+	if m.Scan != "" {
+		scan = &domain.ImageScan{}
+		_ = json.Unmarshal([]byte(m.Scan), scan)
+	}
 	return &domain.Build{
-		ID:            m.ID,
-		ImageRepoName: m.ImageRepoName,
-		GitRef:        m.GitRef,
-		ImageTag:      m.ImageTag,
-		Version:       m.Version,
-		Channel:       m.Channel,
-		Status:        domain.BuildStatus(m.Status),
-		JobName:       m.JobName,
-		Log:           m.Log,
-		CreatedAt:     m.CreatedAt,
-		UpdatedAt:     m.UpdatedAt,
+		ID:              m.ID,
+		ImageRepoName:   m.ImageRepoName,
+		GitRef:          m.GitRef,
+		ImageTag:        m.ImageTag,
+		Version:         m.Version,
+		Channel:         m.Channel,
+		Priority:        m.Priority,
+		Status:          domain.BuildStatus(m.Status),
+		JobName:         m.JobName,
+		Log:             m.Log,
+		CommitSHA:       m.CommitSHA,
+		Digest:          m.Digest,
+		Dockerfile:      m.Dockerfile,
+		StartedAt:       m.StartedAt,
+		DurationSeconds: m.DurationSeconds,
+		Fingerprint:     m.Fingerprint,
+		PrunedAt:        m.PrunedAt,
+		PromotedFrom:    m.PromotedFrom,
+		VerifiedLane:    m.VerifiedLane,
+		Scan:            scan,
+		BuildOptions:    columnsToBuildOptions(m.BuildOptionsColumns),
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
 	}
 }
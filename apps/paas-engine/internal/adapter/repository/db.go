@@ -19,6 +19,13 @@ func OpenDB(dsn string) (*gorm.DB, error) {
 		&ImageRepoModel{},
 		&BuildModel{},
 		&ReleaseModel{},
+		&ReleaseRevisionModel{},
+		&ReleaseCanaryModel{},
+		&ReleaseOperationModel{},
+		&ReleaseDriftModel{},
+		&DriftEventModel{},
+		&LaneModel{},
+		&LaneSleepPolicyModel{},
 		&CIConfigModel{},
 		&PipelineRunModel{},
 		&StageRunModel{},
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"gorm.io/gorm"
+)
+
+var _ port.DriftEventRepository = (*DriftEventRepo)(nil)
+
+type DriftEventRepo struct {
+	db *gorm.DB
+}
+
+func NewDriftEventRepo(db *gorm.DB) *DriftEventRepo {
+	return &DriftEventRepo{db: db}
+}
+
+func (r *DriftEventRepo) Save(ctx context.Context, ev *domain.DriftEvent) error {
+	m, err := driftEventToModel(ev)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r *DriftEventRepo) FindRecent(ctx context.Context, appName, lane string, limit int) ([]*domain.DriftEvent, error) {
+	q := r.db.WithContext(ctx).Order("created_at desc")
+	if appName != "" {
+		q = q.Where("app_name = ?", appName)
+	}
+	if lane != "" {
+		q = q.Where("lane = ?", lane)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var models []DriftEventModel
+	if err := q.Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.DriftEvent, 0, len(models))
+	for i := range models {
+		ev, err := modelToDriftEvent(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+func driftEventToModel(ev *domain.DriftEvent) (*DriftEventModel, error) {
+	items := ev.Items
+	if items == nil {
+		items = []domain.DriftItem{}
+	}
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("marshal drift items: %w", err)
+	}
+	return &DriftEventModel{
+		ID:         ev.ID,
+		ReleaseID:  ev.ReleaseID,
+		AppName:    ev.AppName,
+		Lane:       ev.Lane,
+		DeployName: ev.DeployName,
+		Action:     string(ev.Action),
+		Items:      string(itemsJSON),
+		Message:    ev.Message,
+		CreatedAt:  ev.CreatedAt,
+	}, nil
+}
+
+func modelToDriftEvent(m *DriftEventModel) (*domain.DriftEvent, error) {
+	var items []domain.DriftItem
+	if m.Items != "" && m.Items != "null" {
+		if err := json.Unmarshal([]byte(m.Items), &items); err != nil {
+			return nil, fmt.Errorf("unmarshal drift items: %w", err)
+		}
+	}
+	return &domain.DriftEvent{
+		ID:         m.ID,
+		ReleaseID:  m.ReleaseID,
+		AppName:    m.AppName,
+		Lane:       m.Lane,
+		DeployName: m.DeployName,
+		Action:     domain.DriftAction(m.Action),
+		Items:      items,
+		Message:    m.Message,
+		CreatedAt:  m.CreatedAt,
+	}, nil
+}
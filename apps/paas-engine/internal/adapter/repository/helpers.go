@@ -1,6 +1,14 @@
 package repository
 
-import "strings"
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"gorm.io/gorm"
+)
 
 func isUniqueConstraintError(err error) bool {
 	if err == nil {
@@ -10,3 +18,84 @@ func isUniqueConstraintError(err error) bool {
 	return strings.Contains(msg, "UNIQUE constraint failed") ||
 		strings.Contains(msg, "duplicate key")
 }
+
+// BuildOptionsColumns 是 domain.BuildOptions 的列，嵌入 ImageRepoModel / BuildModel。
+type BuildOptionsColumns struct {
+	BuildArgs       string // JSON 序列化的 map[string]string
+	SecretBuildArgs string // JSON，ARG 名 → bundle/KEY 引用
+	BuildTarget     string
+	BuildPlatform   string
+	Labels          string // JSON 序列化的 map[string]string
+}
+
+func buildOptionsToColumns(o domain.BuildOptions) BuildOptionsColumns {
+	return BuildOptionsColumns{
+		BuildArgs:       marshalStringMap(o.BuildArgs),
+		SecretBuildArgs: marshalStringMap(o.SecretBuildArgs),
+		BuildTarget:     o.Target,
+		BuildPlatform:   o.Platform,
+		Labels:          marshalStringMap(o.Labels),
+	}
+}
+
+func columnsToBuildOptions(c BuildOptionsColumns) domain.BuildOptions {
+	return domain.BuildOptions{
+		BuildArgs:       unmarshalStringMap(c.BuildArgs),
+		SecretBuildArgs: unmarshalStringMap(c.SecretBuildArgs),
+		Target:          c.BuildTarget,
+		Platform:        c.BuildPlatform,
+		Labels:          unmarshalStringMap(c.Labels),
+	}
+}
+
+func marshalStringMap(m map[string]string) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func unmarshalStringMap(s string) map[string]string {
+	if s == "" || s == "null" {
+		return nil
+	}
+	var m map[string]string
+	_ = json.Unmarshal([]byte(s), &m)
+	return m
+}
+
+// LeaseColumns 是 domain.Lease 的列，嵌入后台流程的模型。整行 Update 不写这两列，
+// 只由 claimLease / renewLease 改，避免持有者用内存里的旧值覆盖别人刚抢到的租约。
+type LeaseColumns struct {
+	Owner       string
+	HeartbeatAt *time.Time
+}
+
+var leaseColumnNames = []string{"owner", "heartbeat_at"}
+
+func leaseToColumns(l domain.Lease) LeaseColumns {
+	return LeaseColumns{Owner: l.Owner, HeartbeatAt: l.HeartbeatAt}
+}
+
+func columnsToLease(c LeaseColumns) domain.Lease {
+	return domain.Lease{Owner: c.Owner, HeartbeatAt: c.HeartbeatAt}
+}
+
+// claimLease 在 id 对应的记录处于 statuses 之一、且租约无人持有或心跳早于 expiredBefore 时把租约交给 owner。
+// 条件写在同一条 UPDATE 里，多个实例同时抢只有一个 RowsAffected 为 1。
+func claimLease(ctx context.Context, db *gorm.DB, model any, id string, statuses []string, owner string, now, expiredBefore time.Time) (bool, error) {
+	result := db.WithContext(ctx).Model(model).
+		Where("id = ? AND status IN ?", id, statuses).
+		Where("owner IS NULL OR owner = '' OR heartbeat_at IS NULL OR heartbeat_at < ?", expiredBefore).
+		UpdateColumns(map[string]any{"owner": owner, "heartbeat_at": now})
+	return result.RowsAffected == 1, result.Error
+}
+
+// renewLease 续约，租约已不归 owner 时返回 false。
+func renewLease(ctx context.Context, db *gorm.DB, model any, id, owner string, now time.Time) (bool, error) {
+	result := db.WithContext(ctx).Model(model).
+		Where("id = ? AND owner = ?", id, owner).
+		UpdateColumn("heartbeat_at", now)
+	return result.RowsAffected == 1, result.Error
+}
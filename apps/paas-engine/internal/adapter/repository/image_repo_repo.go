@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
@@ -65,27 +66,66 @@ func (r *ImageRepoRepo) Delete(ctx context.Context, name string) error {
 }
 
 func imageRepoToModel(ir *domain.ImageRepo) *ImageRepoModel {
+	var depPaths string
+	if len(ir.DependencyPaths) > 0 {
+		b, _ := json.Marshal(ir.DependencyPaths)
+		depPaths = string(b)
+	}
+	var retention string
+	if ir.Retention != nil {
+		b, _ := json.Marshal(ir.Retention)
+		retention = string(b)
+	}
+	var scanPolicy string
+	if ir.ScanPolicy != nil {
+		b, _ := json.Marshal(ir.ScanPolicy)
+		scanPolicy = string(b)
+	}
 	return &ImageRepoModel{
-		Name:       ir.Name,
-		Registry:   ir.Registry,
-		GitRepo:    ir.GitRepo,
-		ContextDir: ir.ContextDir,
-		Dockerfile: ir.Dockerfile,
-		NoCache:    ir.NoCache,
-		CreatedAt:  ir.CreatedAt,
-		UpdatedAt:  ir.UpdatedAt,
+		Name:                ir.Name,
+		Registry:            ir.Registry,
+		GitRepo:             ir.GitRepo,
+		ContextDir:          ir.ContextDir,
+		Dockerfile:          ir.Dockerfile,
+		NoCache:             ir.NoCache,
+		Builder:             ir.Builder,
+		DependencyPaths:     depPaths,
+		Retention:           retention,
+		ScanPolicy:          scanPolicy,
+		BuildOptionsColumns: buildOptionsToColumns(ir.BuildOptions),
+		CreatedAt:           ir.CreatedAt,
+		UpdatedAt:           ir.UpdatedAt,
 	}
 }
 
 func modelToImageRepo(m *ImageRepoModel) *domain.ImageRepo {
+	var depPaths []string
+	if m.DependencyPaths != "" {
+		_ = json.Unmarshal([]byte(m.DependencyPaths), &depPaths)
+	}
+	var retention *domain.ImageRetention
+	if m.Retention != "" {
+		retention = &domain.ImageRetention{}
+		_ = json.Unmarshal([]byte(m.Retention), retention)
+	}
+	var scanPolicy *domain.ImageScanPolicy
+	if m.ScanPolicy != "" {
+		scanPolicy = &domain.ImageScanPolicy{}
+		_ = json.Unmarshal([]byte(m.ScanPolicy), scanPolicy)
+	}
 	return &domain.ImageRepo{
-		Name:       m.Name,
-		Registry:   m.Registry,
-		GitRepo:    m.GitRepo,
-		ContextDir: m.ContextDir,
-		Dockerfile: m.Dockerfile,
-		NoCache:    m.NoCache,
-		CreatedAt:  m.CreatedAt,
-		UpdatedAt:  m.UpdatedAt,
+		Name:            m.Name,
+		Registry:        m.Registry,
+		GitRepo:         m.GitRepo,
+		ContextDir:      m.ContextDir,
+		Dockerfile:      m.Dockerfile,
+		NoCache:         m.NoCache,
+		Builder:         m.Builder,
+		DependencyPaths: depPaths,
+		Retention:       retention,
+		ScanPolicy:      scanPolicy,
+		BuildOptions:    columnsToBuildOptions(m.BuildOptionsColumns),
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
 	}
 }
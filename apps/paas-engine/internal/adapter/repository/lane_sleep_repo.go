@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ port.LaneSleepPolicyRepository = (*LaneSleepPolicyRepo)(nil)
+
+type LaneSleepPolicyRepo struct {
+	db *gorm.DB
+}
+
+func NewLaneSleepPolicyRepo(db *gorm.DB) *LaneSleepPolicyRepo {
+	return &LaneSleepPolicyRepo{db: db}
+}
+
+func (r *LaneSleepPolicyRepo) Upsert(ctx context.Context, policy *domain.LaneSleepPolicy) error {
+	m := LaneSleepPolicyModel{
+		Lane:      policy.Lane,
+		IdleHours: policy.IdleHours,
+		Schedule:  policy.Schedule,
+		UpdatedAt: policy.UpdatedAt,
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "lane"}},
+		DoUpdates: clause.AssignmentColumns([]string{"idle_hours", "schedule", "updated_at"}),
+	}).Create(&m).Error
+}
+
+func (r *LaneSleepPolicyRepo) FindByLane(ctx context.Context, lane string) (*domain.LaneSleepPolicy, error) {
+	var m LaneSleepPolicyModel
+	result := r.db.WithContext(ctx).First(&m, "lane = ?", lane)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrLaneSleepPolicyNotFound
+		}
+		return nil, result.Error
+	}
+	return modelToLaneSleepPolicy(&m), nil
+}
+
+func (r *LaneSleepPolicyRepo) FindAll(ctx context.Context) ([]*domain.LaneSleepPolicy, error) {
+	var models []LaneSleepPolicyModel
+	if err := r.db.WithContext(ctx).Order("lane").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.LaneSleepPolicy, 0, len(models))
+	for i := range models {
+		out = append(out, modelToLaneSleepPolicy(&models[i]))
+	}
+	return out, nil
+}
+
+func (r *LaneSleepPolicyRepo) Delete(ctx context.Context, lane string) error {
+	result := r.db.WithContext(ctx).Delete(&LaneSleepPolicyModel{}, "lane = ?", lane)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrLaneSleepPolicyNotFound
+	}
+	return nil
+}
+
+func modelToLaneSleepPolicy(m *LaneSleepPolicyModel) *domain.LaneSleepPolicy {
+	return &domain.LaneSleepPolicy{
+		Lane:      m.Lane,
+		IdleHours: m.IdleHours,
+		Schedule:  m.Schedule,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
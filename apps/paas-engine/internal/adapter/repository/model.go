@@ -17,6 +17,11 @@ type AppModel struct {
 	AllowedLaneClasses string // JSON 序列化的 []string
 	SidecarEnabled     bool
 	Volumes            string // JSON 序列化的 []VolumeMount
+	Resources          string // JSON 序列化的 *ResourceSpec
+	Probes             string // JSON 序列化的 *HealthProbes
+	DriftPolicy        string
+	InitContainers     string // JSON 序列化的 []ExtraContainer
+	Sidecars           string // JSON 序列化的 []ExtraContainer
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 }
@@ -31,46 +36,195 @@ type ImageRepoModel struct {
 	ContextDir string
 	Dockerfile string
 	NoCache    bool
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	Builder    string
+	// JSON 序列化的 []string
+	DependencyPaths string
+	Retention       string // JSON 序列化的 *ImageRetention
+	ScanPolicy      string // JSON 序列化的 *ImageScanPolicy
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	BuildOptionsColumns
 }
 
 func (ImageRepoModel) TableName() string { return "image_repos" }
 
 // BuildModel 是 Build 的数据库持久化模型。
 type BuildModel struct {
+	ID              string `gorm:"primaryKey"`
+	ImageRepoName   string `gorm:"index"`
+	GitRef          string
+	ImageTag        string
+	Version         string
+	Channel         string
+	Priority        int
+	Status          string `gorm:"index"`
+	JobName         string
+	Log             string `gorm:"type:text"`
+	CommitSHA       string
+	Digest          string
+	Dockerfile      string
+	StartedAt       *time.Time
+	DurationSeconds int
+	Fingerprint     string `gorm:"index"`
+	PrunedAt        *time.Time
+	PromotedFrom    string
+	VerifiedLane    string
+	ScanStatus      string `gorm:"index"`     // 冗余 Scan.Status，便于查待扫描的构建
+	Scan            string `gorm:"type:text"` // JSON 序列化的 *ImageScan
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	BuildOptionsColumns
+}
+
+func (BuildModel) TableName() string { return "builds" }
+
+// ReleaseModel 是 Release 的数据库持久化模型。
+type ReleaseModel struct {
 	ID            string `gorm:"primaryKey"`
-	ImageRepoName string `gorm:"index"`
-	GitRef        string
-	ImageTag      string
-	Version       string
-	Channel       string
+	AppName       string `gorm:"uniqueIndex:idx_app_lane"`
+	Lane          string `gorm:"uniqueIndex:idx_app_lane"`
+	Image         string
+	Replicas      int32
+	Envs          string // JSON 序列化
+	Version       string // 自定义版本标识，用于环境变量注入
 	Status        string
-	JobName       string
-	Log           string `gorm:"type:text"`
+	Message       string `gorm:"type:text"`
+	DeployName    string
+	Resources     string // JSON 序列化的 *ResourceSpec
+	Probes        string // JSON 序列化的 *HealthProbes
+	Autoscaling   string // JSON 序列化的 *AutoscalingSpec
+	SleptReplicas int32  // lane 休眠前的副本数
+	CommitSHA     string
+	ImageDigest   string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
 
-func (BuildModel) TableName() string { return "builds" }
+func (ReleaseModel) TableName() string { return "releases" }
 
-// ReleaseModel 是 Release 的数据库持久化模型。
-type ReleaseModel struct {
-	ID         string `gorm:"primaryKey"`
-	AppName    string `gorm:"uniqueIndex:idx_app_lane"`
-	Lane       string `gorm:"uniqueIndex:idx_app_lane"`
-	Image      string
-	Replicas   int32
-	Envs       string // JSON 序列化
-	Version    string // 自定义版本标识，用于环境变量注入
-	Status     string
-	Message    string `gorm:"type:text"`
+// ReleaseRevisionModel 是 release 部署历史的持久化模型。
+// (release_id, revision) 联合主键；revision 在 release 内单调递增，由事务内 max+1 分配。
+type ReleaseRevisionModel struct {
+	ReleaseID   string `gorm:"primaryKey"`
+	Revision    int64  `gorm:"primaryKey;autoIncrement:false"`
+	AppName     string `gorm:"index:idx_revision_app_lane"`
+	Lane        string `gorm:"index:idx_revision_app_lane"`
+	Image       string
+	Version     string
+	Envs        string // JSON 序列化
+	Replicas    int32
+	Resources   string // JSON 序列化的 *ResourceSpec
+	Probes      string // JSON 序列化的 *HealthProbes
+	Autoscaling string // JSON 序列化的 *AutoscalingSpec
+	CommitSHA   string
+	ImageDigest string
+	BundleHash  string
+	Reason      string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"not null"`
+}
+
+func (ReleaseRevisionModel) TableName() string { return "release_revisions" }
+
+// ReleaseCanaryModel 是金丝雀发布的持久化模型，Steps / PreviousTargets 用 jsonb 存。
+type ReleaseCanaryModel struct {
+	ID                  string `gorm:"primaryKey"`
+	ReleaseID           string `gorm:"index"`
+	AppName             string
+	Lane                string
+	CanaryLane          string
+	CanaryReleaseID     string
+	ImageTag            string
+	Image               string
+	GatewayRule         string
+	Steps               string `gorm:"type:jsonb"`
+	CurrentStep         int
+	StepIntervalSeconds int
+	MaxRestarts         int32
+	ScanOverrideReason  string `gorm:"type:text"`
+	PreviousTargets     string `gorm:"type:jsonb"`
+	Status              string `gorm:"index"`
+	Message             string `gorm:"type:text"`
+	LeaseColumns        `gorm:"embedded"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	FinishedAt          *time.Time
+}
+
+func (ReleaseCanaryModel) TableName() string { return "release_canaries" }
+
+// ReleaseOperationModel 是后台部署操作的持久化模型，Events 用 jsonb 存。
+type ReleaseOperationModel struct {
+	ID           string `gorm:"primaryKey"`
+	ReleaseID    string `gorm:"index"`
+	AppName      string
+	Lane         string
+	Type         string
+	Image        string
+	Reason       string
+	Status       string `gorm:"index"`
+	Message      string `gorm:"type:text"`
+	Events       string `gorm:"type:jsonb"`
+	LeaseColumns `gorm:"embedded"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	FinishedAt   *time.Time
+}
+
+func (ReleaseOperationModel) TableName() string { return "release_operations" }
+
+// ReleaseDriftModel 是 release 当前漂移状态的持久化模型，每个 release 至多一行，Items 用 jsonb 存。
+type ReleaseDriftModel struct {
+	ReleaseID  string `gorm:"primaryKey"`
+	AppName    string
+	Lane       string
 	DeployName string
-	CreatedAt  time.Time
+	Policy     string
+	Items      string `gorm:"type:jsonb"`
+	DetectedAt time.Time
 	UpdatedAt  time.Time
 }
 
-func (ReleaseModel) TableName() string { return "releases" }
+func (ReleaseDriftModel) TableName() string { return "release_drifts" }
+
+// DriftEventModel 是漂移处理记录的持久化模型，Items 用 jsonb 存。
+type DriftEventModel struct {
+	ID         string `gorm:"primaryKey"`
+	ReleaseID  string `gorm:"index"`
+	AppName    string `gorm:"index"`
+	Lane       string
+	DeployName string
+	Action     string
+	Items      string    `gorm:"type:jsonb"`
+	Message    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (DriftEventModel) TableName() string { return "drift_events" }
+
+// LaneModel 是登记 lane 的持久化模型。
+type LaneModel struct {
+	Name        string `gorm:"primaryKey"`
+	Class       string
+	Owner       string `gorm:"index"`
+	Description string
+	ExpiresAt   *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (LaneModel) TableName() string { return "lanes" }
+
+// LaneSleepPolicyModel 是 lane 休眠策略的持久化模型。
+type LaneSleepPolicyModel struct {
+	Lane      string `gorm:"primaryKey"`
+	IdleHours int
+	Schedule  string
+	UpdatedAt time.Time
+}
+
+func (LaneSleepPolicyModel) TableName() string { return "lane_sleep_policies" }
 
 // CIConfigModel 是 CIConfig 的数据库持久化模型。
 type CIConfigModel struct {
@@ -95,8 +249,13 @@ type PipelineRunModel struct {
 	Services   string // JSON 序列化的 []string
 	Status     string
 	Message    string `gorm:"type:text"`
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	// 变更检测
+	BaseCommitSHA   string
+	SkippedServices string // JSON 序列化的 []string
+	Config          string `gorm:"type:text"` // JSON 序列化的 pipeline.yml 快照
+	LeaseColumns    `gorm:"embedded"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 func (PipelineRunModel) TableName() string { return "pipeline_runs" }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 	"github.com/chiwei-platform/paas-engine/internal/port"
@@ -171,9 +172,47 @@ func (r *PipelineRunRepo) ExistsByCommitSHA(ctx context.Context, sha string) (bo
 	return count > 0, err
 }
 
+func (r *PipelineRunRepo) FindLatestSucceeded(ctx context.Context, lane string) (*domain.PipelineRun, error) {
+	var m PipelineRunModel
+	result := r.db.WithContext(ctx).
+		Where("lane = ? AND status = ? AND commit_sha != ?", lane, string(domain.PipelineRunSucceeded), "manual").
+		Order("created_at desc").
+		First(&m)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrPipelineRunNotFound
+		}
+		return nil, result.Error
+	}
+	return modelToPipelineRun(&m), nil
+}
+
+func (r *PipelineRunRepo) FindUnfinished(ctx context.Context) ([]*domain.PipelineRun, error) {
+	var models []PipelineRunModel
+	if err := r.db.WithContext(ctx).
+		Where("status IN ?", []string{string(domain.PipelineRunPending), string(domain.PipelineRunRunning)}).
+		Order("created_at asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	runs := make([]*domain.PipelineRun, 0, len(models))
+	for i := range models {
+		runs = append(runs, modelToPipelineRun(&models[i]))
+	}
+	return runs, nil
+}
+
 func (r *PipelineRunRepo) Update(ctx context.Context, run *domain.PipelineRun) error {
 	m := pipelineRunToModel(run)
-	return r.db.WithContext(ctx).Save(m).Error
+	return r.db.WithContext(ctx).Omit(leaseColumnNames...).Save(m).Error
+}
+
+func (r *PipelineRunRepo) ClaimLease(ctx context.Context, id, owner string, now, expiredBefore time.Time) (bool, error) {
+	statuses := []string{string(domain.PipelineRunPending), string(domain.PipelineRunRunning)}
+	return claimLease(ctx, r.db, &PipelineRunModel{}, id, statuses, owner, now, expiredBefore)
+}
+
+func (r *PipelineRunRepo) RenewLease(ctx context.Context, id, owner string, now time.Time) (bool, error) {
+	return renewLease(ctx, r.db, &PipelineRunModel{}, id, owner, now)
 }
 
 // --- StageRun CRUD ---
@@ -240,17 +279,31 @@ func (r *PipelineRunRepo) UpdateJob(ctx context.Context, job *domain.JobRun) err
 
 func pipelineRunToModel(p *domain.PipelineRun) *PipelineRunModel {
 	servicesJSON, _ := json.Marshal(p.Services)
+	var skipped string
+	if len(p.SkippedServices) > 0 {
+		b, _ := json.Marshal(p.SkippedServices)
+		skipped = string(b)
+	}
+	var config string
+	if p.Config != nil {
+		b, _ := json.Marshal(p.Config)
+		config = string(b)
+	}
 	return &PipelineRunModel{
-		ID:         p.ID,
-		CIConfigID: p.CIConfigID,
-		GitRef:     p.GitRef,
-		CommitSHA:  p.CommitSHA,
-		Lane:       p.Lane,
-		Services:   string(servicesJSON),
-		Status:     string(p.Status),
-		Message:    p.Message,
-		CreatedAt:  p.CreatedAt,
-		UpdatedAt:  p.UpdatedAt,
+		ID:              p.ID,
+		CIConfigID:      p.CIConfigID,
+		GitRef:          p.GitRef,
+		CommitSHA:       p.CommitSHA,
+		Lane:            p.Lane,
+		Services:        string(servicesJSON),
+		Status:          string(p.Status),
+		Message:         p.Message,
+		BaseCommitSHA:   p.BaseCommitSHA,
+		SkippedServices: skipped,
+		Config:          config,
+		LeaseColumns:    leaseToColumns(p.Lease),
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
 	}
 }
 
@@ -259,17 +312,30 @@ func modelToPipelineRun(m *PipelineRunModel) *domain.PipelineRun {
 	if m.Services != "" {
 		_ = json.Unmarshal([]byte(m.Services), &services)
 	}
+	var skipped []string
+	if m.SkippedServices != "" {
+		_ = json.Unmarshal([]byte(m.SkippedServices), &skipped)
+	}
+	var config *domain.PipelineConfig
+	if m.Config != "" {
+		config = &domain.PipelineConfig{}
+		_ = json.Unmarshal([]byte(m.Config), config)
+	}
 	return &domain.PipelineRun{
-		ID:         m.ID,
-		CIConfigID: m.CIConfigID,
-		GitRef:     m.GitRef,
-		CommitSHA:  m.CommitSHA,
-		Lane:       m.Lane,
-		Services:   services,
-		Status:     domain.PipelineRunStatus(m.Status),
-		Message:    m.Message,
-		CreatedAt:  m.CreatedAt,
-		UpdatedAt:  m.UpdatedAt,
+		ID:              m.ID,
+		CIConfigID:      m.CIConfigID,
+		GitRef:          m.GitRef,
+		CommitSHA:       m.CommitSHA,
+		Lane:            m.Lane,
+		Services:        services,
+		Status:          domain.PipelineRunStatus(m.Status),
+		Message:         m.Message,
+		BaseCommitSHA:   m.BaseCommitSHA,
+		SkippedServices: skipped,
+		Config:          config,
+		Lease:           columnsToLease(m.LeaseColumns),
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
 	}
 }
 
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"gorm.io/gorm"
+)
+
+var _ port.ReleaseCanaryRepository = (*ReleaseCanaryRepo)(nil)
+
+type ReleaseCanaryRepo struct {
+	db *gorm.DB
+}
+
+func NewReleaseCanaryRepo(db *gorm.DB) *ReleaseCanaryRepo {
+	return &ReleaseCanaryRepo{db: db}
+}
+
+func (r *ReleaseCanaryRepo) Save(ctx context.Context, canary *domain.ReleaseCanary) error {
+	m, err := canaryToModel(canary)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r *ReleaseCanaryRepo) Update(ctx context.Context, canary *domain.ReleaseCanary) error {
+	m, err := canaryToModel(canary)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit(leaseColumnNames...).Save(m).Error
+}
+
+func (r *ReleaseCanaryRepo) ClaimLease(ctx context.Context, id, owner string, now, expiredBefore time.Time) (bool, error) {
+	return claimLease(ctx, r.db, &ReleaseCanaryModel{}, id, []string{string(domain.CanaryStatusRunning)}, owner, now, expiredBefore)
+}
+
+func (r *ReleaseCanaryRepo) RenewLease(ctx context.Context, id, owner string, now time.Time) (bool, error) {
+	return renewLease(ctx, r.db, &ReleaseCanaryModel{}, id, owner, now)
+}
+
+func (r *ReleaseCanaryRepo) FindByID(ctx context.Context, id string) (*domain.ReleaseCanary, error) {
+	var m ReleaseCanaryModel
+	result := r.db.WithContext(ctx).First(&m, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrCanaryNotFound
+		}
+		return nil, result.Error
+	}
+	return modelToCanary(&m)
+}
+
+func (r *ReleaseCanaryRepo) FindLatestByRelease(ctx context.Context, releaseID string) (*domain.ReleaseCanary, error) {
+	var m ReleaseCanaryModel
+	result := r.db.WithContext(ctx).Where("release_id = ?", releaseID).Order("created_at desc").First(&m)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrCanaryNotFound
+		}
+		return nil, result.Error
+	}
+	return modelToCanary(&m)
+}
+
+func (r *ReleaseCanaryRepo) FindRunning(ctx context.Context) ([]*domain.ReleaseCanary, error) {
+	var models []ReleaseCanaryModel
+	if err := r.db.WithContext(ctx).Where("status = ?", string(domain.CanaryStatusRunning)).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.ReleaseCanary, 0, len(models))
+	for i := range models {
+		c, err := modelToCanary(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func canaryToModel(c *domain.ReleaseCanary) (*ReleaseCanaryModel, error) {
+	stepsJSON, err := json.Marshal(c.Steps)
+	if err != nil {
+		return nil, fmt.Errorf("marshal canary steps: %w", err)
+	}
+	targetsJSON, err := json.Marshal(c.PreviousTargets)
+	if err != nil {
+		return nil, fmt.Errorf("marshal canary previous targets: %w", err)
+	}
+	return &ReleaseCanaryModel{
+		ID:                  c.ID,
+		ReleaseID:           c.ReleaseID,
+		AppName:             c.AppName,
+		Lane:                c.Lane,
+		CanaryLane:          c.CanaryLane,
+		CanaryReleaseID:     c.CanaryReleaseID,
+		ImageTag:            c.ImageTag,
+		Image:               c.Image,
+		GatewayRule:         c.GatewayRule,
+		Steps:               string(stepsJSON),
+		CurrentStep:         c.CurrentStep,
+		StepIntervalSeconds: c.StepIntervalSeconds,
+		MaxRestarts:         c.MaxRestarts,
+		ScanOverrideReason:  c.ScanOverrideReason,
+		PreviousTargets:     string(targetsJSON),
+		Status:              string(c.Status),
+		Message:             c.Message,
+		LeaseColumns:        leaseToColumns(c.Lease),
+		CreatedAt:           c.CreatedAt,
+		UpdatedAt:           c.UpdatedAt,
+		FinishedAt:          c.FinishedAt,
+	}, nil
+}
+
+func modelToCanary(m *ReleaseCanaryModel) (*domain.ReleaseCanary, error) {
+	steps := []domain.CanaryStep{}
+	if m.Steps != "" {
+		if err := json.Unmarshal([]byte(m.Steps), &steps); err != nil {
+			return nil, fmt.Errorf("unmarshal canary steps: %w", err)
+		}
+	}
+	targets := []domain.GatewayTarget{}
+	if m.PreviousTargets != "" {
+		if err := json.Unmarshal([]byte(m.PreviousTargets), &targets); err != nil {
+			return nil, fmt.Errorf("unmarshal canary previous targets: %w", err)
+		}
+	}
+	return &domain.ReleaseCanary{
+		ID:                  m.ID,
+		ReleaseID:           m.ReleaseID,
+		AppName:             m.AppName,
+		Lane:                m.Lane,
+		CanaryLane:          m.CanaryLane,
+		CanaryReleaseID:     m.CanaryReleaseID,
+		ImageTag:            m.ImageTag,
+		Image:               m.Image,
+		GatewayRule:         m.GatewayRule,
+		Steps:               steps,
+		CurrentStep:         m.CurrentStep,
+		StepIntervalSeconds: m.StepIntervalSeconds,
+		MaxRestarts:         m.MaxRestarts,
+		ScanOverrideReason:  m.ScanOverrideReason,
+		PreviousTargets:     targets,
+		Status:              domain.CanaryStatus(m.Status),
+		Message:             m.Message,
+		Lease:               columnsToLease(m.LeaseColumns),
+		CreatedAt:           m.CreatedAt,
+		UpdatedAt:           m.UpdatedAt,
+		FinishedAt:          m.FinishedAt,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"gorm.io/gorm"
+)
+
+var _ port.ReleaseDriftRepository = (*ReleaseDriftRepo)(nil)
+
+type ReleaseDriftRepo struct {
+	db *gorm.DB
+}
+
+func NewReleaseDriftRepo(db *gorm.DB) *ReleaseDriftRepo {
+	return &ReleaseDriftRepo{db: db}
+}
+
+func (r *ReleaseDriftRepo) Save(ctx context.Context, drift *domain.ReleaseDrift) error {
+	m, err := releaseDriftToModel(drift)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Save(m).Error
+}
+
+func (r *ReleaseDriftRepo) Delete(ctx context.Context, releaseID string) error {
+	return r.db.WithContext(ctx).Delete(&ReleaseDriftModel{}, "release_id = ?", releaseID).Error
+}
+
+func (r *ReleaseDriftRepo) FindAll(ctx context.Context) ([]*domain.ReleaseDrift, error) {
+	var models []ReleaseDriftModel
+	if err := r.db.WithContext(ctx).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.ReleaseDrift, 0, len(models))
+	for i := range models {
+		d, err := modelToReleaseDrift(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func releaseDriftToModel(d *domain.ReleaseDrift) (*ReleaseDriftModel, error) {
+	itemsJSON, err := json.Marshal(d.Items)
+	if err != nil {
+		return nil, fmt.Errorf("marshal drift items: %w", err)
+	}
+	return &ReleaseDriftModel{
+		ReleaseID:  d.ReleaseID,
+		AppName:    d.AppName,
+		Lane:       d.Lane,
+		DeployName: d.DeployName,
+		Policy:     string(d.Policy),
+		Items:      string(itemsJSON),
+		DetectedAt: d.DetectedAt,
+	}, nil
+}
+
+func modelToReleaseDrift(m *ReleaseDriftModel) (*domain.ReleaseDrift, error) {
+	items := []domain.DriftItem{}
+	if m.Items != "" && m.Items != "null" {
+		if err := json.Unmarshal([]byte(m.Items), &items); err != nil {
+			return nil, fmt.Errorf("unmarshal drift items: %w", err)
+		}
+	}
+	return &domain.ReleaseDrift{
+		ReleaseID:  m.ReleaseID,
+		AppName:    m.AppName,
+		Lane:       m.Lane,
+		DeployName: m.DeployName,
+		Policy:     domain.DriftPolicy(m.Policy),
+		Items:      items,
+		DetectedAt: m.DetectedAt,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"gorm.io/gorm"
+)
+
+var _ port.ReleaseOperationRepository = (*ReleaseOperationRepo)(nil)
+
+type ReleaseOperationRepo struct {
+	db *gorm.DB
+}
+
+func NewReleaseOperationRepo(db *gorm.DB) *ReleaseOperationRepo {
+	return &ReleaseOperationRepo{db: db}
+}
+
+func (r *ReleaseOperationRepo) Save(ctx context.Context, op *domain.ReleaseOperation) error {
+	m, err := operationToModel(op)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r *ReleaseOperationRepo) Update(ctx context.Context, op *domain.ReleaseOperation) error {
+	m, err := operationToModel(op)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Omit(leaseColumnNames...).Save(m).Error
+}
+
+func (r *ReleaseOperationRepo) ClaimLease(ctx context.Context, id, owner string, now, expiredBefore time.Time) (bool, error) {
+	return claimLease(ctx, r.db, &ReleaseOperationModel{}, id, []string{string(domain.ReleaseOperationRunning)}, owner, now, expiredBefore)
+}
+
+func (r *ReleaseOperationRepo) RenewLease(ctx context.Context, id, owner string, now time.Time) (bool, error) {
+	return renewLease(ctx, r.db, &ReleaseOperationModel{}, id, owner, now)
+}
+
+func (r *ReleaseOperationRepo) FindByID(ctx context.Context, id string) (*domain.ReleaseOperation, error) {
+	var m ReleaseOperationModel
+	result := r.db.WithContext(ctx).First(&m, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrReleaseOperationNotFound
+		}
+		return nil, result.Error
+	}
+	return modelToOperation(&m)
+}
+
+func (r *ReleaseOperationRepo) FindRunning(ctx context.Context) ([]*domain.ReleaseOperation, error) {
+	var models []ReleaseOperationModel
+	if err := r.db.WithContext(ctx).Where("status = ?", string(domain.ReleaseOperationRunning)).
+		Order("created_at asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.ReleaseOperation, 0, len(models))
+	for i := range models {
+		op, err := modelToOperation(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+func operationToModel(op *domain.ReleaseOperation) (*ReleaseOperationModel, error) {
+	eventsJSON, err := json.Marshal(op.Events)
+	if err != nil {
+		return nil, fmt.Errorf("marshal operation events: %w", err)
+	}
+	return &ReleaseOperationModel{
+		ID:           op.ID,
+		ReleaseID:    op.ReleaseID,
+		AppName:      op.AppName,
+		Lane:         op.Lane,
+		Type:         string(op.Type),
+		Image:        op.Image,
+		Reason:       op.Reason,
+		Status:       string(op.Status),
+		Message:      op.Message,
+		Events:       string(eventsJSON),
+		LeaseColumns: leaseToColumns(op.Lease),
+		CreatedAt:    op.CreatedAt,
+		UpdatedAt:    op.UpdatedAt,
+		FinishedAt:   op.FinishedAt,
+	}, nil
+}
+
+func modelToOperation(m *ReleaseOperationModel) (*domain.ReleaseOperation, error) {
+	events := []domain.ReleaseOperationEvent{}
+	if m.Events != "" && m.Events != "null" {
+		if err := json.Unmarshal([]byte(m.Events), &events); err != nil {
+			return nil, fmt.Errorf("unmarshal operation events: %w", err)
+		}
+	}
+	return &domain.ReleaseOperation{
+		ID:         m.ID,
+		ReleaseID:  m.ReleaseID,
+		AppName:    m.AppName,
+		Lane:       m.Lane,
+		Type:       domain.ReleaseOperationType(m.Type),
+		Image:      m.Image,
+		Reason:     m.Reason,
+		Status:     domain.ReleaseOperationStatus(m.Status),
+		Message:    m.Message,
+		Events:     events,
+		Lease:      columnsToLease(m.LeaseColumns),
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+		FinishedAt: m.FinishedAt,
+	}, nil
+}
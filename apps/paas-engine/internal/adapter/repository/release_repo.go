@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 	"github.com/chiwei-platform/paas-engine/internal/port"
@@ -99,19 +100,33 @@ func releaseToModel(r *domain.Release) (*ReleaseModel, error) {
 	if err != nil {
 		return nil, err
 	}
+	resourcesJSON, probesJSON, err := marshalWorkloadOverrides(r.Resources, r.Probes)
+	if err != nil {
+		return nil, err
+	}
+	autoscalingJSON, err := marshalAutoscaling(r.Autoscaling)
+	if err != nil {
+		return nil, err
+	}
 	return &ReleaseModel{
-		ID:         r.ID,
-		AppName:    r.AppName,
-		Lane:       r.Lane,
-		Image:      r.Image,
-		Replicas:   r.Replicas,
-		Envs:       string(envsJSON),
-		Version:    r.Version,
-		Status:     string(r.Status),
-		Message:    r.Message,
-		DeployName: r.DeployName,
-		CreatedAt:  r.CreatedAt,
-		UpdatedAt:  r.UpdatedAt,
+		ID:            r.ID,
+		AppName:       r.AppName,
+		Lane:          r.Lane,
+		Image:         r.Image,
+		Replicas:      r.Replicas,
+		Envs:          string(envsJSON),
+		Version:       r.Version,
+		Status:        string(r.Status),
+		Message:       r.Message,
+		DeployName:    r.DeployName,
+		Resources:     resourcesJSON,
+		Probes:        probesJSON,
+		Autoscaling:   autoscalingJSON,
+		SleptReplicas: r.SleptReplicas,
+		CommitSHA:     r.CommitSHA,
+		ImageDigest:   r.ImageDigest,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
 	}, nil
 }
 
@@ -122,18 +137,82 @@ func modelToRelease(m *ReleaseModel) (*domain.Release, error) {
 			return nil, err
 		}
 	}
+	resources, probes, err := unmarshalWorkloadOverrides(m.Resources, m.Probes)
+	if err != nil {
+		return nil, err
+	}
+	autoscaling, err := unmarshalAutoscaling(m.Autoscaling)
+	if err != nil {
+		return nil, err
+	}
 	return &domain.Release{
-		ID:         m.ID,
-		AppName:    m.AppName,
-		Lane:       m.Lane,
-		Image:      m.Image,
-		Replicas:   m.Replicas,
-		Envs:       envs,
-		Version:    m.Version,
-		Status:     domain.ReleaseStatus(m.Status),
-		Message:    m.Message,
-		DeployName: m.DeployName,
-		CreatedAt:  m.CreatedAt,
-		UpdatedAt:  m.UpdatedAt,
+		ID:            m.ID,
+		AppName:       m.AppName,
+		Lane:          m.Lane,
+		Image:         m.Image,
+		Replicas:      m.Replicas,
+		Envs:          envs,
+		Version:       m.Version,
+		Status:        domain.ReleaseStatus(m.Status),
+		Message:       m.Message,
+		DeployName:    m.DeployName,
+		Resources:     resources,
+		Probes:        probes,
+		Autoscaling:   autoscaling,
+		SleptReplicas: m.SleptReplicas,
+		CommitSHA:     m.CommitSHA,
+		ImageDigest:   m.ImageDigest,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
 	}, nil
 }
+
+// marshalWorkloadOverrides 序列化 release 级 resources / probes 覆盖（nil 落 "null"）。
+func marshalWorkloadOverrides(resources *domain.ResourceSpec, probes *domain.HealthProbes) (string, string, error) {
+	resourcesJSON, err := json.Marshal(resources)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal Resources: %w", err)
+	}
+	probesJSON, err := json.Marshal(probes)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal Probes: %w", err)
+	}
+	return string(resourcesJSON), string(probesJSON), nil
+}
+
+// unmarshalWorkloadOverrides 是 marshalWorkloadOverrides 的逆操作，旧数据空串视为未设置。
+func unmarshalWorkloadOverrides(resourcesJSON, probesJSON string) (*domain.ResourceSpec, *domain.HealthProbes, error) {
+	var resources *domain.ResourceSpec
+	if resourcesJSON != "" {
+		if err := json.Unmarshal([]byte(resourcesJSON), &resources); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal Resources: %w", err)
+		}
+	}
+	var probes *domain.HealthProbes
+	if probesJSON != "" {
+		if err := json.Unmarshal([]byte(probesJSON), &probes); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal Probes: %w", err)
+		}
+	}
+	return resources, probes, nil
+}
+
+// marshalAutoscaling 序列化 release 级 HPA 配置（nil 落 "null"）。
+func marshalAutoscaling(spec *domain.AutoscalingSpec) (string, error) {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("marshal Autoscaling: %w", err)
+	}
+	return string(b), nil
+}
+
+// unmarshalAutoscaling 是 marshalAutoscaling 的逆操作，旧数据空串视为未设置。
+func unmarshalAutoscaling(s string) (*domain.AutoscalingSpec, error) {
+	var spec *domain.AutoscalingSpec
+	if s != "" {
+		if err := json.Unmarshal([]byte(s), &spec); err != nil {
+			return nil, fmt.Errorf("unmarshal Autoscaling: %w", err)
+		}
+	}
+	return spec, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ port.ReleaseRevisionRepository = (*ReleaseRevisionRepo)(nil)
+
+type ReleaseRevisionRepo struct {
+	db *gorm.DB
+}
+
+func NewReleaseRevisionRepo(db *gorm.DB) *ReleaseRevisionRepo {
+	return &ReleaseRevisionRepo{db: db}
+}
+
+// Save 在事务内锁住该 release 行后取 max(revision)+1，保证同一 release 并发部署时
+// revision 号不重复；联合主键兜底。
+func (r *ReleaseRevisionRepo) Save(ctx context.Context, rev *domain.ReleaseRevision) error {
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&ReleaseModel{}, "id = ?", rev.ReleaseID).Error; err != nil &&
+			!errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var latest int64
+		if err := tx.Model(&ReleaseRevisionModel{}).
+			Where("release_id = ?", rev.ReleaseID).
+			Select("COALESCE(MAX(revision), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		rev.Revision = latest + 1
+		m, err := releaseRevisionToModel(rev)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(m).Error; err != nil {
+			if isUniqueConstraintError(err) {
+				return domain.ErrAlreadyExists
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *ReleaseRevisionRepo) FindByRelease(ctx context.Context, releaseID string, limit int) ([]*domain.ReleaseRevision, error) {
+	q := r.db.WithContext(ctx).Where("release_id = ?", releaseID).Order("revision desc")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var models []ReleaseRevisionModel
+	if err := q.Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*domain.ReleaseRevision, 0, len(models))
+	for i := range models {
+		rev, err := modelToReleaseRevision(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rev)
+	}
+	return out, nil
+}
+
+func (r *ReleaseRevisionRepo) ListImages(ctx context.Context) ([]string, error) {
+	var images []string
+	if err := r.db.WithContext(ctx).Model(&ReleaseRevisionModel{}).Distinct().Pluck("image", &images).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *ReleaseRevisionRepo) FindByReleaseAndRevision(ctx context.Context, releaseID string, revision int64) (*domain.ReleaseRevision, error) {
+	var m ReleaseRevisionModel
+	result := r.db.WithContext(ctx).First(&m, "release_id = ? AND revision = ?", releaseID, revision)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrReleaseRevisionNotFound
+		}
+		return nil, result.Error
+	}
+	return modelToReleaseRevision(&m)
+}
+
+func releaseRevisionToModel(rev *domain.ReleaseRevision) (*ReleaseRevisionModel, error) {
+	envsJSON, err := json.Marshal(rev.Envs)
+	if err != nil {
+		return nil, err
+	}
+	resourcesJSON, probesJSON, err := marshalWorkloadOverrides(rev.Resources, rev.Probes)
+	if err != nil {
+		return nil, err
+	}
+	autoscalingJSON, err := marshalAutoscaling(rev.Autoscaling)
+	if err != nil {
+		return nil, err
+	}
+	return &ReleaseRevisionModel{
+		ReleaseID:   rev.ReleaseID,
+		Revision:    rev.Revision,
+		AppName:     rev.AppName,
+		Lane:        rev.Lane,
+		Image:       rev.Image,
+		Version:     rev.Version,
+		Envs:        string(envsJSON),
+		Replicas:    rev.Replicas,
+		Resources:   resourcesJSON,
+		Probes:      probesJSON,
+		Autoscaling: autoscalingJSON,
+		CommitSHA:   rev.CommitSHA,
+		ImageDigest: rev.ImageDigest,
+		BundleHash:  rev.BundleHash,
+		Reason:      rev.Reason,
+		CreatedAt:   rev.CreatedAt,
+	}, nil
+}
+
+func modelToReleaseRevision(m *ReleaseRevisionModel) (*domain.ReleaseRevision, error) {
+	var envs map[string]string
+	if m.Envs != "" {
+		if err := json.Unmarshal([]byte(m.Envs), &envs); err != nil {
+			return nil, err
+		}
+	}
+	resources, probes, err := unmarshalWorkloadOverrides(m.Resources, m.Probes)
+	if err != nil {
+		return nil, err
+	}
+	autoscaling, err := unmarshalAutoscaling(m.Autoscaling)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ReleaseRevision{
+		ReleaseID:   m.ReleaseID,
+		Revision:    m.Revision,
+		AppName:     m.AppName,
+		Lane:        m.Lane,
+		Image:       m.Image,
+		Version:     m.Version,
+		Envs:        envs,
+		Replicas:    m.Replicas,
+		Resources:   resources,
+		Probes:      probes,
+		Autoscaling: autoscaling,
+		CommitSHA:   m.CommitSHA,
+		ImageDigest: m.ImageDigest,
+		BundleHash:  m.BundleHash,
+		Reason:      m.Reason,
+		CreatedAt:   m.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+func TestReleaseRevisionRoundTrip(t *testing.T) {
+	original := &domain.ReleaseRevision{
+		ReleaseID:  "rel-1",
+		Revision:   4,
+		AppName:    "agent-service",
+		Lane:       "prod",
+		Image:      "harbor.local/inner-bot/agent-service:1.0.0.3",
+		Version:    "1.0.0.3",
+		Envs:       map[string]string{"LOG_LEVEL": "debug"},
+		Replicas:   2,
+		BundleHash: "abc",
+		Reason:     "rollback to revision 2",
+		CreatedAt:  time.Now(),
+	}
+	m, err := releaseRevisionToModel(original)
+	if err != nil {
+		t.Fatalf("releaseRevisionToModel failed: %v", err)
+	}
+	got, err := modelToReleaseRevision(m)
+	if err != nil {
+		t.Fatalf("modelToReleaseRevision failed: %v", err)
+	}
+	if got.ReleaseID != "rel-1" || got.Revision != 4 || got.Image != original.Image {
+		t.Errorf("identity fields mismatch: %+v", got)
+	}
+	if got.Replicas != 2 || got.Version != "1.0.0.3" || got.BundleHash != "abc" || got.Reason != original.Reason {
+		t.Errorf("payload fields mismatch: %+v", got)
+	}
+	if got.Envs["LOG_LEVEL"] != "debug" {
+		t.Errorf("envs not preserved: %+v", got.Envs)
+	}
+}
@@ -10,6 +10,19 @@ type VolumeMount struct {
 	SubPath   string `json:"sub_path,omitempty"`
 }
 
+// ExtraContainer 是 App 上声明的附加容器：init 容器（主容器启动前按顺序跑完，如数据库迁移）
+// 或 sidecar（与主容器同生命周期，如日志采集、DB proxy）。
+type ExtraContainer struct {
+	Name        string            `json:"name"`
+	Image       string            `json:"image,omitempty"` // 空表示使用 release 的镜像
+	Command     []string          `json:"command,omitempty"`
+	Args        []string          `json:"args,omitempty"`
+	Envs        map[string]string `json:"envs,omitempty"`
+	InheritEnvs bool              `json:"inherit_envs,omitempty"` // 同时注入主容器的 env / envFrom（含 ConfigBundle），Envs 优先
+	Volumes     []VolumeMount     `json:"volumes,omitempty"`
+	Resources   *ResourceSpec     `json:"resources,omitempty"`
+}
+
 // App 代表一个应用定义，是 PaaS 引擎的核心管理单元。
 // App 本身不映射到任何 K8s 资源，仅作为逻辑锚点。
 type App struct {
@@ -26,6 +39,11 @@ type App struct {
 	AllowedLaneClasses []string         `json:"allowed_lane_classes,omitempty"` // 限制可部署的 lane class，nil 或空 = 全允许
 	SidecarEnabled    bool              `json:"sidecar_enabled,omitempty"`
 	Volumes           []VolumeMount     `json:"volumes,omitempty"`
+	Resources         *ResourceSpec     `json:"resources,omitempty"` // 主容器 requests/limits，Release 可逐项覆盖
+	Probes            *HealthProbes     `json:"probes,omitempty"`    // 主容器健康检查，Release 可按探针类别覆盖
+	DriftPolicy       DriftPolicy       `json:"drift_policy,omitempty"` // 线上 Deployment 被改动后的处理方式，空 = report
+	InitContainers    []ExtraContainer  `json:"init_containers,omitempty"` // 主容器前按顺序执行
+	Sidecars          []ExtraContainer  `json:"sidecars,omitempty"`        // 与主容器同 Pod 常驻
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 }
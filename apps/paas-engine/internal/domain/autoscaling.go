@@ -0,0 +1,49 @@
+package domain
+
+import "fmt"
+
+// AutoscalingSpec 配置 Release 的 HPA（autoscaling/v2）。配置后副本数由 HPA 决定，
+// Release.Replicas 不再写入 Deployment；两个 utilization 目标至少配置一个，按主容器
+// requests 的百分比计算，因此对应的 request 必须配置。
+type AutoscalingSpec struct {
+	MinReplicas             int32 `json:"min_replicas"`
+	MaxReplicas             int32 `json:"max_replicas"`
+	TargetCPUUtilization    int32 `json:"target_cpu_utilization,omitempty"`    // 百分比，如 70
+	TargetMemoryUtilization int32 `json:"target_memory_utilization,omitempty"` // 百分比
+}
+
+// AutoscalingStatus 是 HPA 的运行时状态。
+type AutoscalingStatus struct {
+	MinReplicas     int32 `json:"min_replicas"`
+	MaxReplicas     int32 `json:"max_replicas"`
+	CurrentReplicas int32 `json:"current_replicas"`
+	DesiredReplicas int32 `json:"desired_replicas"`
+}
+
+// ValidateAutoscaling 校验副本范围与 utilization 目标。resources 是实际下发的
+// requests/limits（EffectiveResources），用于检查目标对应的 request 是否配置。nil 视为合法。
+func ValidateAutoscaling(spec *AutoscalingSpec, resources *ResourceSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.MinReplicas < 1 {
+		return fmt.Errorf("%w: autoscaling min_replicas must be at least 1", ErrInvalidInput)
+	}
+	if spec.MaxReplicas < spec.MinReplicas {
+		return fmt.Errorf("%w: autoscaling max_replicas (%d) is less than min_replicas (%d)",
+			ErrInvalidInput, spec.MaxReplicas, spec.MinReplicas)
+	}
+	if spec.TargetCPUUtilization == 0 && spec.TargetMemoryUtilization == 0 {
+		return fmt.Errorf("%w: autoscaling requires target_cpu_utilization or target_memory_utilization", ErrInvalidInput)
+	}
+	if spec.TargetCPUUtilization < 0 || spec.TargetMemoryUtilization < 0 {
+		return fmt.Errorf("%w: autoscaling utilization targets must not be negative", ErrInvalidInput)
+	}
+	if spec.TargetCPUUtilization > 0 && (resources == nil || resources.CPURequest == "") {
+		return fmt.Errorf("%w: autoscaling on cpu requires resources.cpu_request", ErrInvalidInput)
+	}
+	if spec.TargetMemoryUtilization > 0 && (resources == nil || resources.MemoryRequest == "") {
+		return fmt.Errorf("%w: autoscaling on memory requires resources.memory_request", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAutoscaling(t *testing.T) {
+	withRequests := &ResourceSpec{CPURequest: "100m", MemoryRequest: "128Mi"}
+	tests := []struct {
+		name      string
+		spec      *AutoscalingSpec
+		resources *ResourceSpec
+		wantErr   bool
+	}{
+		{"nil", nil, nil, false},
+		{"cpu target", &AutoscalingSpec{MinReplicas: 1, MaxReplicas: 5, TargetCPUUtilization: 70}, withRequests, false},
+		{"cpu and memory", &AutoscalingSpec{MinReplicas: 2, MaxReplicas: 2, TargetCPUUtilization: 70, TargetMemoryUtilization: 80}, withRequests, false},
+		{"min zero", &AutoscalingSpec{MinReplicas: 0, MaxReplicas: 5, TargetCPUUtilization: 70}, withRequests, true},
+		{"max below min", &AutoscalingSpec{MinReplicas: 3, MaxReplicas: 2, TargetCPUUtilization: 70}, withRequests, true},
+		{"no target", &AutoscalingSpec{MinReplicas: 1, MaxReplicas: 5}, withRequests, true},
+		{"negative target", &AutoscalingSpec{MinReplicas: 1, MaxReplicas: 5, TargetCPUUtilization: -1, TargetMemoryUtilization: 50}, withRequests, true},
+		{"cpu without request", &AutoscalingSpec{MinReplicas: 1, MaxReplicas: 5, TargetCPUUtilization: 70}, &ResourceSpec{MemoryRequest: "128Mi"}, true},
+		{"memory without resources", &AutoscalingSpec{MinReplicas: 1, MaxReplicas: 5, TargetMemoryUtilization: 70}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAutoscaling(tt.spec, tt.resources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAutoscaling() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+}
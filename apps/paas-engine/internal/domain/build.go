@@ -1,6 +1,16 @@
 package domain
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
 
 // BuildStatus 是 Build 的状态机枚举。
 // 状态流转：Pending → Running → (Succeeded | Failed | Cancelled)
@@ -18,6 +28,110 @@ func (s BuildStatus) IsTerminal() bool {
 	return s == BuildStatusSucceeded || s == BuildStatusFailed || s == BuildStatusCancelled
 }
 
+// 排队构建的优先级，数值小的先出队，同优先级按创建时间先后。
+const (
+	BuildPriorityManual = 0 // 人工触发、prod 流水线
+	BuildPriorityCI     = 1 // CI lane 流水线
+)
+
+// BuildOptions 是 Dockerfile 之外影响构建产物的参数。ImageRepo 上是默认值，
+// 单次构建可逐项覆盖，Build 上记录合并后实际使用的值。
+type BuildOptions struct {
+	BuildArgs map[string]string `json:"build_args,omitempty"` // --build-arg
+	// SecretBuildArgs 是 ARG 名 → "bundle/KEY"，值在提交时从 ConfigBundle 读取，
+	// 经 K8s Secret 注入构建容器，不出现在 Job 参数和构建记录里
+	SecretBuildArgs map[string]string `json:"secret_build_args,omitempty"`
+	Target          string            `json:"target,omitempty"`   // 多阶段构建的目标 stage
+	Platform        string            `json:"platform,omitempty"` // 目标平台，如 linux/arm64，空则与构建节点一致
+	Labels          map[string]string `json:"labels,omitempty"`   // 镜像 label（OCI annotations）
+}
+
+// buildArgNameRegex 与 Dockerfile ARG / 环境变量名规则一致。
+var buildArgNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// buildPlatformRegex 匹配 os/arch[/variant]。
+var buildPlatformRegex = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// buildTargetRegex 是 Dockerfile 中 FROM ... AS <stage> 允许的 stage 名。
+var buildTargetRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// Validate 校验参数名、secret 引用格式和 label key。
+func (o BuildOptions) Validate() error {
+	for name := range o.BuildArgs {
+		if !buildArgNameRegex.MatchString(name) {
+			return fmt.Errorf("%w: invalid build arg name %q", ErrInvalidInput, name)
+		}
+	}
+	for name, ref := range o.SecretBuildArgs {
+		if !buildArgNameRegex.MatchString(name) {
+			return fmt.Errorf("%w: invalid secret build arg name %q", ErrInvalidInput, name)
+		}
+		if _, ok := o.BuildArgs[name]; ok {
+			return fmt.Errorf("%w: build arg %q is both plain and secret", ErrInvalidInput, name)
+		}
+		if _, _, err := ParseSecretBuildArgRef(ref); err != nil {
+			return err
+		}
+	}
+	if o.Target != "" && !buildTargetRegex.MatchString(o.Target) {
+		return fmt.Errorf("%w: invalid build target %q", ErrInvalidInput, o.Target)
+	}
+	if o.Platform != "" && !buildPlatformRegex.MatchString(o.Platform) {
+		return fmt.Errorf("%w: invalid build platform %q, want os/arch", ErrInvalidInput, o.Platform)
+	}
+	for key := range o.Labels {
+		if key == "" || strings.ContainsAny(key, "= \t\n") {
+			return fmt.Errorf("%w: invalid label key %q", ErrInvalidInput, key)
+		}
+	}
+	return nil
+}
+
+// ParseSecretBuildArgRef 解析 "bundle/KEY" 形式的 secret build arg 引用。
+func ParseSecretBuildArgRef(ref string) (bundle, key string, err error) {
+	bundle, key, ok := strings.Cut(ref, "/")
+	if !ok || bundle == "" || key == "" {
+		return "", "", fmt.Errorf("%w: secret build arg %q must be bundle/KEY", ErrInvalidInput, ref)
+	}
+	return bundle, key, nil
+}
+
+// MergeBuildOptions 用 override 覆盖 base：map 按 key 覆盖，Target / Platform 非空时覆盖。
+func MergeBuildOptions(base, override BuildOptions) BuildOptions {
+	merge := func(a, b map[string]string) map[string]string {
+		if len(a) == 0 && len(b) == 0 {
+			return nil
+		}
+		out := maps.Clone(a)
+		if out == nil {
+			out = make(map[string]string, len(b))
+		}
+		maps.Copy(out, b)
+		return out
+	}
+	merged := BuildOptions{
+		BuildArgs:       merge(base.BuildArgs, override.BuildArgs),
+		SecretBuildArgs: merge(base.SecretBuildArgs, override.SecretBuildArgs),
+		Target:          base.Target,
+		Platform:        base.Platform,
+		Labels:          merge(base.Labels, override.Labels),
+	}
+	// 单次构建把 secret 参数改成明文（或反之）时以覆盖方为准
+	for name := range override.BuildArgs {
+		delete(merged.SecretBuildArgs, name)
+	}
+	for name := range override.SecretBuildArgs {
+		delete(merged.BuildArgs, name)
+	}
+	if override.Target != "" {
+		merged.Target = override.Target
+	}
+	if override.Platform != "" {
+		merged.Platform = override.Platform
+	}
+	return merged
+}
+
 // Build 代表一次镜像构建任务，对应 K8s 中的 Kaniko Job。
 type Build struct {
 	ID            string      `json:"id"`
@@ -26,14 +140,118 @@ type Build struct {
 	ImageTag      string      `json:"image_tag"`
 	Version       string      `json:"version"`           // 语义化版本号，如 "1.0.0.2"
 	Channel       string      `json:"channel"`           // "stable" 或 "test"
+	Priority      int         `json:"priority"`          // 见 BuildPriority*，数值小的先出队
 	Status        BuildStatus `json:"status"`
+	// QueuePosition 是 pending 构建在全局队列里的位置（从 1 开始），查询时计算，不落库
+	QueuePosition int `json:"queue_position,omitempty"`
 	JobName       string      `json:"job_name,omitempty"`
 	Log           string      `json:"log,omitempty"`
+	// 构建溯源：提交时 GitRef 解析出的 commit、推送后 registry 返回的 digest
+	CommitSHA       string     `json:"commit_sha,omitempty"`
+	Digest          string     `json:"digest,omitempty"`     // sha256:...，仅 succeeded 时有值
+	Dockerfile      string     `json:"dockerfile,omitempty"` // 相对仓库根目录的 Dockerfile 路径
+	StartedAt       *time.Time `json:"started_at,omitempty"` // Job 提交成功的时刻
+	DurationSeconds int        `json:"duration_seconds,omitempty"`
+	// Fingerprint 标识构建输入（commit + 上下文 + Dockerfile + 构建参数），相同即产物相同；无法确定 commit 时为空
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Reused 表示本次请求命中了已有的相同构建、没有新起 Job，查询时设置，不落库
+	Reused bool `json:"reused,omitempty"`
+	// PrunedAt 是镜像按保留策略从 registry 删除的时刻，之后不能再部署或复用
+	PrunedAt *time.Time `json:"pruned_at,omitempty"`
+	// PromotedFrom 是晋升来源构建的 ID：没有重新构建，而是给来源构建的同一 digest 打了 stable tag
+	PromotedFrom string `json:"promoted_from,omitempty"`
+	// VerifiedLane 是晋升时作为验证证据的 lane（来源镜像在该 lane 部署过），未要求证据时为空
+	VerifiedLane string `json:"verified_lane,omitempty"`
+	// Scan 是构建产物的漏洞扫描结果，ImageRepo 未开启扫描时为空
+	Scan *ImageScan `json:"scan,omitempty"`
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
+
+	// 合并 ImageRepo 默认值后实际使用的构建参数（secret 参数只记引用）
+	BuildOptions
 }
 
 // CanCancel 判断当前状态是否允许取消。
 func (b *Build) CanCancel() bool {
 	return b.Status == BuildStatusPending || b.Status == BuildStatusRunning
 }
+
+// ElapsedSeconds 返回从 Job 提交到 now 的秒数，未提交过（StartedAt 为空）时从创建时刻算。
+func (b *Build) ElapsedSeconds(now time.Time) int {
+	start := b.CreatedAt
+	if b.StartedAt != nil {
+		start = *b.StartedAt
+	}
+	return int(now.Sub(start).Seconds())
+}
+
+// ImageRefWithDigest 返回按 digest 固定的镜像地址（tag@sha256:...），没有 digest 时返回 ImageTag。
+func (b *Build) ImageRefWithDigest() string {
+	return ImageRefWithDigest(b.ImageTag, b.Digest)
+}
+
+// ImageRefWithDigest 把 digest 拼到镜像地址后面；tag 保留只为可读，拉取以 digest 为准。
+func ImageRefWithDigest(image, digest string) string {
+	if digest == "" || image == "" {
+		return image
+	}
+	return image + "@" + digest
+}
+
+// DockerfilePath 返回构建实际使用的 Dockerfile 路径（相对仓库根目录）。
+func DockerfilePath(contextDir, dockerfile string) string {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if contextDir == "" || contextDir == "." {
+		return dockerfile
+	}
+	return path.Join(contextDir, dockerfile)
+}
+
+// IsFullCommitSHA 判断 ref 是否为完整的 40 位 commit SHA。
+func IsFullCommitSHA(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildFingerprint 计算构建输入的指纹：同一 commit、channel、上下文目录、Dockerfile 和构建参数
+// 产出的镜像视为相同，可直接复用。channel 计入指纹：main 上的 stable 构建不能复用分支上
+// 同一 commit 的 test 构建，否则发布门禁看到的 channel 不对。commitSHA 为空时无法判定，返回空。
+// secret 参数只计入引用，secret 值轮换后需要 force 重新构建。
+func BuildFingerprint(commitSHA, channel, contextDir, dockerfile string, opts BuildOptions) string {
+	if commitSHA == "" {
+		return ""
+	}
+	if contextDir == "" {
+		contextDir = "."
+	}
+	var sb strings.Builder
+	sb.WriteString(commitSHA + "\n")
+	sb.WriteString("channel:" + channel + "\n")
+	sb.WriteString(path.Clean(contextDir) + "\n")
+	sb.WriteString(DockerfilePath(contextDir, dockerfile) + "\n")
+	writeMap := func(prefix string, m map[string]string) {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			sb.WriteString(prefix + k + "=" + m[k] + "\n")
+		}
+	}
+	writeMap("arg:", opts.BuildArgs)
+	writeMap("secret:", opts.SecretBuildArgs)
+	writeMap("label:", opts.Labels)
+	if opts.Target != "" {
+		sb.WriteString("target:" + opts.Target + "\n")
+	}
+	if opts.Platform != "" {
+		sb.WriteString("platform:" + opts.Platform + "\n")
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
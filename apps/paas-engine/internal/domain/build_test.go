@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuildFingerprint(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	base := BuildFingerprint(sha, ChannelStable, "apps/agent-service", "", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}})
+	if base == "" {
+		t.Fatal("fingerprint should not be empty with a commit")
+	}
+	if BuildFingerprint("", ChannelStable, "apps/agent-service", "", BuildOptions{}) != "" {
+		t.Error("fingerprint without commit should be empty")
+	}
+
+	// 尾部斜杠、默认 Dockerfile 显式写出、参数顺序不影响指纹
+	if fp := BuildFingerprint(sha, ChannelStable, "apps/agent-service/", "Dockerfile", BuildOptions{BuildArgs: map[string]string{"B": "2", "A": "1"}}); fp != base {
+		t.Errorf("fingerprint = %s, want %s", fp, base)
+	}
+	different := map[string]string{
+		"commit":     BuildFingerprint("fedcba9876543210fedcba9876543210fedcba98", ChannelStable, "apps/agent-service", "", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}}),
+		"context":    BuildFingerprint(sha, ChannelStable, "apps/lark-server", "", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}}),
+		"dockerfile": BuildFingerprint(sha, ChannelStable, "apps/agent-service", "Dockerfile.prod", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}}),
+		"build args": BuildFingerprint(sha, ChannelStable, "apps/agent-service", "", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "3"}}),
+		"channel":    BuildFingerprint(sha, ChannelTest, "apps/agent-service", "", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}}),
+		"target":     BuildFingerprint(sha, ChannelStable, "apps/agent-service", "", BuildOptions{BuildArgs: map[string]string{"A": "1", "B": "2"}, Target: "runtime"}),
+		"secret ref": BuildFingerprint(sha, ChannelStable, "apps/agent-service", "", BuildOptions{BuildArgs: map[string]string{"A": "1"}, SecretBuildArgs: map[string]string{"B": "npm/TOKEN"}}),
+	}
+	for name, fp := range different {
+		if fp == base {
+			t.Errorf("changing %s should change the fingerprint", name)
+		}
+	}
+}
+
+func TestMergeBuildOptions(t *testing.T) {
+	base := BuildOptions{
+		BuildArgs:       map[string]string{"NODE_ENV": "production", "REGISTRY": "npmmirror"},
+		SecretBuildArgs: map[string]string{"NPM_TOKEN": "npm/TOKEN"},
+		Target:          "runtime",
+		Labels:          map[string]string{"team": "bot"},
+	}
+	got := MergeBuildOptions(base, BuildOptions{
+		BuildArgs: map[string]string{"NODE_ENV": "test", "NPM_TOKEN": "public"},
+		Target:    "debug",
+	})
+	if got.BuildArgs["NODE_ENV"] != "test" || got.BuildArgs["REGISTRY"] != "npmmirror" {
+		t.Errorf("build args = %v", got.BuildArgs)
+	}
+	// 覆盖成明文参数后不再从 secret 取
+	if _, ok := got.SecretBuildArgs["NPM_TOKEN"]; ok || got.BuildArgs["NPM_TOKEN"] != "public" {
+		t.Errorf("NPM_TOKEN should become plain: args %v secrets %v", got.BuildArgs, got.SecretBuildArgs)
+	}
+	if got.Target != "debug" || got.Labels["team"] != "bot" {
+		t.Errorf("target = %q labels = %v", got.Target, got.Labels)
+	}
+	// 不改动 base
+	if base.BuildArgs["NODE_ENV"] != "production" || base.SecretBuildArgs["NPM_TOKEN"] == "" {
+		t.Errorf("base mutated: %+v", base)
+	}
+	if empty := MergeBuildOptions(BuildOptions{}, BuildOptions{}); empty.BuildArgs != nil || empty.Labels != nil {
+		t.Errorf("empty merge = %+v", empty)
+	}
+}
+
+func TestBuildOptionsValidate(t *testing.T) {
+	valid := BuildOptions{
+		BuildArgs:       map[string]string{"NODE_ENV": "production"},
+		SecretBuildArgs: map[string]string{"NPM_TOKEN": "npm/TOKEN"},
+		Target:          "build-stage.1",
+		Platform:        "linux/arm/v7",
+		Labels:          map[string]string{"org.opencontainers.image.vendor": "chiwei"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	invalid := map[string]BuildOptions{
+		"arg name":         {BuildArgs: map[string]string{"1BAD": "x"}},
+		"secret ref":       {SecretBuildArgs: map[string]string{"TOKEN": "no-slash"}},
+		"plain and secret": {BuildArgs: map[string]string{"TOKEN": "x"}, SecretBuildArgs: map[string]string{"TOKEN": "npm/TOKEN"}},
+		"target":           {Target: "-bad"},
+		"label key with =": {Labels: map[string]string{"a=b": "c"}},
+	}
+	for name, opts := range invalid {
+		if err := opts.Validate(); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultCanaryLane 是金丝雀 Deployment 默认所在的 lane。ppe-* 连 prod 基建，
+// 正好是灰度语义；Deployment 名为 {app}-{canary_lane}，由 lane-sidecar 按 X-Ctx-Lane 路由。
+const DefaultCanaryLane = "ppe-canary"
+
+// DefaultCanarySteps 是未指定步进计划时的默认流量比例（百分比，最后一步必须是 100）。
+var DefaultCanarySteps = []int{5, 25, 50, 100}
+
+type CanaryStatus string
+
+const (
+	CanaryStatusRunning  CanaryStatus = "running"
+	CanaryStatusPromoted CanaryStatus = "promoted"
+	CanaryStatusAborted  CanaryStatus = "aborted"
+	CanaryStatusFailed   CanaryStatus = "failed" // 晋升阶段失败，需人工介入
+)
+
+func (s CanaryStatus) IsTerminal() bool {
+	return s != CanaryStatusRunning
+}
+
+type CanaryStepStatus string
+
+const (
+	CanaryStepPending CanaryStepStatus = "pending"
+	CanaryStepRunning CanaryStepStatus = "running"
+	CanaryStepPassed  CanaryStepStatus = "passed"
+	CanaryStepFailed  CanaryStepStatus = "failed"
+)
+
+// CanaryStep 是步进计划中的一步：把 Percent% 的稳定流量切到金丝雀，观察 StepInterval 后
+// 用 GetDeploymentStatus 的 ready / restarts 判定是否通过。
+type CanaryStep struct {
+	Percent    int              `json:"percent"`
+	Status     CanaryStepStatus `json:"status"`
+	Ready      int32            `json:"ready"`
+	Desired    int32            `json:"desired"`
+	Restarts   int32            `json:"restarts"` // 相对金丝雀部署完成时的新增重启次数
+	Message    string           `json:"message,omitempty"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// ReleaseCanary 是某个 Release 的一次金丝雀发布。新镜像部署为 CanaryLane 上的独立
+// Release，按 Steps 通过 gateway 规则 GatewayRule 的 set-weights 逐步放量；全部通过后
+// 把镜像晋升到稳定 Release，任一步失败则恢复 PreviousTargets 并删掉金丝雀。
+type ReleaseCanary struct {
+	ID                  string          `json:"id"`
+	ReleaseID           string          `json:"release_id"`
+	AppName             string          `json:"app_name"`
+	Lane                string          `json:"lane"` // 稳定 Release 的 lane
+	CanaryLane          string          `json:"canary_lane"`
+	CanaryReleaseID     string          `json:"canary_release_id,omitempty"`
+	ImageTag            string          `json:"image_tag"`
+	Image               string          `json:"image"`
+	GatewayRule         string          `json:"gateway_rule"`
+	Steps               []CanaryStep    `json:"steps"`
+	CurrentStep         int             `json:"current_step"` // 正在执行 / 最后执行的 step 下标
+	StepIntervalSeconds int             `json:"step_interval_seconds"`
+	MaxRestarts         int32           `json:"max_restarts"`
+	ScanOverrideReason  string          `json:"scan_override_reason,omitempty"` // 晋升时豁免扫描门禁的原因
+	PreviousTargets     []GatewayTarget `json:"previous_targets"`               // 放量前的 targets，abort 时恢复
+	Status              CanaryStatus    `json:"status"`
+	Message             string          `json:"message,omitempty"`
+	Lease                               // running 期间驱动流程的实例
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	FinishedAt          *time.Time      `json:"finished_at,omitempty"`
+}
+
+// ValidateCanarySteps 要求百分比严格递增、落在 (0, 100]，且最后一步为 100。
+func ValidateCanarySteps(steps []int) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%w: canary steps must not be empty", ErrInvalidInput)
+	}
+	prev := 0
+	for i, p := range steps {
+		if p <= prev || p > 100 {
+			return fmt.Errorf("%w: canary steps[%d]=%d must be strictly increasing within (0, 100]", ErrInvalidInput, i, p)
+		}
+		prev = p
+	}
+	if prev != 100 {
+		return fmt.Errorf("%w: last canary step must be 100, got %d", ErrInvalidInput, prev)
+	}
+	return nil
+}
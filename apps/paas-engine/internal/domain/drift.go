@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DriftPolicy 决定检测到漂移（K8s 上的 Deployment 被人工改过）后的处理方式。
+type DriftPolicy string
+
+const (
+	DriftPolicyReport DriftPolicy = "report" // 只记录事件、暴露指标（默认）
+	DriftPolicyHeal   DriftPolicy = "heal"   // 按 release 期望状态重新下发
+)
+
+// ValidateDriftPolicy 校验 App 上的漂移策略，空串视为 report。
+func ValidateDriftPolicy(p DriftPolicy) error {
+	switch p {
+	case "", DriftPolicyReport, DriftPolicyHeal:
+		return nil
+	}
+	return fmt.Errorf("%w: drift_policy %q must be one of report, heal", ErrInvalidInput, p)
+}
+
+// EffectiveDriftPolicy 返回 App 实际生效的漂移策略。
+func (a *App) EffectiveDriftPolicy() DriftPolicy {
+	if a.DriftPolicy == "" {
+		return DriftPolicyReport
+	}
+	return a.DriftPolicy
+}
+
+// DriftField 是发生漂移的字段。
+type DriftField string
+
+const (
+	DriftFieldDeployment DriftField = "deployment" // Deployment 整个不见了
+	DriftFieldImage      DriftField = "image"
+	DriftFieldReplicas   DriftField = "replicas"
+	DriftFieldEnv        DriftField = "env" // 逐个 key 报告，Key 为变量名
+	DriftFieldEnvFrom    DriftField = "env_from"
+	DriftFieldSidecar    DriftField = "sidecar"
+)
+
+// DriftItem 是一处期望值与线上值的差异。缺失一侧用空串表示。
+type DriftItem struct {
+	Field    DriftField `json:"field"`
+	Key      string     `json:"key,omitempty"`
+	Expected string     `json:"expected"`
+	Actual   string     `json:"actual"`
+}
+
+// ReleaseDrift 是某个 release 当前的漂移状态（最近一轮对账的结果）。
+type ReleaseDrift struct {
+	ReleaseID  string      `json:"release_id"`
+	AppName    string      `json:"app_name"`
+	Lane       string      `json:"lane"`
+	DeployName string      `json:"deploy_name"`
+	Policy     DriftPolicy `json:"policy"`
+	Items      []DriftItem `json:"items"`
+	DetectedAt time.Time   `json:"detected_at"` // 这组差异第一次被发现的时间
+}
+
+type DriftAction string
+
+const (
+	DriftActionReported   DriftAction = "reported"    // 发现新的漂移（或差异内容变了）
+	DriftActionHealed     DriftAction = "healed"      // 已按期望状态重新下发
+	DriftActionHealFailed DriftAction = "heal_failed" // 重新下发失败，Message 为原因
+	DriftActionResolved   DriftAction = "resolved"    // 漂移在 report 模式下自行消失
+)
+
+// DriftEvent 是一条漂移处理记录。同一组差异持续存在时不会重复记录。
+type DriftEvent struct {
+	ID         string      `json:"id"`
+	ReleaseID  string      `json:"release_id"`
+	AppName    string      `json:"app_name"`
+	Lane       string      `json:"lane"`
+	DeployName string      `json:"deploy_name"`
+	Action     DriftAction `json:"action"`
+	Items      []DriftItem `json:"items,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
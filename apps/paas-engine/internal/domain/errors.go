@@ -12,6 +12,10 @@ var (
 	ErrCannotDelete  = errors.New("cannot delete")
 	ErrCannotCancel  = errors.New("cannot cancel")
 
+	// ErrOperationSuperseded 表示部署操作没有执行完就被同一 release 上更新的操作顶替，
+	// 或被其他 paas-engine 实例接管，结果由后来者决定
+	ErrOperationSuperseded = errors.New("release operation superseded")
+
 	ErrAppNotFound       = fmt.Errorf("app %w", ErrNotFound)
 	ErrBuildNotFound     = fmt.Errorf("build %w", ErrNotFound)
 	ErrReleaseNotFound   = fmt.Errorf("release %w", ErrNotFound)
@@ -24,4 +28,10 @@ var (
 	ErrPipelineRunNotFound   = fmt.Errorf("pipeline run %w", ErrNotFound)
 	ErrDynamicConfigNotFound = fmt.Errorf("dynamic config %w", ErrNotFound)
 	ErrGatewayRuleNotFound   = fmt.Errorf("gateway rule %w", ErrNotFound)
+
+	ErrReleaseRevisionNotFound  = fmt.Errorf("release revision %w", ErrNotFound)
+	ErrCanaryNotFound           = fmt.Errorf("canary %w", ErrNotFound)
+	ErrReleaseOperationNotFound = fmt.Errorf("release operation %w", ErrNotFound)
+	ErrLaneSleepPolicyNotFound  = fmt.Errorf("lane sleep policy %w", ErrNotFound)
+	ErrLaneNotFound             = fmt.Errorf("lane %w", ErrNotFound)
 )
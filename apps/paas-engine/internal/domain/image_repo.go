@@ -2,23 +2,141 @@ package domain
 
 import (
 	"fmt"
+	"path"
+	"slices"
+	"strings"
 	"time"
 )
 
 // ImageRepo 代表一个镜像仓库的构建配置，与运行时 App 解耦。
 // 多个 App 可以共享同一个 ImageRepo（如 worker 共享主服务镜像）。
 type ImageRepo struct {
-	Name       string    `json:"name"`
-	Registry   string    `json:"registry"`    // 镜像仓库地址前缀，如 harbor.local/inner-bot/agent-service
-	GitRepo    string    `json:"git_repo"`    // Git 仓库地址
-	ContextDir string    `json:"context_dir"` // 构建上下文子目录
-	Dockerfile string    `json:"dockerfile"`  // Dockerfile 路径（相对 context），空则使用默认 Dockerfile
-	NoCache    bool      `json:"no_cache"`    // true = 强制关闭构建缓存，忽略全局 cacheRepo 配置
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Name            string    `json:"name"`
+	Registry        string    `json:"registry"`                   // 镜像仓库地址前缀，如 harbor.local/inner-bot/agent-service
+	GitRepo         string    `json:"git_repo"`                   // Git 仓库地址
+	ContextDir      string    `json:"context_dir"`                // 构建上下文子目录
+	Dockerfile      string    `json:"dockerfile"`                 // Dockerfile 路径（相对 context），空则使用默认 Dockerfile
+	NoCache         bool      `json:"no_cache"`                   // true = 强制关闭构建缓存，忽略全局 cacheRepo 配置
+	DependencyPaths []string  `json:"dependency_paths,omitempty"` // 上下文之外、改动后也要重新构建的路径，如 packages/ts-shared
+	Builder         string    `json:"builder,omitempty"`          // 构建后端：kaniko（默认）/ buildkit
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Retention 是镜像保留策略，nil 表示不清理
+	Retention *ImageRetention `json:"retention,omitempty"`
+	// ScanPolicy 是漏洞扫描策略，nil 表示不扫描
+	ScanPolicy *ImageScanPolicy `json:"scan_policy,omitempty"`
+
+	// 构建参数默认值，单次构建可覆盖
+	BuildOptions
+}
+
+// 构建后端。ImageRepo.Builder 为空时使用 kaniko。
+const (
+	BuilderKaniko   = "kaniko"
+	BuilderBuildKit = "buildkit"
+)
+
+// ValidateBuilder 校验构建后端名，空串表示默认（kaniko）。
+func ValidateBuilder(builder string) error {
+	switch builder {
+	case "", BuilderKaniko, BuilderBuildKit:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown builder %q (want %s or %s)", ErrInvalidInput, builder, BuilderKaniko, BuilderBuildKit)
+}
+
+// ImageRetention 是 ImageRepo 的镜像保留策略。被任意 Release（当前或历史 revision）引用的镜像总是保留；
+// 其余成功构建按 channel 只保留最近 KeepLast 个，test channel 的构建另外超过 TestMaxAgeDays 天即清理。
+type ImageRetention struct {
+	KeepLast       int `json:"keep_last,omitempty"`         // 每个 channel 保留最近 N 个，0 = 不按数量清理
+	TestMaxAgeDays int `json:"test_max_age_days,omitempty"` // test channel 构建的最长保留天数，0 = 不按时间清理
+}
+
+// Validate 要求各项非负。
+func (r *ImageRetention) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.KeepLast < 0 || r.TestMaxAgeDays < 0 {
+		return fmt.Errorf("%w: retention keep_last / test_max_age_days must not be negative", ErrInvalidInput)
+	}
+	return nil
+}
+
+// Enabled 判断策略是否会清理任何镜像。
+func (r *ImageRetention) Enabled() bool {
+	return r != nil && (r.KeepLast > 0 || r.TestMaxAgeDays > 0)
+}
+
+// SelectPrunable 从同一 ImageRepo 的构建里挑出按策略应清理的镜像。只考虑已成功、未清理过的构建；
+// referenced 是被 Release 引用的完整镜像地址。与保留下来的构建共用 digest 的不清理 ——
+// registry 按 digest 删除 manifest 会连带删掉保留构建的 tag。
+func (r *ImageRetention) SelectPrunable(builds []*Build, referenced map[string]bool, now time.Time) []*Build {
+	if !r.Enabled() {
+		return nil
+	}
+	candidates := make([]*Build, 0, len(builds))
+	for _, b := range builds {
+		if b.Status == BuildStatusSucceeded && b.PrunedAt == nil && b.ImageTag != "" {
+			candidates = append(candidates, b)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b *Build) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	var prune []*Build
+	keptDigests := make(map[string]bool)
+	seen := make(map[string]int) // channel → 已遍历的较新构建数
+	for _, b := range candidates {
+		seen[b.Channel]++
+		expired := r.KeepLast > 0 && seen[b.Channel] > r.KeepLast
+		if b.Channel == ChannelTest && r.TestMaxAgeDays > 0 &&
+			now.Sub(b.CreatedAt) > time.Duration(r.TestMaxAgeDays)*24*time.Hour {
+			expired = true
+		}
+		if expired && !referenced[b.ImageTag] {
+			prune = append(prune, b)
+		} else if b.Digest != "" {
+			keptDigests[b.Digest] = true
+		}
+	}
+	return slices.DeleteFunc(prune, func(b *Build) bool { return b.Digest != "" && keptDigests[b.Digest] })
 }
 
 // FullImageRef 拼出完整镜像引用：registry:tag。
 func (ir *ImageRepo) FullImageRef(tag string) string {
 	return fmt.Sprintf("%s:%s", ir.Registry, tag)
 }
+
+// ChangePaths 返回改动后需要重新构建该镜像的仓库路径：构建上下文加上 DependencyPaths。
+// 根目录上下文（共享包服务）约定 Dockerfile 放在 apps/<service>/ 下，取 Dockerfile 所在目录代替整个仓库。
+func (ir *ImageRepo) ChangePaths() []string {
+	dir := ir.ContextDir
+	if dir == "" || dir == "." {
+		dir = path.Dir(DockerfilePath(ir.ContextDir, ir.Dockerfile))
+	}
+	return append([]string{dir}, ir.DependencyPaths...)
+}
+
+// PathsChanged 判断 changed 中是否有文件落在 paths 的某个目录（或就是该文件）下。"." 匹配任意改动。
+func PathsChanged(paths, changed []string) bool {
+	for _, p := range paths {
+		p = path.Clean(p)
+		for _, f := range changed {
+			if p == "." || f == p || strings.HasPrefix(f, p+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ImageTagOf 取完整镜像引用里的 tag（FullImageRef 的逆操作）。registry 可带端口，
+// 所以只看最后一段路径；没有 tag 时返回空串。
+func ImageTagOf(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
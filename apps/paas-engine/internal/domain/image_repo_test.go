@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImageTagOf(t *testing.T) {
+	cases := map[string]string{
+		"harbor.local/inner-bot/api:1.0.0.1":      "1.0.0.1",
+		"registry:5000/inner-bot/api:feat-abc123": "feat-abc123",
+		"registry:5000/inner-bot/api":             "",
+		"api:latest":                              "latest",
+		"":                                        "",
+	}
+	for image, want := range cases {
+		if got := ImageTagOf(image); got != want {
+			t.Errorf("ImageTagOf(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestImageRepoChangePaths(t *testing.T) {
+	changed := []string{"apps/lark-server/src/index.ts", "packages/ts-shared/src/log.ts"}
+	cases := []struct {
+		name string
+		repo ImageRepo
+		want bool
+	}{
+		{"own context untouched", ImageRepo{ContextDir: "apps/agent-service"}, false},
+		{"own context changed", ImageRepo{ContextDir: "apps/lark-server"}, true},
+		{"prefix is not a directory match", ImageRepo{ContextDir: "apps/lark"}, false},
+		{"dependency changed", ImageRepo{ContextDir: "apps/channel-server", DependencyPaths: []string{"packages/ts-shared/"}}, true},
+		{"root context uses Dockerfile dir", ImageRepo{ContextDir: ".", Dockerfile: "apps/tool-service/Dockerfile"}, false},
+		{"root context with root Dockerfile", ImageRepo{ContextDir: "."}, true},
+	}
+	for _, tc := range cases {
+		if got := PathsChanged(tc.repo.ChangePaths(), changed); got != tc.want {
+			t.Errorf("%s: PathsChanged(%v) = %v, want %v", tc.name, tc.repo.ChangePaths(), got, tc.want)
+		}
+	}
+	if PathsChanged([]string{"."}, nil) {
+		t.Error("no changed files should never match")
+	}
+}
+
+func TestImageRetentionSelectPrunable(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	build := func(version, channel string, ageDays int, digest string) *Build {
+		return &Build{
+			ImageTag:  "harbor.local/inner-bot/api:" + version,
+			Version:   version,
+			Channel:   channel,
+			Status:    BuildStatusSucceeded,
+			Digest:    digest,
+			CreatedAt: now.Add(-time.Duration(ageDays) * 24 * time.Hour),
+		}
+	}
+	pruned := now
+	builds := []*Build{
+		build("1.0.0.5", ChannelStable, 1, "sha256:5"),
+		build("1.0.0.4", ChannelStable, 2, "sha256:4"),
+		build("1.0.0.3", ChannelStable, 3, "sha256:3"), // 超出 keep_last，但被 release 引用
+		build("1.0.0.2", ChannelStable, 4, "sha256:2"), // 超出 keep_last
+		build("1.0.0.1", ChannelStable, 5, "sha256:5"), // 超出 keep_last，但与保留构建共用 digest
+		build("1.0.0-feat.2", ChannelTest, 1, "sha256:t2"),
+		build("1.0.0-feat.1", ChannelTest, 10, "sha256:t1"), // 在 keep_last 内但超过 test 最长保留
+		{ImageTag: "harbor.local/inner-bot/api:1.0.0.0", Channel: ChannelStable, Status: BuildStatusFailed},
+		{ImageTag: "harbor.local/inner-bot/api:0.9.0.1", Channel: ChannelStable, Status: BuildStatusSucceeded, PrunedAt: &pruned},
+	}
+	referenced := map[string]bool{"harbor.local/inner-bot/api:1.0.0.3": true}
+
+	policy := &ImageRetention{KeepLast: 2, TestMaxAgeDays: 7}
+	var got []string
+	for _, b := range policy.SelectPrunable(builds, referenced, now) {
+		got = append(got, b.Version)
+	}
+	want := []string{"1.0.0.2", "1.0.0-feat.1"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("SelectPrunable() = %v, want %v", got, want)
+	}
+
+	var disabled *ImageRetention
+	if disabled.SelectPrunable(builds, nil, now) != nil {
+		t.Error("nil policy must not prune anything")
+	}
+	if err := (&ImageRetention{KeepLast: -1}).Validate(); err == nil {
+		t.Error("expected error for negative keep_last")
+	}
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 漏洞严重级别，与 Trivy 一致，按从低到高排列。
+const (
+	SeverityUnknown  = "UNKNOWN"
+	SeverityLow      = "LOW"
+	SeverityMedium   = "MEDIUM"
+	SeverityHigh     = "HIGH"
+	SeverityCritical = "CRITICAL"
+)
+
+var severityOrder = []string{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// ScanStatus 是镜像扫描的状态。构建成功后若 ImageRepo 开启了扫描，进入 pending 等待扫描。
+type ScanStatus string
+
+const (
+	ScanStatusPending   ScanStatus = "pending"
+	ScanStatusRunning   ScanStatus = "running"
+	ScanStatusSucceeded ScanStatus = "succeeded"
+	ScanStatusFailed    ScanStatus = "failed"
+)
+
+// ImageScan 是一个构建产物的漏洞扫描结果。
+type ImageScan struct {
+	Status    ScanStatus     `json:"status"`
+	Scanner   string         `json:"scanner,omitempty"`
+	Counts    map[string]int `json:"counts,omitempty"`     // 严重级别 → 漏洞数
+	ReportURL string         `json:"report_url,omitempty"` // 完整报告地址
+	Message   string         `json:"message,omitempty"`    // 扫描失败原因
+	ScannedAt *time.Time     `json:"scanned_at,omitempty"`
+}
+
+// CountAtLeast 返回严重级别不低于 severity 的漏洞总数。
+func (s *ImageScan) CountAtLeast(severity string) int {
+	if s == nil {
+		return 0
+	}
+	threshold := slices.Index(severityOrder, severity)
+	total := 0
+	for sev, n := range s.Counts {
+		if slices.Index(severityOrder, sev) >= threshold {
+			total += n
+		}
+	}
+	return total
+}
+
+// ImageScanPolicy 是 ImageRepo 的漏洞扫描策略：开启后每次构建成功都扫描；设置了 BlockSeverity 时，
+// 不低于该级别的漏洞数超过 MaxAllowed（或还没有扫描结果）的镜像不能发布到 prod 类 lane，除非发布时给出豁免原因。
+type ImageScanPolicy struct {
+	Enabled       bool   `json:"enabled"`
+	BlockSeverity string `json:"block_severity,omitempty"` // 空 = 只扫描不拦截
+	MaxAllowed    int    `json:"max_allowed,omitempty"`
+}
+
+// Validate 校验严重级别和数量上限。
+func (p *ImageScanPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.BlockSeverity != "" && !slices.Contains(severityOrder, p.BlockSeverity) {
+		return fmt.Errorf("%w: unknown block_severity %q (want one of %s)",
+			ErrInvalidInput, p.BlockSeverity, strings.Join(severityOrder, ", "))
+	}
+	if p.MaxAllowed < 0 {
+		return fmt.Errorf("%w: scan max_allowed must not be negative", ErrInvalidInput)
+	}
+	return nil
+}
+
+// Gates 判断策略是否拦截发布。
+func (p *ImageScanPolicy) Gates() bool {
+	return p != nil && p.Enabled && p.BlockSeverity != ""
+}
+
+// Violation 返回 scan 违反策略的原因，不违反时返回空。没有成功的扫描结果也视为违反。
+func (p *ImageScanPolicy) Violation(scan *ImageScan) string {
+	if !p.Gates() {
+		return ""
+	}
+	if scan == nil {
+		return "image has not been scanned"
+	}
+	if scan.Status != ScanStatusSucceeded {
+		return fmt.Sprintf("image scan is %s", scan.Status)
+	}
+	if n := scan.CountAtLeast(p.BlockSeverity); n > p.MaxAllowed {
+		return fmt.Sprintf("image has %d vulnerabilities at %s or above (max %d)", n, p.BlockSeverity, p.MaxAllowed)
+	}
+	return ""
+}
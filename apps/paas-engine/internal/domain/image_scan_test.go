@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestImageScanPolicyViolation(t *testing.T) {
+	policy := &ImageScanPolicy{Enabled: true, BlockSeverity: SeverityHigh, MaxAllowed: 1}
+	tests := []struct {
+		name string
+		scan *ImageScan
+		want bool
+	}{
+		{name: "未扫描", scan: nil, want: true},
+		{name: "扫描中", scan: &ImageScan{Status: ScanStatusRunning}, want: true},
+		{name: "扫描失败", scan: &ImageScan{Status: ScanStatusFailed}, want: true},
+		{name: "低于阈值级别不计", scan: &ImageScan{Status: ScanStatusSucceeded, Counts: map[string]int{SeverityMedium: 10, SeverityHigh: 1}}, want: false},
+		{name: "HIGH+CRITICAL 超过上限", scan: &ImageScan{Status: ScanStatusSucceeded, Counts: map[string]int{SeverityHigh: 1, SeverityCritical: 1}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Violation(tt.scan); (got != "") != tt.want {
+				t.Errorf("Violation() = %q, want violation %v", got, tt.want)
+			}
+		})
+	}
+
+	// 只扫描不拦截
+	if got := (&ImageScanPolicy{Enabled: true}).Violation(nil); got != "" {
+		t.Errorf("policy without block_severity should not gate, got %q", got)
+	}
+	if err := (&ImageScanPolicy{BlockSeverity: "SEVERE"}).Validate(); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Validate() error = %v, want ErrInvalidInput", err)
+	}
+}
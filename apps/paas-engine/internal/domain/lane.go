@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"slices"
+	"time"
 )
 
 // LaneClass 表示 lane 的环境类别。fail-closed：未知类别一律 reject。
@@ -62,3 +63,69 @@ func ClassifyLane(lane string, whitelist []string) (LaneClass, error) {
 		ErrInvalidInput, lane,
 	)
 }
+
+// Lane 是一条登记过的 lane：谁在用、做什么、什么时候到期。
+// 未登记的 lane 照旧可用（只按名字校验）；登记且到期的 lane 会被整体回收：
+// release、配置包 lane 覆盖、动态配置、网关规则和 CI 配置一次清掉。
+type Lane struct {
+	Name        string     `json:"name"`
+	Class       string     `json:"class"` // prod / coe / ppe，由 Name 推导
+	Owner       string     `json:"owner"`
+	Description string     `json:"description,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // nil 表示不过期
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// NewLane 校验并构造一条 lane。ttl<=0 表示不过期；prod 类 lane 不允许设置 TTL。
+func NewLane(name, owner, description string, ttl time.Duration, whitelist []string, now time.Time) (*Lane, error) {
+	class, err := ClassifyLane(name, whitelist)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lane{
+		Name:        name,
+		Class:       class.String(),
+		Owner:       owner,
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := l.SetTTL(ttl, now); err != nil {
+		return nil, err
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Validate 校验 lane 的必填项与 TTL 约束。
+func (l *Lane) Validate() error {
+	if l.Owner == "" {
+		return fmt.Errorf("%w: lane owner is required", ErrInvalidInput)
+	}
+	if l.ExpiresAt != nil && l.Class == LaneClassProd.String() {
+		return fmt.Errorf("%w: prod lane %q cannot expire", ErrInvalidInput, l.Name)
+	}
+	return nil
+}
+
+// SetTTL 把到期时间设为 now+ttl；ttl<=0 清除到期时间。
+func (l *Lane) SetTTL(ttl time.Duration, now time.Time) error {
+	if ttl <= 0 {
+		l.ExpiresAt = nil
+		return nil
+	}
+	if l.Class == LaneClassProd.String() {
+		return fmt.Errorf("%w: prod lane %q cannot expire", ErrInvalidInput, l.Name)
+	}
+	expiresAt := now.Add(ttl)
+	l.ExpiresAt = &expiresAt
+	return nil
+}
+
+// Expired 报告 lane 在 now 时刻是否已到期。
+func (l *Lane) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LaneSleepPolicy 决定非 prod lane 何时被缩到 0 副本（休眠）。两个条件任一满足即休眠：
+//   - IdleHours：lane 上最后一次部署 / 唤醒之后持续 N 小时没有动静
+//   - Schedule：每日休眠时段 "HH:MM-HH:MM"（可跨零点），进入时段时休眠；
+//     时段内有过部署或唤醒则本时段内不再休眠
+//
+// 休眠的 lane 通过 POST /lanes/{lane}:wake 或下一次部署唤醒。
+type LaneSleepPolicy struct {
+	Lane      string    `json:"lane"`
+	IdleHours int       `json:"idle_hours"`         // 0 表示不按空闲时间休眠
+	Schedule  string    `json:"schedule,omitempty"` // 空表示不按时段休眠
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CanLaneSleep 报告 lane 是否允许休眠：只有 coe-* / ppe-* lane 可以，prod 类一律不休眠。
+func CanLaneSleep(lane string, whitelist []string) error {
+	class, err := ClassifyLane(lane, whitelist)
+	if err != nil {
+		return err
+	}
+	if class != LaneClassCoe && class != LaneClassPpe {
+		return fmt.Errorf("%w: lane %q is a %s lane and never sleeps", ErrInvalidInput, lane, class)
+	}
+	return nil
+}
+
+// Validate 校验休眠策略的取值。
+func (p *LaneSleepPolicy) Validate() error {
+	if p.IdleHours < 0 {
+		return fmt.Errorf("%w: idle_hours must not be negative", ErrInvalidInput)
+	}
+	if p.Schedule != "" {
+		if _, err := ParseSleepWindow(p.Schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShouldSleep 判断 lane 在 now 时刻是否应该休眠。lastActivity 是 lane 上最后一次
+// 部署或唤醒的时间；loc 是 Schedule 所在的时区。返回值第二项为休眠原因。
+func (p *LaneSleepPolicy) ShouldSleep(lastActivity, now time.Time, loc *time.Location) (bool, string) {
+	if p.IdleHours > 0 && now.Sub(lastActivity) >= time.Duration(p.IdleHours)*time.Hour {
+		return true, fmt.Sprintf("idle for %dh", p.IdleHours)
+	}
+	if p.Schedule != "" {
+		w, err := ParseSleepWindow(p.Schedule)
+		if err != nil {
+			return false, ""
+		}
+		if start, ok := w.CurrentStart(now.In(loc)); ok && lastActivity.Before(start) {
+			return true, "sleep schedule " + p.Schedule
+		}
+	}
+	return false, ""
+}
+
+// SleepWindow 是每日休眠时段，以当天零点起的分钟数表示，End 可以小于 Start（跨零点）。
+type SleepWindow struct {
+	Start int
+	End   int
+}
+
+// ParseSleepWindow 解析 "HH:MM-HH:MM"。起止相同视为非法（无法区分全天和空时段）。
+func ParseSleepWindow(s string) (SleepWindow, error) {
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return SleepWindow{}, fmt.Errorf("%w: schedule %q must look like 22:00-08:00", ErrInvalidInput, s)
+	}
+	start, err := parseClock(strings.TrimSpace(startStr))
+	if err != nil {
+		return SleepWindow{}, fmt.Errorf("%w: schedule %q: %v", ErrInvalidInput, s, err)
+	}
+	end, err := parseClock(strings.TrimSpace(endStr))
+	if err != nil {
+		return SleepWindow{}, fmt.Errorf("%w: schedule %q: %v", ErrInvalidInput, s, err)
+	}
+	if start == end {
+		return SleepWindow{}, fmt.Errorf("%w: schedule %q start and end must differ", ErrInvalidInput, s)
+	}
+	return SleepWindow{Start: start, End: end}, nil
+}
+
+// CurrentStart 返回 t 所在时段的开始时间；t 不在时段内时第二个返回值为 false。
+func (w SleepWindow) CurrentStart(t time.Time) (time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	start := midnight.Add(time.Duration(w.Start) * time.Minute)
+	if start.After(t) {
+		start = start.AddDate(0, 0, -1)
+	}
+	length := w.End - w.Start
+	if length < 0 {
+		length += 24 * 60
+	}
+	if t.Before(start.Add(time.Duration(length) * time.Minute)) {
+		return start, true
+	}
+	return time.Time{}, false
+}
+
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("time %q must be HH:MM", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("hour in %q must be 00-23", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 || m > 59 || len(mm) != 2 {
+		return 0, fmt.Errorf("minute in %q must be 00-59", s)
+	}
+	return h*60 + m, nil
+}
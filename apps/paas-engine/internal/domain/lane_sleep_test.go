@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSleepWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SleepWindow
+		wantErr bool
+	}{
+		{"22:00-08:00", SleepWindow{Start: 22 * 60, End: 8 * 60}, false},
+		{"01:30-06:45", SleepWindow{Start: 90, End: 6*60 + 45}, false},
+		{" 20:00 - 23:59 ", SleepWindow{Start: 20 * 60, End: 23*60 + 59}, false},
+		{"22:00", SleepWindow{}, true},
+		{"24:00-08:00", SleepWindow{}, true},
+		{"22:0-08:00", SleepWindow{}, true},
+		{"08:00-08:00", SleepWindow{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSleepWindow(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("expected ErrInvalidInput, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseSleepWindow(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLaneSleepPolicy_ShouldSleep(t *testing.T) {
+	loc := time.UTC
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, loc) }
+
+	tests := []struct {
+		name   string
+		policy LaneSleepPolicy
+		last   time.Time
+		now    time.Time
+		want   bool
+	}{
+		{"idle exceeded", LaneSleepPolicy{IdleHours: 24}, at(1, 10, 0), at(2, 10, 0), true},
+		{"idle not reached", LaneSleepPolicy{IdleHours: 24}, at(1, 10, 0), at(2, 9, 59), false},
+		{"idle disabled", LaneSleepPolicy{}, at(1, 10, 0), at(9, 10, 0), false},
+		{"in window, active before start", LaneSleepPolicy{Schedule: "22:00-08:00"}, at(1, 21, 0), at(1, 23, 0), true},
+		{"in window after midnight", LaneSleepPolicy{Schedule: "22:00-08:00"}, at(1, 21, 0), at(2, 3, 0), true},
+		{"woken inside window", LaneSleepPolicy{Schedule: "22:00-08:00"}, at(2, 1, 0), at(2, 3, 0), false},
+		{"outside window", LaneSleepPolicy{Schedule: "22:00-08:00"}, at(1, 9, 0), at(2, 12, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.policy.ShouldSleep(tt.last, tt.now, loc)
+			if got != tt.want {
+				t.Fatalf("ShouldSleep() = %v (%q), want %v", got, reason, tt.want)
+			}
+			if got && reason == "" {
+				t.Fatal("expected a reason when sleeping")
+			}
+		})
+	}
+}
+
+func TestCanLaneSleep(t *testing.T) {
+	for _, lane := range []string{"coe-feat", "ppe-release"} {
+		if err := CanLaneSleep(lane, nil); err != nil {
+			t.Errorf("CanLaneSleep(%q) = %v, want nil", lane, err)
+		}
+	}
+	if err := CanLaneSleep("prod", nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CanLaneSleep(prod) = %v, want ErrInvalidInput", err)
+	}
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"testing"
+	"time"
 )
 
 func TestClassifyLane_ErrorWrapsInvalidInput(t *testing.T) {
@@ -60,3 +61,33 @@ func TestClassifyLane(t *testing.T) {
 		})
 	}
 }
+
+func TestNewLane(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	lane, err := NewLane("ppe-ab", "alice", "", 24*time.Hour, nil, now)
+	if err != nil {
+		t.Fatalf("NewLane: %v", err)
+	}
+	if lane.Class != "ppe" {
+		t.Errorf("class = %q, want ppe", lane.Class)
+	}
+	if lane.Expired(now.Add(23*time.Hour)) || !lane.Expired(now.Add(24*time.Hour)) {
+		t.Errorf("expiry boundary wrong, expires_at = %v", lane.ExpiresAt)
+	}
+
+	forever, err := NewLane("legacy", "ops", "", 0, []string{"legacy"}, now)
+	if err != nil {
+		t.Fatalf("NewLane(whitelisted): %v", err)
+	}
+	if forever.Class != "prod" || forever.Expired(now.AddDate(10, 0, 0)) {
+		t.Errorf("lane without ttl must never expire: %+v", forever)
+	}
+
+	if _, err := NewLane("coe-x", "", "", 0, nil, now); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("missing owner: expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := NewLane("blue", "ops", "", time.Hour, nil, now); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("prod lane with ttl: expected ErrInvalidInput, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// 后台流程（金丝雀、部署操作、pipeline run）由某个 paas-engine 实例在 goroutine 里驱动。
+// 蓝绿自部署时新旧实例共用一个库，流程记录上的租约标明谁在驱动它：持有者每隔
+// LeaseRenewInterval 续约，超过 LeaseTTL 没续约才视为持有者已退出、可以被接管。
+const (
+	LeaseTTL           = 30 * time.Second
+	LeaseRenewInterval = 10 * time.Second
+)
+
+// Lease 是流程记录上的执行权租约。Owner 为空或 HeartbeatAt 为空（租约引入前的记录）都视为无人持有。
+type Lease struct {
+	Owner       string     `json:"owner,omitempty"`
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+}
+
+// Hold 把租约记为 owner 在 now 持有。
+func (l *Lease) Hold(owner string, now time.Time) {
+	l.Owner = owner
+	l.HeartbeatAt = &now
+}
+
+// Expired 报告租约在 now 是否已无人持有。
+func (l Lease) Expired(now time.Time) bool {
+	return l.Owner == "" || l.HeartbeatAt == nil || l.HeartbeatAt.Before(LeaseExpiredBefore(now))
+}
+
+// LeaseExpiredBefore 返回 now 时刻的过期界线：心跳早于它的租约已过期。
+func LeaseExpiredBefore(now time.Time) time.Time {
+	return now.Add(-LeaseTTL)
+}
@@ -1,9 +1,13 @@
 package domain
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // PipelineRunStatus 是 PipelineRun / StageRun / JobRun 共用的状态枚举。
-// 状态流转：Pending → Running → (Succeeded | Failed | Cancelled)
+// 状态流转：Pending → Running → (Succeeded | Failed | Cancelled)；
+// 服务自上次成功以来没有改动时，它的 job（及全部 job 都跳过的 stage）直接为 Skipped。
 type PipelineRunStatus string
 
 const (
@@ -12,12 +16,16 @@ const (
 	PipelineRunSucceeded PipelineRunStatus = "succeeded"
 	PipelineRunFailed    PipelineRunStatus = "failed"
 	PipelineRunCancelled PipelineRunStatus = "cancelled"
+	PipelineRunSkipped   PipelineRunStatus = "skipped"
 )
 
 func (s PipelineRunStatus) IsTerminal() bool {
-	return s == PipelineRunSucceeded || s == PipelineRunFailed || s == PipelineRunCancelled
+	return s == PipelineRunSucceeded || s == PipelineRunFailed || s == PipelineRunCancelled || s == PipelineRunSkipped
 }
 
+// SkippedNoChangesLog 是因无改动跳过的 job 的日志。
+const SkippedNoChangesLog = "skipped: no changes"
+
 // StageType 表示 pipeline 阶段类型。
 type StageType string
 
@@ -28,6 +36,12 @@ const (
 	StageE2E      StageType = "e2e"
 )
 
+// E2E 阶段的 job 类型：各服务的接口测试、飞书全链路测试。
+const (
+	JobTypeE2EHTTP = "e2e-http"
+	JobTypeE2ELark = "e2e-lark"
+)
+
 // CIConfig 注册一个 CI 泳道（make ci-init 创建）。
 type CIConfig struct {
 	ID        string    `json:"id"`
@@ -47,13 +61,33 @@ type PipelineRun struct {
 	CommitSHA  string            `json:"commit_sha"`
 	Lane       string            `json:"lane"`
 	Services   []string          `json:"services"`             // 本次参与的服务
+	// 变更检测：对比的上次成功 run 的 commit，以及因此跳过的服务（没有改动且 lane 上已部署）
+	BaseCommitSHA   string   `json:"base_commit_sha,omitempty"`
+	SkippedServices []string `json:"skipped_services,omitempty"`
+	// Config 是 run 开始时从 commit 上读取的 pipeline.yml 快照，各测试阶段按它执行
+	Config *PipelineConfig `json:"config,omitempty"`
 	Status     PipelineRunStatus `json:"status"`
 	Message    string            `json:"message,omitempty"`
+	Lease                        // pending / running 期间执行 run 的实例
 	Stages     []StageRun        `json:"stages,omitempty"`     // 嵌套返回（查详情时）
 	CreatedAt  time.Time         `json:"created_at"`
 	UpdatedAt  time.Time         `json:"updated_at"`
 }
 
+// Skips 判断服务的各阶段是否因无改动跳过。
+func (r *PipelineRun) Skips(service string) bool {
+	return slices.Contains(r.SkippedServices, service)
+}
+
+// CheckoutRef 返回测试、构建检出代码用的 ref：有完整 commit 时固定在 commit 上，
+// 避免分支在 run 执行期间被推进；手动触发等没有 commit 的 run 用 GitRef。
+func (r *PipelineRun) CheckoutRef() string {
+	if IsFullCommitSHA(r.CommitSHA) {
+		return r.CommitSHA
+	}
+	return r.GitRef
+}
+
 // StageRun 是 pipeline 中的一个阶段。
 type StageRun struct {
 	ID            string            `json:"id"`
@@ -80,3 +114,8 @@ type JobRun struct {
 	CreatedAt  time.Time         `json:"created_at"`
 	UpdatedAt  time.Time         `json:"updated_at"`
 }
+
+// RunsTestPod 判断 job 是否由 TestExecutor 起测试 Pod 执行，这类 job 的日志可以从 Pod / Loki 读取。
+func (j *JobRun) RunsTestPod() bool {
+	return j.JobType == string(StageUnitTest) || j.JobType == JobTypeE2EHTTP || j.JobType == JobTypeE2ELark
+}
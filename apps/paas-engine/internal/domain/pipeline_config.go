@@ -1,17 +1,52 @@
 package domain
 
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PipelineConfigFile 是仓库根目录下声明 CI 测试的配置文件，按 run 的 commit 读取。
+const PipelineConfigFile = "pipeline.yml"
+
+// PipelineRuntimes 是测试 Job 支持的 runtime。
+var PipelineRuntimes = []string{"go", "python", "bun"}
+
+// DefaultLarkFlowTimeout 是 lark_flow 没有配置 timeout 时的超时。
+const DefaultLarkFlowTimeout = 10 * time.Minute
+
+// E2E job 由平台注入的环境变量，e2e_env / lark_flow.env 不能覆盖。
+// 测试经 api-gateway 访问服务，请求带上 $LANE_HEADER: $LANE 即路由到被测 lane（没部署的服务落回 prod）。
+const (
+	E2EEnvLane       = "LANE"
+	E2EEnvGatewayURL = "GATEWAY_URL"
+	E2EEnvLaneHeader = "LANE_HEADER"
+)
+
+// LaneHeader 是 api-gateway 识别请求所属 lane 的 header。
+const LaneHeader = "x-lane"
+
+var e2eReservedEnvs = []string{E2EEnvLane, E2EEnvGatewayURL, E2EEnvLaneHeader}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // PipelineConfig 对应 pipeline.yml 的解析结果。
 type PipelineConfig struct {
 	Services map[string]ServiceTestConfig `json:"services"`
 	LarkFlow *LarkFlowConfig              `json:"lark_flow,omitempty"`
 }
 
-// ServiceTestConfig 描述单个服务的测试命令。
+// ServiceTestConfig 描述单个服务的测试命令和部署顺序。命令在仓库根目录执行。
 type ServiceTestConfig struct {
-	Runtime  string            `json:"runtime"`
+	Runtime  string            `json:"runtime,omitempty"`
 	UnitTest string            `json:"unit_test,omitempty"`
 	E2ETest  string            `json:"e2e_test,omitempty"`
 	E2EEnv   map[string]string `json:"e2e_env,omitempty"`
+	// DeployAfter 列出要先部署完成的服务（同一 run 里的），如 lane-sidecar 的使用方依赖 lite-registry
+	DeployAfter []string `json:"deploy_after,omitempty"`
 }
 
 // LarkFlowConfig 描述飞书全链路 E2E 测试。
@@ -21,3 +56,140 @@ type LarkFlowConfig struct {
 	Timeout string            `json:"timeout,omitempty"`
 	Env     map[string]string `json:"env,omitempty"`
 }
+
+// Service 返回服务的测试配置，没有配置时 ok 为 false。
+func (c *PipelineConfig) Service(name string) (ServiceTestConfig, bool) {
+	if c == nil {
+		return ServiceTestConfig{}, false
+	}
+	svc, ok := c.Services[name]
+	return svc, ok
+}
+
+// TimeoutDuration 返回 lark_flow 的超时，未配置时为 DefaultLarkFlowTimeout。只对校验过的配置调用。
+func (c *LarkFlowConfig) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(c.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultLarkFlowTimeout
+}
+
+// E2EEnvs 合并 E2E job 的环境变量：pipeline.yml 里声明的变量加上平台注入的 lane 信息。
+func E2EEnvs(lane, gatewayURL string, env map[string]string) map[string]string {
+	envs := maps.Clone(env)
+	if envs == nil {
+		envs = make(map[string]string, len(e2eReservedEnvs))
+	}
+	envs[E2EEnvLane] = lane
+	envs[E2EEnvGatewayURL] = gatewayURL
+	envs[E2EEnvLaneHeader] = LaneHeader
+	return envs
+}
+
+// Validate 校验 pipeline.yml，一次列出全部问题：配置了命令的服务必须声明受支持的 runtime，
+// 环境变量名合法且不占用平台注入的变量，deploy_after 不成环，lark_flow 的命令必填、超时可解析。
+func (c *PipelineConfig) Validate() error {
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(c.Services)) {
+		svc := c.Services[name]
+		field := "services." + name
+		if name == "" {
+			problems = append(problems, "services: empty service name")
+		}
+		if svc.UnitTest == "" && svc.E2ETest == "" && len(svc.DeployAfter) == 0 {
+			problems = append(problems, field+": none of unit_test, e2e_test, deploy_after is set")
+		}
+		// 只声明部署顺序的服务不跑测试，可以不写 runtime
+		if svc.Runtime != "" || svc.UnitTest != "" || svc.E2ETest != "" {
+			problems = append(problems, checkRuntime(field, svc.Runtime)...)
+		}
+		problems = append(problems, checkEnvNames(field+".e2e_env", svc.E2EEnv)...)
+		if len(svc.E2EEnv) > 0 && svc.E2ETest == "" {
+			problems = append(problems, field+".e2e_env: set without e2e_test")
+		}
+		for _, dep := range svc.DeployAfter {
+			if dep == "" || dep == name {
+				problems = append(problems, fmt.Sprintf("%s.deploy_after: invalid dependency %q", field, dep))
+			}
+		}
+	}
+	if cycle := c.deployCycle(); cycle != nil {
+		problems = append(problems, "deploy_after: cycle "+strings.Join(cycle, " -> "))
+	}
+	if lf := c.LarkFlow; lf != nil {
+		problems = append(problems, checkRuntime("lark_flow", lf.Runtime)...)
+		if strings.TrimSpace(lf.Cmd) == "" {
+			problems = append(problems, "lark_flow.cmd: required")
+		}
+		if lf.Timeout != "" {
+			if d, err := time.ParseDuration(lf.Timeout); err != nil || d <= 0 {
+				problems = append(problems, fmt.Sprintf("lark_flow.timeout: invalid duration %q", lf.Timeout))
+			}
+		}
+		problems = append(problems, checkEnvNames("lark_flow.env", lf.Env)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidInput, PipelineConfigFile, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// deployCycle 返回 deploy_after 中的一个环（首尾相同），没有环时返回 nil。
+func (c *PipelineConfig) deployCycle() []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			start := slices.Index(path, name)
+			return append(slices.Clone(path[start:]), name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range c.Services[name].DeployAfter {
+			if dep == name {
+				continue // 自依赖单独报错
+			}
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Services)) {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func checkRuntime(field, runtime string) []string {
+	if !slices.Contains(PipelineRuntimes, runtime) {
+		return []string{fmt.Sprintf("%s.runtime: unsupported runtime %q (want one of %s)",
+			field, runtime, strings.Join(PipelineRuntimes, ", "))}
+	}
+	return nil
+}
+
+func checkEnvNames(field string, env map[string]string) []string {
+	var problems []string
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		if !envNameRe.MatchString(k) {
+			problems = append(problems, fmt.Sprintf("%s: invalid variable name %q", field, k))
+		}
+		if slices.Contains(e2eReservedEnvs, k) {
+			problems = append(problems, fmt.Sprintf("%s: %s is set by the pipeline", field, k))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPipelineConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  PipelineConfig
+		want []string // 错误信息应包含的片段，空表示合法
+	}{
+		{
+			name: "合法",
+			cfg: PipelineConfig{
+				Services: map[string]ServiceTestConfig{
+					"paas-engine":   {Runtime: "go", UnitTest: "go test ./..."},
+					"lane-sidecar":  {DeployAfter: []string{"paas-engine"}},
+					"agent-service": {Runtime: "python", E2ETest: "pytest e2e/", E2EEnv: map[string]string{"API_URL": "x"}},
+				},
+				LarkFlow: &LarkFlowConfig{Runtime: "python", Cmd: "python flow.py", Timeout: "10m"},
+			},
+		},
+		{
+			name: "runtime 不支持、没有命令",
+			cfg:  PipelineConfig{Services: map[string]ServiceTestConfig{"svc": {Runtime: "node"}}},
+			want: []string{`services.svc.runtime: unsupported runtime "node"`, "services.svc: none of unit_test, e2e_test, deploy_after"},
+		},
+		{
+			name: "只声明部署顺序不需要 runtime",
+			cfg: PipelineConfig{Services: map[string]ServiceTestConfig{
+				"lite-registry":  {},
+				"channel-server": {DeployAfter: []string{"lite-registry"}},
+			}},
+			want: []string{"services.lite-registry: none of"},
+		},
+		{
+			name: "deploy_after 成环",
+			cfg: PipelineConfig{Services: map[string]ServiceTestConfig{
+				"a": {DeployAfter: []string{"b"}},
+				"b": {DeployAfter: []string{"c", "b"}},
+				"c": {DeployAfter: []string{"a"}},
+			}},
+			want: []string{`services.b.deploy_after: invalid dependency "b"`, "deploy_after: cycle a -> b -> c -> a"},
+		},
+		{
+			name: "e2e_env 变量名非法且没有 e2e_test",
+			cfg:  PipelineConfig{Services: map[string]ServiceTestConfig{"svc": {Runtime: "bun", UnitTest: "bun test", E2EEnv: map[string]string{"1BAD": "x"}}}},
+			want: []string{`services.svc.e2e_env: invalid variable name "1BAD"`, "set without e2e_test"},
+		},
+		{
+			name: "覆盖平台注入的变量",
+			cfg:  PipelineConfig{LarkFlow: &LarkFlowConfig{Runtime: "bun", Cmd: "bun run flow", Env: map[string]string{"LANE": "prod"}}},
+			want: []string{"lark_flow.env: LANE is set by the pipeline"},
+		},
+		{
+			name: "lark_flow 缺命令、超时非法",
+			cfg:  PipelineConfig{LarkFlow: &LarkFlowConfig{Runtime: "python", Timeout: "-1m"}},
+			want: []string{"lark_flow.cmd: required", `lark_flow.timeout: invalid duration "-1m"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("Validate() error = %v, want ErrInvalidInput", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("Validate() error = %q, want it to mention %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestE2EEnvs(t *testing.T) {
+	declared := map[string]string{"PAAS_API": "http://paas-engine:8080"}
+	envs := E2EEnvs("coe-auth", "http://api-gateway.prod:8080", declared)
+	want := map[string]string{
+		"PAAS_API":    "http://paas-engine:8080",
+		"LANE":        "coe-auth",
+		"GATEWAY_URL": "http://api-gateway.prod:8080",
+		"LANE_HEADER": "x-lane",
+	}
+	if len(envs) != len(want) {
+		t.Fatalf("envs = %v, want %v", envs, want)
+	}
+	for k, v := range want {
+		if envs[k] != v {
+			t.Errorf("%s = %q, want %q", k, envs[k], v)
+		}
+	}
+	if len(declared) != 1 {
+		t.Errorf("E2EEnvs modified the declared env: %v", declared)
+	}
+
+	if got := (&LarkFlowConfig{}).TimeoutDuration(); got != DefaultLarkFlowTimeout {
+		t.Errorf("default timeout = %s", got)
+	}
+	if got := (&LarkFlowConfig{Timeout: "5m"}).TimeoutDuration(); got.Minutes() != 5 {
+		t.Errorf("timeout = %s, want 5m", got)
+	}
+}
@@ -11,6 +11,8 @@ const (
 	ReleaseStatusPending  ReleaseStatus = "pending"
 	ReleaseStatusDeployed ReleaseStatus = "deployed"
 	ReleaseStatusFailed   ReleaseStatus = "failed"
+	// ReleaseStatusSleeping 表示 lane 休眠，Deployment 被缩到 0 副本，唤醒时恢复 SleptReplicas
+	ReleaseStatusSleeping ReleaseStatus = "sleeping"
 )
 
 // Release 代表一个 App 在某条 Lane 上的部署快照。
@@ -27,8 +29,17 @@ type Release struct {
 	Status     ReleaseStatus     `json:"status"`
 	Message    string            `json:"message,omitempty"`     // 部署失败原因
 	DeployName string            `json:"deploy_name,omitempty"` // K8s Deployment 名称
-	CreatedAt  time.Time         `json:"created_at"`
-	UpdatedAt  time.Time         `json:"updated_at"`
+	Resources  *ResourceSpec     `json:"resources,omitempty"`   // 覆盖 App.Resources 的非空字段
+	Probes     *HealthProbes     `json:"probes,omitempty"`      // 覆盖 App.Probes 中配置了的探针
+	// Autoscaling 非空时由 HPA 管理副本数，Replicas 只作为首次创建时的参考
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+	// SleptReplicas 是休眠前线上的副本数，仅 sleeping 状态下有值
+	SleptReplicas int32 `json:"slept_replicas,omitempty"`
+	// CommitSHA / ImageDigest 来自产出该镜像的 Build；有 digest 时按 digest 部署
+	CommitSHA   string    `json:"commit_sha,omitempty"`
+	ImageDigest string    `json:"image_digest,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // DeploymentStatus 表示 Deployment 的运行时状态。
@@ -38,6 +49,9 @@ type DeploymentStatus struct {
 	Ready      int32       `json:"ready"`
 	Available  int32       `json:"available"`
 	Pods       []PodStatus `json:"pods"`
+	Sleeping   bool        `json:"sleeping,omitempty"` // 所在 lane 休眠中，Desired 为 0
+	// Autoscaling 仅在 Deployment 挂了 HPA 时返回
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
 }
 
 // PodStatus 表示单个 Pod 的运行时状态。
@@ -49,7 +63,38 @@ type PodStatus struct {
 	Reason   string `json:"reason,omitempty"`
 }
 
+// DeployImage 返回下发到 Deployment 的镜像地址：有 digest 时按 digest 固定，
+// 避免同名 tag 被覆盖推送后线上跑的不是构建记录里的那份镜像。
+func (r *Release) DeployImage() string {
+	return ImageRefWithDigest(r.Image, r.ImageDigest)
+}
+
 // ResourceName 返回该 Release 对应的 K8s 资源名称（Deployment/Service 共用）。
 func (r *Release) ResourceName() string {
 	return r.AppName + "-" + r.Lane
 }
+
+// ReleaseRevision 是某个 Release 一次成功部署的不可变记录。
+// Release 本身是 (app, lane) 上的可变行，每次部署都原地覆盖；revision 按 release 内
+// 单调递增的 Revision 号逐条追加，回滚也是写入一条更大的新 revision，而非把号倒回去
+// （与 GatewayRuleSnapshot 同一套语义）。
+// BundleHash 只记录部署时 ConfigBundle 解析结果的摘要，不落明文（bundle 里多是密钥），
+// 回滚时按当前 bundle 重新解析，hash 不同说明 bundle 在两次部署之间被改过。
+type ReleaseRevision struct {
+	ReleaseID   string            `json:"release_id"`
+	Revision    int64             `json:"revision"`
+	AppName     string            `json:"app_name"`
+	Lane        string            `json:"lane"`
+	Image       string            `json:"image"`
+	Version     string            `json:"version,omitempty"`
+	Envs        map[string]string `json:"envs,omitempty"`
+	Replicas    int32             `json:"replicas"`
+	Resources   *ResourceSpec     `json:"resources,omitempty"`
+	Probes      *HealthProbes     `json:"probes,omitempty"`
+	Autoscaling *AutoscalingSpec  `json:"autoscaling,omitempty"`
+	CommitSHA   string            `json:"commit_sha,omitempty"`
+	ImageDigest string            `json:"image_digest,omitempty"`
+	BundleHash  string            `json:"bundle_hash,omitempty"`
+	Reason      string            `json:"reason,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
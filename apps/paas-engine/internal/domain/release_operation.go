@@ -0,0 +1,64 @@
+package domain
+
+import "time"
+
+// ReleaseOperationType 区分触发部署的入口。
+type ReleaseOperationType string
+
+const (
+	ReleaseOperationDeploy   ReleaseOperationType = "deploy"   // POST /releases（创建或按 app+lane 覆盖）
+	ReleaseOperationUpdate   ReleaseOperationType = "update"   // PUT /releases/{id}
+	ReleaseOperationRollback ReleaseOperationType = "rollback" // POST /releases/{id}:rollback
+	ReleaseOperationWake     ReleaseOperationType = "wake"     // lane 唤醒：恢复休眠前的副本数
+)
+
+type ReleaseOperationStatus string
+
+const (
+	ReleaseOperationRunning   ReleaseOperationStatus = "running"
+	ReleaseOperationSucceeded ReleaseOperationStatus = "succeeded"
+	ReleaseOperationFailed    ReleaseOperationStatus = "failed"
+)
+
+// 进度事件类型。
+const (
+	OperationEventApplied           = "applied"            // Deployment / Service 已下发
+	OperationEventReplicaSetCreated = "replicaset_created" // 观察到新的 ReplicaSet
+	OperationEventPodsReady         = "pods_ready"         // 就绪副本数变化，Message 形如 "pods ready 1/2"
+	OperationEventResumed           = "resumed"            // 执行它的实例退出后被接管
+	OperationEventSucceeded         = "succeeded"
+	OperationEventFailed            = "failed" // Message 为失败原因
+)
+
+// ReleaseOperationEvent 是一次部署过程中的一条进度记录。
+type ReleaseOperationEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message,omitempty"`
+}
+
+// ReleaseOperation 是一次后台部署（下发 + 等 rollout）的持久化记录。
+// Release 行在操作开始前就以 pending 落库，操作结束后再写回 deployed / failed；
+// 执行它的实例退出（租约过期）后仍为 running 的操作会被重新接管（下发是幂等的，重放一次即可）。
+// 同一 release 上新操作开始时，旧的 running 操作会被取消并标记为 failed。
+type ReleaseOperation struct {
+	ID         string                  `json:"id"`
+	ReleaseID  string                  `json:"release_id"`
+	AppName    string                  `json:"app_name"`
+	Lane       string                  `json:"lane"`
+	Type       ReleaseOperationType    `json:"type"`
+	Image      string                  `json:"image"`
+	Reason     string                  `json:"reason,omitempty"` // 成功后写入 revision 的 reason
+	Status     ReleaseOperationStatus  `json:"status"`
+	Message    string                  `json:"message,omitempty"`
+	Events     []ReleaseOperationEvent `json:"events"`
+	Lease                              // running 期间执行操作的实例
+	CreatedAt  time.Time               `json:"created_at"`
+	UpdatedAt  time.Time               `json:"updated_at"`
+	FinishedAt *time.Time              `json:"finished_at,omitempty"`
+}
+
+// IsTerminal 判断操作是否已结束。
+func (o *ReleaseOperation) IsTerminal() bool {
+	return o.Status == ReleaseOperationSucceeded || o.Status == ReleaseOperationFailed
+}
@@ -0,0 +1,311 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ResourceSpec 是主容器的 CPU / 内存 requests 与 limits，值用 K8s quantity 写法
+// （CPU 如 "100m"、"0.5"、"2"；内存如 "128Mi"、"1Gi"）。空串表示不设置。
+type ResourceSpec struct {
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+}
+
+type ProbeType string
+
+const (
+	ProbeTypeHTTP ProbeType = "http"
+	ProbeTypeTCP  ProbeType = "tcp"
+	ProbeTypeExec ProbeType = "exec"
+)
+
+// ProbeSpec 描述一个健康检查探针。Port 为 0 时 http/tcp 探针使用 App.Port；
+// 各项时间/阈值为 0 时沿用 K8s 默认值。
+type ProbeSpec struct {
+	Type                ProbeType `json:"type"`
+	Path                string    `json:"path,omitempty"`    // http
+	Port                int       `json:"port,omitempty"`    // http / tcp
+	Command             []string  `json:"command,omitempty"` // exec
+	InitialDelaySeconds int32     `json:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int32     `json:"period_seconds,omitempty"`
+	TimeoutSeconds      int32     `json:"timeout_seconds,omitempty"`
+	FailureThreshold    int32     `json:"failure_threshold,omitempty"`
+	SuccessThreshold    int32     `json:"success_threshold,omitempty"`
+}
+
+// HealthProbes 是主容器的三类探针，nil 表示不配置该探针。
+type HealthProbes struct {
+	Readiness *ProbeSpec `json:"readiness,omitempty"`
+	Liveness  *ProbeSpec `json:"liveness,omitempty"`
+	Startup   *ProbeSpec `json:"startup,omitempty"`
+}
+
+// EffectiveResources 合并 App 默认值与 Release 覆盖：Release 上非空的字段逐项覆盖 App。
+// 两者都没配置返回 nil。
+func EffectiveResources(app *App, release *Release) *ResourceSpec {
+	var base, override *ResourceSpec
+	if app != nil {
+		base = app.Resources
+	}
+	if release != nil {
+		override = release.Resources
+	}
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := ResourceSpec{}
+	if base != nil {
+		merged = *base
+	}
+	if override != nil {
+		if override.CPURequest != "" {
+			merged.CPURequest = override.CPURequest
+		}
+		if override.CPULimit != "" {
+			merged.CPULimit = override.CPULimit
+		}
+		if override.MemoryRequest != "" {
+			merged.MemoryRequest = override.MemoryRequest
+		}
+		if override.MemoryLimit != "" {
+			merged.MemoryLimit = override.MemoryLimit
+		}
+	}
+	return &merged
+}
+
+// EffectiveProbes 合并 App 默认值与 Release 覆盖：Release 上配置了的探针整条替换 App 的同类探针。
+// 两者都没配置返回 nil。
+func EffectiveProbes(app *App, release *Release) *HealthProbes {
+	var base, override *HealthProbes
+	if app != nil {
+		base = app.Probes
+	}
+	if release != nil {
+		override = release.Probes
+	}
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := HealthProbes{}
+	if base != nil {
+		merged = *base
+	}
+	if override != nil {
+		if override.Readiness != nil {
+			merged.Readiness = override.Readiness
+		}
+		if override.Liveness != nil {
+			merged.Liveness = override.Liveness
+		}
+		if override.Startup != nil {
+			merged.Startup = override.Startup
+		}
+	}
+	return &merged
+}
+
+// ValidateResources 校验 quantity 格式、取值为正，且 request 不超过 limit。nil 视为合法。
+func ValidateResources(spec *ResourceSpec) error {
+	if spec == nil {
+		return nil
+	}
+	cpuReq, err := parseCPU("cpu_request", spec.CPURequest)
+	if err != nil {
+		return err
+	}
+	cpuLim, err := parseCPU("cpu_limit", spec.CPULimit)
+	if err != nil {
+		return err
+	}
+	if cpuReq > 0 && cpuLim > 0 && cpuReq > cpuLim {
+		return fmt.Errorf("%w: cpu_request %q exceeds cpu_limit %q", ErrInvalidInput, spec.CPURequest, spec.CPULimit)
+	}
+	memReq, err := parseMemory("memory_request", spec.MemoryRequest)
+	if err != nil {
+		return err
+	}
+	memLim, err := parseMemory("memory_limit", spec.MemoryLimit)
+	if err != nil {
+		return err
+	}
+	if memReq > 0 && memLim > 0 && memReq > memLim {
+		return fmt.Errorf("%w: memory_request %q exceeds memory_limit %q", ErrInvalidInput, spec.MemoryRequest, spec.MemoryLimit)
+	}
+	return nil
+}
+
+// ValidateProbes 校验每条探针的类型、目标与时间参数。appPort 用于 http/tcp 探针未显式
+// 指定端口时的兜底；Worker（appPort=0）只能用显式端口或 exec 探针。nil 视为合法。
+func ValidateProbes(probes *HealthProbes, appPort int) error {
+	if probes == nil {
+		return nil
+	}
+	if err := validateProbe("readiness", probes.Readiness, appPort); err != nil {
+		return err
+	}
+	if err := validateProbe("liveness", probes.Liveness, appPort); err != nil {
+		return err
+	}
+	return validateProbe("startup", probes.Startup, appPort)
+}
+
+func validateProbe(kind string, p *ProbeSpec, appPort int) error {
+	if p == nil {
+		return nil
+	}
+	switch p.Type {
+	case ProbeTypeHTTP:
+		if !strings.HasPrefix(p.Path, "/") {
+			return fmt.Errorf("%w: %s probe path must start with '/'", ErrInvalidInput, kind)
+		}
+		if err := validateProbePort(kind, p.Port, appPort); err != nil {
+			return err
+		}
+	case ProbeTypeTCP:
+		if err := validateProbePort(kind, p.Port, appPort); err != nil {
+			return err
+		}
+	case ProbeTypeExec:
+		if len(p.Command) == 0 || p.Command[0] == "" {
+			return fmt.Errorf("%w: %s exec probe requires a command", ErrInvalidInput, kind)
+		}
+	default:
+		return fmt.Errorf("%w: %s probe type %q must be one of http, tcp, exec", ErrInvalidInput, kind, p.Type)
+	}
+
+	if p.InitialDelaySeconds < 0 || p.PeriodSeconds < 0 || p.TimeoutSeconds < 0 ||
+		p.FailureThreshold < 0 || p.SuccessThreshold < 0 {
+		return fmt.Errorf("%w: %s probe timings and thresholds must not be negative", ErrInvalidInput, kind)
+	}
+	// K8s 要求 liveness / startup 的 successThreshold 只能为 1
+	if kind != "readiness" && p.SuccessThreshold > 1 {
+		return fmt.Errorf("%w: %s probe success_threshold must be 1", ErrInvalidInput, kind)
+	}
+	if p.PeriodSeconds > 0 && p.TimeoutSeconds > p.PeriodSeconds {
+		return fmt.Errorf("%w: %s probe timeout_seconds (%d) exceeds period_seconds (%d)",
+			ErrInvalidInput, kind, p.TimeoutSeconds, p.PeriodSeconds)
+	}
+	return nil
+}
+
+func validateProbePort(kind string, port, appPort int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("%w: %s probe port %d out of range", ErrInvalidInput, kind, port)
+	}
+	if port == 0 && appPort <= 0 {
+		return fmt.Errorf("%w: %s probe needs an explicit port because app exposes none", ErrInvalidInput, kind)
+	}
+	return nil
+}
+
+// quantityRegex 拆出 K8s quantity 的数值与后缀（不支持科学计数法，平台内没人用）。
+var quantityRegex = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([A-Za-z]*)$`)
+
+var memorySuffixes = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+}
+
+// parseCPU 把 CPU quantity 转成核数，空串返回 0。
+func parseCPU(field, v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	num, suffix, err := splitQuantity(field, v)
+	if err != nil {
+		return 0, err
+	}
+	switch suffix {
+	case "":
+	case "m":
+		num /= 1000
+	default:
+		return 0, fmt.Errorf("%w: %s %q has unsupported unit (use cores or millicores, e.g. 500m)", ErrInvalidInput, field, v)
+	}
+	if num <= 0 {
+		return 0, fmt.Errorf("%w: %s %q must be positive", ErrInvalidInput, field, v)
+	}
+	return num, nil
+}
+
+// parseMemory 把内存 quantity 转成字节数，空串返回 0。
+func parseMemory(field, v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	num, suffix, err := splitQuantity(field, v)
+	if err != nil {
+		return 0, err
+	}
+	mult, ok := memorySuffixes[suffix]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s %q has unsupported unit (use e.g. 128Mi, 1Gi)", ErrInvalidInput, field, v)
+	}
+	num *= mult
+	if num < 1 {
+		return 0, fmt.Errorf("%w: %s %q must be positive", ErrInvalidInput, field, v)
+	}
+	return num, nil
+}
+
+func splitQuantity(field, v string) (float64, string, error) {
+	m := quantityRegex.FindStringSubmatch(v)
+	if m == nil {
+		return 0, "", fmt.Errorf("%w: %s %q is not a valid quantity", ErrInvalidInput, field, v)
+	}
+	num, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %s %q is not a valid quantity", ErrInvalidInput, field, v)
+	}
+	return num, m[2], nil
+}
+
+// reservedContainerNames 是平台自己注入的容器名，App 声明的附加容器不能占用。
+var reservedContainerNames = []string{"lane-sidecar", "lane-sidecar-init"}
+
+// ValidateExtraContainers 校验 App 声明的 init 容器和 sidecar：名字合法且在 Pod 内唯一
+// （不能与主容器同名、不能占用平台保留名），挂载路径为绝对路径，资源取值合法。
+func ValidateExtraContainers(appName string, initContainers, sidecars []ExtraContainer) error {
+	seen := map[string]bool{appName: true}
+	for _, name := range reservedContainerNames {
+		seen[name] = true
+	}
+	check := func(kind string, containers []ExtraContainer) error {
+		for _, c := range containers {
+			if err := ValidateK8sName(c.Name); err != nil {
+				return fmt.Errorf("%s %q: %w", kind, c.Name, err)
+			}
+			if seen[c.Name] {
+				return fmt.Errorf("%w: %s name %q is already used in the pod", ErrInvalidInput, kind, c.Name)
+			}
+			seen[c.Name] = true
+			for _, v := range c.Volumes {
+				if v.PVCName == "" || !strings.HasPrefix(v.MountPath, "/") {
+					return fmt.Errorf("%w: %s %q volume needs pvc_name and an absolute mount_path", ErrInvalidInput, kind, c.Name)
+				}
+			}
+			if err := ValidateResources(c.Resources); err != nil {
+				return fmt.Errorf("%s %q: %w", kind, c.Name, err)
+			}
+		}
+		return nil
+	}
+	if err := check("init container", initContainers); err != nil {
+		return err
+	}
+	return check("sidecar", sidecars)
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *ResourceSpec
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"empty", &ResourceSpec{}, false},
+		{"millicores and Mi", &ResourceSpec{CPURequest: "100m", CPULimit: "1", MemoryRequest: "128Mi", MemoryLimit: "1Gi"}, false},
+		{"decimal cores", &ResourceSpec{CPURequest: "0.5", CPULimit: "500m"}, false},
+		{"only limits", &ResourceSpec{CPULimit: "2", MemoryLimit: "512M"}, false},
+		{"cpu request above limit", &ResourceSpec{CPURequest: "2", CPULimit: "500m"}, true},
+		{"memory request above limit", &ResourceSpec{MemoryRequest: "2Gi", MemoryLimit: "1Gi"}, true},
+		{"zero cpu", &ResourceSpec{CPURequest: "0"}, true},
+		{"negative memory", &ResourceSpec{MemoryLimit: "-1Gi"}, true},
+		{"cpu with memory unit", &ResourceSpec{CPULimit: "1Gi"}, true},
+		{"memory unknown unit", &ResourceSpec{MemoryRequest: "128MB"}, true},
+		{"garbage", &ResourceSpec{MemoryRequest: "lots"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateResources(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		probes  *HealthProbes
+		appPort int
+		wantErr bool
+	}{
+		{"nil", nil, 8080, false},
+		{"http uses app port", &HealthProbes{Readiness: &ProbeSpec{Type: ProbeTypeHTTP, Path: "/healthz"}}, 8080, false},
+		{"tcp explicit port on worker", &HealthProbes{Liveness: &ProbeSpec{Type: ProbeTypeTCP, Port: 9000}}, 0, false},
+		{"exec on worker", &HealthProbes{Startup: &ProbeSpec{Type: ProbeTypeExec, Command: []string{"cat", "/tmp/ready"}}}, 0, false},
+		{"readiness success threshold", &HealthProbes{Readiness: &ProbeSpec{Type: ProbeTypeHTTP, Path: "/", SuccessThreshold: 3}}, 8080, false},
+		{"unknown type", &HealthProbes{Readiness: &ProbeSpec{Type: "grpc"}}, 8080, true},
+		{"http without leading slash", &HealthProbes{Readiness: &ProbeSpec{Type: ProbeTypeHTTP, Path: "healthz"}}, 8080, true},
+		{"http on worker without port", &HealthProbes{Readiness: &ProbeSpec{Type: ProbeTypeHTTP, Path: "/healthz"}}, 0, true},
+		{"port out of range", &HealthProbes{Liveness: &ProbeSpec{Type: ProbeTypeTCP, Port: 70000}}, 8080, true},
+		{"exec without command", &HealthProbes{Liveness: &ProbeSpec{Type: ProbeTypeExec}}, 8080, true},
+		{"negative delay", &HealthProbes{Liveness: &ProbeSpec{Type: ProbeTypeTCP, InitialDelaySeconds: -1}}, 8080, true},
+		{"liveness success threshold", &HealthProbes{Liveness: &ProbeSpec{Type: ProbeTypeTCP, SuccessThreshold: 2}}, 8080, true},
+		{"timeout above period", &HealthProbes{Readiness: &ProbeSpec{Type: ProbeTypeTCP, PeriodSeconds: 5, TimeoutSeconds: 10}}, 8080, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProbes(tt.probes, tt.appPort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateProbes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEffectiveResources_ReleaseOverridesPerField(t *testing.T) {
+	app := &App{Resources: &ResourceSpec{CPURequest: "100m", MemoryLimit: "512Mi"}}
+	rel := &Release{Resources: &ResourceSpec{MemoryLimit: "1Gi"}}
+
+	got := EffectiveResources(app, rel)
+	if got.CPURequest != "100m" || got.MemoryLimit != "1Gi" {
+		t.Errorf("EffectiveResources = %+v", got)
+	}
+	if app.Resources.MemoryLimit != "512Mi" {
+		t.Error("merge must not mutate App.Resources")
+	}
+	if EffectiveResources(&App{}, &Release{}) != nil {
+		t.Error("expected nil when neither app nor release sets resources")
+	}
+}
+
+func TestEffectiveProbes_ReleaseReplacesWholeProbe(t *testing.T) {
+	app := &App{Probes: &HealthProbes{
+		Readiness: &ProbeSpec{Type: ProbeTypeHTTP, Path: "/ready"},
+		Liveness:  &ProbeSpec{Type: ProbeTypeTCP},
+	}}
+	rel := &Release{Probes: &HealthProbes{Readiness: &ProbeSpec{Type: ProbeTypeExec, Command: []string{"true"}}}}
+
+	got := EffectiveProbes(app, rel)
+	if got.Readiness.Type != ProbeTypeExec || got.Readiness.Path != "" {
+		t.Errorf("readiness not replaced: %+v", got.Readiness)
+	}
+	if got.Liveness == nil || got.Liveness.Type != ProbeTypeTCP {
+		t.Errorf("liveness should be inherited from app: %+v", got.Liveness)
+	}
+}
+
+func TestValidateExtraContainers(t *testing.T) {
+	tests := []struct {
+		name     string
+		inits    []ExtraContainer
+		sidecars []ExtraContainer
+		wantErr  bool
+	}{
+		{"none", nil, nil, false},
+		{"migrate and log shipper", []ExtraContainer{{Name: "migrate", Command: []string{"migrate", "up"}}},
+			[]ExtraContainer{{Name: "vector", Image: "vector:0.40", Volumes: []VolumeMount{{PVCName: "logs", MountPath: "/logs"}}}}, false},
+		{"invalid name", []ExtraContainer{{Name: "Migrate_DB"}}, nil, true},
+		{"same name as app", nil, []ExtraContainer{{Name: "agent-service"}}, true},
+		{"reserved lane sidecar", nil, []ExtraContainer{{Name: "lane-sidecar"}}, true},
+		{"duplicate across init and sidecar", []ExtraContainer{{Name: "proxy"}}, []ExtraContainer{{Name: "proxy"}}, true},
+		{"relative mount path", []ExtraContainer{{Name: "migrate", Volumes: []VolumeMount{{PVCName: "data", MountPath: "data"}}}}, nil, true},
+		{"bad resources", nil, []ExtraContainer{{Name: "proxy", Resources: &ResourceSpec{CPURequest: "2", CPULimit: "1"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExtraContainers("agent-service", tt.inits, tt.sidecars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateExtraContainers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("expected ErrInvalidInput, got %v", err)
+			}
+		})
+	}
+}
@@ -16,8 +16,48 @@ var (
 		Help: "Number of builds currently in progress.",
 	})
 
+	BuildsQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "paas_builds_queued",
+		Help: "Number of builds waiting in the build queue.",
+	})
+
+	BuildsReused = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "paas_builds_reused_total",
+		Help: "Total number of build requests served by an existing build with the same fingerprint.",
+	})
+
+	BuildsPromoted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "paas_builds_promoted_total",
+		Help: "Total number of test builds promoted to stable by re-tagging the same digest.",
+	})
+
+	ImageScansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "paas_image_scans_total",
+		Help: "Total number of image vulnerability scans by result.",
+	}, []string{"status"})
+
+	ImagesPruned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "paas_images_pruned_total",
+		Help: "Total number of image tags deleted from the registry by the retention policy.",
+	}, []string{"image_repo"})
+
 	ReleasesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "paas_releases_total",
 		Help: "Total number of releases by lane.",
 	}, []string{"lane"})
+
+	ReleaseDriftFields = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "paas_release_drift_fields",
+		Help: "Number of drifted fields between a release and its live Deployment.",
+	}, []string{"app", "lane"})
+
+	DriftHealsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "paas_drift_heals_total",
+		Help: "Total number of drift auto-heal attempts by result.",
+	}, []string{"app", "lane", "result"})
+
+	DriftLastReconcileTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "paas_drift_last_reconcile_timestamp_seconds",
+		Help: "Unix time of the last completed drift reconcile pass.",
+	})
 )
@@ -2,13 +2,21 @@ package port
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 )
 
+// BuildResult 是 Job 状态变更时上报的构建结果。
+type BuildResult struct {
+	Status domain.BuildStatus
+	Log    string
+	Digest string // 推送成功的镜像 digest（sha256:...），仅 succeeded 时有值
+}
+
 // BuildStatusCallback 在 Job 状态变更时被调用。
-type BuildStatusCallback func(buildID string, status domain.BuildStatus, log string)
+type BuildStatusCallback func(buildID string, result BuildResult)
 
 // AppLogQuery 封装应用日志查询的所有参数。
 type AppLogQuery struct {
@@ -40,16 +48,41 @@ type BuildSubmission struct {
 	Dockerfile string // Dockerfile 路径（相对 context），空则使用默认
 	ImageTag   string // 完整镜像地址含 tag
 	NoCache    bool   // true = 强制关闭构建缓存
+	Builder    string // 构建后端（domain.BuilderKaniko / BuilderBuildKit），空 = kaniko
+
+	BuildArgs       map[string]string // --build-arg 明文参数
+	SecretBuildArgs map[string]string // 已解析出值的 secret 参数，不得出现在 Job 参数里
+	Target          string            // --target
+	Platform        string            // 目标平台，如 linux/arm64
+	Labels          map[string]string // --label
+}
+
+// SubmittedBuild 是 Submit 成功后的结果。
+type SubmittedBuild struct {
+	JobName   string
+	CommitSHA string // GitRef 解析出的 commit，构建上下文固定在这个 commit 上；未配置解析且 GitRef 不是完整 SHA 时为空
 }
 
-// BuildExecutor 负责驱动 Kaniko Job 的生命周期。
+// BuildExecutor 负责驱动构建 Job（Kaniko / BuildKit）的生命周期。
 type BuildExecutor interface {
-	// Submit 创建 Kaniko Job 并返回 Job 名称。
-	Submit(ctx context.Context, sub *BuildSubmission) (jobName string, err error)
+	// Submit 把 GitRef 解析为 commit 后创建构建 Job。
+	Submit(ctx context.Context, sub *BuildSubmission) (*SubmittedBuild, error)
 	// Cancel 删除对应 Job。
 	Cancel(ctx context.Context, jobName string) error
 	// Watch 启动 Informer 监听，状态变更时调用 callback。
 	Watch(ctx context.Context, callback BuildStatusCallback) error
 	// GetLogs 获取构建 Pod 的容器日志。
 	GetLogs(ctx context.Context, buildID string) (string, error)
+	// FollowLogs 跟随构建 Pod 的容器日志直到容器退出；没有 Pod 时返回 nil。
+	FollowLogs(ctx context.Context, buildID string) (io.ReadCloser, error)
+}
+
+// GitRefResolver 把 branch / tag / 短 SHA 解析为完整 commit SHA。
+type GitRefResolver interface {
+	ResolveCommit(ctx context.Context, gitRepo, ref string) (string, error)
+}
+
+// GitAncestry 判断 commit 是否已合入某个分支，镜像晋升前据此确认 commit 在 main 上。
+type GitAncestry interface {
+	IsAncestor(ctx context.Context, gitRepo, commit, ref string) (bool, error)
 }
@@ -8,7 +8,17 @@ import (
 
 // Deployer 负责将 Release 翻译为 K8s Deployment + Service 并下发。
 type Deployer interface {
-	Deploy(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) error
+	// Apply 下发 Deployment / Service / 配置 Secret，不等待 rollout。
+	Apply(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) error
+	// WaitForRollout 轮询 Deployment 直到所有副本就绪、失败或超时。
+	// onEvent 非 nil 时上报进度（新 ReplicaSet、就绪副本数变化），Time 由调用方填。
+	WaitForRollout(ctx context.Context, name string, onEvent func(domain.ReleaseOperationEvent)) error
+	// DiffDeployment 对比 Release 期望的 Deployment 与集群中的实际 spec，返回不一致项。
+	// 只读，不修改集群；Deployment 不存在时返回一条 deployment 类型的漂移。
+	DiffDeployment(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) ([]domain.DriftItem, error)
+	// Scale 只改 Deployment 副本数（lane 休眠 / 唤醒），返回调整前的副本数。
+	// 缩到 0 时一并删除 HPA；扩回来时按 release.Autoscaling 重建 HPA。
+	Scale(ctx context.Context, release *domain.Release, replicas int32) (int32, error)
 	Delete(ctx context.Context, release *domain.Release, hasOtherReleases bool) error
 	// GetDeploymentStatus 查询指定 Deployment 的运行时状态（副本数 + Pod 列表）。
 	GetDeploymentStatus(ctx context.Context, name string) (*domain.DeploymentStatus, error)
@@ -2,6 +2,8 @@ package port
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 )
@@ -21,10 +23,27 @@ type TestSubmission struct {
 
 // TestExecutor 负责驱动测试 K8s Job 的生命周期。
 type TestExecutor interface {
+	// Submit 为 JobRunID 起测试 Job；同一 JobRunID 的 Job 已存在时直接返回它的名字。
 	Submit(ctx context.Context, sub *TestSubmission) (jobName string, err error)
 	Cancel(ctx context.Context, jobName string) error
+	// Watch 监听测试 Job 状态，启动时对已有的 Job 也回调一次。
 	Watch(ctx context.Context, callback TestStatusCallback) error
 	GetLogs(ctx context.Context, jobRunID string) (string, error)
+	// FollowLogs 跟随测试 Pod 的容器日志直到容器退出；没有 Pod 时返回 nil。
+	FollowLogs(ctx context.Context, jobRunID string) (io.ReadCloser, error)
+}
+
+// GitComparer 列出两个 commit 之间改动的文件，用于 monorepo 变更检测。
+type GitComparer interface {
+	// ChangedFiles 返回 base..head 改动的文件路径（相对仓库根目录，改名时新旧路径都在内）。
+	// head 不是 base 的后继（分支被 force push）或改动过多无法列全时返回错误，调用方应按全量处理。
+	ChangedFiles(ctx context.Context, gitRepo, base, head string) ([]string, error)
+}
+
+// PipelineConfigLoader 读取仓库在某个 ref 上的 pipeline.yml。
+type PipelineConfigLoader interface {
+	// LoadPipelineConfig 文件不存在时返回 domain.ErrNotFound，无法解析时返回 domain.ErrInvalidInput。
+	LoadPipelineConfig(ctx context.Context, gitRepo, ref string) (*domain.PipelineConfig, error)
 }
 
 // CIConfigRepository 管理 CI 配置的持久化。
@@ -44,7 +63,15 @@ type PipelineRunRepository interface {
 	FindByID(ctx context.Context, id string) (*domain.PipelineRun, error)
 	FindByLane(ctx context.Context, lane string, limit int) ([]*domain.PipelineRun, error)
 	ExistsByCommitSHA(ctx context.Context, sha string) (bool, error)
+	// FindLatestSucceeded 返回 lane 上最近一次成功、且有具体 commit（非 manual 触发）的 run。
+	FindLatestSucceeded(ctx context.Context, lane string) (*domain.PipelineRun, error)
+	// FindUnfinished 返回 pending / running 的 run，按创建时间升序，启动时接管用。
+	FindUnfinished(ctx context.Context) ([]*domain.PipelineRun, error)
 	Update(ctx context.Context, run *domain.PipelineRun) error
+	// ClaimLease 在 pending / running 的 run 的租约无人持有或心跳早于 expiredBefore 时交给 owner，返回是否抢到。
+	ClaimLease(ctx context.Context, id, owner string, now, expiredBefore time.Time) (bool, error)
+	// RenewLease 续约，租约已被其他实例接管时返回 false。
+	RenewLease(ctx context.Context, id, owner string, now time.Time) (bool, error)
 
 	SaveStage(ctx context.Context, stage *domain.StageRun) error
 	FindStagesByRunID(ctx context.Context, runID string) ([]domain.StageRun, error)
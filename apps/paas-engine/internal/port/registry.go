@@ -0,0 +1,11 @@
+package port
+
+import "context"
+
+// ImageRegistry 通过 OCI distribution API 管理 registry 里的镜像。
+type ImageRegistry interface {
+	// DeleteImage 删除完整镜像地址（registry/name:tag）指向的镜像，已不存在时返回 nil。
+	DeleteImage(ctx context.Context, image string) error
+	// TagImage 给 source（registry/name@sha256:...）指向的同一份 manifest 打上 target 的 tag。
+	TagImage(ctx context.Context, source, target string) error
+}
@@ -2,6 +2,7 @@ package port
 
 import (
 	"context"
+	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
 )
@@ -21,7 +22,16 @@ type BuildRepository interface {
 	FindLatestSuccessful(ctx context.Context, imageRepoName string) (*domain.Build, error)
 	FindLatestVersioned(ctx context.Context, imageRepoName string) (*domain.Build, error)
 	FindByImageTag(ctx context.Context, imageTag string) (*domain.Build, error)
+	// FindByStatus 按出队顺序（priority 升序、created_at 升序）返回指定状态的构建。
+	FindByStatus(ctx context.Context, status domain.BuildStatus) ([]*domain.Build, error)
+	// FindByFingerprint 按创建时间倒序返回该 ImageRepo 下指纹相同的构建。
+	FindByFingerprint(ctx context.Context, imageRepoName, fingerprint string) ([]*domain.Build, error)
+	// FindByScanStatus 按更新时间升序返回镜像扫描处于指定状态的构建。
+	FindByScanStatus(ctx context.Context, status domain.ScanStatus) ([]*domain.Build, error)
 	Update(ctx context.Context, build *domain.Build) error
+	// UpdateFromStatus 仅在库里的构建仍处于 from 状态时整行写入 build，返回是否写入。
+	// 状态流转（出队、提交、取消、结束）都走这里，并发的两方（多个实例同时出队、出队时被取消）只有一方生效。
+	UpdateFromStatus(ctx context.Context, build *domain.Build, from domain.BuildStatus) (bool, error)
 }
 
 type ImageRepoRepository interface {
@@ -41,6 +51,81 @@ type ReleaseRepository interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// ReleaseRevisionRepository 存放每次成功部署的不可变 revision 历史。
+// Release 被删除时 revision 不随之删除（历史只追加）。
+type ReleaseRevisionRepository interface {
+	// Save 在事务内为该 release 分配下一个 revision 号（max+1）并落库，
+	// 分配到的号回填进 rev.Revision。
+	Save(ctx context.Context, rev *domain.ReleaseRevision) error
+	// FindByRelease 按 revision 倒序返回最近 limit 条（limit<=0 返回全部）。
+	FindByRelease(ctx context.Context, releaseID string, limit int) ([]*domain.ReleaseRevision, error)
+	// FindByReleaseAndRevision 取一条 revision，不存在返回 ErrReleaseRevisionNotFound。
+	FindByReleaseAndRevision(ctx context.Context, releaseID string, revision int64) (*domain.ReleaseRevision, error)
+	// ListImages 返回所有 revision 部署过的镜像地址（去重），镜像清理据此保留历史可回滚的镜像。
+	ListImages(ctx context.Context) ([]string, error)
+}
+
+// ReleaseCanaryRepository 持久化金丝雀发布及其每一步的观测结果。
+type ReleaseCanaryRepository interface {
+	Save(ctx context.Context, canary *domain.ReleaseCanary) error
+	Update(ctx context.Context, canary *domain.ReleaseCanary) error
+	FindByID(ctx context.Context, id string) (*domain.ReleaseCanary, error)
+	// FindLatestByRelease 返回该 release 最近一次金丝雀，不存在返回 ErrCanaryNotFound。
+	FindLatestByRelease(ctx context.Context, releaseID string) (*domain.ReleaseCanary, error)
+	// FindRunning 返回所有 running 状态的金丝雀（回收持有者已退出的流程）。
+	FindRunning(ctx context.Context) ([]*domain.ReleaseCanary, error)
+	// ClaimLease 在 running 金丝雀的租约无人持有或心跳早于 expiredBefore 时交给 owner，返回是否抢到。
+	ClaimLease(ctx context.Context, id, owner string, now, expiredBefore time.Time) (bool, error)
+	// RenewLease 续约，租约已被其他实例接管时返回 false。
+	RenewLease(ctx context.Context, id, owner string, now time.Time) (bool, error)
+}
+
+// ReleaseOperationRepository 持久化后台部署操作及其进度事件。
+type ReleaseOperationRepository interface {
+	Save(ctx context.Context, op *domain.ReleaseOperation) error
+	Update(ctx context.Context, op *domain.ReleaseOperation) error
+	FindByID(ctx context.Context, id string) (*domain.ReleaseOperation, error)
+	// FindRunning 返回所有 running 状态的操作（接管执行实例已退出的部署）。
+	FindRunning(ctx context.Context) ([]*domain.ReleaseOperation, error)
+	// ClaimLease 在 running 操作的租约无人持有或心跳早于 expiredBefore 时交给 owner，返回是否抢到。
+	ClaimLease(ctx context.Context, id, owner string, now, expiredBefore time.Time) (bool, error)
+	// RenewLease 续约，租约已被其他实例接管时返回 false。
+	RenewLease(ctx context.Context, id, owner string, now time.Time) (bool, error)
+}
+
+// ReleaseDriftRepository 持久化每个 release 当前的漂移状态（最近一轮对账的结果），
+// 多个 paas-engine 实例据此看到同一份漂移列表、判断差异是否已经上报过。
+type ReleaseDriftRepository interface {
+	// Save 按 release ID 插入或覆盖。
+	Save(ctx context.Context, drift *domain.ReleaseDrift) error
+	Delete(ctx context.Context, releaseID string) error
+	FindAll(ctx context.Context) ([]*domain.ReleaseDrift, error)
+}
+
+// DriftEventRepository 持久化漂移检测与自愈记录（只追加）。
+type DriftEventRepository interface {
+	Save(ctx context.Context, ev *domain.DriftEvent) error
+	// FindRecent 按时间倒序返回最近 limit 条，appName / lane 为空表示不过滤。
+	FindRecent(ctx context.Context, appName, lane string, limit int) ([]*domain.DriftEvent, error)
+}
+
+// LaneRepository 存放登记过的 lane（owner / TTL 等元数据）。
+type LaneRepository interface {
+	Save(ctx context.Context, lane *domain.Lane) error
+	FindByName(ctx context.Context, name string) (*domain.Lane, error)
+	FindAll(ctx context.Context) ([]*domain.Lane, error)
+	Update(ctx context.Context, lane *domain.Lane) error
+	Delete(ctx context.Context, name string) error
+}
+
+// LaneSleepPolicyRepository 存放按 lane 显式配置的休眠策略（未配置的 lane 用默认策略）。
+type LaneSleepPolicyRepository interface {
+	Upsert(ctx context.Context, policy *domain.LaneSleepPolicy) error
+	FindByLane(ctx context.Context, lane string) (*domain.LaneSleepPolicy, error)
+	FindAll(ctx context.Context) ([]*domain.LaneSleepPolicy, error)
+	Delete(ctx context.Context, lane string) error
+}
+
 type ConfigBundleRepository interface {
 	Save(ctx context.Context, bundle *domain.ConfigBundle) error
 	FindByName(ctx context.Context, name string) (*domain.ConfigBundle, error)
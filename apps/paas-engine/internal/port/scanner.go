@@ -0,0 +1,16 @@
+package port
+
+import "context"
+
+// ScanReport 是一次镜像漏洞扫描的汇总。
+type ScanReport struct {
+	Scanner   string
+	Counts    map[string]int // 严重级别（domain.Severity*）→ 漏洞数
+	ReportURL string         // 完整报告地址，扫描器不提供时为空
+}
+
+// ImageScanner 扫描镜像漏洞（Trivy Job 等）。
+type ImageScanner interface {
+	// Scan 扫描 image（按 digest 固定的地址），阻塞到扫描结束。scanID 用于命名扫描任务，同一 scanID 重入时复用已有任务。
+	Scan(ctx context.Context, scanID, image string) (*ScanReport, error)
+}
@@ -32,6 +32,11 @@ type CreateAppRequest struct {
 	Envs              map[string]string `json:"envs"`
 	ConfigBundles     []string             `json:"config_bundles"`
 	Volumes           []domain.VolumeMount `json:"volumes"`
+	Resources         *domain.ResourceSpec `json:"resources"`
+	Probes            *domain.HealthProbes `json:"probes"`
+	DriftPolicy       domain.DriftPolicy   `json:"drift_policy"`
+	InitContainers    []domain.ExtraContainer `json:"init_containers"`
+	Sidecars          []domain.ExtraContainer `json:"sidecars"`
 }
 
 func (s *AppService) CreateApp(ctx context.Context, req CreateAppRequest) (*domain.App, error) {
@@ -41,6 +46,18 @@ func (s *AppService) CreateApp(ctx context.Context, req CreateAppRequest) (*doma
 	if req.Port < 0 {
 		return nil, domain.ErrInvalidInput
 	}
+	if err := domain.ValidateResources(req.Resources); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateProbes(req.Probes, req.Port); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateDriftPolicy(req.DriftPolicy); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateExtraContainers(req.Name, req.InitContainers, req.Sidecars); err != nil {
+		return nil, err
+	}
 	// 校验 ImageRepo 存在
 	if req.ImageRepoName != "" {
 		if _, err := s.imageRepoRepo.FindByName(ctx, req.ImageRepoName); err != nil {
@@ -67,6 +84,11 @@ func (s *AppService) CreateApp(ctx context.Context, req CreateAppRequest) (*doma
 		Envs:              req.Envs,
 		ConfigBundles:     req.ConfigBundles,
 		Volumes:           req.Volumes,
+		Resources:         req.Resources,
+		Probes:            req.Probes,
+		DriftPolicy:       req.DriftPolicy,
+		InitContainers:    req.InitContainers,
+		Sidecars:          req.Sidecars,
 		CreatedAt:         now,
 		UpdatedAt:         now,
 	}
@@ -129,6 +151,34 @@ func (s *AppService) UpdateApp(ctx context.Context, name string, body []byte) (*
 	if err := ApplyField(fields, "allowed_lane_classes", &app.AllowedLaneClasses); err != nil {
 		return nil, domain.ErrInvalidInput
 	}
+	if err := ApplyField(fields, "resources", &app.Resources); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "probes", &app.Probes); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "drift_policy", &app.DriftPolicy); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "init_containers", &app.InitContainers); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "sidecars", &app.Sidecars); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	// 合并后整体校验（port 改成 0 时 http/tcp 探针可能失去兜底端口）
+	if err := domain.ValidateResources(app.Resources); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateProbes(app.Probes, app.Port); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateDriftPolicy(app.DriftPolicy); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateExtraContainers(app.Name, app.InitContainers, app.Sidecars); err != nil {
+		return nil, err
+	}
 	if _, ok := fields["config_bundles"]; ok && len(app.ConfigBundles) > 0 {
 		if err := s.validateConfigBundles(ctx, app.ConfigBundles); err != nil {
 			return nil, err
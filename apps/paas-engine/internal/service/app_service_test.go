@@ -202,3 +202,30 @@ func TestUpdateApp_AcceptsAllowedLaneClasses(t *testing.T) {
 		t.Errorf("AllowedLaneClasses = %v, want [prod]", app.AllowedLaneClasses)
 	}
 }
+
+func TestCreateApp_RejectsInvalidResources(t *testing.T) {
+	svc := NewAppService(&stubAppRepo{}, &stubImageRepoRepo{}, &stubReleaseRepo{}, nil)
+
+	_, err := svc.CreateApp(context.Background(), CreateAppRequest{
+		Name:      "myapp",
+		Port:      8080,
+		Resources: &domain.ResourceSpec{CPURequest: "2", CPULimit: "1"},
+	})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestUpdateApp_PortZeroInvalidatesHTTPProbe(t *testing.T) {
+	appRepo := &stubAppRepo{app: &domain.App{
+		Name:   "myapp",
+		Port:   8080,
+		Probes: &domain.HealthProbes{Readiness: &domain.ProbeSpec{Type: domain.ProbeTypeHTTP, Path: "/healthz"}},
+	}}
+	svc := NewAppService(appRepo, &stubImageRepoRepo{}, &stubReleaseRepo{}, nil)
+
+	_, err := svc.UpdateApp(context.Background(), "myapp", []byte(`{"port":0}`))
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
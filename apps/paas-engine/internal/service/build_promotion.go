@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/metrics"
+	"github.com/google/uuid"
+)
+
+// PromoteBuildRequest 是把 test channel 构建晋升为 stable 版本的参数。
+type PromoteBuildRequest struct {
+	Version      string `json:"version,omitempty"`       // 显式指定 stable 版本（可选）
+	Bump         string `json:"bump,omitempty"`          // "major"/"minor"/"patch"/""（可选）
+	VerifiedLane string `json:"verified_lane,omitempty"` // 要求来源镜像在该 lane 部署过（可选）
+}
+
+// PromoteBuild 把 lane 里验证过的 test channel 构建晋升为 stable：不重新构建，而是在 registry 里
+// 给同一 digest 打上新版本 tag，并记一条 stable 的 Build，prod 跑的就是测试过的那份产物。
+// 要求构建成功、commit 已合入 main；指定 VerifiedLane 时还要求来源镜像在该 lane 部署过。
+// 同一构建重复晋升返回已有的晋升结果。
+func (s *BuildService) PromoteBuild(ctx context.Context, imageRepoName, id string, req PromoteBuildRequest) (*domain.Build, error) {
+	if s.cfg.Registry == nil || s.cfg.Ancestry == nil {
+		return nil, fmt.Errorf("%w: image promotion is not supported", domain.ErrInvalidInput)
+	}
+	source, err := s.GetBuild(ctx, imageRepoName, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPromotable(source); err != nil {
+		return nil, err
+	}
+	if promoted := s.findPromotion(ctx, source); promoted != nil {
+		promoted.Reused = true
+		return promoted, nil
+	}
+
+	imageRepo, err := s.imageRepoRepo.FindByName(ctx, imageRepoName)
+	if err != nil {
+		return nil, err
+	}
+	onMain, err := s.cfg.Ancestry.IsAncestor(ctx, imageRepo.GitRepo, source.CommitSHA, "main")
+	if err != nil {
+		return nil, fmt.Errorf("check commit %s on main: %w", source.CommitSHA, err)
+	}
+	if !onMain {
+		return nil, fmt.Errorf("%w: commit %s of build %s is not on main yet", domain.ErrInvalidInput, source.CommitSHA, source.ID)
+	}
+	if req.VerifiedLane != "" {
+		if err := s.checkLaneEvidence(ctx, source, req.VerifiedLane); err != nil {
+			return nil, err
+		}
+	}
+
+	nextVersion, err := s.nextVersion(ctx, imageRepoName, req.Version, req.Bump)
+	if err != nil {
+		return nil, err
+	}
+	target := imageRepo.FullImageRef(nextVersion.String())
+	if err := s.cfg.Registry.TagImage(ctx, source.ImageRefWithDigest(), target); err != nil {
+		return nil, fmt.Errorf("tag %s as %s: %w", source.ImageTag, target, err)
+	}
+
+	now := time.Now()
+	build := &domain.Build{
+		ID:            uuid.New().String(),
+		ImageRepoName: imageRepoName,
+		GitRef:        source.GitRef,
+		ImageTag:      target,
+		Version:       nextVersion.String(),
+		Channel:       domain.ChannelStable,
+		Status:        domain.BuildStatusSucceeded,
+		CommitSHA:     source.CommitSHA,
+		Digest:        source.Digest,
+		Dockerfile:    source.Dockerfile,
+		Fingerprint:   source.Fingerprint,
+		BuildOptions:  source.BuildOptions,
+		PromotedFrom:  source.ID,
+		VerifiedLane:  req.VerifiedLane,
+		Scan:          source.Scan, // 同一 digest，扫描结果照搬
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.buildRepo.Save(ctx, build); err != nil {
+		return nil, err
+	}
+	metrics.BuildsPromoted.Inc()
+	slog.Info("build promoted", "image_repo", imageRepoName, "from", source.ID, "from_version", source.Version,
+		"version", build.Version, "digest", build.Digest, "verified_lane", req.VerifiedLane)
+	return build, nil
+}
+
+// checkPromotable 校验来源构建：成功、未被清理、是 test channel，且有 digest 和 commit 可追溯。
+func checkPromotable(b *domain.Build) error {
+	channel := b.Channel
+	if channel == "" {
+		channel = domain.ResolveChannel(b.GitRef)
+	}
+	switch {
+	case b.Status != domain.BuildStatusSucceeded:
+		return fmt.Errorf("%w: build %s is %s, only succeeded builds can be promoted", domain.ErrInvalidInput, b.ID, b.Status)
+	case b.PrunedAt != nil:
+		return fmt.Errorf("%w: 镜像 %s 已按保留策略从 registry 删除", domain.ErrInvalidInput, b.ImageTag)
+	case channel != domain.ChannelTest:
+		return fmt.Errorf("%w: build %s is already on channel %s", domain.ErrInvalidInput, b.ID, channel)
+	case b.Digest == "":
+		return fmt.Errorf("%w: build %s has no recorded digest, cannot promote", domain.ErrInvalidInput, b.ID)
+	case b.CommitSHA == "":
+		return fmt.Errorf("%w: build %s has no resolved commit, cannot promote", domain.ErrInvalidInput, b.ID)
+	}
+	return nil
+}
+
+// findPromotion 返回 source 已有的、镜像仍在的晋升结果，没有则返回 nil。
+func (s *BuildService) findPromotion(ctx context.Context, source *domain.Build) *domain.Build {
+	builds, err := s.buildRepo.FindByImageRepo(ctx, source.ImageRepoName)
+	if err != nil {
+		slog.Warn("failed to look up promotions", "build_id", source.ID, "error", err)
+		return nil
+	}
+	for _, b := range builds {
+		if b.PromotedFrom == source.ID && b.PrunedAt == nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// checkLaneEvidence 确认来源镜像在 lane 上部署过：当前 Release 或任一历史 revision 用的是同一 digest 或同一镜像地址。
+func (s *BuildService) checkLaneEvidence(ctx context.Context, source *domain.Build, lane string) error {
+	if s.cfg.Releases == nil || s.cfg.Revisions == nil {
+		return fmt.Errorf("%w: lane verification is not supported", domain.ErrInvalidInput)
+	}
+	deployed := func(image, digest string) bool {
+		return image == source.ImageTag || (digest != "" && digest == source.Digest)
+	}
+	releases, err := s.cfg.Releases.FindAll(ctx, "", lane)
+	if err != nil {
+		return err
+	}
+	for _, rel := range releases {
+		if deployed(rel.Image, rel.ImageDigest) {
+			return nil
+		}
+		revs, err := s.cfg.Revisions.FindByRelease(ctx, rel.ID, 0)
+		if err != nil {
+			return err
+		}
+		for _, rev := range revs {
+			if deployed(rev.Image, rev.ImageDigest) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: build %s (%s) was never deployed to lane %s", domain.ErrInvalidInput, source.ID, source.Version, lane)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+type stubAncestry struct {
+	onMain bool
+}
+
+func (a *stubAncestry) IsAncestor(_ context.Context, _, _, ref string) (bool, error) {
+	return a.onMain && ref == "main", nil
+}
+
+const promoteCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func newPromotionTestService(onMain bool) (*BuildService, *stubBuildRepo, *stubRegistry, *releaseTestReleaseRepo, *stubRevisionRepo) {
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b-test": {
+			ID:            "b-test",
+			ImageRepoName: "myapp",
+			GitRef:        "feat/login",
+			ImageTag:      "harbor.local/inner-bot/myapp:1.0.0.4",
+			Version:       "1.0.0.4",
+			Channel:       domain.ChannelTest,
+			Status:        domain.BuildStatusSucceeded,
+			CommitSHA:     promoteCommit,
+			Digest:        "sha256:abc",
+			CreatedAt:     time.Now(),
+		},
+	}}
+	buildRepo.latestVersiond = &domain.Build{Version: "1.0.0.4"}
+	registry := &stubRegistry{}
+	releaseRepo := newReleaseTestReleaseRepo()
+	revisionRepo := newStubRevisionRepo()
+	svc := NewBuildService(newTestImageRepoRepo("myapp", "harbor.local/inner-bot/myapp"), buildRepo, nil, nil, BuildServiceConfig{
+		Registry:  registry,
+		Ancestry:  &stubAncestry{onMain: onMain},
+		Releases:  releaseRepo,
+		Revisions: revisionRepo,
+	})
+	return svc, buildRepo, registry, releaseRepo, revisionRepo
+}
+
+func TestPromoteBuild(t *testing.T) {
+	svc, buildRepo, registry, _, _ := newPromotionTestService(true)
+	ctx := context.Background()
+
+	promoted, err := svc.PromoteBuild(ctx, "myapp", "b-test", PromoteBuildRequest{})
+	if err != nil {
+		t.Fatalf("PromoteBuild() error = %v", err)
+	}
+	if promoted.Channel != domain.ChannelStable || promoted.Version != "1.0.0.5" || promoted.PromotedFrom != "b-test" {
+		t.Errorf("promoted = %+v", promoted)
+	}
+	if promoted.Digest != "sha256:abc" || promoted.CommitSHA != promoteCommit || promoted.Status != domain.BuildStatusSucceeded {
+		t.Errorf("promoted build must carry the source digest and commit: %+v", promoted)
+	}
+	// 同一 digest 打新 tag，不重新构建
+	if got := registry.tagged["harbor.local/inner-bot/myapp:1.0.0.5"]; got != "harbor.local/inner-bot/myapp:1.0.0.4@sha256:abc" {
+		t.Errorf("tagged from %q", got)
+	}
+	if _, ok := buildRepo.builds[promoted.ID]; !ok {
+		t.Error("promotion should be recorded as a build")
+	}
+
+	// 重复晋升返回已有结果
+	again, err := svc.PromoteBuild(ctx, "myapp", "b-test", PromoteBuildRequest{})
+	if err != nil {
+		t.Fatalf("second PromoteBuild() error = %v", err)
+	}
+	if again.ID != promoted.ID || !again.Reused || len(registry.tagged) != 1 {
+		t.Errorf("second promotion = %+v, tagged = %v", again, registry.tagged)
+	}
+
+	// 晋升结果本身已是 stable，不能再晋升
+	if _, err := svc.PromoteBuild(ctx, "myapp", promoted.ID, PromoteBuildRequest{}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for stable build, got %v", err)
+	}
+}
+
+func TestPromoteBuild_CommitNotOnMain(t *testing.T) {
+	svc, _, registry, _, _ := newPromotionTestService(false)
+
+	_, err := svc.PromoteBuild(context.Background(), "myapp", "b-test", PromoteBuildRequest{})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+	if len(registry.tagged) != 0 {
+		t.Error("must not tag when commit is not on main")
+	}
+}
+
+func TestPromoteBuild_Rejected(t *testing.T) {
+	cases := map[string]func(b *domain.Build){
+		"failed":    func(b *domain.Build) { b.Status = domain.BuildStatusFailed },
+		"pruned":    func(b *domain.Build) { now := time.Now(); b.PrunedAt = &now },
+		"no digest": func(b *domain.Build) { b.Digest = "" },
+		"no commit": func(b *domain.Build) { b.CommitSHA = "" },
+	}
+	for name, mutate := range cases {
+		svc, buildRepo, _, _, _ := newPromotionTestService(true)
+		mutate(buildRepo.builds["b-test"])
+		if _, err := svc.PromoteBuild(context.Background(), "myapp", "b-test", PromoteBuildRequest{}); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+}
+
+func TestPromoteBuild_VerifiedLane(t *testing.T) {
+	svc, _, _, releaseRepo, revisionRepo := newPromotionTestService(true)
+	ctx := context.Background()
+
+	if _, err := svc.PromoteBuild(ctx, "myapp", "b-test", PromoteBuildRequest{VerifiedLane: "ppe-login"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput without lane evidence, got %v", err)
+	}
+
+	// lane 当前已换成别的镜像，但历史 revision 部署过来源 digest
+	releaseRepo.Save(ctx, &domain.Release{ID: "r1", AppName: "myapp", Lane: "ppe-login", Image: "harbor.local/inner-bot/myapp:1.0.0.6"})
+	revisionRepo.Save(ctx, &domain.ReleaseRevision{ReleaseID: "r1", Image: "harbor.local/inner-bot/myapp:1.0.0.4", ImageDigest: "sha256:abc"})
+
+	promoted, err := svc.PromoteBuild(ctx, "myapp", "b-test", PromoteBuildRequest{VerifiedLane: "ppe-login"})
+	if err != nil {
+		t.Fatalf("PromoteBuild() error = %v", err)
+	}
+	if promoted.VerifiedLane != "ppe-login" {
+		t.Errorf("VerifiedLane = %q", promoted.VerifiedLane)
+	}
+}
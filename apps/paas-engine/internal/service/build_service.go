@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
@@ -13,11 +15,31 @@ import (
 	"github.com/google/uuid"
 )
 
+// BuildServiceConfig 是构建队列的并发上限（<=0 表示不限）、去重用的 ref 解析器、secret 构建参数来源和晋升依赖。
+type BuildServiceConfig struct {
+	MaxConcurrent        int                         // 全局同时运行的 Kaniko Job 数
+	MaxConcurrentPerRepo int                         // 单个 ImageRepo 同时运行的 Kaniko Job 数
+	QueueInterval        time.Duration               // 兜底出队检查间隔（进程重启后接管 pending 构建），<=0 关闭
+	RefResolver          port.GitRefResolver         // 建构建前把 GitRef 解析为 commit 以便去重，nil 则只有完整 SHA 能去重
+	ConfigBundles        port.ConfigBundleRepository // 读取 secret build arg 的值，nil 时不支持 secret 参数
+
+	// 镜像晋升：Registry 给同一 digest 打 stable tag，Ancestry 确认 commit 已合入 main，
+	// Releases / Revisions 查 lane 验证证据。Registry 或 Ancestry 为 nil 时不支持晋升
+	Registry  port.ImageRegistry
+	Ancestry  port.GitAncestry
+	Releases  port.ReleaseRepository
+	Revisions port.ReleaseRevisionRepository
+}
+
+// BuildService 管理构建。新构建先以 pending 状态落库排队，由 dispatch 在并发上限内
+// 按优先级出队提交给 BuildExecutor；builds 表本身就是持久化的队列。
 type BuildService struct {
 	imageRepoRepo port.ImageRepoRepository
 	buildRepo     port.BuildRepository
 	executor      port.BuildExecutor
 	logQuerier    port.LogQuerier
+	cfg           BuildServiceConfig
+	dispatchMu    sync.Mutex
 }
 
 func NewBuildService(
@@ -25,19 +47,28 @@ func NewBuildService(
 	buildRepo port.BuildRepository,
 	executor port.BuildExecutor,
 	logQuerier port.LogQuerier,
+	cfg BuildServiceConfig,
 ) *BuildService {
 	return &BuildService{
 		imageRepoRepo: imageRepoRepo,
 		buildRepo:     buildRepo,
 		executor:      executor,
 		logQuerier:    logQuerier,
+		cfg:           cfg,
 	}
 }
 
 type CreateBuildRequest struct {
-	GitRef  string `json:"git_ref"`
-	Version string `json:"version,omitempty"` // 显式指定版本（可选）
-	Bump    string `json:"bump,omitempty"`    // "major"/"minor"/"patch"/""（可选）
+	GitRef   string `json:"git_ref"`
+	Version  string `json:"version,omitempty"` // 显式指定版本（可选）
+	Bump     string `json:"bump,omitempty"`    // "major"/"minor"/"patch"/""（可选）
+	Priority int    `json:"-"`                 // 出队优先级，HTTP 触发恒为 BuildPriorityManual
+	Force    bool   `json:"force,omitempty"`   // 跳过去重，即使已有相同输入的构建也重新构建
+	// CommitSHA 把构建固定在 GitRef 上的某个完整 commit（CI 流水线用），不再解析 GitRef 的当前 head
+	CommitSHA string `json:"-"`
+
+	// 覆盖 ImageRepo 上的构建参数默认值
+	domain.BuildOptions
 }
 
 func (s *BuildService) CreateBuild(ctx context.Context, imageRepoName string, req CreateBuildRequest) (*domain.Build, error) {
@@ -52,35 +83,52 @@ func (s *BuildService) CreateBuild(ctx context.Context, imageRepoName string, re
 	if err := domain.ValidateGitRef(req.GitRef); err != nil {
 		return nil, err
 	}
+	if req.CommitSHA != "" && !domain.IsFullCommitSHA(req.CommitSHA) {
+		return nil, fmt.Errorf("%w: commit_sha must be a full 40-character commit", domain.ErrInvalidInput)
+	}
 
-	// 解析当前版本
-	var currentVersion domain.Version
-	latestBuild, err := s.buildRepo.FindLatestVersioned(ctx, imageRepoName)
-	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+	opts := domain.MergeBuildOptions(imageRepo.BuildOptions, req.BuildOptions)
+	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
-	if latestBuild != nil {
-		currentVersion, _ = domain.ParseVersion(latestBuild.Version)
+	// secret 引用在建构建时就校验，别等到出队才失败
+	if _, err := s.resolveSecretBuildArgs(ctx, opts.SecretBuildArgs); err != nil {
+		return nil, err
 	}
 
-	// 计算下一版本
-	var nextVersion domain.Version
-	if req.Version != "" {
-		nextVersion, err = domain.ParseVersion(req.Version)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
-		}
-		if nextVersion.Compare(currentVersion) <= 0 {
-			return nil, fmt.Errorf("%w: version %s must be greater than current %s",
-				domain.ErrInvalidInput, nextVersion, currentVersion)
+	// 相同输入（commit + channel + 上下文 + Dockerfile + 构建参数）已有成功或进行中的构建时直接复用：
+	// 不起 Kaniko、不 bump 版本。显式指定版本或 bump 时要产出新 tag，不复用。
+	commitSHA := req.CommitSHA
+	if commitSHA == "" {
+		commitSHA = s.resolveCommit(ctx, imageRepo, req.GitRef)
+	}
+	channel := domain.ResolveChannel(req.GitRef)
+	fingerprint := domain.BuildFingerprint(commitSHA, channel, imageRepo.ContextDir, imageRepo.Dockerfile, opts)
+	if fingerprint != "" && !req.Force && req.Version == "" && req.Bump == "" {
+		if reused := s.findReusable(ctx, imageRepoName, fingerprint); reused != nil {
+			slog.Info("build reused", "image_repo", imageRepoName, "git_ref", req.GitRef,
+				"commit", commitSHA, "build_id", reused.ID, "status", reused.Status)
+			metrics.BuildsReused.Inc()
+			reused.Reused = true
+			s.fillQueuePositions(ctx, reused)
+			return reused, nil
 		}
-	} else {
-		nextVersion = currentVersion.Next(req.Bump)
+	}
+
+	nextVersion, err := s.nextVersion(ctx, imageRepoName, req.Version, req.Bump)
+	if err != nil {
+		return nil, err
 	}
 
 	tag := nextVersion.String()
 	fullImageRef := imageRepo.FullImageRef(tag)
-	channel := domain.ResolveChannel(req.GitRef)
+
+	// 默认 OCI label 记录版本和 commit，显式配置的同名 label 优先
+	defaults := map[string]string{"org.opencontainers.image.version": nextVersion.String()}
+	if commitSHA != "" {
+		defaults["org.opencontainers.image.revision"] = commitSHA
+	}
+	opts.Labels = domain.MergeBuildOptions(domain.BuildOptions{Labels: defaults}, domain.BuildOptions{Labels: opts.Labels}).Labels
 
 	now := time.Now()
 	build := &domain.Build{
@@ -90,6 +138,11 @@ func (s *BuildService) CreateBuild(ctx context.Context, imageRepoName string, re
 		ImageTag:      fullImageRef,
 		Version:       nextVersion.String(),
 		Channel:       channel,
+		Dockerfile:    domain.DockerfilePath(imageRepo.ContextDir, imageRepo.Dockerfile),
+		Priority:      req.Priority,
+		CommitSHA:     commitSHA,
+		Fingerprint:   fingerprint,
+		BuildOptions:  opts,
 		Status:        domain.BuildStatusPending,
 		CreatedAt:     now,
 		UpdatedAt:     now,
@@ -98,31 +151,277 @@ func (s *BuildService) CreateBuild(ctx context.Context, imageRepoName string, re
 		return nil, err
 	}
 
-	if s.executor != nil {
-		sub := &port.BuildSubmission{
-			BuildID:    build.ID,
-			GitRepo:    imageRepo.GitRepo,
-			GitRef:     req.GitRef,
-			ContextDir: imageRepo.ContextDir,
-			Dockerfile: imageRepo.Dockerfile,
-			ImageTag:   fullImageRef,
-			NoCache:    imageRepo.NoCache,
-		}
-		jobName, err := s.executor.Submit(ctx, sub)
+	// 有空位立即出队，否则留在队列里等前面的构建结束
+	s.dispatch(ctx)
+	if latest, err := s.buildRepo.FindByID(ctx, build.ID); err == nil {
+		build = latest
+	}
+	s.fillQueuePositions(ctx, build)
+	return build, nil
+}
+
+// nextVersion 基于 ImageRepo 最新版本计算下一版本；显式指定的 version 必须大于当前版本。
+func (s *BuildService) nextVersion(ctx context.Context, imageRepoName, version, bump string) (domain.Version, error) {
+	var currentVersion domain.Version
+	latestBuild, err := s.buildRepo.FindLatestVersioned(ctx, imageRepoName)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.Version{}, err
+	}
+	if latestBuild != nil {
+		currentVersion, _ = domain.ParseVersion(latestBuild.Version)
+	}
+
+	if version == "" {
+		return currentVersion.Next(bump), nil
+	}
+	next, err := domain.ParseVersion(version)
+	if err != nil {
+		return domain.Version{}, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	if next.Compare(currentVersion) <= 0 {
+		return domain.Version{}, fmt.Errorf("%w: version %s must be greater than current %s",
+			domain.ErrInvalidInput, next, currentVersion)
+	}
+	return next, nil
+}
+
+// resolveCommit 把 GitRef 解析为完整 commit；解析不了（无 resolver、出错）返回空，此时不做去重。
+func (s *BuildService) resolveCommit(ctx context.Context, imageRepo *domain.ImageRepo, ref string) string {
+	if domain.IsFullCommitSHA(ref) {
+		return ref
+	}
+	if s.cfg.RefResolver == nil {
+		return ""
+	}
+	sha, err := s.cfg.RefResolver.ResolveCommit(ctx, imageRepo.GitRepo, ref)
+	if err != nil {
+		slog.Warn("failed to resolve git ref, skip build dedup", "git_repo", imageRepo.GitRepo, "git_ref", ref, "error", err)
+		return ""
+	}
+	return sha
+}
+
+// resolveSecretBuildArgs 从 ConfigBundle 读出 secret build arg 的值（ARG 名 → 值）。
+func (s *BuildService) resolveSecretBuildArgs(ctx context.Context, refs map[string]string) (map[string]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	if s.cfg.ConfigBundles == nil {
+		return nil, fmt.Errorf("%w: secret build args are not supported", domain.ErrInvalidInput)
+	}
+	bundles := make(map[string]*domain.ConfigBundle)
+	values := make(map[string]string, len(refs))
+	for name, ref := range refs {
+		bundleName, key, err := domain.ParseSecretBuildArgRef(ref)
 		if err != nil {
-			build.Status = domain.BuildStatusFailed
-			build.Log = err.Error()
-			_ = s.buildRepo.Update(ctx, build)
+			return nil, err
+		}
+		bundle, ok := bundles[bundleName]
+		if !ok {
+			if bundle, err = s.cfg.ConfigBundles.FindByName(ctx, bundleName); err != nil {
+				return nil, fmt.Errorf("secret build arg %s: %w", name, err)
+			}
+			bundles[bundleName] = bundle
+		}
+		value, ok := bundle.Keys[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: secret build arg %s: key %s not found in config bundle %s",
+				domain.ErrInvalidInput, name, key, bundleName)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// findReusable 返回指纹相同、已成功或仍在排队/构建中的最新构建，没有则返回 nil。
+func (s *BuildService) findReusable(ctx context.Context, imageRepoName, fingerprint string) *domain.Build {
+	builds, err := s.buildRepo.FindByFingerprint(ctx, imageRepoName, fingerprint)
+	if err != nil {
+		slog.Warn("failed to look up builds by fingerprint", "image_repo", imageRepoName, "error", err)
+		return nil
+	}
+	for _, b := range builds {
+		if b.PrunedAt != nil {
+			continue // 镜像已从 registry 删除
+		}
+		if b.Status == domain.BuildStatusSucceeded || !b.Status.IsTerminal() {
+			return b
+		}
+	}
+	return nil
+}
+
+// dispatch 在并发上限内按优先级把 pending 构建提交给 BuildExecutor。
+// 新建、构建结束、取消时各触发一次，Start 里定时兜底；串行执行，避免并发出队超限。
+func (s *BuildService) dispatch(ctx context.Context) {
+	if s.executor == nil {
+		return
+	}
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	running, err := s.buildRepo.FindByStatus(ctx, domain.BuildStatusRunning)
+	if err != nil {
+		slog.Error("build queue: failed to list running builds", "error", err)
+		return
+	}
+	pending, err := s.buildRepo.FindByStatus(ctx, domain.BuildStatusPending)
+	if err != nil {
+		slog.Error("build queue: failed to list pending builds", "error", err)
+		return
+	}
+
+	total := len(running)
+	perRepo := make(map[string]int)
+	for _, b := range running {
+		perRepo[b.ImageRepoName]++
+	}
+	queued := 0
+	for _, b := range pending {
+		if !withinLimit(total, s.cfg.MaxConcurrent) || !withinLimit(perRepo[b.ImageRepoName], s.cfg.MaxConcurrentPerRepo) {
+			queued++
+			continue
+		}
+		if s.submit(ctx, b) {
+			total++
+			perRepo[b.ImageRepoName]++
+		}
+	}
+	metrics.BuildsQueued.Set(float64(queued))
+}
+
+func withinLimit(n, limit int) bool {
+	return limit <= 0 || n < limit
+}
+
+// submit 提交单个构建，返回是否进入 running。先在库里把构建从 pending 改成 running 占住它：
+// 其他实例已经出队或构建刚被取消时不提交。提交失败直接标记 failed，不再重排队。
+func (s *BuildService) submit(ctx context.Context, build *domain.Build) bool {
+	startedAt := time.Now()
+	build.Status = domain.BuildStatusRunning
+	build.StartedAt = &startedAt
+	build.UpdatedAt = startedAt
+	claimed, err := s.buildRepo.UpdateFromStatus(ctx, build, domain.BuildStatusPending)
+	if err != nil {
+		slog.Error("build queue: failed to claim build", "build_id", build.ID, "error", err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	metrics.BuildsInProgress.Inc()
+
+	fail := func(err error) bool {
+		build.Status = domain.BuildStatusFailed
+		build.Log = err.Error()
+		build.UpdatedAt = time.Now()
+		build.DurationSeconds = build.ElapsedSeconds(build.UpdatedAt)
+		if ok, _ := s.buildRepo.UpdateFromStatus(ctx, build, domain.BuildStatusRunning); ok {
+			metrics.BuildsInProgress.Dec()
 			metrics.BuildsTotal.WithLabelValues("failed").Inc()
-			return build, nil
 		}
-		build.JobName = jobName
-		build.Status = domain.BuildStatusRunning
-		_ = s.buildRepo.Update(ctx, build)
-		metrics.BuildsInProgress.Inc()
+		slog.Warn("build submission failed", "build_id", build.ID, "error", err)
+		return false
 	}
+	imageRepo, err := s.imageRepoRepo.FindByName(ctx, build.ImageRepoName)
+	if err != nil {
+		return fail(err)
+	}
+	secretArgs, err := s.resolveSecretBuildArgs(ctx, build.SecretBuildArgs)
+	if err != nil {
+		return fail(err)
+	}
+	// 建构建时已解析出 commit 的，按 commit 提交，保证产物和指纹一致
+	gitRef := build.GitRef
+	if build.CommitSHA != "" {
+		gitRef = build.CommitSHA
+	}
+	sub := &port.BuildSubmission{
+		BuildID:    build.ID,
+		GitRepo:    imageRepo.GitRepo,
+		GitRef:     gitRef,
+		ContextDir: imageRepo.ContextDir,
+		Dockerfile: imageRepo.Dockerfile,
+		ImageTag:   build.ImageTag,
+		NoCache:    imageRepo.NoCache,
+		Builder:    imageRepo.Builder,
 
-	return build, nil
+		BuildArgs:       build.BuildArgs,
+		SecretBuildArgs: secretArgs,
+		Target:          build.Target,
+		Platform:        build.Platform,
+		Labels:          build.Labels,
+	}
+	submitted, err := s.executor.Submit(ctx, sub)
+	if err != nil {
+		return fail(err)
+	}
+	build.JobName = submitted.JobName
+	build.CommitSHA = submitted.CommitSHA
+	build.UpdatedAt = time.Now()
+	recorded, err := s.buildRepo.UpdateFromStatus(ctx, build, domain.BuildStatusRunning)
+	if err != nil {
+		slog.Error("build queue: failed to record submitted job", "build_id", build.ID, "job", submitted.JobName, "error", err)
+		return true
+	}
+	if !recorded {
+		// 提交期间构建被取消了，取消时还没有 Job 可删，由这里删掉刚建的 Job
+		slog.Info("build cancelled during submission, deleting its job", "build_id", build.ID, "job", submitted.JobName)
+		if err := s.executor.Cancel(ctx, submitted.JobName); err != nil {
+			slog.Warn("failed to delete job of cancelled build", "build_id", build.ID, "job", submitted.JobName, "error", err)
+		}
+		return false
+	}
+	return true
+}
+
+// fillQueuePositions 给 pending 构建填上在全局队列里的位置。
+func (s *BuildService) fillQueuePositions(ctx context.Context, builds ...*domain.Build) {
+	hasPending := false
+	for _, b := range builds {
+		hasPending = hasPending || b.Status == domain.BuildStatusPending
+	}
+	if !hasPending {
+		return
+	}
+	pending, err := s.buildRepo.FindByStatus(ctx, domain.BuildStatusPending)
+	if err != nil {
+		slog.Warn("build queue: failed to compute queue positions", "error", err)
+		return
+	}
+	positions := make(map[string]int, len(pending))
+	for i, b := range pending {
+		positions[b.ID] = i + 1
+	}
+	for _, b := range builds {
+		if b.Status == domain.BuildStatusPending {
+			b.QueuePosition = positions[b.ID]
+		}
+	}
+}
+
+// Start 启动兜底出队循环：进程重启后接管 pending 构建，也防止漏掉某次触发。ctx 取消时退出。
+func (s *BuildService) Start(ctx context.Context) {
+	if s.cfg.QueueInterval <= 0 {
+		return
+	}
+	slog.Info("build queue started", "interval", s.cfg.QueueInterval,
+		"max_concurrent", s.cfg.MaxConcurrent, "max_concurrent_per_repo", s.cfg.MaxConcurrentPerRepo)
+
+	s.dispatch(ctx)
+
+	ticker := time.NewTicker(s.cfg.QueueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("build queue stopped")
+			return
+		case <-ticker.C:
+			s.dispatch(ctx)
+		}
+	}
 }
 
 func (s *BuildService) GetBuild(ctx context.Context, imageRepoName, id string) (*domain.Build, error) {
@@ -133,6 +432,7 @@ func (s *BuildService) GetBuild(ctx context.Context, imageRepoName, id string) (
 	if build.ImageRepoName != imageRepoName {
 		return nil, domain.ErrBuildNotFound
 	}
+	s.fillQueuePositions(ctx, build)
 	return build, nil
 }
 
@@ -147,29 +447,54 @@ func (s *BuildService) ListBuilds(ctx context.Context, imageRepoName string) ([]
 	if _, err := s.imageRepoRepo.FindByName(ctx, imageRepoName); err != nil {
 		return nil, err
 	}
-	return s.buildRepo.FindByImageRepo(ctx, imageRepoName)
+	builds, err := s.buildRepo.FindByImageRepo(ctx, imageRepoName)
+	if err != nil {
+		return nil, err
+	}
+	s.fillQueuePositions(ctx, builds...)
+	return builds, nil
 }
 
+// CancelBuild 按读到的状态条件写入 cancelled，写入前状态变了（刚被出队或已结束）就按最新状态重来；
+// 状态只会向前走，最多重试两次。先落库再删 Job：还在队列里的构建没有 Job，直接出队；
+// 出队提交中的构建 Job 名还没落库，由 submit 发现被取消后删掉。
 func (s *BuildService) CancelBuild(ctx context.Context, imageRepoName, id string) error {
 	build, err := s.GetBuild(ctx, imageRepoName, id)
 	if err != nil {
 		return err
 	}
-	if !build.CanCancel() {
-		return domain.ErrCannotCancel
+	for {
+		if !build.CanCancel() {
+			return domain.ErrCannotCancel
+		}
+		from := build.Status
+		build.Status = domain.BuildStatusCancelled
+		build.QueuePosition = 0
+		build.UpdatedAt = time.Now()
+		if from == domain.BuildStatusRunning {
+			build.DurationSeconds = build.ElapsedSeconds(build.UpdatedAt)
+		}
+		cancelled, err := s.buildRepo.UpdateFromStatus(ctx, build, from)
+		if err != nil {
+			return err
+		}
+		if cancelled {
+			if from == domain.BuildStatusRunning {
+				metrics.BuildsInProgress.Dec()
+			}
+			break
+		}
+		if build, err = s.GetBuild(ctx, imageRepoName, id); err != nil {
+			return err
+		}
 	}
+	metrics.BuildsTotal.WithLabelValues("cancelled").Inc()
 	if s.executor != nil && build.JobName != "" {
 		if err := s.executor.Cancel(ctx, build.JobName); err != nil {
-			return err
+			return fmt.Errorf("build cancelled but deleting job %s failed: %w", build.JobName, err)
 		}
 	}
-	build.Status = domain.BuildStatusCancelled
-	build.UpdatedAt = time.Now()
-	if err := s.buildRepo.Update(ctx, build); err != nil {
-		return err
-	}
-	metrics.BuildsInProgress.Dec()
-	metrics.BuildsTotal.WithLabelValues("cancelled").Inc()
+	s.dispatch(ctx)
 	return nil
 }
 
@@ -194,24 +519,61 @@ func (s *BuildService) GetBuildLogs(ctx context.Context, imageRepoName, id strin
 		}
 	}
 
-	// 2. 尝试从 Loki 查询历史日志
+	return s.historyLogs(ctx, build), nil
+}
+
+// FollowBuildLogs 从第 offset 行开始逐行输出构建日志直到构建结束：构建 Pod 在时跟随 Pod 日志，
+// Pod 被清理后接着读 Loki / build.Log。
+func (s *BuildService) FollowBuildLogs(ctx context.Context, imageRepoName, id string, offset int, emit func(LogLine) error) error {
+	build, err := s.GetBuild(ctx, imageRepoName, id)
+	if err != nil {
+		return err
+	}
+	src := logSource{
+		history: func(ctx context.Context) (string, error) {
+			if build.Status == domain.BuildStatusPending {
+				return "", nil
+			}
+			return s.historyLogs(ctx, build), nil
+		},
+		finished: func(ctx context.Context) (bool, error) {
+			latest, err := s.buildRepo.FindByID(ctx, id)
+			if err != nil {
+				return false, err
+			}
+			build = latest
+			return build.Status.IsTerminal(), nil
+		},
+	}
+	if s.executor != nil {
+		src.follow = func(ctx context.Context) (io.ReadCloser, error) {
+			return s.executor.FollowLogs(ctx, id)
+		}
+	}
+	return streamLogs(ctx, src, offset, emit)
+}
+
+// historyLogs 读取构建 Pod 已不在时的日志：先查 Loki，查不到返回存储的 build.Log。
+func (s *BuildService) historyLogs(ctx context.Context, build *domain.Build) string {
 	if s.logQuerier != nil {
 		start := build.CreatedAt.Add(-1 * time.Minute)
 		end := build.UpdatedAt.Add(5 * time.Minute)
+		if now := time.Now(); !build.Status.IsTerminal() && now.After(end) {
+			end = now // 构建还在进行，查到当前
+		}
 		logs, err := s.logQuerier.QueryBuildLogs(ctx, "paas-builds", build.ID, start, end)
 		if err != nil {
-			slog.Warn("failed to get loki logs, falling back to build.Log", "build_id", id, "error", err)
+			slog.Warn("failed to get loki logs, falling back to build.Log", "build_id", build.ID, "error", err)
 		} else if logs != "" {
-			return logs, nil
+			return logs
 		}
 	}
-
-	// 3. 降级：返回存储的日志
-	return build.Log, nil
+	return build.Log
 }
 
-// OnBuildStatusChange 是 Informer callback，更新 Build 状态。
-func (s *BuildService) OnBuildStatusChange(buildID string, status domain.BuildStatus, logMsg string) {
+// OnBuildStatusChange 是 Informer callback，更新 Build 状态；进入终态时记录耗时和推送的 digest。
+func (s *BuildService) OnBuildStatusChange(buildID string, result port.BuildResult) {
+	status := result.Status
 	ctx := context.Background()
 	build, err := s.buildRepo.FindByID(ctx, buildID)
 	if err != nil {
@@ -221,14 +583,42 @@ func (s *BuildService) OnBuildStatusChange(buildID string, status domain.BuildSt
 	if build.Status.IsTerminal() {
 		return
 	}
+	from := build.Status
 	build.Status = status
-	build.Log = logMsg
+	build.Log = result.Log
 	build.UpdatedAt = time.Now()
-	if err := s.buildRepo.Update(ctx, build); err != nil {
+	if status.IsTerminal() {
+		build.DurationSeconds = build.ElapsedSeconds(build.UpdatedAt)
+	}
+	if result.Digest != "" {
+		build.Digest = result.Digest
+	}
+	if status == domain.BuildStatusSucceeded && s.scanEnabled(ctx, build.ImageRepoName) {
+		build.Scan = &domain.ImageScan{Status: domain.ScanStatusPending}
+	}
+	updated, err := s.buildRepo.UpdateFromStatus(ctx, build, from)
+	if err != nil {
 		slog.Error("OnBuildStatusChange: failed to update build", "build_id", buildID, "error", err)
 	}
+	if !updated {
+		return // 读出之后被取消了
+	}
 	if status.IsTerminal() {
 		metrics.BuildsInProgress.Dec()
 		metrics.BuildsTotal.WithLabelValues(string(status)).Inc()
+		// 腾出了并发名额，让排队的构建出队
+		s.dispatch(ctx)
+	}
+}
+
+// scanEnabled 判断 ImageRepo 是否开启了漏洞扫描，开启时构建成功后排入扫描队列。
+func (s *BuildService) scanEnabled(ctx context.Context, imageRepoName string) bool {
+	if s.imageRepoRepo == nil {
+		return false
+	}
+	imageRepo, err := s.imageRepoRepo.FindByName(ctx, imageRepoName)
+	if err != nil {
+		return false
 	}
+	return imageRepo.ScanPolicy != nil && imageRepo.ScanPolicy.Enabled
 }
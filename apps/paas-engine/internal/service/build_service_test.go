@@ -3,27 +3,49 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
 )
 
 // --- stubs for build tests ---
 
 type stubBuildRepo struct {
 	saved          *domain.Build
-	latestVersiond *domain.Build // FindLatestVersioned 返回
+	latestVersiond *domain.Build            // FindLatestVersioned 返回
+	builds         map[string]*domain.Build // FindByID / FindByImageTag 查找，Update 回写
 }
 
 func (s *stubBuildRepo) Save(_ context.Context, b *domain.Build) error {
 	s.saved = b
+	if s.builds != nil {
+		cp := *b
+		s.builds[b.ID] = &cp
+	}
 	return nil
 }
-func (s *stubBuildRepo) FindByID(_ context.Context, _ string) (*domain.Build, error) {
+func (s *stubBuildRepo) FindByID(_ context.Context, id string) (*domain.Build, error) {
+	if b, ok := s.builds[id]; ok {
+		cp := *b
+		return &cp, nil
+	}
 	return nil, domain.ErrBuildNotFound
 }
-func (s *stubBuildRepo) FindByImageRepo(_ context.Context, _ string) ([]*domain.Build, error) {
-	return nil, nil
+func (s *stubBuildRepo) FindByImageRepo(_ context.Context, imageRepoName string) ([]*domain.Build, error) {
+	var out []*domain.Build
+	for _, b := range s.builds {
+		if b.ImageRepoName == imageRepoName {
+			cp := *b
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
 }
 func (s *stubBuildRepo) FindLatestSuccessful(_ context.Context, _ string) (*domain.Build, error) {
 	return nil, domain.ErrBuildNotFound
@@ -34,10 +56,68 @@ func (s *stubBuildRepo) FindLatestVersioned(_ context.Context, _ string) (*domai
 	}
 	return nil, domain.ErrBuildNotFound
 }
-func (s *stubBuildRepo) FindByImageTag(_ context.Context, _ string) (*domain.Build, error) {
+func (s *stubBuildRepo) FindByImageTag(_ context.Context, imageTag string) (*domain.Build, error) {
+	for _, b := range s.builds {
+		if b.ImageTag == imageTag {
+			cp := *b
+			return &cp, nil
+		}
+	}
 	return nil, domain.ErrBuildNotFound
 }
-func (s *stubBuildRepo) Update(_ context.Context, _ *domain.Build) error { return nil }
+func (s *stubBuildRepo) FindByStatus(_ context.Context, status domain.BuildStatus) ([]*domain.Build, error) {
+	var out []*domain.Build
+	for _, b := range s.builds {
+		if b.Status == status {
+			cp := *b
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority < out[j].Priority
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+func (s *stubBuildRepo) FindByFingerprint(_ context.Context, imageRepoName, fingerprint string) ([]*domain.Build, error) {
+	var out []*domain.Build
+	for _, b := range s.builds {
+		if b.ImageRepoName == imageRepoName && b.Fingerprint == fingerprint {
+			cp := *b
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+func (s *stubBuildRepo) FindByScanStatus(_ context.Context, status domain.ScanStatus) ([]*domain.Build, error) {
+	var out []*domain.Build
+	for _, b := range s.builds {
+		if b.Scan != nil && b.Scan.Status == status {
+			cp := *b
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	return out, nil
+}
+func (s *stubBuildRepo) Update(_ context.Context, b *domain.Build) error {
+	if s.builds != nil {
+		cp := *b
+		s.builds[b.ID] = &cp
+	}
+	return nil
+}
+func (s *stubBuildRepo) UpdateFromStatus(_ context.Context, b *domain.Build, from domain.BuildStatus) (bool, error) {
+	if cur, ok := s.builds[b.ID]; !ok || cur.Status != from {
+		return false, nil
+	}
+	cp := *b
+	s.builds[b.ID] = &cp
+	return true, nil
+}
 
 func newTestImageRepoRepo(name, registry string) *stubImageRepoRepo {
 	return &stubImageRepoRepo{repo: &domain.ImageRepo{
@@ -50,7 +130,7 @@ func newTestImageRepoRepo(name, registry string) *stubImageRepoRepo {
 func TestCreateBuild_InitialVersion(t *testing.T) {
 	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
 	buildRepo := &stubBuildRepo{}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	build, err := svc.CreateBuild(context.Background(), "agent-service", CreateBuildRequest{
 		GitRef: "main",
@@ -74,7 +154,7 @@ func TestCreateBuild_AutoIncrementBuild(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.0.0.3"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	build, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef: "feature-branch",
@@ -95,7 +175,7 @@ func TestCreateBuild_BumpPatch(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.0.0.5"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	build, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef: "main",
@@ -114,7 +194,7 @@ func TestCreateBuild_BumpMinor(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.0.2.5"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	build, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef: "main",
@@ -133,7 +213,7 @@ func TestCreateBuild_BumpMajor(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.2.3.5"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	build, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef: "main",
@@ -152,7 +232,7 @@ func TestCreateBuild_ExplicitVersion(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.0.0.3"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	build, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef:  "main",
@@ -171,7 +251,7 @@ func TestCreateBuild_ExplicitVersionTooLow(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.0.0.3"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	_, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef:  "main",
@@ -187,7 +267,7 @@ func TestCreateBuild_ExplicitVersionEqualCurrent(t *testing.T) {
 	buildRepo := &stubBuildRepo{
 		latestVersiond: &domain.Build{Version: "1.0.0.3"},
 	}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	_, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 		GitRef:  "main",
@@ -210,7 +290,7 @@ func TestCreateBuild_ChannelFromGitRef(t *testing.T) {
 	for _, tt := range tests {
 		imageRepoRepo := newTestImageRepoRepo("myapp", "harbor.local/inner-bot/myapp")
 		buildRepo := &stubBuildRepo{}
-		svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+		svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 		build, err := svc.CreateBuild(context.Background(), "myapp", CreateBuildRequest{
 			GitRef: tt.gitRef,
@@ -227,7 +307,7 @@ func TestCreateBuild_ChannelFromGitRef(t *testing.T) {
 func TestCreateBuild_ImageRepoNotFound(t *testing.T) {
 	imageRepoRepo := &stubImageRepoRepo{err: domain.ErrImageRepoNotFound}
 	buildRepo := &stubBuildRepo{}
-	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil)
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
 
 	_, err := svc.CreateBuild(context.Background(), "nonexistent", CreateBuildRequest{
 		GitRef: "main",
@@ -236,3 +316,355 @@ func TestCreateBuild_ImageRepoNotFound(t *testing.T) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
+
+func TestCreateBuild_RecordsDockerfilePath(t *testing.T) {
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	imageRepoRepo.repo.ContextDir = "apps/agent-service"
+	svc := NewBuildService(imageRepoRepo, &stubBuildRepo{}, nil, nil, BuildServiceConfig{})
+
+	build, err := svc.CreateBuild(context.Background(), "agent-service", CreateBuildRequest{GitRef: "main"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if build.Dockerfile != "apps/agent-service/Dockerfile" {
+		t.Errorf("Dockerfile = %q, want apps/agent-service/Dockerfile", build.Dockerfile)
+	}
+}
+
+func TestOnBuildStatusChange_RecordsDigestAndDuration(t *testing.T) {
+	startedAt := time.Now().Add(-90 * time.Second)
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b1": {ID: "b1", ImageTag: "harbor.local/inner-bot/agent-service:1.0.0.1", Status: domain.BuildStatusRunning, StartedAt: &startedAt},
+	}}
+	svc := NewBuildService(nil, buildRepo, nil, nil, BuildServiceConfig{})
+
+	svc.OnBuildStatusChange("b1", port.BuildResult{Status: domain.BuildStatusSucceeded, Digest: "sha256:abc"})
+
+	got := buildRepo.builds["b1"]
+	if got.Status != domain.BuildStatusSucceeded || got.Digest != "sha256:abc" {
+		t.Errorf("build = %+v, want succeeded with digest", got)
+	}
+	if got.DurationSeconds < 89 || got.DurationSeconds > 95 {
+		t.Errorf("DurationSeconds = %d, want ~90", got.DurationSeconds)
+	}
+	if got.Scan != nil {
+		t.Errorf("Scan = %+v, want nil without scan policy", got.Scan)
+	}
+}
+
+func TestOnBuildStatusChange_QueuesScan(t *testing.T) {
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	imageRepoRepo.repo.ScanPolicy = &domain.ImageScanPolicy{Enabled: true}
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b1": {ID: "b1", ImageRepoName: "agent-service", Status: domain.BuildStatusRunning},
+	}}
+	svc := NewBuildService(imageRepoRepo, buildRepo, nil, nil, BuildServiceConfig{})
+
+	svc.OnBuildStatusChange("b1", port.BuildResult{Status: domain.BuildStatusSucceeded, Digest: "sha256:abc"})
+
+	if scan := buildRepo.builds["b1"].Scan; scan == nil || scan.Status != domain.ScanStatusPending {
+		t.Errorf("Scan = %+v, want pending", scan)
+	}
+}
+
+// stubBuildExecutor 记录提交和取消的构建。
+type stubBuildExecutor struct {
+	submitted []string
+	refs      []string // 提交时的 GitRef
+	last      *port.BuildSubmission
+	cancelled []string
+	podLogs   string               // FollowLogs 返回的 Pod 日志，空表示没有 Pod
+	onSubmit  func(buildID string) // 提交过程中插入的动作，模拟并发的取消 / 出队
+}
+
+func (e *stubBuildExecutor) Submit(_ context.Context, sub *port.BuildSubmission) (*port.SubmittedBuild, error) {
+	if e.onSubmit != nil {
+		e.onSubmit(sub.BuildID)
+	}
+	e.submitted = append(e.submitted, sub.BuildID)
+	e.last = sub
+	e.refs = append(e.refs, sub.GitRef)
+	return &port.SubmittedBuild{JobName: "kaniko-" + sub.BuildID, CommitSHA: sub.GitRef}, nil
+}
+func (e *stubBuildExecutor) Cancel(_ context.Context, jobName string) error {
+	e.cancelled = append(e.cancelled, jobName)
+	return nil
+}
+func (e *stubBuildExecutor) Watch(_ context.Context, _ port.BuildStatusCallback) error { return nil }
+func (e *stubBuildExecutor) GetLogs(_ context.Context, _ string) (string, error)       { return "", nil }
+func (e *stubBuildExecutor) FollowLogs(_ context.Context, _ string) (io.ReadCloser, error) {
+	if e.podLogs == "" {
+		return nil, nil
+	}
+	return io.NopCloser(strings.NewReader(e.podLogs)), nil
+}
+
+func TestBuildQueue_ConcurrencyLimitsAndPriority(t *testing.T) {
+	ctx := context.Background()
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	executor := &stubBuildExecutor{}
+	svc := NewBuildService(imageRepoRepo, buildRepo, executor, nil, BuildServiceConfig{MaxConcurrent: 2, MaxConcurrentPerRepo: 1})
+
+	create := func(repo string, priority int) *domain.Build {
+		t.Helper()
+		b, err := svc.CreateBuild(ctx, repo, CreateBuildRequest{GitRef: "main", Priority: priority})
+		if err != nil {
+			t.Fatalf("CreateBuild(%s) error = %v", repo, err)
+		}
+		return b
+	}
+
+	a1 := create("agent-service", domain.BuildPriorityManual)
+	a2 := create("agent-service", domain.BuildPriorityManual) // 同 repo 已有一个在跑
+	b1 := create("lark-server", domain.BuildPriorityCI)
+	d1 := create("tool-service", domain.BuildPriorityCI) // 全局已满
+	c1 := create("chat-response-worker", domain.BuildPriorityManual)
+
+	if a1.Status != domain.BuildStatusRunning || b1.Status != domain.BuildStatusRunning {
+		t.Fatalf("a1=%s b1=%s, want both running", a1.Status, b1.Status)
+	}
+	if a2.Status != domain.BuildStatusPending || a2.QueuePosition != 1 {
+		t.Errorf("a2 = %s pos %d, want pending at 1", a2.Status, a2.QueuePosition)
+	}
+	// 人工构建后来也排在 CI 构建前面
+	got, err := svc.GetBuild(ctx, "tool-service", d1.ID)
+	if err != nil {
+		t.Fatalf("GetBuild() error = %v", err)
+	}
+	if got.QueuePosition != 3 {
+		t.Errorf("d1 queue position = %d, want 3 (behind manual c1)", got.QueuePosition)
+	}
+
+	// 取消排队中的构建：不碰 executor，直接出队
+	if err := svc.CancelBuild(ctx, "chat-response-worker", c1.ID); err != nil {
+		t.Fatalf("CancelBuild() error = %v", err)
+	}
+	if len(executor.cancelled) != 0 {
+		t.Errorf("queued build should not cancel a job, got %v", executor.cancelled)
+	}
+	if got, _ := svc.GetBuild(ctx, "tool-service", d1.ID); got.QueuePosition != 2 {
+		t.Errorf("d1 queue position after cancel = %d, want 2", got.QueuePosition)
+	}
+
+	// b1 结束：a2 仍被 repo 上限挡住，d1 出队
+	svc.OnBuildStatusChange(b1.ID, port.BuildResult{Status: domain.BuildStatusSucceeded})
+	// a1 结束：a2 出队
+	svc.OnBuildStatusChange(a1.ID, port.BuildResult{Status: domain.BuildStatusFailed})
+
+	want := []string{a1.ID, b1.ID, d1.ID, a2.ID}
+	if len(executor.submitted) != len(want) {
+		t.Fatalf("submitted = %v, want %v", executor.submitted, want)
+	}
+	for i := range want {
+		if executor.submitted[i] != want[i] {
+			t.Errorf("submitted[%d] = %s, want %s", i, executor.submitted[i], want[i])
+		}
+	}
+	if st := buildRepo.builds[c1.ID].Status; st != domain.BuildStatusCancelled {
+		t.Errorf("c1 status = %s, want cancelled", st)
+	}
+}
+
+// TestBuildQueue_ClaimsBeforeSubmit 覆盖出队提交期间的并发：另一个实例同时出队不会重复提交，
+// 提交期间被取消的构建保持 cancelled，刚建的 Job 被删掉。
+func TestBuildQueue_ClaimsBeforeSubmit(t *testing.T) {
+	ctx := context.Background()
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	executor := &stubBuildExecutor{}
+	svc := NewBuildService(imageRepoRepo, buildRepo, executor, nil, BuildServiceConfig{})
+	otherExecutor := &stubBuildExecutor{}
+	other := NewBuildService(imageRepoRepo, buildRepo, otherExecutor, nil, BuildServiceConfig{})
+
+	executor.onSubmit = func(string) { other.dispatch(ctx) }
+	b, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main"})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+	if b.Status != domain.BuildStatusRunning || len(otherExecutor.submitted) != 0 {
+		t.Errorf("status = %s, other instance submitted %v; want running and submitted once", b.Status, otherExecutor.submitted)
+	}
+
+	executor.onSubmit = func(id string) { buildRepo.builds[id].Status = domain.BuildStatusCancelled }
+	c, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "feat/x", Force: true})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+	if c.Status != domain.BuildStatusCancelled {
+		t.Errorf("status = %s, want cancelled build not resurrected", c.Status)
+	}
+	if len(executor.cancelled) != 1 || executor.cancelled[0] != "kaniko-"+c.ID {
+		t.Errorf("cancelled jobs = %v, want the job submitted for the cancelled build", executor.cancelled)
+	}
+}
+
+type fixedRefResolver struct{ sha string }
+
+func (r fixedRefResolver) ResolveCommit(_ context.Context, _, _ string) (string, error) {
+	return r.sha, nil
+}
+
+func TestCreateBuild_ReusesIdenticalBuild(t *testing.T) {
+	ctx := context.Background()
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	executor := &stubBuildExecutor{}
+	svc := NewBuildService(imageRepoRepo, buildRepo, executor, nil, BuildServiceConfig{RefResolver: fixedRefResolver{sha: sha}})
+
+	first, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main"})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+	if first.Reused || first.CommitSHA != sha || first.Fingerprint == "" {
+		t.Fatalf("first build = reused %v commit %q fingerprint %q", first.Reused, first.CommitSHA, first.Fingerprint)
+	}
+	// 按解析出的 commit 提交，而不是分支名
+	if len(executor.refs) != 1 || executor.refs[0] != sha {
+		t.Errorf("submitted refs = %v, want [%s]", executor.refs, sha)
+	}
+
+	// 同一 commit 再来一次（构建还在跑）：复用，不起新 Job、不 bump 版本
+	again, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main"})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+	if !again.Reused || again.ID != first.ID || again.Version != first.Version {
+		t.Errorf("second build = %s v%s reused %v, want reuse of %s", again.ID, again.Version, again.Reused, first.ID)
+	}
+
+	// 失败的构建不复用
+	svc.OnBuildStatusChange(first.ID, port.BuildResult{Status: domain.BuildStatusFailed})
+	buildRepo.latestVersiond = buildRepo.builds[first.ID]
+	retry, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main"})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+	if retry.Reused || retry.ID == first.ID {
+		t.Fatalf("failed build must not be reused, got %s reused %v", retry.ID, retry.Reused)
+	}
+
+	// 成功后复用；force、显式版本和 bump 都强制重新构建
+	svc.OnBuildStatusChange(retry.ID, port.BuildResult{Status: domain.BuildStatusSucceeded, Digest: "sha256:abc"})
+	buildRepo.latestVersiond = buildRepo.builds[retry.ID]
+	if b, _ := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main"}); !b.Reused || b.Digest != "sha256:abc" {
+		t.Errorf("succeeded build should be reused, got %+v", b)
+	}
+	if b, _ := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main", Force: true}); b.Reused {
+		t.Error("force should skip dedup")
+	}
+	if b, _ := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main", Version: "9.0.0.0"}); b.Reused {
+		t.Error("explicit version should skip dedup")
+	}
+	// 请求的 bump 不能因为复用被悄悄丢掉
+	if b, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main", Bump: "minor"}); err != nil || b.Reused || b.Version == retry.Version {
+		t.Errorf("bump should skip dedup, got %+v (err %v)", b, err)
+	}
+	if len(executor.submitted) != 5 {
+		t.Errorf("submitted %d jobs, want 5", len(executor.submitted))
+	}
+}
+
+// TestCreateBuild_DoesNotReuseAcrossChannels：同一 commit 先在分支上构建（test channel），
+// 合入 main 后的构建必须产出 stable 镜像，不能复用分支那次。
+func TestCreateBuild_DoesNotReuseAcrossChannels(t *testing.T) {
+	ctx := context.Background()
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	svc := NewBuildService(imageRepoRepo, buildRepo, &stubBuildExecutor{}, nil, BuildServiceConfig{RefResolver: fixedRefResolver{sha: sha}})
+
+	branch, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "feat/x"})
+	if err != nil {
+		t.Fatalf("CreateBuild(feat/x) error = %v", err)
+	}
+	svc.OnBuildStatusChange(branch.ID, port.BuildResult{Status: domain.BuildStatusSucceeded})
+	buildRepo.latestVersiond = buildRepo.builds[branch.ID]
+
+	stable, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "main"})
+	if err != nil {
+		t.Fatalf("CreateBuild(main) error = %v", err)
+	}
+	if stable.Reused || stable.ID == branch.ID || stable.Channel != domain.ChannelStable {
+		t.Errorf("main build = %s channel %s reused %v, want a new stable build", stable.ID, stable.Channel, stable.Reused)
+	}
+	if again, _ := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "feat/x"}); !again.Reused || again.ID != branch.ID {
+		t.Errorf("branch rebuild = %s reused %v, want reuse of %s", again.ID, again.Reused, branch.ID)
+	}
+}
+
+// TestCreateBuild_PinnedCommit：CI 构建固定在 run 的 commit 上，即使分支 head 已经前进。
+func TestCreateBuild_PinnedCommit(t *testing.T) {
+	ctx := context.Background()
+	const runSHA = "0123456789abcdef0123456789abcdef01234567"
+	const headSHA = "89abcdef0123456789abcdef0123456789abcdef"
+	imageRepoRepo := newTestImageRepoRepo("agent-service", "harbor.local/inner-bot/agent-service")
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	executor := &stubBuildExecutor{}
+	svc := NewBuildService(imageRepoRepo, buildRepo, executor, nil, BuildServiceConfig{RefResolver: fixedRefResolver{sha: headSHA}})
+
+	build, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "feat/x", CommitSHA: runSHA})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+	if build.CommitSHA != runSHA || build.GitRef != "feat/x" || build.Channel != domain.ChannelTest {
+		t.Errorf("build commit/ref/channel = %s/%s/%s, want %s/feat/x/test", build.CommitSHA, build.GitRef, build.Channel, runSHA)
+	}
+	if len(executor.refs) != 1 || executor.refs[0] != runSHA {
+		t.Errorf("submitted refs = %v, want the pinned commit", executor.refs)
+	}
+
+	if _, err := svc.CreateBuild(ctx, "agent-service", CreateBuildRequest{GitRef: "feat/x", CommitSHA: "0123456"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("short commit error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestCreateBuild_BuildOptions(t *testing.T) {
+	ctx := context.Background()
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	imageRepoRepo := newTestImageRepoRepo("lark-server", "harbor.local/inner-bot/lark-server")
+	imageRepoRepo.repo.BuildOptions = domain.BuildOptions{
+		BuildArgs:       map[string]string{"NODE_ENV": "production"},
+		SecretBuildArgs: map[string]string{"NPM_TOKEN": "npm/TOKEN"},
+		Target:          "runtime",
+	}
+	bundles := newStubConfigBundleRepo()
+	bundles.bundles["npm"] = &domain.ConfigBundle{Name: "npm", Keys: map[string]string{"TOKEN": "s3cret"}}
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	executor := &stubBuildExecutor{}
+	svc := NewBuildService(imageRepoRepo, buildRepo, executor, nil, BuildServiceConfig{ConfigBundles: bundles})
+
+	build, err := svc.CreateBuild(ctx, "lark-server", CreateBuildRequest{
+		GitRef: sha,
+		BuildOptions: domain.BuildOptions{
+			BuildArgs: map[string]string{"NODE_ENV": "test"},
+			Labels:    map[string]string{"org.opencontainers.image.vendor": "chiwei"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateBuild() error = %v", err)
+	}
+
+	// 记录合并后的参数，secret 只记引用
+	if build.BuildArgs["NODE_ENV"] != "test" || build.Target != "runtime" || build.SecretBuildArgs["NPM_TOKEN"] != "npm/TOKEN" {
+		t.Errorf("recorded options = %+v", build.BuildOptions)
+	}
+	if build.Labels["org.opencontainers.image.revision"] != sha || build.Labels["org.opencontainers.image.version"] != build.Version ||
+		build.Labels["org.opencontainers.image.vendor"] != "chiwei" {
+		t.Errorf("labels = %v", build.Labels)
+	}
+	// 提交时才解析 secret 值
+	if executor.last == nil || executor.last.SecretBuildArgs["NPM_TOKEN"] != "s3cret" || executor.last.Target != "runtime" {
+		t.Errorf("submission = %+v", executor.last)
+	}
+
+	// 引用不存在的 key 建构建时就拒绝
+	_, err = svc.CreateBuild(ctx, "lark-server", CreateBuildRequest{
+		GitRef:       "main",
+		BuildOptions: domain.BuildOptions{SecretBuildArgs: map[string]string{"NPM_TOKEN": "npm/MISSING"}},
+	})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for missing secret key, got %v", err)
+	}
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"github.com/google/uuid"
+)
+
+// defaultCanaryStepInterval 是每一步放量后的默认观察时长（秒）。
+const defaultCanaryStepInterval = 60
+
+// CanaryService 驱动 Release 的金丝雀发布：新镜像先部署为 canary lane 上的独立 Release，
+// 再通过 GatewayRuleService 的 set-weights 按步进计划放量，每步用 GetDeploymentStatus
+// 检查 ready / restarts，全部通过自动晋升到稳定 Release，任一步失败自动回滚权重。
+//
+// 流程只在启动它的实例里跑，由 canary 记录上的租约标明；蓝绿自部署时新旧实例共用一个库，
+// 只有租约过期（持有者已退出）的金丝雀才会被其他实例回滚。
+//
+// 已知限制：canary target 强制 lane，稳定 target 若是 lane 留空的透传规则，
+// 带 x-lane 的请求也会按比例落到金丝雀上。
+type CanaryService struct {
+	releaseSvc *ReleaseService
+	gatewaySvc *GatewayRuleService
+	repo       port.ReleaseCanaryRepository
+
+	// stepUnit 是 StepIntervalSeconds 的时间单位，测试里改小。
+	stepUnit time.Duration
+	// owner 是本实例的租约持有者 ID，测试里模拟多实例时改写。
+	owner string
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc // canary ID → 运行中流程的 cancel
+}
+
+func NewCanaryService(releaseSvc *ReleaseService, gatewaySvc *GatewayRuleService, repo port.ReleaseCanaryRepository) *CanaryService {
+	return &CanaryService{
+		releaseSvc: releaseSvc,
+		gatewaySvc: gatewaySvc,
+		repo:       repo,
+		stepUnit:   time.Second,
+		owner:      instanceID,
+		cancels:    make(map[string]context.CancelFunc),
+	}
+}
+
+// StartCanaryRequest 是 POST /releases/{id}/canary 的请求体。
+type StartCanaryRequest struct {
+	ImageTag            string `json:"image_tag"`
+	GatewayRule         string `json:"gateway_rule"`          // 承载该 app 流量的 gateway 规则名
+	CanaryLane          string `json:"canary_lane"`           // 可选，默认 ppe-canary，必须是 ppe-* lane
+	Steps               []int  `json:"steps"`                 // 可选，默认 5,25,50,100
+	StepIntervalSeconds int    `json:"step_interval_seconds"` // 可选，默认 60
+	MaxRestarts         int32  `json:"max_restarts"`          // 每步允许的新增重启次数，默认 0
+	Replicas            int32  `json:"replicas"`              // 金丝雀副本数，默认 1
+	// ScanOverrideReason 豁免漏洞扫描门禁的原因，晋升时带到稳定 release，可选
+	ScanOverrideReason string `json:"scan_override_reason,omitempty"`
+}
+
+// StartCanary 校验请求、记录放量前的 targets 并落库，随后在后台执行步进流程。
+func (s *CanaryService) StartCanary(ctx context.Context, releaseID string, req StartCanaryRequest) (*domain.ReleaseCanary, error) {
+	release, err := s.releaseSvc.releaseRepo.FindByID(ctx, releaseID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ImageTag == "" || req.GatewayRule == "" {
+		return nil, fmt.Errorf("%w: image_tag and gateway_rule are required", domain.ErrInvalidInput)
+	}
+	steps := req.Steps
+	if len(steps) == 0 {
+		steps = domain.DefaultCanarySteps
+	}
+	if err := domain.ValidateCanarySteps(steps); err != nil {
+		return nil, err
+	}
+	if req.StepIntervalSeconds < 0 || req.MaxRestarts < 0 || req.Replicas < 0 {
+		return nil, fmt.Errorf("%w: step_interval_seconds, max_restarts and replicas must not be negative", domain.ErrInvalidInput)
+	}
+	if req.StepIntervalSeconds == 0 {
+		req.StepIntervalSeconds = defaultCanaryStepInterval
+	}
+	canaryLane := req.CanaryLane
+	if canaryLane == "" {
+		canaryLane = domain.DefaultCanaryLane
+	}
+	if class, err := domain.ClassifyLane(canaryLane, nil); err != nil || class != domain.LaneClassPpe {
+		return nil, fmt.Errorf("%w: canary_lane %q must be a ppe-* lane", domain.ErrInvalidInput, canaryLane)
+	}
+
+	latest, err := s.repo.FindLatestByRelease(ctx, releaseID)
+	if err != nil && !errors.Is(err, domain.ErrCanaryNotFound) {
+		return nil, err
+	}
+	if latest != nil && latest.Status == domain.CanaryStatusRunning {
+		return nil, fmt.Errorf("%w: canary %s is still running for release %s", domain.ErrAlreadyExists, latest.ID, releaseID)
+	}
+
+	// 镜像门禁按稳定 lane 提前跑一遍：金丝雀最终要晋升到这里
+	app, err := s.releaseSvc.appRepo.FindByName(ctx, release.AppName)
+	if err != nil {
+		return nil, err
+	}
+	fullImage, _, err := s.releaseSvc.resolveImage(ctx, app, req.ImageTag, release.Lane, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.releaseSvc.checkScanGate(ctx, app, fullImage, release.Lane, req.ScanOverrideReason); err != nil {
+		return nil, err
+	}
+
+	rule, err := s.gatewaySvc.Get(ctx, req.GatewayRule)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := findStableTarget(rule.Targets, release); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	canary := &domain.ReleaseCanary{
+		ID:                  uuid.New().String(),
+		ReleaseID:           release.ID,
+		AppName:             release.AppName,
+		Lane:                release.Lane,
+		CanaryLane:          canaryLane,
+		ImageTag:            req.ImageTag,
+		Image:               fullImage,
+		GatewayRule:         req.GatewayRule,
+		StepIntervalSeconds: req.StepIntervalSeconds,
+		MaxRestarts:         req.MaxRestarts,
+		ScanOverrideReason:  req.ScanOverrideReason,
+		PreviousTargets:     rule.Targets,
+		Status:              domain.CanaryStatusRunning,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	for _, p := range steps {
+		canary.Steps = append(canary.Steps, domain.CanaryStep{Percent: p, Status: domain.CanaryStepPending})
+	}
+	canary.Hold(s.owner, now)
+	if err := s.repo.Save(ctx, canary); err != nil {
+		return nil, err
+	}
+
+	replicas := req.Replicas
+	if replicas == 0 {
+		replicas = 1
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancels[canary.ID] = cancel
+	s.mu.Unlock()
+
+	// 返回给调用方的是快照副本，后台流程独占原对象
+	out := *canary
+	out.Steps = append([]domain.CanaryStep(nil), canary.Steps...)
+	go s.run(runCtx, canary, release, replicas)
+
+	return &out, nil
+}
+
+// GetCanary 返回 release 最近一次金丝雀。
+func (s *CanaryService) GetCanary(ctx context.Context, releaseID string) (*domain.ReleaseCanary, error) {
+	if _, err := s.releaseSvc.releaseRepo.FindByID(ctx, releaseID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindLatestByRelease(ctx, releaseID)
+}
+
+// AbortCanary 中止 release 上正在运行的金丝雀：本进程内的流程直接 cancel，由它自己回滚；
+// 流程的持有者已退出（租约过期）则抢到租约后在当前调用里同步回滚。流程还在其他实例里跑时
+// 不能从这里回滚（两边会同时写权重），返回 ErrCannotCancel。
+func (s *CanaryService) AbortCanary(ctx context.Context, releaseID string) (*domain.ReleaseCanary, error) {
+	canary, err := s.GetCanary(ctx, releaseID)
+	if err != nil {
+		return nil, err
+	}
+	if canary.Status.IsTerminal() {
+		return nil, fmt.Errorf("%w: canary is already %s", domain.ErrCannotCancel, canary.Status)
+	}
+	s.mu.Lock()
+	cancel, ok := s.cancels[canary.ID]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+		return canary, nil
+	}
+	claimed, err := s.claim(ctx, canary)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, fmt.Errorf("%w: canary is running on paas-engine instance %s", domain.ErrCannotCancel, canary.Owner)
+	}
+	s.abort(canary, "aborted by request")
+	return canary, nil
+}
+
+// AbortInterrupted 回滚所有持有者已退出（租约过期）的 running 金丝雀，例如重启或蓝绿切换
+// 打断的流程。恢复放量前的权重比继续放量更安全。多个实例同时回收时只有抢到租约的那个回滚。
+func (s *CanaryService) AbortInterrupted(ctx context.Context) error {
+	running, err := s.repo.FindRunning(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range running {
+		s.mu.Lock()
+		_, active := s.cancels[c.ID]
+		s.mu.Unlock()
+		if active {
+			continue
+		}
+		previous := c.Owner
+		claimed, err := s.claim(ctx, c)
+		if err != nil {
+			slog.Error("failed to claim interrupted canary", "canary_id", c.ID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		slog.Warn("aborting interrupted canary", "canary_id", c.ID, "release_id", c.ReleaseID, "previous_owner", previous)
+		s.abort(c, "interrupted: paas-engine instance driving it stopped")
+	}
+	return nil
+}
+
+// claim 在 c 的租约过期时把它交给本实例，抢到后 c 上的租约同步更新。
+func (s *CanaryService) claim(ctx context.Context, c *domain.ReleaseCanary) (bool, error) {
+	now := time.Now()
+	if !c.Expired(now) {
+		return false, nil
+	}
+	claimed, err := s.repo.ClaimLease(ctx, c.ID, s.owner, now, domain.LeaseExpiredBefore(now))
+	if err != nil || !claimed {
+		return false, err
+	}
+	c.Hold(s.owner, now)
+	return true, nil
+}
+
+// run 执行一次金丝雀：部署 → 挂 target → 逐步放量并检查 → 晋升。
+// 只有步间等待响应 ctx 取消；部署和权重写入都用 Background，避免被打断在半路。
+func (s *CanaryService) run(ctx context.Context, c *domain.ReleaseCanary, stable *domain.Release, replicas int32) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, c.ID)
+		s.mu.Unlock()
+	}()
+	// 租约被接管说明本实例续约中断过太久，别的实例已经在回滚：停下放量，回滚是幂等的
+	stopLease := holdLease("canary", c.ID, func(ctx context.Context, now time.Time) (bool, error) {
+		return s.repo.RenewLease(ctx, c.ID, s.owner, now)
+	}, func() {
+		s.mu.Lock()
+		cancel := s.cancels[c.ID]
+		s.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	})
+	defer stopLease()
+	bg := context.Background()
+	slog.Info("canary started", "id", c.ID, "app", c.AppName, "lane", c.CanaryLane, "image", c.Image)
+
+	canaryRel, err := s.releaseSvc.CreateOrUpdateRelease(bg, CreateReleaseRequest{
+		AppName:   c.AppName,
+		Lane:      c.CanaryLane,
+		ImageTag:  c.ImageTag,
+		Replicas:  replicas,
+		Envs:      stable.Envs,
+		Resources: stable.Resources,
+		Probes:    stable.Probes,
+	})
+	if canaryRel != nil {
+		c.CanaryReleaseID = canaryRel.ID
+	}
+	if err != nil {
+		s.abort(c, fmt.Sprintf("deploy canary: %v", err))
+		return
+	}
+	if canaryRel.Status != domain.ReleaseStatusDeployed {
+		s.abort(c, fmt.Sprintf("deploy canary: %s", canaryRel.Message))
+		return
+	}
+	s.save(c)
+
+	baseline, err := s.releaseSvc.GetReleaseStatus(bg, canaryRel.ID)
+	if err != nil {
+		s.abort(c, fmt.Sprintf("get canary status: %v", err))
+		return
+	}
+	baseRestarts := totalRestarts(baseline)
+
+	stableTarget, err := findStableTarget(c.PreviousTargets, stable)
+	if err != nil {
+		s.abort(c, err.Error())
+		return
+	}
+	canaryTarget := stableTarget
+	canaryTarget.Lane = c.CanaryLane
+	canaryTarget.Weight = 0
+	if _, err := s.gatewaySvc.AddTarget(bg, c.GatewayRule, canaryTarget, s.reason(c, "attach canary target")); err != nil {
+		s.abort(c, fmt.Sprintf("attach canary target: %v", err))
+		return
+	}
+
+	for i := range c.Steps {
+		step := &c.Steps[i]
+		startedAt := time.Now()
+		c.CurrentStep = i
+		step.Status = domain.CanaryStepRunning
+		step.StartedAt = &startedAt
+		s.save(c)
+
+		weights := canaryWeights(c.PreviousTargets, stableTarget, c.CanaryLane, step.Percent)
+		if _, err := s.gatewaySvc.SetWeights(bg, c.GatewayRule, SetWeightsRequest{
+			Reason:  s.reason(c, fmt.Sprintf("step %d: %d%%", i+1, step.Percent)),
+			Weights: weights,
+		}); err != nil {
+			s.failStep(c, step, fmt.Sprintf("set weights: %v", err))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			s.failStep(c, step, "aborted by request")
+			return
+		case <-time.After(time.Duration(c.StepIntervalSeconds) * s.stepUnit):
+		}
+
+		status, err := s.releaseSvc.GetReleaseStatus(bg, canaryRel.ID)
+		if err != nil {
+			s.failStep(c, step, fmt.Sprintf("get canary status: %v", err))
+			return
+		}
+		step.Ready = status.Ready
+		step.Desired = status.Desired
+		step.Restarts = totalRestarts(status) - baseRestarts
+		if status.Desired == 0 || status.Ready < status.Desired {
+			s.failStep(c, step, fmt.Sprintf("canary not ready: %d/%d pods ready", status.Ready, status.Desired))
+			return
+		}
+		if step.Restarts > c.MaxRestarts {
+			s.failStep(c, step, fmt.Sprintf("canary restarted %d time(s), max %d", step.Restarts, c.MaxRestarts))
+			return
+		}
+		finishedAt := time.Now()
+		step.Status = domain.CanaryStepPassed
+		step.FinishedAt = &finishedAt
+		s.save(c)
+	}
+
+	s.promote(c, stable)
+}
+
+// promote 把金丝雀镜像部署到稳定 Release，然后恢复放量前的权重并拆掉金丝雀。
+// 晋升部署失败时保持金丝雀承接流量（它刚通过了全部检查），标记 failed 等人工处理。
+func (s *CanaryService) promote(c *domain.ReleaseCanary, stable *domain.Release) {
+	bg := context.Background()
+	current, err := s.releaseSvc.releaseRepo.FindByID(bg, stable.ID)
+	if err != nil {
+		s.finish(c, domain.CanaryStatusFailed, fmt.Sprintf("promote: %v", err))
+		return
+	}
+	promoted, err := s.releaseSvc.CreateOrUpdateRelease(bg, CreateReleaseRequest{
+		AppName:   current.AppName,
+		Lane:      current.Lane,
+		ImageTag:  c.ImageTag,
+		Replicas:  current.Replicas,
+		Envs:      current.Envs,
+		Resources: current.Resources,
+		Probes:    current.Probes,
+		// 金丝雀本身固定副本数，晋升时保留稳定 release 的自动伸缩配置
+		Autoscaling:        current.Autoscaling,
+		ScanOverrideReason: c.ScanOverrideReason,
+	})
+	if err != nil {
+		s.finish(c, domain.CanaryStatusFailed, fmt.Sprintf("promote: %v", err))
+		return
+	}
+	if promoted.Status != domain.ReleaseStatusDeployed {
+		s.finish(c, domain.CanaryStatusFailed, fmt.Sprintf("promote: %s", promoted.Message))
+		return
+	}
+	if errs := s.teardown(c); len(errs) > 0 {
+		s.finish(c, domain.CanaryStatusPromoted, "promoted; cleanup: "+strings.Join(errs, "; "))
+		return
+	}
+	s.finish(c, domain.CanaryStatusPromoted, "")
+}
+
+func (s *CanaryService) failStep(c *domain.ReleaseCanary, step *domain.CanaryStep, msg string) {
+	finishedAt := time.Now()
+	step.Status = domain.CanaryStepFailed
+	step.Message = msg
+	step.FinishedAt = &finishedAt
+	s.abort(c, msg)
+}
+
+// abort 恢复放量前的权重、摘掉 canary target 并删除金丝雀 Release。
+func (s *CanaryService) abort(c *domain.ReleaseCanary, reason string) {
+	msg := reason
+	if errs := s.teardown(c); len(errs) > 0 {
+		msg += "; cleanup: " + strings.Join(errs, "; ")
+	}
+	s.finish(c, domain.CanaryStatusAborted, msg)
+}
+
+// teardown 把规则权重恢复成 PreviousTargets（canary target 置 0 后移除），再删金丝雀 Release。
+// 每一步失败都继续往下做，返回所有错误。
+func (s *CanaryService) teardown(c *domain.ReleaseCanary) []string {
+	bg := context.Background()
+	var errs []string
+
+	rule, err := s.gatewaySvc.Get(bg, c.GatewayRule)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("get gateway rule: %v", err))
+	} else {
+		attached := false
+		for _, t := range rule.Targets {
+			if t.Service == c.AppName && t.Lane == c.CanaryLane {
+				attached = true
+				break
+			}
+		}
+		if attached {
+			weights := make([]TargetWeight, 0, len(c.PreviousTargets)+1)
+			for _, t := range c.PreviousTargets {
+				weights = append(weights, TargetWeight{Service: t.Service, Lane: t.Lane, Weight: t.Weight})
+			}
+			weights = append(weights, TargetWeight{Service: c.AppName, Lane: c.CanaryLane, Weight: 0})
+			if _, err := s.gatewaySvc.SetWeights(bg, c.GatewayRule, SetWeightsRequest{
+				Reason:  s.reason(c, "restore weights"),
+				Weights: weights,
+			}); err != nil {
+				errs = append(errs, fmt.Sprintf("restore weights: %v", err))
+			} else if _, err := s.gatewaySvc.RemoveTarget(bg, c.GatewayRule, c.AppName, c.CanaryLane, s.reason(c, "detach canary target")); err != nil {
+				errs = append(errs, fmt.Sprintf("detach canary target: %v", err))
+			}
+		}
+	}
+
+	if c.CanaryReleaseID != "" {
+		if err := s.releaseSvc.DeleteRelease(bg, c.CanaryReleaseID); err != nil && !errors.Is(err, domain.ErrReleaseNotFound) {
+			errs = append(errs, fmt.Sprintf("delete canary release: %v", err))
+		}
+	}
+	for _, e := range errs {
+		slog.Error("canary teardown error", "id", c.ID, "error", e)
+	}
+	return errs
+}
+
+func (s *CanaryService) finish(c *domain.ReleaseCanary, status domain.CanaryStatus, msg string) {
+	now := time.Now()
+	c.Status = status
+	c.Message = msg
+	c.FinishedAt = &now
+	s.save(c)
+	slog.Info("canary finished", "id", c.ID, "app", c.AppName, "status", status, "message", msg)
+}
+
+func (s *CanaryService) save(c *domain.ReleaseCanary) {
+	c.UpdatedAt = time.Now()
+	if err := s.repo.Update(context.Background(), c); err != nil {
+		slog.Error("failed to persist canary", "id", c.ID, "error", err)
+	}
+}
+
+func (s *CanaryService) reason(c *domain.ReleaseCanary, what string) string {
+	return fmt.Sprintf("canary %s/%s %s: %s", c.AppName, c.CanaryLane, c.ImageTag, what)
+}
+
+// findStableTarget 在规则里找承载稳定 Release 流量的 target：service 为 app 名，
+// lane 等于稳定 lane；prod 也接受 lane 留空的透传 target（基线规则都是这种写法）。
+func findStableTarget(targets []domain.GatewayTarget, stable *domain.Release) (domain.GatewayTarget, error) {
+	for _, t := range targets {
+		if t.Service != stable.AppName {
+			continue
+		}
+		if t.Lane == stable.Lane || (t.Lane == "" && stable.Lane == domain.DefaultLane) {
+			if t.Weight <= 0 {
+				return t, fmt.Errorf("%w: stable target %s/%s has no traffic to shift", domain.ErrInvalidInput, t.Service, t.Lane)
+			}
+			return t, nil
+		}
+	}
+	return domain.GatewayTarget{}, fmt.Errorf("%w: gateway rule has no target for %s in lane %s",
+		domain.ErrInvalidInput, stable.AppName, stable.Lane)
+}
+
+// canaryWeights 从放量前的权重出发，把稳定 target 的 percent% 挪给 canary target，
+// 其他 target 权重不变，总和保持 100。
+func canaryWeights(previous []domain.GatewayTarget, stable domain.GatewayTarget, canaryLane string, percent int) []TargetWeight {
+	shift := (stable.Weight*percent + 50) / 100
+	weights := make([]TargetWeight, 0, len(previous)+1)
+	for _, t := range previous {
+		w := t.Weight
+		if t.Service == stable.Service && t.Lane == stable.Lane {
+			w -= shift
+		}
+		weights = append(weights, TargetWeight{Service: t.Service, Lane: t.Lane, Weight: w})
+	}
+	return append(weights, TargetWeight{Service: stable.Service, Lane: canaryLane, Weight: shift})
+}
+
+func totalRestarts(status *domain.DeploymentStatus) int32 {
+	var n int32
+	for _, p := range status.Pods {
+		n += p.Restarts
+	}
+	return n
+}
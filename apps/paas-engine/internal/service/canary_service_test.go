@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// stubCanaryRepo 是线程安全的内存实现：后台流程写、测试轮询读。
+type stubCanaryRepo struct {
+	mu       sync.Mutex
+	canaries map[string]domain.ReleaseCanary
+	order    []string
+}
+
+func newStubCanaryRepo() *stubCanaryRepo {
+	return &stubCanaryRepo{canaries: make(map[string]domain.ReleaseCanary)}
+}
+
+func (r *stubCanaryRepo) put(c *domain.ReleaseCanary) {
+	cp := *c
+	cp.Steps = append([]domain.CanaryStep(nil), c.Steps...)
+	r.canaries[c.ID] = cp
+}
+
+func (r *stubCanaryRepo) Save(_ context.Context, c *domain.ReleaseCanary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(c)
+	r.order = append(r.order, c.ID)
+	return nil
+}
+
+// Update 和真实实现一样不写租约列。
+func (r *stubCanaryRepo) Update(_ context.Context, c *domain.ReleaseCanary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lease := r.canaries[c.ID].Lease
+	r.put(c)
+	cp := r.canaries[c.ID]
+	cp.Lease = lease
+	r.canaries[c.ID] = cp
+	return nil
+}
+
+func (r *stubCanaryRepo) ClaimLease(_ context.Context, id, owner string, now, expiredBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.canaries[id]
+	if !ok || c.Status != domain.CanaryStatusRunning ||
+		(c.Owner != "" && c.HeartbeatAt != nil && !c.HeartbeatAt.Before(expiredBefore)) {
+		return false, nil
+	}
+	c.Hold(owner, now)
+	r.canaries[id] = c
+	return true, nil
+}
+
+func (r *stubCanaryRepo) RenewLease(_ context.Context, id, owner string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.canaries[id]
+	if !ok || c.Owner != owner {
+		return false, nil
+	}
+	c.HeartbeatAt = &now
+	r.canaries[id] = c
+	return true, nil
+}
+
+func (r *stubCanaryRepo) FindByID(_ context.Context, id string) (*domain.ReleaseCanary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.canaries[id]
+	if !ok {
+		return nil, domain.ErrCanaryNotFound
+	}
+	return &c, nil
+}
+
+func (r *stubCanaryRepo) FindLatestByRelease(_ context.Context, releaseID string) (*domain.ReleaseCanary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.order) - 1; i >= 0; i-- {
+		if c := r.canaries[r.order[i]]; c.ReleaseID == releaseID {
+			return &c, nil
+		}
+	}
+	return nil, domain.ErrCanaryNotFound
+}
+
+func (r *stubCanaryRepo) FindRunning(_ context.Context) ([]*domain.ReleaseCanary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*domain.ReleaseCanary
+	for _, id := range r.order {
+		if c := r.canaries[id]; c.Status == domain.CanaryStatusRunning {
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
+
+type canaryFixture struct {
+	svc           *CanaryService
+	canaryRepo    *stubCanaryRepo
+	gwRepo        *stubGatewayRuleRepo
+	releaseRepo   *releaseTestReleaseRepo
+	imageRepoRepo *stubImageRepoRepo
+	buildRepo     *stubBuildRepo
+	stable        *domain.Release
+}
+
+func newCanaryFixture(t *testing.T, status *domain.DeploymentStatus) *canaryFixture {
+	t.Helper()
+	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp", ImageRepoName: "myapp", Port: 8080}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "myapp", Registry: "harbor.local/inner-bot/myapp"}}
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	releaseRepo := newReleaseTestReleaseRepo()
+	releaseSvc := NewReleaseService(appRepo, imageRepoRepo, buildRepo, releaseRepo, nil, nil,
+		&stubDeployer{status: status}, nil, ReleaseServiceConfig{})
+
+	stable, err := releaseSvc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "v1", Replicas: 2,
+	})
+	if err != nil {
+		t.Fatalf("seed stable release: %v", err)
+	}
+
+	gwRepo := newStubGatewayRuleRepo()
+	gwRepo.rules["myapp-api"] = &domain.GatewayRule{
+		Name:       "myapp-api",
+		Enabled:    true,
+		Priority:   100,
+		PathPrefix: "/api/myapp/",
+		Match:      domain.GatewayMatch{PathPrefix: "/api/myapp/"},
+		Targets:    []domain.GatewayTarget{{Service: "myapp", Lane: "", Port: 8080, Weight: 100}},
+		Version:    1,
+	}
+
+	canaryRepo := newStubCanaryRepo()
+	svc := NewCanaryService(releaseSvc, NewGatewayRuleService(gwRepo), canaryRepo)
+	svc.stepUnit = time.Millisecond
+	return &canaryFixture{
+		svc: svc, canaryRepo: canaryRepo, gwRepo: gwRepo, releaseRepo: releaseRepo,
+		imageRepoRepo: imageRepoRepo, buildRepo: buildRepo, stable: stable,
+	}
+}
+
+func waitCanaryDone(t *testing.T, repo *stubCanaryRepo, id string) *domain.ReleaseCanary {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c, err := repo.FindByID(context.Background(), id)
+		if err == nil && c.Status.IsTerminal() {
+			return c
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("canary %s did not finish in time", id)
+	return nil
+}
+
+func TestCanary_PromotesAfterAllStepsPass(t *testing.T) {
+	f := newCanaryFixture(t, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+
+	started, err := f.svc.StartCanary(context.Background(), f.stable.ID, StartCanaryRequest{
+		ImageTag: "v2", GatewayRule: "myapp-api", Steps: []int{20, 100}, StepIntervalSeconds: 1,
+	})
+	if err != nil {
+		t.Fatalf("StartCanary: %v", err)
+	}
+	if started.Status != domain.CanaryStatusRunning || started.CanaryLane != domain.DefaultCanaryLane {
+		t.Errorf("unexpected initial canary: %+v", started)
+	}
+
+	done := waitCanaryDone(t, f.canaryRepo, started.ID)
+	if done.Status != domain.CanaryStatusPromoted {
+		t.Fatalf("status = %q, message = %q", done.Status, done.Message)
+	}
+	for i, st := range done.Steps {
+		if st.Status != domain.CanaryStepPassed {
+			t.Errorf("step %d status = %q", i, st.Status)
+		}
+	}
+
+	stable, _ := f.releaseRepo.FindByID(context.Background(), f.stable.ID)
+	if stable.Image != "harbor.local/inner-bot/myapp:v2" {
+		t.Errorf("stable image = %q, want promoted v2", stable.Image)
+	}
+	if _, err := f.releaseRepo.FindByID(context.Background(), done.CanaryReleaseID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("canary release should be deleted, got %v", err)
+	}
+
+	rule := f.gwRepo.rules["myapp-api"]
+	if len(rule.Targets) != 1 || rule.Targets[0].Weight != 100 {
+		t.Errorf("rule targets not restored: %+v", rule.Targets)
+	}
+	// 放量过程落进了快照历史：20% 那一步 canary 拿到 20
+	found := false
+	for _, snap := range f.gwRepo.snapshots {
+		for _, tg := range snap.Rules[0].Targets {
+			if tg.Lane == domain.DefaultCanaryLane && tg.Weight == 20 {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a snapshot with canary weight 20")
+	}
+}
+
+func TestCanary_AbortsWhenNotReady(t *testing.T) {
+	f := newCanaryFixture(t, &domain.DeploymentStatus{Desired: 1, Ready: 0})
+
+	started, err := f.svc.StartCanary(context.Background(), f.stable.ID, StartCanaryRequest{
+		ImageTag: "v2", GatewayRule: "myapp-api", StepIntervalSeconds: 1,
+	})
+	if err != nil {
+		t.Fatalf("StartCanary: %v", err)
+	}
+
+	done := waitCanaryDone(t, f.canaryRepo, started.ID)
+	if done.Status != domain.CanaryStatusAborted {
+		t.Fatalf("status = %q, want aborted", done.Status)
+	}
+	if done.Steps[0].Status != domain.CanaryStepFailed || done.Steps[1].Status != domain.CanaryStepPending {
+		t.Errorf("steps = %+v", done.Steps)
+	}
+
+	rule := f.gwRepo.rules["myapp-api"]
+	if len(rule.Targets) != 1 || rule.Targets[0].Lane != "" || rule.Targets[0].Weight != 100 {
+		t.Errorf("weights not restored: %+v", rule.Targets)
+	}
+	stable, _ := f.releaseRepo.FindByID(context.Background(), f.stable.ID)
+	if stable.Image != "harbor.local/inner-bot/myapp:v1" {
+		t.Errorf("stable image changed on abort: %q", stable.Image)
+	}
+}
+
+func TestCanary_StartValidation(t *testing.T) {
+	f := newCanaryFixture(t, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	ctx := context.Background()
+
+	cases := []StartCanaryRequest{
+		{GatewayRule: "myapp-api"},
+		{ImageTag: "v2", GatewayRule: "myapp-api", Steps: []int{50, 25, 100}},
+		{ImageTag: "v2", GatewayRule: "myapp-api", Steps: []int{10, 50}},
+		{ImageTag: "v2", GatewayRule: "myapp-api", CanaryLane: "coe-canary"},
+	}
+	for i, req := range cases {
+		if _, err := f.svc.StartCanary(ctx, f.stable.ID, req); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("case %d: expected ErrInvalidInput, got %v", i, err)
+		}
+	}
+
+	f.gwRepo.rules["myapp-api"].Targets[0].Service = "other-service"
+	if _, err := f.svc.StartCanary(ctx, f.stable.ID, StartCanaryRequest{ImageTag: "v2", GatewayRule: "myapp-api"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("rule without stable target: expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestCanary_ScanGate(t *testing.T) {
+	f := newCanaryFixture(t, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	ctx := context.Background()
+	f.imageRepoRepo.repo.ScanPolicy = &domain.ImageScanPolicy{Enabled: true, BlockSeverity: domain.SeverityCritical}
+	f.buildRepo.builds["b2"] = &domain.Build{
+		ID: "b2", ImageTag: "harbor.local/inner-bot/myapp:v2", Channel: domain.ChannelStable, Status: domain.BuildStatusSucceeded,
+		Scan: &domain.ImageScan{Status: domain.ScanStatusSucceeded, Counts: map[string]int{domain.SeverityCritical: 1}},
+	}
+
+	// 金丝雀 lane 不是 prod 类，但最终要晋升到稳定 lane，启动时就按稳定 lane 拦截
+	req := StartCanaryRequest{ImageTag: "v2", GatewayRule: "myapp-api", Steps: []int{100}, StepIntervalSeconds: 1}
+	if _, err := f.svc.StartCanary(ctx, f.stable.ID, req); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("canary with vulnerable image error = %v, want ErrInvalidInput", err)
+	}
+
+	// 豁免原因随金丝雀保存，晋升时带给稳定 release
+	req.ScanOverrideReason = "CVE 不影响本服务，已评估"
+	started, err := f.svc.StartCanary(ctx, f.stable.ID, req)
+	if err != nil {
+		t.Fatalf("StartCanary with override: %v", err)
+	}
+	done := waitCanaryDone(t, f.canaryRepo, started.ID)
+	if done.Status != domain.CanaryStatusPromoted || done.ScanOverrideReason != req.ScanOverrideReason {
+		t.Fatalf("status = %q, message = %q, override = %q", done.Status, done.Message, done.ScanOverrideReason)
+	}
+	stable, _ := f.releaseRepo.FindByID(ctx, f.stable.ID)
+	if stable.Image != "harbor.local/inner-bot/myapp:v2" {
+		t.Errorf("stable image = %q, want promoted v2", stable.Image)
+	}
+}
+
+func TestCanary_AbortInterruptedOnlyExpiredLeases(t *testing.T) {
+	f := newCanaryFixture(t, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	ctx := context.Background()
+	now := time.Now()
+	stale := now.Add(-2 * domain.LeaseTTL)
+
+	seed := func(id, releaseID string, lease domain.Lease) {
+		_ = f.canaryRepo.Save(ctx, &domain.ReleaseCanary{
+			ID: id, ReleaseID: releaseID, AppName: "myapp", Lane: "prod", CanaryLane: domain.DefaultCanaryLane,
+			GatewayRule: "myapp-api", Status: domain.CanaryStatusRunning, Lease: lease, CreatedAt: now,
+		})
+	}
+	seed("stale", "rel-stale", domain.Lease{Owner: "old-instance", HeartbeatAt: &stale})
+	seed("legacy", "rel-legacy", domain.Lease{})
+	// 另一个实例还在驱动的金丝雀（蓝绿切换期间的旧实例）
+	seed("live", f.stable.ID, domain.Lease{Owner: "other-instance", HeartbeatAt: &now})
+
+	if err := f.svc.AbortInterrupted(ctx); err != nil {
+		t.Fatalf("AbortInterrupted: %v", err)
+	}
+	for _, id := range []string{"stale", "legacy"} {
+		c, _ := f.canaryRepo.FindByID(ctx, id)
+		if c.Status != domain.CanaryStatusAborted || c.Owner != f.svc.owner {
+			t.Errorf("%s: status = %q owner = %q, want aborted by this instance", id, c.Status, c.Owner)
+		}
+	}
+	live, _ := f.canaryRepo.FindByID(ctx, "live")
+	if live.Status != domain.CanaryStatusRunning || live.Owner != "other-instance" {
+		t.Errorf("live canary was touched: status = %q owner = %q", live.Status, live.Owner)
+	}
+	if _, err := f.svc.AbortCanary(ctx, f.stable.ID); !errors.Is(err, domain.ErrCannotCancel) {
+		t.Errorf("AbortCanary on another instance's canary: expected ErrCannotCancel, got %v", err)
+	}
+
+	// 租约过期后，并发回收的两个实例只有一个抢到
+	f.canaryRepo.canaries["live"] = func() domain.ReleaseCanary {
+		c := f.canaryRepo.canaries["live"]
+		c.HeartbeatAt = &stale
+		return c
+	}()
+	other := NewCanaryService(f.svc.releaseSvc, f.svc.gatewaySvc, f.canaryRepo)
+	other.owner = "new-instance"
+	mine, _ := f.canaryRepo.FindByID(ctx, "live")
+	theirs, _ := f.canaryRepo.FindByID(ctx, "live")
+	first, _ := f.svc.claim(ctx, mine)
+	second, _ := other.claim(ctx, theirs)
+	if !first || second {
+		t.Errorf("claims = %v, %v; want only the first to win", first, second)
+	}
+}
+
+func TestCanaryWeights_ShiftsOnlyStableTarget(t *testing.T) {
+	prev := []domain.GatewayTarget{
+		{Service: "myapp", Lane: "prod", Weight: 80},
+		{Service: "myapp", Lane: "ppe-ab", Weight: 20},
+	}
+	got := canaryWeights(prev, prev[0], "ppe-canary", 25)
+	want := map[string]int{"prod": 60, "ppe-ab": 20, "ppe-canary": 20}
+	sum := 0
+	for _, w := range got {
+		sum += w.Weight
+		if want[w.Lane] != w.Weight {
+			t.Errorf("lane %s weight = %d, want %d", w.Lane, w.Weight, want[w.Lane])
+		}
+	}
+	if sum != 100 {
+		t.Errorf("weights sum = %d", sum)
+	}
+}
@@ -274,6 +274,54 @@ func (s *ConfigBundleService) DeleteLaneOverrides(ctx context.Context, bundleNam
 	return bundle, nil
 }
 
+// PurgeLaneOverrides 从所有配置包中删除该泳道的覆盖值，返回被修改的配置包名。
+func (s *ConfigBundleService) PurgeLaneOverrides(ctx context.Context, lane string) ([]string, error) {
+	bundles, err := s.bundleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	purged := []string{}
+	for _, bundle := range bundles {
+		if _, ok := bundle.LaneOverrides[lane]; !ok {
+			continue
+		}
+		delete(bundle.LaneOverrides, lane)
+		bundle.UpdatedAt = time.Now()
+		if err := s.bundleRepo.Update(ctx, bundle); err != nil {
+			return purged, fmt.Errorf("config bundle %s: %w", bundle.Name, err)
+		}
+		purged = append(purged, bundle.Name)
+	}
+	return purged, nil
+}
+
+// CopyLaneOverrides 把所有配置包里 from 泳道的覆盖值复制给 to 泳道（整体替换 to 已有的覆盖），
+// 返回被修改的配置包名。
+func (s *ConfigBundleService) CopyLaneOverrides(ctx context.Context, from, to string) ([]string, error) {
+	bundles, err := s.bundleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	copied := []string{}
+	for _, bundle := range bundles {
+		overrides, ok := bundle.LaneOverrides[from]
+		if !ok {
+			continue
+		}
+		cp := make(map[string]string, len(overrides))
+		for k, v := range overrides {
+			cp[k] = v
+		}
+		bundle.LaneOverrides[to] = cp
+		bundle.UpdatedAt = time.Now()
+		if err := s.bundleRepo.Update(ctx, bundle); err != nil {
+			return copied, fmt.Errorf("config bundle %s: %w", bundle.Name, err)
+		}
+		copied = append(copied, bundle.Name)
+	}
+	return copied, nil
+}
+
 // DeleteLaneOverrideKey 删除某个泳道覆盖中的单个 key。
 func (s *ConfigBundleService) DeleteLaneOverrideKey(ctx context.Context, bundleName, lane, keyName string) (*domain.ConfigBundle, error) {
 	bundle, err := s.bundleRepo.FindByName(ctx, bundleName)
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/metrics"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+	"github.com/google/uuid"
+)
+
+// DriftService 周期性对比 release 期望状态与集群中的 Deployment（有人 kubectl edit /
+// scale 过），按 App 的 drift_policy 只上报或重新下发。只检查 deployed 状态的 release，
+// 部署中 / 失败的 release 由 ReleaseService 负责。
+//
+// 当前漂移落在 driftRepo 里：多个实例（蓝绿切换期间）各自对账时看到同一份列表，
+// 已经上报过的差异也不会被另一个实例再记一次事件。漂移 gauge 仍是各实例自己对账的结果。
+type DriftService struct {
+	appRepo         port.AppRepository
+	releaseRepo     port.ReleaseRepository
+	driftRepo       port.ReleaseDriftRepository
+	eventRepo       port.DriftEventRepository
+	deployer        port.Deployer
+	configBundleSvc *ConfigBundleService
+	interval        time.Duration
+
+	reconcileMu sync.Mutex // 串行化 ReconcileOnce（定时器与手动触发可能并发）
+	mu          sync.Mutex
+	gauged      map[string][2]string // releaseID → 已上报 gauge 的 {app, lane}
+}
+
+func NewDriftService(
+	appRepo port.AppRepository,
+	releaseRepo port.ReleaseRepository,
+	driftRepo port.ReleaseDriftRepository,
+	eventRepo port.DriftEventRepository,
+	deployer port.Deployer,
+	configBundleSvc *ConfigBundleService,
+	interval time.Duration,
+) *DriftService {
+	return &DriftService{
+		appRepo:         appRepo,
+		releaseRepo:     releaseRepo,
+		driftRepo:       driftRepo,
+		eventRepo:       eventRepo,
+		deployer:        deployer,
+		configBundleSvc: configBundleSvc,
+		interval:        interval,
+		gauged:          make(map[string][2]string),
+	}
+}
+
+// Start 启动对账循环，ctx 取消时退出。interval<=0 或未配置 deployer 时不启动。
+func (s *DriftService) Start(ctx context.Context) {
+	if s.deployer == nil || s.interval <= 0 {
+		return
+	}
+	slog.Info("drift reconciler started", "interval", s.interval)
+
+	// 启动时立即对账一次
+	if err := s.ReconcileOnce(ctx); err != nil {
+		slog.Error("DriftService.Start: reconcile failed", "error", err)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("drift reconciler stopped")
+			return
+		case <-ticker.C:
+			if err := s.ReconcileOnce(ctx); err != nil {
+				slog.Error("DriftService.Start: reconcile failed", "error", err)
+			}
+		}
+	}
+}
+
+// ReconcileOnce 对所有 deployed 的 release 做一轮对账。单个 release 比对失败只记日志，
+// 保留它上一轮的结果。
+func (s *DriftService) ReconcileOnce(ctx context.Context) error {
+	if s.deployer == nil {
+		return fmt.Errorf("drift reconcile requires k8s deployer")
+	}
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+
+	releases, err := s.releaseRepo.FindAll(ctx, "", "")
+	if err != nil {
+		return fmt.Errorf("list releases: %w", err)
+	}
+	drifts, err := s.driftRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("load drift state: %w", err)
+	}
+	current := make(map[string]*domain.ReleaseDrift, len(drifts))
+	for _, d := range drifts {
+		current[d.ReleaseID] = d
+	}
+
+	seen := make(map[string]bool, len(releases))
+	for _, release := range releases {
+		if release.Status != domain.ReleaseStatusDeployed {
+			continue
+		}
+		seen[release.ID] = true
+		if err := s.reconcileRelease(ctx, release, current[release.ID]); err != nil {
+			slog.Error("DriftService.ReconcileOnce: failed to check release", "release_id", release.ID, "app", release.AppName, "lane", release.Lane, "error", err)
+		}
+	}
+	s.forgetUnseen(ctx, seen, current)
+	metrics.DriftLastReconcileTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// reconcileRelease 比对一个 release，prev 是它上一轮的漂移（没有为 nil）。
+func (s *DriftService) reconcileRelease(ctx context.Context, release *domain.Release, prev *domain.ReleaseDrift) error {
+	app, err := s.appRepo.FindByName(ctx, release.AppName)
+	if err != nil {
+		return err
+	}
+	bundleEnvs, err := s.resolveBundleEnvs(ctx, app, release.Lane)
+	if err != nil {
+		return err
+	}
+	items, err := s.deployer.DiffDeployment(ctx, release, app, bundleEnvs)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		s.setCurrent(ctx, release, prev, nil)
+		if prev != nil {
+			s.recordEvent(ctx, release, domain.DriftActionResolved, nil, "")
+		}
+		return nil
+	}
+
+	policy := app.EffectiveDriftPolicy()
+	drift := &domain.ReleaseDrift{
+		ReleaseID:  release.ID,
+		AppName:    release.AppName,
+		Lane:       release.Lane,
+		DeployName: release.ResourceName(),
+		Policy:     policy,
+		Items:      items,
+		DetectedAt: time.Now(),
+	}
+	changed := prev == nil || driftFingerprint(prev.Items) != driftFingerprint(items)
+	if !changed {
+		drift.DetectedAt = prev.DetectedAt
+	}
+
+	if policy == domain.DriftPolicyHeal {
+		if err := s.heal(ctx, release, app, bundleEnvs); err != nil {
+			metrics.DriftHealsTotal.WithLabelValues(release.AppName, release.Lane, "failed").Inc()
+			s.setCurrent(ctx, release, prev, drift)
+			// 同一组差异持续修复失败时只记一次
+			if changed {
+				s.recordEvent(ctx, release, domain.DriftActionHealFailed, items, err.Error())
+			}
+			return nil
+		}
+		metrics.DriftHealsTotal.WithLabelValues(release.AppName, release.Lane, "healed").Inc()
+		s.setCurrent(ctx, release, prev, nil)
+		s.recordEvent(ctx, release, domain.DriftActionHealed, items, "")
+		return nil
+	}
+
+	s.setCurrent(ctx, release, prev, drift)
+	if changed {
+		s.recordEvent(ctx, release, domain.DriftActionReported, items, "")
+	}
+	return nil
+}
+
+// heal 按 release 期望状态重新下发。下发前重新读 release：期间有新的部署操作
+// （状态不再是 deployed 或 UpdatedAt 变了）就放弃，避免把旧 spec 盖回去。
+func (s *DriftService) heal(ctx context.Context, release *domain.Release, app *domain.App, bundleEnvs map[string]string) error {
+	latest, err := s.releaseRepo.FindByID(ctx, release.ID)
+	if err != nil {
+		return fmt.Errorf("reload release: %w", err)
+	}
+	if latest.Status != domain.ReleaseStatusDeployed || !latest.UpdatedAt.Equal(release.UpdatedAt) {
+		return fmt.Errorf("release changed during reconcile, skip heal")
+	}
+	if err := s.deployer.Apply(ctx, latest, app, bundleEnvs); err != nil {
+		return fmt.Errorf("re-apply: %w", err)
+	}
+	return nil
+}
+
+func (s *DriftService) resolveBundleEnvs(ctx context.Context, app *domain.App, lane string) (map[string]string, error) {
+	if s.configBundleSvc == nil || len(app.ConfigBundles) == 0 {
+		return nil, nil
+	}
+	bundleEnvs, err := s.configBundleSvc.ResolveBundleEnvs(ctx, app, lane)
+	if err != nil {
+		return nil, fmt.Errorf("resolve config bundles: %w", err)
+	}
+	return bundleEnvs, nil
+}
+
+// recordEvent 写漂移事件，失败只记日志。
+func (s *DriftService) recordEvent(ctx context.Context, release *domain.Release, action domain.DriftAction, items []domain.DriftItem, message string) {
+	slog.Info("release drift", "action", action, "app", release.AppName, "lane", release.Lane, "fields", len(items))
+	if s.eventRepo == nil {
+		return
+	}
+	ev := &domain.DriftEvent{
+		ID:         uuid.New().String(),
+		ReleaseID:  release.ID,
+		AppName:    release.AppName,
+		Lane:       release.Lane,
+		DeployName: release.ResourceName(),
+		Action:     action,
+		Items:      items,
+		Message:    message,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.eventRepo.Save(ctx, ev); err != nil {
+		slog.Error("DriftService.recordEvent: failed to save event", "release_id", release.ID, "error", err)
+	}
+}
+
+// setCurrent 把 release 的当前漂移从 prev 更新为 drift（nil 表示无漂移）并同步 gauge。
+// 落库失败只记日志，下一轮对账会重写。
+func (s *DriftService) setCurrent(ctx context.Context, release *domain.Release, prev, drift *domain.ReleaseDrift) {
+	var err error
+	if drift != nil {
+		err = s.driftRepo.Save(ctx, drift)
+	} else if prev != nil {
+		err = s.driftRepo.Delete(ctx, release.ID)
+	}
+	if err != nil {
+		slog.Error("DriftService.setCurrent: failed to persist drift", "release_id", release.ID, "error", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels := [2]string{release.AppName, release.Lane}
+	if old, ok := s.gauged[release.ID]; ok && old != labels {
+		metrics.ReleaseDriftFields.DeleteLabelValues(old[0], old[1])
+	}
+	s.gauged[release.ID] = labels
+	count := 0
+	if drift != nil {
+		count = len(drift.Items)
+	}
+	metrics.ReleaseDriftFields.WithLabelValues(labels[0], labels[1]).Set(float64(count))
+}
+
+// forgetUnseen 清掉本轮没再出现的 release（已删除或不再是 deployed）的状态和 gauge。
+func (s *DriftService) forgetUnseen(ctx context.Context, seen map[string]bool, current map[string]*domain.ReleaseDrift) {
+	for id := range current {
+		if seen[id] {
+			continue
+		}
+		if err := s.driftRepo.Delete(ctx, id); err != nil {
+			slog.Error("DriftService.forgetUnseen: failed to delete drift", "release_id", id, "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, labels := range s.gauged {
+		if seen[id] {
+			continue
+		}
+		metrics.ReleaseDriftFields.DeleteLabelValues(labels[0], labels[1])
+		delete(s.gauged, id)
+	}
+}
+
+// ListDrift 返回当前存在漂移的 release，按 app、lane 排序。
+func (s *DriftService) ListDrift(ctx context.Context) ([]*domain.ReleaseDrift, error) {
+	out, err := s.driftRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AppName != out[j].AppName {
+			return out[i].AppName < out[j].AppName
+		}
+		return out[i].Lane < out[j].Lane
+	})
+	return out, nil
+}
+
+// ListEvents 返回最近的漂移事件，appName / lane 为空表示不过滤。
+func (s *DriftService) ListEvents(ctx context.Context, appName, lane string, limit int) ([]*domain.DriftEvent, error) {
+	if s.eventRepo == nil {
+		return []*domain.DriftEvent{}, nil
+	}
+	return s.eventRepo.FindRecent(ctx, appName, lane, limit)
+}
+
+func driftFingerprint(items []domain.DriftItem) string {
+	parts := make([]string, 0, len(items))
+	for _, it := range items {
+		parts = append(parts, fmt.Sprintf("%s|%s|%s|%s", it.Field, it.Key, it.Expected, it.Actual))
+	}
+	return strings.Join(parts, "\n")
+}
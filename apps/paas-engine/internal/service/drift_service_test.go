@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+type stubDriftEventRepo struct {
+	events []*domain.DriftEvent
+}
+
+func (r *stubDriftEventRepo) Save(_ context.Context, ev *domain.DriftEvent) error {
+	r.events = append(r.events, ev)
+	return nil
+}
+func (r *stubDriftEventRepo) FindRecent(_ context.Context, _, _ string, _ int) ([]*domain.DriftEvent, error) {
+	return r.events, nil
+}
+
+type stubReleaseDriftRepo struct {
+	drifts map[string]domain.ReleaseDrift
+}
+
+func (r *stubReleaseDriftRepo) Save(_ context.Context, d *domain.ReleaseDrift) error {
+	r.drifts[d.ReleaseID] = *d
+	return nil
+}
+func (r *stubReleaseDriftRepo) Delete(_ context.Context, releaseID string) error {
+	delete(r.drifts, releaseID)
+	return nil
+}
+func (r *stubReleaseDriftRepo) FindAll(_ context.Context) ([]*domain.ReleaseDrift, error) {
+	out := make([]*domain.ReleaseDrift, 0, len(r.drifts))
+	for _, d := range r.drifts {
+		out = append(out, &d)
+	}
+	return out, nil
+}
+
+func (r *stubDriftEventRepo) actions() []domain.DriftAction {
+	out := make([]domain.DriftAction, 0, len(r.events))
+	for _, ev := range r.events {
+		out = append(out, ev.Action)
+	}
+	return out
+}
+
+// driftReleaseRepo 的 FindAll 返回全部 release（releaseTestReleaseRepo 的 FindAll 恒为空）。
+type driftReleaseRepo struct {
+	*releaseTestReleaseRepo
+}
+
+func (r *driftReleaseRepo) FindAll(_ context.Context, _, _ string) ([]*domain.Release, error) {
+	out := make([]*domain.Release, 0, len(r.releases))
+	for _, rel := range r.releases {
+		out = append(out, rel)
+	}
+	return out, nil
+}
+
+// driftDeployer 返回预设的差异，Apply 后视为已修复。
+type driftDeployer struct {
+	stubDeployer
+	items   []domain.DriftItem
+	applies int
+}
+
+func (d *driftDeployer) DiffDeployment(_ context.Context, _ *domain.Release, _ *domain.App, _ map[string]string) ([]domain.DriftItem, error) {
+	return d.items, nil
+}
+func (d *driftDeployer) Apply(_ context.Context, _ *domain.Release, _ *domain.App, _ map[string]string) error {
+	d.applies++
+	d.items = nil
+	return nil
+}
+
+func newDriftFixture(policy domain.DriftPolicy, status domain.ReleaseStatus) (*DriftService, *driftDeployer, *stubDriftEventRepo) {
+	releaseRepo := &driftReleaseRepo{newReleaseTestReleaseRepo()}
+	releaseRepo.Save(context.Background(), &domain.Release{
+		ID: "rel-1", AppName: "myapp", Lane: "prod", Image: "harbor.local/inner-bot/myapp:1.0.0.1", Replicas: 2, Status: status,
+	})
+	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp", DriftPolicy: policy}}
+	deployer := &driftDeployer{items: []domain.DriftItem{
+		{Field: domain.DriftFieldReplicas, Expected: "2", Actual: "5"},
+	}}
+	events := &stubDriftEventRepo{}
+	drifts := &stubReleaseDriftRepo{drifts: make(map[string]domain.ReleaseDrift)}
+	svc := NewDriftService(appRepo, releaseRepo, drifts, events, deployer, nil, 0)
+	return svc, deployer, events
+}
+
+func listedDrift(svc *DriftService) int {
+	drifts, _ := svc.ListDrift(context.Background())
+	return len(drifts)
+}
+
+func TestDriftReconcile_ReportPolicy(t *testing.T) {
+	svc, deployer, events := newDriftFixture("", domain.ReleaseStatusDeployed)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := svc.ReconcileOnce(ctx); err != nil {
+			t.Fatalf("ReconcileOnce: %v", err)
+		}
+	}
+	if deployer.applies != 0 {
+		t.Errorf("report policy should not re-apply, got %d applies", deployer.applies)
+	}
+	drifts, _ := svc.ListDrift(ctx)
+	if len(drifts) != 1 || drifts[0].Policy != domain.DriftPolicyReport || len(drifts[0].Items) != 1 {
+		t.Fatalf("unexpected drift list: %+v", drifts)
+	}
+	// 同一组差异只记一次
+	if got := events.actions(); len(got) != 1 || got[0] != domain.DriftActionReported {
+		t.Fatalf("events = %v, want [reported]", got)
+	}
+
+	// 线上被改回来后记 resolved 并从列表移除
+	deployer.items = nil
+	if err := svc.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if listedDrift(svc) != 0 {
+		t.Errorf("drift should be cleared after resolve")
+	}
+	if got := events.actions(); len(got) != 2 || got[1] != domain.DriftActionResolved {
+		t.Fatalf("events = %v, want [reported resolved]", got)
+	}
+}
+
+func TestDriftReconcile_HealPolicy(t *testing.T) {
+	svc, deployer, events := newDriftFixture(domain.DriftPolicyHeal, domain.ReleaseStatusDeployed)
+
+	if err := svc.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if deployer.applies != 1 {
+		t.Fatalf("heal policy should re-apply once, got %d", deployer.applies)
+	}
+	if listedDrift(svc) != 0 {
+		t.Errorf("healed release should not be listed as drifted")
+	}
+	if got := events.actions(); len(got) != 1 || got[0] != domain.DriftActionHealed {
+		t.Fatalf("events = %v, want [healed]", got)
+	}
+	if len(events.events[0].Items) != 1 {
+		t.Errorf("healed event should carry the drift items")
+	}
+}
+
+func TestDriftReconcile_SkipsNonDeployedRelease(t *testing.T) {
+	svc, deployer, events := newDriftFixture(domain.DriftPolicyHeal, domain.ReleaseStatusPending)
+
+	if err := svc.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if deployer.applies != 0 || len(events.events) != 0 || listedDrift(svc) != 0 {
+		t.Fatalf("pending release must not be reconciled: applies=%d events=%d", deployer.applies, len(events.events))
+	}
+}
+
+// TestDriftReconcile_SharedAcrossInstances 模拟蓝绿切换期间的两个实例：漂移状态在库里共享，
+// 另一个实例看到同一份列表，也不会把已上报的差异再记一次。
+func TestDriftReconcile_SharedAcrossInstances(t *testing.T) {
+	svc, deployer, events := newDriftFixture("", domain.ReleaseStatusDeployed)
+	ctx := context.Background()
+	other := NewDriftService(svc.appRepo, svc.releaseRepo, svc.driftRepo, events, deployer, nil, 0)
+
+	if err := svc.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce: %v", err)
+	}
+	if n := listedDrift(other); n != 1 {
+		t.Fatalf("other instance lists %d drifts, want 1", n)
+	}
+	if err := other.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce on other instance: %v", err)
+	}
+	if got := events.actions(); len(got) != 1 {
+		t.Fatalf("events = %v, want a single reported", got)
+	}
+
+	deployer.items = nil
+	if err := other.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("ReconcileOnce on other instance: %v", err)
+	}
+	if n := listedDrift(svc); n != 0 {
+		t.Errorf("drift resolved on the other instance is still listed here: %d", n)
+	}
+}
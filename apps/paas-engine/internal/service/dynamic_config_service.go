@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
@@ -100,3 +101,42 @@ func (s *DynamicConfigService) Delete(ctx context.Context, key, lane string) err
 	}
 	return s.repo.DeleteByKey(ctx, key)
 }
+
+// CopyLane 把 from 泳道的配置复制到 to 泳道（同 key 覆盖），返回复制的 key。
+// from 为 prod 时不复制：prod 是基线，其他泳道解析时本来就会用 prod 补缺。
+func (s *DynamicConfigService) CopyLane(ctx context.Context, from, to string) ([]string, error) {
+	if from == domain.DefaultLane {
+		return []string{}, nil
+	}
+	configs, err := s.repo.FindByLane(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	copied := []string{}
+	for _, c := range configs {
+		if err := s.repo.Upsert(ctx, &domain.DynamicConfig{Key: c.Key, Lane: to, Value: c.Value, UpdatedAt: time.Now()}); err != nil {
+			return copied, fmt.Errorf("dynamic config %s: %w", c.Key, err)
+		}
+		copied = append(copied, c.Key)
+	}
+	return copied, nil
+}
+
+// DeleteLane 删除某个 lane 的全部配置，返回被删除的 key。prod 是兜底 lane，不允许整体删除。
+func (s *DynamicConfigService) DeleteLane(ctx context.Context, lane string) ([]string, error) {
+	if lane == "" || lane == domain.DefaultLane {
+		return nil, fmt.Errorf("%w: cannot delete all dynamic configs of lane %q", domain.ErrInvalidInput, lane)
+	}
+	configs, err := s.repo.FindByLane(ctx, lane)
+	if err != nil {
+		return nil, err
+	}
+	deleted := []string{}
+	for _, c := range configs {
+		if err := s.repo.DeleteByKeyAndLane(ctx, c.Key, lane); err != nil {
+			return deleted, fmt.Errorf("dynamic config %s: %w", c.Key, err)
+		}
+		deleted = append(deleted, c.Key)
+	}
+	return deleted, nil
+}
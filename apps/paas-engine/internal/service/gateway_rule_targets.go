@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+// AddTarget 向规则追加一个 target（按 service+lane 识别）。已存在同身份 target 时不做改动。
+// 追加后照常跑整条规则校验，因此新 target 一般以 weight 0 加入，再用 SetWeights 放量。
+func (s *GatewayRuleService) AddTarget(ctx context.Context, name string, target domain.GatewayTarget, reason string) (*WeightsChange, error) {
+	return s.mutateTargets(ctx, name, reason, func(targets []domain.GatewayTarget) ([]domain.GatewayTarget, bool) {
+		for _, t := range targets {
+			if t.Service == target.Service && t.Lane == target.Lane {
+				return targets, false
+			}
+		}
+		return append(targets, target), true
+	})
+}
+
+// RemoveTarget 从规则里删掉 service+lane 对应的 target，其权重不会自动转移，
+// 调用方需先用 SetWeights 把它排空到 0。不存在时不做改动。
+func (s *GatewayRuleService) RemoveTarget(ctx context.Context, name, service, lane, reason string) (*WeightsChange, error) {
+	return s.mutateTargets(ctx, name, reason, func(targets []domain.GatewayTarget) ([]domain.GatewayTarget, bool) {
+		out := make([]domain.GatewayTarget, 0, len(targets))
+		for _, t := range targets {
+			if t.Service == service && t.Lane == lane {
+				continue
+			}
+			out = append(out, t)
+		}
+		return out, len(out) != len(targets)
+	})
+}
+
+func (s *GatewayRuleService) mutateTargets(
+	ctx context.Context,
+	name, reason string,
+	fn func([]domain.GatewayTarget) ([]domain.GatewayTarget, bool),
+) (*WeightsChange, error) {
+	var change *WeightsChange
+	err := s.repo.Tx(ctx, func(txRepo port.GatewayRuleRepository) error {
+		rule, err := txRepo.FindByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		before := make([]domain.GatewayTarget, len(rule.Targets))
+		copy(before, rule.Targets)
+
+		after, changed := fn(append([]domain.GatewayTarget(nil), rule.Targets...))
+		if !changed {
+			change = &WeightsChange{Name: name, BeforeTargets: before, AfterTargets: before, Reason: reason}
+			return nil
+		}
+		rule.Targets = after
+		if err := domain.ValidateGatewayRule(*rule); err != nil {
+			return fmt.Errorf("update targets of %s: %w", name, err)
+		}
+		rule.Version++
+		rule.UpdatedAt = time.Now()
+		if err := txRepo.Upsert(ctx, rule); err != nil {
+			return err
+		}
+		snapVersion, err := recordSnapshot(ctx, txRepo, reason)
+		if err != nil {
+			return err
+		}
+		change = &WeightsChange{
+			Name:          name,
+			BeforeTargets: before,
+			AfterTargets:  after,
+			Reason:        reason,
+			Version:       snapVersion,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// LaneRulesPurge 是 PurgeLane 的结果。
+type LaneRulesPurge struct {
+	Deleted []string `json:"deleted"` // 整条删除的规则
+	Updated []string `json:"updated"` // 去掉了该 lane target 的规则
+	Version int64    `json:"version,omitempty"`
+}
+
+// PurgeLane 清掉所有引用 lane 的规则，整体在一个事务里完成并只写一条快照：
+//   - request_lane 匹配该 lane、或 target 全在该 lane 的规则整条删除
+//   - 其余规则去掉该 lane 的 target，腾出的权重并到剩下的第一个 target 上
+func (s *GatewayRuleService) PurgeLane(ctx context.Context, lane, reason string) (*LaneRulesPurge, error) {
+	result := &LaneRulesPurge{Deleted: []string{}, Updated: []string{}}
+	err := s.repo.Tx(ctx, func(txRepo port.GatewayRuleRepository) error {
+		rules, err := txRepo.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if rule.RequestLane == lane || rule.Match.RequestLane == lane {
+				if err := txRepo.Delete(ctx, rule.Name); err != nil {
+					return err
+				}
+				result.Deleted = append(result.Deleted, rule.Name)
+				continue
+			}
+			kept := make([]domain.GatewayTarget, 0, len(rule.Targets))
+			freed := 0
+			for _, t := range rule.Targets {
+				if t.Lane == lane {
+					freed += t.Weight
+					continue
+				}
+				kept = append(kept, t)
+			}
+			if len(kept) == len(rule.Targets) {
+				continue
+			}
+			if len(kept) == 0 {
+				if err := txRepo.Delete(ctx, rule.Name); err != nil {
+					return err
+				}
+				result.Deleted = append(result.Deleted, rule.Name)
+				continue
+			}
+			kept[0].Weight += freed
+			rule.Targets = kept
+			if err := domain.ValidateGatewayRule(*rule); err != nil {
+				return fmt.Errorf("purge lane %s from %s: %w", lane, rule.Name, err)
+			}
+			rule.Version++
+			rule.UpdatedAt = time.Now()
+			if err := txRepo.Upsert(ctx, rule); err != nil {
+				return err
+			}
+			result.Updated = append(result.Updated, rule.Name)
+		}
+		if len(result.Deleted) == 0 && len(result.Updated) == 0 {
+			return nil
+		}
+		result.Version, err = recordSnapshot(ctx, txRepo, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
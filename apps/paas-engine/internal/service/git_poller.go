@@ -61,8 +61,12 @@ func NewGitPoller(
 	}
 }
 
-// Start 启动轮询循环，ctx 取消时退出。
+// Start 启动轮询循环，ctx 取消时退出。interval<=0 时不轮询，只靠 webhook 触发。
 func (p *GitPoller) Start(ctx context.Context) {
+	if p.interval <= 0 {
+		slog.Info("git poller disabled", "interval", p.interval)
+		return
+	}
 	slog.Info("git poller started", "owner", p.owner, "repo", p.repo, "interval", p.interval)
 
 	// 启动时立即 poll 一次
@@ -92,7 +96,7 @@ func (p *GitPoller) pollAll(ctx context.Context) {
 
 	for _, cfg := range configs {
 		// 跳过 main/master 分支
-		if cfg.Branch == "main" || cfg.Branch == "master" {
+		if isMainBranch(cfg.Branch) {
 			continue
 		}
 		p.pollBranch(ctx, cfg.Lane, cfg.Branch)
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/metrics"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+// ImageGCService 按各 ImageRepo 的 retention 策略周期性删除 registry 里的旧镜像 tag，
+// 并把对应 Build 标记为已清理。被当前 Release、任意历史 revision 或没结束的流水线 run 引用的镜像不删。
+type ImageGCService struct {
+	imageRepoRepo port.ImageRepoRepository
+	buildRepo     port.BuildRepository
+	releaseRepo   port.ReleaseRepository
+	revisionRepo  port.ReleaseRevisionRepository
+	pipelineRepo  port.PipelineRunRepository
+	registry      port.ImageRegistry
+	interval      time.Duration
+
+	mu sync.Mutex // 串行化清理（定时器与手动触发可能并发）
+}
+
+func NewImageGCService(
+	imageRepoRepo port.ImageRepoRepository,
+	buildRepo port.BuildRepository,
+	releaseRepo port.ReleaseRepository,
+	revisionRepo port.ReleaseRevisionRepository,
+	pipelineRepo port.PipelineRunRepository,
+	registry port.ImageRegistry,
+	interval time.Duration,
+) *ImageGCService {
+	return &ImageGCService{
+		imageRepoRepo: imageRepoRepo,
+		buildRepo:     buildRepo,
+		releaseRepo:   releaseRepo,
+		revisionRepo:  revisionRepo,
+		pipelineRepo:  pipelineRepo,
+		registry:      registry,
+		interval:      interval,
+	}
+}
+
+// ImageGCReport 是一个 ImageRepo 一轮清理的结果。
+type ImageGCReport struct {
+	ImageRepo string   `json:"image_repo"`
+	DryRun    bool     `json:"dry_run,omitempty"`
+	Pruned    []string `json:"pruned"`           // 已删除（dry run 时为将删除）的镜像地址
+	Errors    []string `json:"errors,omitempty"` // 删除失败的镜像，下一轮重试
+}
+
+// Start 启动清理循环，ctx 取消时退出。interval<=0 时不启动。
+func (s *ImageGCService) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	slog.Info("image gc started", "interval", s.interval)
+
+	s.collectAll(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("image gc stopped")
+			return
+		case <-ticker.C:
+			s.collectAll(ctx)
+		}
+	}
+}
+
+func (s *ImageGCService) collectAll(ctx context.Context) {
+	repos, err := s.imageRepoRepo.FindAll(ctx)
+	if err != nil {
+		slog.Error("ImageGCService: list image repos failed", "error", err)
+		return
+	}
+	for _, repo := range repos {
+		if !repo.Retention.Enabled() {
+			continue
+		}
+		report, err := s.collect(ctx, repo, false)
+		if err != nil {
+			slog.Error("ImageGCService: collect failed", "image_repo", repo.Name, "error", err)
+			continue
+		}
+		if len(report.Pruned) > 0 || len(report.Errors) > 0 {
+			slog.Info("image gc done", "image_repo", repo.Name, "pruned", len(report.Pruned), "errors", len(report.Errors))
+		}
+	}
+}
+
+// CollectImageRepo 立即按策略清理一个 ImageRepo。dryRun 时只返回将被删除的镜像。
+func (s *ImageGCService) CollectImageRepo(ctx context.Context, name string, dryRun bool) (*ImageGCReport, error) {
+	repo, err := s.imageRepoRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !repo.Retention.Enabled() {
+		return nil, fmt.Errorf("%w: image repo %s has no retention policy", domain.ErrInvalidInput, name)
+	}
+	return s.collect(ctx, repo, dryRun)
+}
+
+func (s *ImageGCService) collect(ctx context.Context, repo *domain.ImageRepo, dryRun bool) (*ImageGCReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	referenced, err := s.referencedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	builds, err := s.buildRepo.FindByImageRepo(ctx, repo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("list builds: %w", err)
+	}
+	pinned, err := s.pipelineBuilds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, build := range builds {
+		if pinned[build.ID] {
+			referenced[build.ImageTag] = true
+		}
+	}
+
+	report := &ImageGCReport{ImageRepo: repo.Name, DryRun: dryRun, Pruned: []string{}}
+	for _, build := range repo.Retention.SelectPrunable(builds, referenced, time.Now()) {
+		if dryRun {
+			report.Pruned = append(report.Pruned, build.ImageTag)
+			continue
+		}
+		if err := s.registry.DeleteImage(ctx, build.ImageTag); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", build.ImageTag, err))
+			continue
+		}
+		now := time.Now()
+		build.PrunedAt = &now
+		build.UpdatedAt = now
+		if err := s.buildRepo.Update(ctx, build); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: mark build %s pruned: %v", build.ImageTag, build.ID, err))
+			continue
+		}
+		metrics.ImagesPruned.WithLabelValues(repo.Name).Inc()
+		report.Pruned = append(report.Pruned, build.ImageTag)
+	}
+	return report, nil
+}
+
+// referencedImages 汇总当前 Release 和全部历史 revision 的镜像地址。
+func (s *ImageGCService) referencedImages(ctx context.Context) (map[string]bool, error) {
+	releases, err := s.releaseRepo.FindAll(ctx, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("list releases: %w", err)
+	}
+	images, err := s.revisionRepo.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list revision images: %w", err)
+	}
+	referenced := make(map[string]bool, len(releases)+len(images))
+	for _, r := range releases {
+		referenced[r.Image] = true
+	}
+	for _, image := range images {
+		referenced[image] = true
+	}
+	return referenced, nil
+}
+
+// pipelineBuilds 返回 pending / running 的流水线 run 的 build job 关联的构建 ID。
+// 这些构建（包括去重复用的旧构建）还没部署，不能在部署前被删掉。
+func (s *ImageGCService) pipelineBuilds(ctx context.Context) (map[string]bool, error) {
+	runs, err := s.pipelineRepo.FindUnfinished(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list unfinished pipeline runs: %w", err)
+	}
+	ids := make(map[string]bool)
+	for _, run := range runs {
+		stages, err := s.pipelineRepo.FindStagesByRunID(ctx, run.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list stages of pipeline run %s: %w", run.ID, err)
+		}
+		for _, stage := range stages {
+			if stage.Stage != domain.StageBuild {
+				continue
+			}
+			jobs, err := s.pipelineRepo.FindJobsByStageID(ctx, stage.ID)
+			if err != nil {
+				return nil, fmt.Errorf("list jobs of stage %s: %w", stage.ID, err)
+			}
+			for _, job := range jobs {
+				if job.RefID != "" {
+					ids[job.RefID] = true
+				}
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+type stubRegistry struct {
+	deleted []string
+	tagged  map[string]string // target → source
+}
+
+func (r *stubRegistry) DeleteImage(_ context.Context, image string) error {
+	r.deleted = append(r.deleted, image)
+	return nil
+}
+
+func (r *stubRegistry) TagImage(_ context.Context, source, target string) error {
+	if r.tagged == nil {
+		r.tagged = make(map[string]string)
+	}
+	r.tagged[target] = source
+	return nil
+}
+
+func newGCTestService(retention *domain.ImageRetention) (*ImageGCService, *stubBuildRepo, *stubRegistry) {
+	svc, buildRepo, registry, _ := newGCTestServiceWithPipelines(retention)
+	return svc, buildRepo, registry
+}
+
+func newGCTestServiceWithPipelines(retention *domain.ImageRetention) (*ImageGCService, *stubBuildRepo, *stubRegistry, *stubPipelineRunRepo) {
+	now := time.Now()
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{}}
+	for i, v := range []string{"1.0.0.3", "1.0.0.2", "1.0.0.1"} {
+		buildRepo.builds["b"+v] = &domain.Build{
+			ID:            "b" + v,
+			ImageRepoName: "api",
+			ImageTag:      "harbor.local/inner-bot/api:" + v,
+			Version:       v,
+			Channel:       domain.ChannelStable,
+			Status:        domain.BuildStatusSucceeded,
+			CreatedAt:     now.Add(-time.Duration(i) * time.Hour),
+		}
+	}
+	releaseRepo := newReleaseTestReleaseRepo()
+	releaseRepo.Save(context.Background(), &domain.Release{ID: "r1", AppName: "api", Image: "harbor.local/inner-bot/api:1.0.0.1"})
+
+	registry := &stubRegistry{}
+	pipelineRepo := newStubPipelineRunRepo()
+	svc := NewImageGCService(
+		&stubImageRepoRepo{repo: &domain.ImageRepo{Name: "api", Retention: retention}},
+		buildRepo, releaseRepo, newStubRevisionRepo(), pipelineRepo, registry, 0,
+	)
+	return svc, buildRepo, registry, pipelineRepo
+}
+
+func TestCollectImageRepo(t *testing.T) {
+	svc, buildRepo, registry := newGCTestService(&domain.ImageRetention{KeepLast: 1})
+
+	report, err := svc.CollectImageRepo(context.Background(), "api", false)
+	if err != nil {
+		t.Fatalf("CollectImageRepo() error = %v", err)
+	}
+	// 1.0.0.1 被当前 Release 引用，保留
+	if len(report.Pruned) != 1 || report.Pruned[0] != "harbor.local/inner-bot/api:1.0.0.2" {
+		t.Errorf("pruned = %v", report.Pruned)
+	}
+	if len(registry.deleted) != 1 {
+		t.Errorf("registry deleted = %v", registry.deleted)
+	}
+	if buildRepo.builds["b1.0.0.2"].PrunedAt == nil {
+		t.Error("pruned build should be marked")
+	}
+	if buildRepo.builds["b1.0.0.1"].PrunedAt != nil || buildRepo.builds["b1.0.0.3"].PrunedAt != nil {
+		t.Error("kept builds must not be marked")
+	}
+
+	// 再跑一轮不会重复删除
+	report, _ = svc.CollectImageRepo(context.Background(), "api", false)
+	if len(report.Pruned) != 0 {
+		t.Errorf("second run pruned = %v", report.Pruned)
+	}
+}
+
+func TestCollectImageRepo_KeepsBuildsOfUnfinishedRuns(t *testing.T) {
+	svc, _, _, pipelineRepo := newGCTestServiceWithPipelines(&domain.ImageRetention{KeepLast: 1})
+	ctx := context.Background()
+	// 运行中的 run 复用了旧构建 1.0.0.2，还没部署；已结束的 run 不再保护它的构建
+	for _, r := range []struct {
+		id, buildID string
+		status      domain.PipelineRunStatus
+	}{{"run-1", "b1.0.0.2", domain.PipelineRunRunning}, {"run-2", "b1.0.0.3", domain.PipelineRunSucceeded}} {
+		_ = pipelineRepo.Save(ctx, &domain.PipelineRun{ID: r.id, Status: r.status})
+		_ = pipelineRepo.SaveStage(ctx, &domain.StageRun{ID: r.id + "-build", PipelineRunID: r.id, Stage: domain.StageBuild})
+		_ = pipelineRepo.SaveJob(ctx, &domain.JobRun{ID: r.id + "-job", StageRunID: r.id + "-build", Name: "api", RefID: r.buildID})
+	}
+
+	report, err := svc.CollectImageRepo(ctx, "api", true)
+	if err != nil {
+		t.Fatalf("CollectImageRepo() error = %v", err)
+	}
+	if len(report.Pruned) != 0 {
+		t.Errorf("pruned = %v, builds of unfinished runs must be kept", report.Pruned)
+	}
+
+	_ = pipelineRepo.Update(ctx, &domain.PipelineRun{ID: "run-1", Status: domain.PipelineRunSucceeded})
+	report, _ = svc.CollectImageRepo(ctx, "api", true)
+	if len(report.Pruned) != 1 || report.Pruned[0] != "harbor.local/inner-bot/api:1.0.0.2" {
+		t.Errorf("pruned after the run finished = %v", report.Pruned)
+	}
+}
+
+func TestCollectImageRepo_DryRun(t *testing.T) {
+	svc, buildRepo, registry := newGCTestService(&domain.ImageRetention{KeepLast: 1})
+
+	report, err := svc.CollectImageRepo(context.Background(), "api", true)
+	if err != nil {
+		t.Fatalf("CollectImageRepo() error = %v", err)
+	}
+	if !report.DryRun || len(report.Pruned) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(registry.deleted) != 0 || buildRepo.builds["b1.0.0.2"].PrunedAt != nil {
+		t.Error("dry run must not delete anything")
+	}
+}
+
+func TestCollectImageRepo_NoPolicy(t *testing.T) {
+	svc, _, _ := newGCTestService(nil)
+
+	_, err := svc.CollectImageRepo(context.Background(), "api", false)
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
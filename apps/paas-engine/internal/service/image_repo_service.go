@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
@@ -18,12 +19,20 @@ func NewImageRepoService(imageRepoRepo port.ImageRepoRepository, appRepo port.Ap
 }
 
 type CreateImageRepoRequest struct {
-	Name       string `json:"name"`
-	Registry   string `json:"registry"`
-	GitRepo    string `json:"git_repo"`
-	ContextDir string `json:"context_dir"`
-	Dockerfile string `json:"dockerfile"`
-	NoCache    bool   `json:"no_cache"`
+	Name            string   `json:"name"`
+	Registry        string   `json:"registry"`
+	GitRepo         string   `json:"git_repo"`
+	ContextDir      string   `json:"context_dir"`
+	Dockerfile      string   `json:"dockerfile"`
+	NoCache         bool     `json:"no_cache"`
+	Builder         string   `json:"builder,omitempty"`          // kaniko（默认）/ buildkit
+	DependencyPaths []string `json:"dependency_paths,omitempty"` // CI 变更检测用
+
+	Retention  *domain.ImageRetention  `json:"retention,omitempty"`
+	ScanPolicy *domain.ImageScanPolicy `json:"scan_policy,omitempty"`
+
+	// 构建参数默认值
+	domain.BuildOptions
 }
 
 func (s *ImageRepoService) CreateImageRepo(ctx context.Context, req CreateImageRepoRequest) (*domain.ImageRepo, error) {
@@ -39,17 +48,37 @@ func (s *ImageRepoService) CreateImageRepo(ctx context.Context, req CreateImageR
 	if err := domain.ValidateContextDir(req.ContextDir); err != nil {
 		return nil, err
 	}
+	if err := validateDependencyPaths(req.DependencyPaths); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateBuilder(req.Builder); err != nil {
+		return nil, err
+	}
+	if err := req.Retention.Validate(); err != nil {
+		return nil, err
+	}
+	if err := req.ScanPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := req.BuildOptions.Validate(); err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	repo := &domain.ImageRepo{
-		Name:       req.Name,
-		Registry:   req.Registry,
-		GitRepo:    req.GitRepo,
-		ContextDir: req.ContextDir,
-		Dockerfile: req.Dockerfile,
-		NoCache:    req.NoCache,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		Name:            req.Name,
+		Registry:        req.Registry,
+		GitRepo:         req.GitRepo,
+		ContextDir:      req.ContextDir,
+		Dockerfile:      req.Dockerfile,
+		NoCache:         req.NoCache,
+		Builder:         req.Builder,
+		Retention:       req.Retention,
+		ScanPolicy:      req.ScanPolicy,
+		DependencyPaths: req.DependencyPaths,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		BuildOptions:    req.BuildOptions,
 	}
 	if err := s.imageRepoRepo.Save(ctx, repo); err != nil {
 		return nil, err
@@ -91,6 +120,33 @@ func (s *ImageRepoService) UpdateImageRepo(ctx context.Context, name string, bod
 	if err := ApplyField(fields, "no_cache", &repo.NoCache); err != nil {
 		return nil, domain.ErrInvalidInput
 	}
+	if err := ApplyField(fields, "dependency_paths", &repo.DependencyPaths); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "builder", &repo.Builder); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "retention", &repo.Retention); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "scan_policy", &repo.ScanPolicy); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "build_args", &repo.BuildArgs); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "secret_build_args", &repo.SecretBuildArgs); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "target", &repo.Target); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "platform", &repo.Platform); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "labels", &repo.Labels); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
 
 	// 合并后校验
 	if repo.Registry == "" {
@@ -102,6 +158,21 @@ func (s *ImageRepoService) UpdateImageRepo(ctx context.Context, name string, bod
 	if err := domain.ValidateContextDir(repo.ContextDir); err != nil {
 		return nil, err
 	}
+	if err := validateDependencyPaths(repo.DependencyPaths); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateBuilder(repo.Builder); err != nil {
+		return nil, err
+	}
+	if err := repo.Retention.Validate(); err != nil {
+		return nil, err
+	}
+	if err := repo.ScanPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := repo.BuildOptions.Validate(); err != nil {
+		return nil, err
+	}
 
 	repo.UpdatedAt = time.Now()
 	if err := s.imageRepoRepo.Update(ctx, repo); err != nil {
@@ -110,6 +181,19 @@ func (s *ImageRepoService) UpdateImageRepo(ctx context.Context, name string, bod
 	return repo, nil
 }
 
+// validateDependencyPaths 按构建上下文的规则校验依赖路径。
+func validateDependencyPaths(paths []string) error {
+	for _, p := range paths {
+		if p == "" {
+			return fmt.Errorf("%w: dependency path must not be empty", domain.ErrInvalidInput)
+		}
+		if err := domain.ValidateContextDir(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *ImageRepoService) DeleteImageRepo(ctx context.Context, name string) error {
 	if _, err := s.imageRepoRepo.FindByName(ctx, name); err != nil {
 		return err
@@ -107,6 +107,43 @@ func TestCreateImageRepo_InvalidGitRepo(t *testing.T) {
 	}
 }
 
+func TestCreateImageRepo_InvalidDependencyPath(t *testing.T) {
+	svc := NewImageRepoService(&stubImageRepoRepo{}, &stubAppRepo{})
+
+	_, err := svc.CreateImageRepo(context.Background(), CreateImageRepoRequest{
+		Name:            "myrepo",
+		Registry:        "harbor.local/inner-bot/test",
+		GitRepo:         "example/repo.git",
+		ContextDir:      "apps/myrepo",
+		DependencyPaths: []string{"packages/ts-shared", "../secrets"},
+	})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestUpdateImageRepo_Builder(t *testing.T) {
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{
+		Name:     "myrepo",
+		Registry: "harbor.local/inner-bot/test",
+		GitRepo:  "example/repo.git",
+	}}
+	svc := NewImageRepoService(imageRepoRepo, &stubAppRepo{})
+
+	updated, err := svc.UpdateImageRepo(context.Background(), "myrepo", []byte(`{"builder":"buildkit"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Builder != domain.BuilderBuildKit {
+		t.Errorf("builder = %q, want buildkit", updated.Builder)
+	}
+
+	_, err = svc.UpdateImageRepo(context.Background(), "myrepo", []byte(`{"builder":"docker"}`))
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for unknown builder, got %v", err)
+	}
+}
+
 func TestUpdateImageRepo_PartialKeepsExisting(t *testing.T) {
 	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{
 		Name:       "myrepo",
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/metrics"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+// ImageScanService 扫描构建产物的漏洞。ImageRepo 开启 scan_policy 时，构建成功后 BuildService 把扫描标为
+// pending，这里定时取出逐个扫描；builds 表本身就是扫描队列，进程重启时中断的 running 扫描重新执行。
+type ImageScanService struct {
+	imageRepoRepo port.ImageRepoRepository
+	buildRepo     port.BuildRepository
+	scanner       port.ImageScanner
+	interval      time.Duration
+	timeout       time.Duration // 单次扫描超时
+
+	mu   sync.Mutex    // 串行化扫描（定时器与手动触发可能并发）
+	kick chan struct{} // 手动触发后立即扫描，不等下一个周期
+}
+
+func NewImageScanService(
+	imageRepoRepo port.ImageRepoRepository,
+	buildRepo port.BuildRepository,
+	scanner port.ImageScanner,
+	interval time.Duration,
+	timeout time.Duration,
+) *ImageScanService {
+	return &ImageScanService{
+		imageRepoRepo: imageRepoRepo,
+		buildRepo:     buildRepo,
+		scanner:       scanner,
+		interval:      interval,
+		timeout:       timeout,
+		kick:          make(chan struct{}, 1),
+	}
+}
+
+// Start 启动扫描循环，ctx 取消时退出。interval<=0 或没有扫描器时不启动。
+func (s *ImageScanService) Start(ctx context.Context) {
+	if s.interval <= 0 || s.scanner == nil {
+		return
+	}
+	slog.Info("image scan started", "interval", s.interval, "timeout", s.timeout)
+
+	s.requeueInterrupted(ctx)
+	s.scanPending(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("image scan stopped")
+			return
+		case <-ticker.C:
+			s.scanPending(ctx)
+		case <-s.kick:
+			s.scanPending(ctx)
+		}
+	}
+}
+
+// RequestScan 把一个成功的构建重新排入扫描队列（首次扫描或漏洞库更新后复扫）。
+func (s *ImageScanService) RequestScan(ctx context.Context, imageRepoName, id string) (*domain.Build, error) {
+	if s.scanner == nil || s.interval <= 0 {
+		return nil, fmt.Errorf("%w: image scanning is not enabled", domain.ErrInvalidInput)
+	}
+	build, err := s.buildRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if build.ImageRepoName != imageRepoName {
+		return nil, domain.ErrBuildNotFound
+	}
+	if build.Status != domain.BuildStatusSucceeded || build.PrunedAt != nil {
+		return nil, fmt.Errorf("%w: build %s has no image to scan", domain.ErrInvalidInput, id)
+	}
+	if build.Scan != nil && (build.Scan.Status == domain.ScanStatusPending || build.Scan.Status == domain.ScanStatusRunning) {
+		return build, nil
+	}
+	build.Scan = &domain.ImageScan{Status: domain.ScanStatusPending}
+	build.UpdatedAt = time.Now()
+	if err := s.buildRepo.Update(ctx, build); err != nil {
+		return nil, err
+	}
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+	return build, nil
+}
+
+// requeueInterrupted 把上次进程退出时还在 running 的扫描放回队列。
+func (s *ImageScanService) requeueInterrupted(ctx context.Context) {
+	builds, err := s.buildRepo.FindByScanStatus(ctx, domain.ScanStatusRunning)
+	if err != nil {
+		slog.Error("ImageScanService: list running scans failed", "error", err)
+		return
+	}
+	for _, b := range builds {
+		b.Scan.Status = domain.ScanStatusPending
+		if err := s.buildRepo.Update(ctx, b); err != nil {
+			slog.Error("ImageScanService: requeue scan failed", "build_id", b.ID, "error", err)
+		}
+	}
+}
+
+func (s *ImageScanService) scanPending(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	builds, err := s.buildRepo.FindByScanStatus(ctx, domain.ScanStatusPending)
+	if err != nil {
+		slog.Error("ImageScanService: list pending scans failed", "error", err)
+		return
+	}
+	for _, b := range builds {
+		if ctx.Err() != nil {
+			return
+		}
+		s.scan(ctx, b)
+	}
+}
+
+// scan 扫描一个构建并写回结果。扫描失败记为 failed，门禁按未通过处理，可手动复扫。
+func (s *ImageScanService) scan(ctx context.Context, build *domain.Build) {
+	build.Scan = &domain.ImageScan{Status: domain.ScanStatusRunning}
+	if err := s.buildRepo.Update(ctx, build); err != nil {
+		slog.Error("ImageScanService: mark scan running failed", "build_id", build.ID, "error", err)
+		return
+	}
+
+	scanCtx := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		scanCtx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	report, err := s.scanner.Scan(scanCtx, build.ID, build.ImageRefWithDigest())
+	now := time.Now()
+	result := &domain.ImageScan{Status: domain.ScanStatusSucceeded, ScannedAt: &now}
+	if err != nil {
+		result.Status = domain.ScanStatusFailed
+		result.Message = err.Error()
+		slog.Warn("image scan failed", "build_id", build.ID, "image", build.ImageTag, "error", err)
+	} else {
+		result.Scanner = report.Scanner
+		result.Counts = report.Counts
+		result.ReportURL = report.ReportURL
+		slog.Info("image scanned", "build_id", build.ID, "image", build.ImageTag, "counts", report.Counts)
+	}
+	metrics.ImageScansTotal.WithLabelValues(string(result.Status)).Inc()
+
+	// 扫描期间构建可能被更新（如被清理），重新读出再写回扫描结果
+	if latest, err := s.buildRepo.FindByID(ctx, build.ID); err == nil {
+		build = latest
+	}
+	build.Scan = result
+	build.UpdatedAt = now
+	if err := s.buildRepo.Update(ctx, build); err != nil {
+		slog.Error("ImageScanService: save scan result failed", "build_id", build.ID, "error", err)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+type stubScanner struct {
+	counts map[string]int
+	err    error
+	images []string
+}
+
+func (s *stubScanner) Scan(_ context.Context, _, image string) (*port.ScanReport, error) {
+	s.images = append(s.images, image)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &port.ScanReport{Scanner: "trivy", Counts: s.counts}, nil
+}
+
+func newScanTestService(scanner *stubScanner) (*ImageScanService, *stubBuildRepo) {
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b1": {
+			ID: "b1", ImageRepoName: "api", ImageTag: "harbor.local/inner-bot/api:1.0.0.1", Digest: "sha256:aaa",
+			Status: domain.BuildStatusSucceeded,
+		},
+	}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{
+		Name:       "api",
+		ScanPolicy: &domain.ImageScanPolicy{Enabled: true, BlockSeverity: domain.SeverityHigh},
+	}}
+	return NewImageScanService(imageRepoRepo, buildRepo, scanner, time.Minute, time.Minute), buildRepo
+}
+
+func TestImageScanService_ScanPending(t *testing.T) {
+	scanner := &stubScanner{counts: map[string]int{domain.SeverityCritical: 2}}
+	svc, buildRepo := newScanTestService(scanner)
+	ctx := context.Background()
+
+	build, err := svc.RequestScan(ctx, "api", "b1")
+	if err != nil {
+		t.Fatalf("RequestScan() error = %v", err)
+	}
+	if build.Scan.Status != domain.ScanStatusPending {
+		t.Errorf("scan status = %s, want pending", build.Scan.Status)
+	}
+
+	svc.scanPending(ctx)
+
+	scan := buildRepo.builds["b1"].Scan
+	if scan.Status != domain.ScanStatusSucceeded || scan.Counts[domain.SeverityCritical] != 2 || scan.ScannedAt == nil {
+		t.Errorf("scan = %+v", scan)
+	}
+	if len(scanner.images) != 1 || scanner.images[0] != "harbor.local/inner-bot/api:1.0.0.1@sha256:aaa" {
+		t.Errorf("scanned images = %v, want digest-pinned image", scanner.images)
+	}
+
+	if _, err := svc.RequestScan(ctx, "other", "b1"); !errors.Is(err, domain.ErrBuildNotFound) {
+		t.Errorf("RequestScan() of other image repo error = %v, want ErrBuildNotFound", err)
+	}
+}
+
+func TestImageScanService_ScanFailed(t *testing.T) {
+	svc, buildRepo := newScanTestService(&stubScanner{err: errors.New("scan job trivy-b1 failed")})
+	ctx := context.Background()
+
+	// 进程重启：中断的 running 扫描重新排队
+	buildRepo.builds["b1"].Scan = &domain.ImageScan{Status: domain.ScanStatusRunning}
+	svc.requeueInterrupted(ctx)
+	svc.scanPending(ctx)
+
+	scan := buildRepo.builds["b1"].Scan
+	if scan.Status != domain.ScanStatusFailed || scan.Message == "" {
+		t.Errorf("scan = %+v, want failed with message", scan)
+	}
+}
+
+func TestCreateRelease_ScanGate(t *testing.T) {
+	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp", ImageRepoName: "myapp", Port: 8080}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{
+		Name:       "myapp",
+		Registry:   "harbor.local/inner-bot/myapp",
+		ScanPolicy: &domain.ImageScanPolicy{Enabled: true, BlockSeverity: domain.SeverityCritical},
+	}}
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b1": {
+			ID: "b1", ImageTag: "harbor.local/inner-bot/myapp:1.0.0.3", Channel: domain.ChannelStable, Status: domain.BuildStatusSucceeded,
+			Scan: &domain.ImageScan{Status: domain.ScanStatusSucceeded, Counts: map[string]int{domain.SeverityCritical: 1}},
+		},
+	}}
+	svc := NewReleaseService(appRepo, imageRepoRepo, buildRepo, newLaneReleaseRepo(), nil, nil, &stubDeployer{}, nil, ReleaseServiceConfig{})
+	ctx := context.Background()
+
+	_, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "1.0.0.3"})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("prod release with vulnerable image error = %v, want ErrInvalidInput", err)
+	}
+
+	// 非 prod 类 lane 不拦截
+	if _, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "coe-x", ImageTag: "1.0.0.3"}); err != nil {
+		t.Errorf("non-prod release error = %v", err)
+	}
+
+	// 填写豁免原因后放行
+	if _, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "1.0.0.3", ScanOverrideReason: "CVE 不影响本服务，已评估",
+	}); err != nil {
+		t.Errorf("release with override reason error = %v", err)
+	}
+}
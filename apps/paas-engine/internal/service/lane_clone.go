@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// CloneLaneRequest 描述一次 lane 克隆：把 From 上的 release 复制到目标 lane。
+type CloneLaneRequest struct {
+	From          string
+	Apps          []string // 为空时复制 From 上的全部 release
+	DynamicConfig bool     // 同时复制 From 的动态配置覆盖
+	ConfigBundles bool     // 同时复制各配置包里 From 的 lane 覆盖
+}
+
+// LaneCloneResult 是单个 app 的克隆结果：成功时带新 release 和部署操作，失败时带原因。
+type LaneCloneResult struct {
+	App         string          `json:"app"`
+	Release     *domain.Release `json:"release,omitempty"`
+	OperationID string          `json:"operation_id,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+type LaneCloneReport struct {
+	Lane            string            `json:"lane"`
+	From            string            `json:"from"`
+	Releases        []LaneCloneResult `json:"releases"`
+	BundleOverrides []string          `json:"bundle_overrides,omitempty"` // 复制了 lane 覆盖的配置包
+	DynamicConfigs  []string          `json:"dynamic_configs,omitempty"`  // 复制的动态配置 key
+	Errors          []string          `json:"errors,omitempty"`           // 配置复制失败
+}
+
+// CloneLane 把 From 上的 release（镜像、envs、副本数、资源、探针、HPA）复制到 lane。
+// 配置先于 release 复制，部署时解析到的配置包 envs 已包含新 lane 的覆盖。
+// 每个 app 走和 StartCreateOrUpdateRelease 相同的校验（ClassifyLane、AllowedLaneClasses、
+// RequiredKeys、prod 镜像门禁），互不影响，结果逐个报告；部署本身在后台进行。
+func (s *LaneService) CloneLane(ctx context.Context, lane string, req CloneLaneRequest) (*LaneCloneReport, error) {
+	if req.From == "" {
+		return nil, fmt.Errorf("%w: source lane (from) is required", domain.ErrInvalidInput)
+	}
+	if req.From == lane {
+		return nil, fmt.Errorf("%w: cannot clone lane %q onto itself", domain.ErrInvalidInput, lane)
+	}
+	if _, err := domain.ClassifyLane(lane, s.cfg.LegacyLaneWhitelist); err != nil {
+		return nil, fmt.Errorf("lane clone rejected: %w", err)
+	}
+
+	sources, err := s.releaseSvc.ListReleases(ctx, "", req.From)
+	if err != nil {
+		return nil, err
+	}
+	byApp := make(map[string]*domain.Release, len(sources))
+	for _, r := range sources {
+		byApp[r.AppName] = r
+	}
+	apps := req.Apps
+	if len(apps) == 0 {
+		apps = slices.Sorted(maps.Keys(byApp))
+	}
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("%w: lane %q has no releases to clone", domain.ErrInvalidInput, req.From)
+	}
+
+	report := &LaneCloneReport{Lane: lane, From: req.From, Releases: []LaneCloneResult{}}
+	if req.ConfigBundles {
+		if report.BundleOverrides, err = s.configBundleSvc.CopyLaneOverrides(ctx, req.From, lane); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("config bundles: %v", err))
+		}
+	}
+	if req.DynamicConfig {
+		if report.DynamicConfigs, err = s.dynamicConfigSvc.CopyLane(ctx, req.From, lane); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("dynamic configs: %v", err))
+		}
+	}
+
+	for _, app := range apps {
+		result := LaneCloneResult{App: app}
+		src, ok := byApp[app]
+		if !ok {
+			result.Error = fmt.Sprintf("no release of %s in lane %s", app, req.From)
+			report.Releases = append(report.Releases, result)
+			continue
+		}
+		release, op, err := s.releaseSvc.StartCreateOrUpdateRelease(ctx, CreateReleaseRequest{
+			AppName:     app,
+			Lane:        lane,
+			ImageTag:    domain.ImageTagOf(src.Image),
+			Replicas:    src.Replicas,
+			Envs:        maps.Clone(src.Envs),
+			Version:     src.Version,
+			Resources:   src.Resources,
+			Probes:      src.Probes,
+			Autoscaling: src.Autoscaling,
+		})
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Release = release
+			result.OperationID = op.ID
+		}
+		report.Releases = append(report.Releases, result)
+	}
+
+	slog.Info("lane cloned", "lane", lane, "from", req.From, "apps", len(apps))
+	return report, nil
+}
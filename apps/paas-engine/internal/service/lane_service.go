@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+type LaneServiceConfig struct {
+	ExpiryInterval      time.Duration // 到期检查间隔，<=0 关闭自动回收
+	LegacyLaneWhitelist []string
+}
+
+// LaneService 管理登记的 lane，并负责把一条 lane 在各处留下的东西一次性清掉：
+// release、配置包 lane 覆盖、动态配置、网关规则、CI 配置和休眠策略。
+type LaneService struct {
+	laneRepo         port.LaneRepository
+	releaseSvc       *ReleaseService
+	configBundleSvc  *ConfigBundleService
+	dynamicConfigSvc *DynamicConfigService
+	gatewayRuleSvc   *GatewayRuleService
+	ciConfigRepo     port.CIConfigRepository
+	sleepPolicyRepo  port.LaneSleepPolicyRepository
+	cfg              LaneServiceConfig
+	now              func() time.Time
+}
+
+func NewLaneService(
+	laneRepo port.LaneRepository,
+	releaseSvc *ReleaseService,
+	configBundleSvc *ConfigBundleService,
+	dynamicConfigSvc *DynamicConfigService,
+	gatewayRuleSvc *GatewayRuleService,
+	ciConfigRepo port.CIConfigRepository,
+	sleepPolicyRepo port.LaneSleepPolicyRepository,
+	cfg LaneServiceConfig,
+) *LaneService {
+	return &LaneService{
+		laneRepo:         laneRepo,
+		releaseSvc:       releaseSvc,
+		configBundleSvc:  configBundleSvc,
+		dynamicConfigSvc: dynamicConfigSvc,
+		gatewayRuleSvc:   gatewayRuleSvc,
+		ciConfigRepo:     ciConfigRepo,
+		sleepPolicyRepo:  sleepPolicyRepo,
+		cfg:              cfg,
+		now:              time.Now,
+	}
+}
+
+type CreateLaneRequest struct {
+	Name        string `json:"name"`
+	Owner       string `json:"owner"`
+	Description string `json:"description"`
+	TTLHours    int    `json:"ttl_hours"` // 0 表示不过期
+}
+
+func (s *LaneService) CreateLane(ctx context.Context, req CreateLaneRequest) (*domain.Lane, error) {
+	if req.TTLHours < 0 {
+		return nil, fmt.Errorf("%w: ttl_hours must not be negative", domain.ErrInvalidInput)
+	}
+	lane, err := domain.NewLane(req.Name, req.Owner, req.Description, time.Duration(req.TTLHours)*time.Hour, s.cfg.LegacyLaneWhitelist, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.laneRepo.Save(ctx, lane); err != nil {
+		return nil, err
+	}
+	slog.Info("lane registered", "lane", lane.Name, "owner", lane.Owner, "expires_at", lane.ExpiresAt)
+	return lane, nil
+}
+
+func (s *LaneService) GetLane(ctx context.Context, name string) (*domain.Lane, error) {
+	return s.laneRepo.FindByName(ctx, name)
+}
+
+func (s *LaneService) ListLanes(ctx context.Context) ([]*domain.Lane, error) {
+	return s.laneRepo.FindAll(ctx)
+}
+
+// UpdateLane 部分更新 owner / description；ttl_hours 出现时从现在起重新计算到期时间（0 取消到期）。
+func (s *LaneService) UpdateLane(ctx context.Context, name string, body []byte) (*domain.Lane, error) {
+	lane, err := s.laneRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := ParseFields(body)
+	if err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "owner", &lane.Owner); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "description", &lane.Description); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	now := s.now()
+	if _, ok := fields["ttl_hours"]; ok {
+		var ttlHours int
+		if err := ApplyField(fields, "ttl_hours", &ttlHours); err != nil || ttlHours < 0 {
+			return nil, fmt.Errorf("%w: ttl_hours must be a non-negative integer", domain.ErrInvalidInput)
+		}
+		if err := lane.SetTTL(time.Duration(ttlHours)*time.Hour, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := lane.Validate(); err != nil {
+		return nil, err
+	}
+	lane.UpdatedAt = now
+	if err := s.laneRepo.Update(ctx, lane); err != nil {
+		return nil, err
+	}
+	return lane, nil
+}
+
+// LaneTeardownReport 记录一次 lane 回收在各处清掉了什么。
+// Errors 非空时 lane 记录保留，下次删除 / 到期检查会重试剩下的部分。
+type LaneTeardownReport struct {
+	Lane                string   `json:"lane"`
+	Releases            []string `json:"releases"`              // 删除的 release（app 名）
+	BundleOverrides     []string `json:"bundle_overrides"`      // 去掉了该 lane 覆盖的配置包
+	DynamicConfigs      []string `json:"dynamic_configs"`       // 删除的动态配置 key
+	GatewayRulesDeleted []string `json:"gateway_rules_deleted"` // 整条删除的网关规则
+	GatewayRulesUpdated []string `json:"gateway_rules_updated"` // 去掉了该 lane target 的网关规则
+	CIConfigArchived    bool     `json:"ci_config_archived"`
+	Completed           bool     `json:"completed"`
+	Errors              []string `json:"errors,omitempty"`
+}
+
+// TeardownLane 回收一条登记过的 lane。先摘网关流量，再删 release 和各处配置，
+// 全部成功才删除 lane 记录。prod 类 lane 不允许回收。
+func (s *LaneService) TeardownLane(ctx context.Context, name, reason string) (*LaneTeardownReport, error) {
+	lane, err := s.laneRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if lane.Class == domain.LaneClassProd.String() {
+		return nil, fmt.Errorf("%w: prod lane %q cannot be torn down", domain.ErrInvalidInput, name)
+	}
+
+	report := &LaneTeardownReport{Lane: name}
+	fail := func(step string, err error) {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	if purge, err := s.gatewayRuleSvc.PurgeLane(ctx, name, fmt.Sprintf("lane %s teardown: %s", name, reason)); err != nil {
+		fail("gateway rules", err)
+	} else {
+		report.GatewayRulesDeleted = purge.Deleted
+		report.GatewayRulesUpdated = purge.Updated
+	}
+	report.Releases, err = s.releaseSvc.DeleteLaneReleases(ctx, name)
+	if err != nil {
+		fail("releases", err)
+	}
+	report.BundleOverrides, err = s.configBundleSvc.PurgeLaneOverrides(ctx, name)
+	if err != nil {
+		fail("config bundles", err)
+	}
+	report.DynamicConfigs, err = s.dynamicConfigSvc.DeleteLane(ctx, name)
+	if err != nil {
+		fail("dynamic configs", err)
+	}
+	if err := s.archiveCIConfig(ctx, name); err != nil {
+		fail("ci config", err)
+	} else {
+		report.CIConfigArchived = true
+	}
+	if err := s.sleepPolicyRepo.Delete(ctx, name); err != nil {
+		fail("sleep policy", err)
+	}
+
+	if len(report.Errors) == 0 {
+		if err := s.laneRepo.Delete(ctx, name); err != nil {
+			fail("lane", err)
+		}
+	}
+	report.Completed = len(report.Errors) == 0
+	if report.Completed {
+		slog.Info("lane torn down", "lane", name, "reason", reason, "releases", len(report.Releases))
+	} else {
+		slog.Error("lane teardown incomplete", "lane", name, "reason", reason, "errors", report.Errors)
+	}
+	return report, nil
+}
+
+// archiveCIConfig 归档 lane 的 CI 配置（与 UnregisterCI 一致），没有则跳过。
+func (s *LaneService) archiveCIConfig(ctx context.Context, lane string) error {
+	cfg, err := s.ciConfigRepo.FindByLane(ctx, lane)
+	if errors.Is(err, domain.ErrCIConfigNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cfg.Status = "archived"
+	cfg.UpdatedAt = s.now()
+	return s.ciConfigRepo.Update(ctx, cfg)
+}
+
+// Start 启动到期回收循环，ctx 取消时退出。
+func (s *LaneService) Start(ctx context.Context) {
+	if s.cfg.ExpiryInterval <= 0 {
+		return
+	}
+	slog.Info("lane expiry started", "interval", s.cfg.ExpiryInterval)
+
+	s.expireAndLog(ctx)
+
+	ticker := time.NewTicker(s.cfg.ExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("lane expiry stopped")
+			return
+		case <-ticker.C:
+			s.expireAndLog(ctx)
+		}
+	}
+}
+
+func (s *LaneService) expireAndLog(ctx context.Context) {
+	if _, err := s.ExpireOnce(ctx); err != nil {
+		slog.Error("LaneService: expiry check failed", "error", err)
+	}
+}
+
+// ExpireOnce 回收所有已到期的 lane，返回每条 lane 的回收结果。
+func (s *LaneService) ExpireOnce(ctx context.Context) ([]*LaneTeardownReport, error) {
+	lanes, err := s.laneRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	reports := []*LaneTeardownReport{}
+	for _, lane := range lanes {
+		if !lane.Expired(now) {
+			continue
+		}
+		report, err := s.TeardownLane(ctx, lane.Name, "expired")
+		if err != nil {
+			slog.Error("LaneService: failed to tear down expired lane", "lane", lane.Name, "error", err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
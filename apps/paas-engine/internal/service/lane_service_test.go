@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+type stubLaneRepo struct {
+	lanes map[string]*domain.Lane
+}
+
+func (r *stubLaneRepo) Save(_ context.Context, lane *domain.Lane) error {
+	if _, ok := r.lanes[lane.Name]; ok {
+		return domain.ErrAlreadyExists
+	}
+	r.lanes[lane.Name] = lane
+	return nil
+}
+func (r *stubLaneRepo) FindByName(_ context.Context, name string) (*domain.Lane, error) {
+	l, ok := r.lanes[name]
+	if !ok {
+		return nil, domain.ErrLaneNotFound
+	}
+	cp := *l
+	return &cp, nil
+}
+func (r *stubLaneRepo) FindAll(_ context.Context) ([]*domain.Lane, error) {
+	var out []*domain.Lane
+	for _, l := range r.lanes {
+		cp := *l
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+func (r *stubLaneRepo) Update(_ context.Context, lane *domain.Lane) error {
+	r.lanes[lane.Name] = lane
+	return nil
+}
+func (r *stubLaneRepo) Delete(_ context.Context, name string) error {
+	delete(r.lanes, name)
+	return nil
+}
+
+type stubCIConfigRepo struct {
+	configs map[string]*domain.CIConfig // key = lane
+}
+
+func (r *stubCIConfigRepo) Save(_ context.Context, cfg *domain.CIConfig) error {
+	r.configs[cfg.Lane] = cfg
+	return nil
+}
+func (r *stubCIConfigRepo) FindByID(_ context.Context, id string) (*domain.CIConfig, error) {
+	for _, c := range r.configs {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, domain.ErrCIConfigNotFound
+}
+func (r *stubCIConfigRepo) FindByLane(_ context.Context, lane string) (*domain.CIConfig, error) {
+	c, ok := r.configs[lane]
+	if !ok || c.Status != "active" {
+		return nil, domain.ErrCIConfigNotFound
+	}
+	return c, nil
+}
+func (r *stubCIConfigRepo) FindByBranch(_ context.Context, branch string) (*domain.CIConfig, error) {
+	for _, c := range r.configs {
+		if c.Branch == branch && c.Status == "active" {
+			return c, nil
+		}
+	}
+	return nil, domain.ErrCIConfigNotFound
+}
+func (r *stubCIConfigRepo) FindActive(_ context.Context) ([]*domain.CIConfig, error) {
+	var out []*domain.CIConfig
+	for _, c := range r.configs {
+		if c.Status == "active" {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+func (r *stubCIConfigRepo) Update(_ context.Context, cfg *domain.CIConfig) error {
+	r.configs[cfg.Lane] = cfg
+	return nil
+}
+func (r *stubCIConfigRepo) Delete(_ context.Context, id string) error {
+	for lane, c := range r.configs {
+		if c.ID == id {
+			delete(r.configs, lane)
+		}
+	}
+	return nil
+}
+
+type laneFixture struct {
+	svc         *LaneService
+	lanes       *stubLaneRepo
+	releaseRepo *laneReleaseRepo
+	bundles     *stubConfigBundleRepo
+	dynConfigs  *stubDynamicConfigRepo
+	rules       *stubGatewayRuleRepo
+	ciConfigs   *stubCIConfigRepo
+	policies    *stubLaneSleepPolicyRepo
+	opRepo      *stubOperationRepo
+}
+
+func gatewayRule(name, requestLane string, targets ...domain.GatewayTarget) *domain.GatewayRule {
+	return &domain.GatewayRule{
+		Name: name, Enabled: true, Priority: 100, PathPrefix: "/api/" + name + "/", RequestLane: requestLane,
+		Match:   domain.GatewayMatch{PathPrefix: "/api/" + name + "/", RequestLane: requestLane},
+		Targets: targets,
+	}
+}
+
+func newLaneFixture(releases ...*domain.Release) *laneFixture {
+	apps := &multiAppRepo{byName: map[string]*domain.App{
+		"api":    {Name: "api", ImageRepoName: "api", Port: 8080},
+		"worker": {Name: "worker", ImageRepoName: "api"},
+		"proxy":  {Name: "proxy", ImageRepoName: "api", AllowedLaneClasses: []string{"prod"}},
+	}}
+	f := &laneFixture{
+		lanes:       &stubLaneRepo{lanes: make(map[string]*domain.Lane)},
+		releaseRepo: newLaneReleaseRepo(releases...),
+		bundles:     newStubConfigBundleRepo(),
+		dynConfigs:  newStubDynamicConfigRepo(),
+		rules:       newStubGatewayRuleRepo(),
+		ciConfigs:   &stubCIConfigRepo{configs: make(map[string]*domain.CIConfig)},
+		policies:    &stubLaneSleepPolicyRepo{policies: make(map[string]*domain.LaneSleepPolicy)},
+		opRepo:      newStubOperationRepo(),
+	}
+	deployer := &scaleDeployer{scaled: make(map[string][]int32)}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "api", Registry: "harbor.local/inner-bot/api"}}
+	releaseSvc := NewReleaseService(apps, imageRepoRepo, &stubBuildRepo{}, f.releaseRepo, nil, f.opRepo, deployer, nil, ReleaseServiceConfig{})
+	f.svc = NewLaneService(
+		f.lanes,
+		releaseSvc,
+		NewConfigBundleService(f.bundles, apps, f.releaseRepo, ConfigBundleServiceConfig{}),
+		NewDynamicConfigService(f.dynConfigs),
+		NewGatewayRuleService(f.rules),
+		f.ciConfigs,
+		f.policies,
+		LaneServiceConfig{},
+	)
+	return f
+}
+
+func TestCreateLane(t *testing.T) {
+	f := newLaneFixture()
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	f.svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	lane, err := f.svc.CreateLane(ctx, CreateLaneRequest{Name: "coe-feat", Owner: "alice", TTLHours: 48})
+	if err != nil {
+		t.Fatalf("CreateLane: %v", err)
+	}
+	if lane.Class != "coe" || lane.ExpiresAt == nil || !lane.ExpiresAt.Equal(now.Add(48*time.Hour)) {
+		t.Errorf("lane = %+v", lane)
+	}
+	if _, err := f.svc.CreateLane(ctx, CreateLaneRequest{Name: "coe-feat", Owner: "bob"}); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Errorf("duplicate lane: expected ErrAlreadyExists, got %v", err)
+	}
+	if _, err := f.svc.CreateLane(ctx, CreateLaneRequest{Name: "prod", Owner: "ops", TTLHours: 1}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("prod lane with ttl: expected ErrInvalidInput, got %v", err)
+	}
+	if _, err := f.svc.CreateLane(ctx, CreateLaneRequest{Name: "feat", Owner: "alice"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("unprefixed lane: expected ErrInvalidInput, got %v", err)
+	}
+
+	updated, err := f.svc.UpdateLane(ctx, "coe-feat", []byte(`{"ttl_hours":0,"description":"auth rework"}`))
+	if err != nil {
+		t.Fatalf("UpdateLane: %v", err)
+	}
+	if updated.ExpiresAt != nil || updated.Description != "auth rework" || updated.Owner != "alice" {
+		t.Errorf("updated lane = %+v", updated)
+	}
+}
+
+func TestTeardownLane(t *testing.T) {
+	f := newLaneFixture(
+		deployedRelease("r1", "api", "coe-feat", time.Now()),
+		deployedRelease("r2", "worker", "coe-feat", time.Now()),
+		deployedRelease("r3", "api", "prod", time.Now()),
+	)
+	ctx := context.Background()
+	if _, err := f.svc.CreateLane(ctx, CreateLaneRequest{Name: "coe-feat", Owner: "alice"}); err != nil {
+		t.Fatalf("CreateLane: %v", err)
+	}
+	f.bundles.bundles["redis"] = &domain.ConfigBundle{Name: "redis", LaneOverrides: map[string]map[string]string{
+		"coe-feat": {"HOST": "redis-feat"},
+		"coe-keep": {"HOST": "redis-keep"},
+	}}
+	f.dynConfigs.configs[compositeKey("flag", "coe-feat")] = &domain.DynamicConfig{Key: "flag", Lane: "coe-feat", Value: "on"}
+	f.dynConfigs.configs[compositeKey("flag", "prod")] = &domain.DynamicConfig{Key: "flag", Lane: "prod", Value: "off"}
+	f.rules.rules["split"] = gatewayRule("split", "",
+		domain.GatewayTarget{Service: "api", Lane: "prod", Port: 8080, Weight: 90},
+		domain.GatewayTarget{Service: "api", Lane: "coe-feat", Port: 8080, Weight: 10})
+	f.rules.rules["feat-only"] = gatewayRule("feat-only", "coe-feat",
+		domain.GatewayTarget{Service: "api", Lane: "prod", Port: 8080, Weight: 100})
+	f.rules.rules["untouched"] = gatewayRule("untouched", "",
+		domain.GatewayTarget{Service: "api", Lane: "prod", Port: 8080, Weight: 100})
+	f.ciConfigs.configs["coe-feat"] = &domain.CIConfig{ID: "ci-1", Lane: "coe-feat", Status: "active"}
+	f.policies.policies["coe-feat"] = &domain.LaneSleepPolicy{Lane: "coe-feat", IdleHours: 4}
+
+	report, err := f.svc.TeardownLane(ctx, "coe-feat", "manual")
+	if err != nil {
+		t.Fatalf("TeardownLane: %v", err)
+	}
+	if !report.Completed || len(report.Errors) > 0 {
+		t.Fatalf("teardown incomplete: %v", report.Errors)
+	}
+
+	slices.Sort(report.Releases)
+	if !slices.Equal(report.Releases, []string{"api", "worker"}) {
+		t.Errorf("releases = %v", report.Releases)
+	}
+	if rels, _ := f.releaseRepo.FindAll(ctx, "", ""); len(rels) != 1 || rels[0].Lane != "prod" {
+		t.Errorf("remaining releases = %v", rels)
+	}
+	if _, ok := f.bundles.bundles["redis"].LaneOverrides["coe-feat"]; ok {
+		t.Error("bundle lane override not removed")
+	}
+	if _, ok := f.bundles.bundles["redis"].LaneOverrides["coe-keep"]; !ok {
+		t.Error("other lane override must be kept")
+	}
+	if len(f.dynConfigs.configs) != 1 {
+		t.Errorf("dynamic configs left = %d, want only prod", len(f.dynConfigs.configs))
+	}
+	if _, ok := f.rules.rules["feat-only"]; ok {
+		t.Error("rule matching the lane should be deleted")
+	}
+	split := f.rules.rules["split"]
+	if len(split.Targets) != 1 || split.Targets[0].Lane != "prod" || split.Targets[0].Weight != 100 {
+		t.Errorf("split targets = %+v", split.Targets)
+	}
+	if len(f.rules.snapshots) != 1 {
+		t.Errorf("expected a single snapshot for the purge, got %d", len(f.rules.snapshots))
+	}
+	if f.ciConfigs.configs["coe-feat"].Status != "archived" {
+		t.Errorf("ci config status = %q", f.ciConfigs.configs["coe-feat"].Status)
+	}
+	if _, ok := f.policies.policies["coe-feat"]; ok {
+		t.Error("sleep policy not removed")
+	}
+	if _, err := f.svc.GetLane(ctx, "coe-feat"); !errors.Is(err, domain.ErrLaneNotFound) {
+		t.Errorf("lane record should be deleted, got %v", err)
+	}
+}
+
+func TestTeardownLane_RejectsProd(t *testing.T) {
+	f := newLaneFixture()
+	ctx := context.Background()
+	if _, err := f.svc.CreateLane(ctx, CreateLaneRequest{Name: "prod", Owner: "ops"}); err != nil {
+		t.Fatalf("CreateLane: %v", err)
+	}
+	if _, err := f.svc.TeardownLane(ctx, "prod", "manual"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestExpireOnce(t *testing.T) {
+	f := newLaneFixture(
+		deployedRelease("r1", "api", "coe-old", time.Now()),
+		deployedRelease("r2", "api", "coe-new", time.Now()),
+	)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	f.svc.now = func() time.Time { return now }
+	for _, req := range []CreateLaneRequest{
+		{Name: "coe-old", Owner: "alice", TTLHours: 1},
+		{Name: "coe-new", Owner: "bob", TTLHours: 48},
+		{Name: "ppe-forever", Owner: "carol"},
+	} {
+		if _, err := f.svc.CreateLane(ctx, req); err != nil {
+			t.Fatalf("CreateLane(%s): %v", req.Name, err)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	reports, err := f.svc.ExpireOnce(ctx)
+	if err != nil {
+		t.Fatalf("ExpireOnce: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Lane != "coe-old" || !reports[0].Completed {
+		t.Fatalf("reports = %+v", reports)
+	}
+	if _, err := f.releaseRepo.FindByID(ctx, "r1"); !errors.Is(err, domain.ErrReleaseNotFound) {
+		t.Errorf("expired lane release should be deleted, got %v", err)
+	}
+	if _, err := f.releaseRepo.FindByID(ctx, "r2"); err != nil {
+		t.Errorf("unexpired lane release should stay: %v", err)
+	}
+	if lanes, _ := f.svc.ListLanes(ctx); len(lanes) != 2 {
+		t.Errorf("lanes left = %d, want 2", len(lanes))
+	}
+}
+
+func TestCloneLane(t *testing.T) {
+	src := deployedRelease("r1", "api", "coe-src", time.Now())
+	src.Image = "harbor.local/inner-bot/api:1.2.3.4"
+	src.Replicas = 2
+	src.Envs = map[string]string{"DEBUG": "1"}
+	f := newLaneFixture(
+		src,
+		deployedRelease("r2", "worker", "coe-src", time.Now()),
+		deployedRelease("r3", "proxy", "coe-src", time.Now()),
+	)
+	f.bundles.bundles["redis"] = &domain.ConfigBundle{Name: "redis", LaneOverrides: map[string]map[string]string{
+		"coe-src": {"HOST": "redis-src"},
+	}}
+	f.dynConfigs.configs[compositeKey("flag", "coe-src")] = &domain.DynamicConfig{Key: "flag", Lane: "coe-src", Value: "on"}
+	ctx := context.Background()
+
+	report, err := f.svc.CloneLane(ctx, "coe-copy", CloneLaneRequest{
+		From:          "coe-src",
+		Apps:          []string{"api", "proxy", "missing"},
+		DynamicConfig: true,
+		ConfigBundles: true,
+	})
+	if err != nil {
+		t.Fatalf("CloneLane: %v", err)
+	}
+	if len(report.Releases) != 3 {
+		t.Fatalf("expected 3 results, got %+v", report.Releases)
+	}
+
+	api := report.Releases[0]
+	if api.Error != "" || api.OperationID == "" {
+		t.Fatalf("api clone failed: %+v", api)
+	}
+	if done := waitOperationDone(t, f.opRepo, api.OperationID); done.Status != domain.ReleaseOperationSucceeded {
+		t.Fatalf("clone deploy = %q / %q", done.Status, done.Message)
+	}
+	cloned, err := f.releaseRepo.FindByAppAndLane(ctx, "api", "coe-copy")
+	if err != nil {
+		t.Fatalf("cloned release: %v", err)
+	}
+	if cloned.Image != src.Image || cloned.Replicas != 2 || cloned.Envs["DEBUG"] != "1" {
+		t.Errorf("cloned release = %+v", cloned)
+	}
+
+	if proxy := report.Releases[1]; proxy.Error == "" || proxy.Release != nil {
+		t.Errorf("proxy must be rejected by AllowedLaneClasses: %+v", proxy)
+	}
+	if missing := report.Releases[2]; missing.Error == "" {
+		t.Errorf("missing app must be reported: %+v", missing)
+	}
+
+	if got := f.bundles.bundles["redis"].LaneOverrides["coe-copy"]["HOST"]; got != "redis-src" {
+		t.Errorf("bundle override HOST = %q", got)
+	}
+	if c, ok := f.dynConfigs.configs[compositeKey("flag", "coe-copy")]; !ok || c.Value != "on" {
+		t.Errorf("dynamic config not copied: %+v", c)
+	}
+}
+
+func TestCloneLane_RejectsBadInput(t *testing.T) {
+	f := newLaneFixture(deployedRelease("r1", "api", "coe-src", time.Now()))
+	ctx := context.Background()
+	for name, tc := range map[string]struct {
+		lane string
+		req  CloneLaneRequest
+	}{
+		"no source":     {"coe-copy", CloneLaneRequest{}},
+		"same lane":     {"coe-src", CloneLaneRequest{From: "coe-src"}},
+		"bad lane name": {"feat", CloneLaneRequest{From: "coe-src"}},
+		"empty source":  {"coe-copy", CloneLaneRequest{From: "coe-empty"}},
+	} {
+		if _, err := f.svc.CloneLane(ctx, tc.lane, tc.req); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+}
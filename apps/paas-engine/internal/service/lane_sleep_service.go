@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+type LaneSleepServiceConfig struct {
+	DefaultIdleHours    int            // 未单独配置策略的 coe/ppe lane 的空闲休眠小时数，0 关闭
+	Location            *time.Location // Schedule 的时区
+	Interval            time.Duration  // 检查间隔，<=0 关闭自动休眠
+	LegacyLaneWhitelist []string
+}
+
+// LaneSleepService 管理 lane 休眠策略，并周期性把空闲 / 进入休眠时段的 coe、ppe lane
+// 缩到 0。休眠与唤醒本身由 ReleaseService 执行。
+type LaneSleepService struct {
+	releaseSvc *ReleaseService
+	policyRepo port.LaneSleepPolicyRepository
+	cfg        LaneSleepServiceConfig
+	now        func() time.Time
+}
+
+func NewLaneSleepService(releaseSvc *ReleaseService, policyRepo port.LaneSleepPolicyRepository, cfg LaneSleepServiceConfig) *LaneSleepService {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	return &LaneSleepService{releaseSvc: releaseSvc, policyRepo: policyRepo, cfg: cfg, now: time.Now}
+}
+
+// LaneSleepStatus 是某条 lane 的休眠策略与当前状态。
+type LaneSleepStatus struct {
+	Lane         string                  `json:"lane"`
+	Policy       *domain.LaneSleepPolicy `json:"policy"`
+	IsDefault    bool                    `json:"is_default"` // 未单独配置，使用默认策略
+	Sleeping     bool                    `json:"sleeping"`
+	Releases     int                     `json:"releases"`
+	LastActivity *time.Time              `json:"last_activity,omitempty"` // 最后一次部署 / 唤醒 / 休眠
+}
+
+type SetLaneSleepPolicyRequest struct {
+	IdleHours int    `json:"idle_hours"`
+	Schedule  string `json:"schedule"`
+}
+
+func (s *LaneSleepService) GetStatus(ctx context.Context, lane string) (*LaneSleepStatus, error) {
+	if err := domain.CanLaneSleep(lane, s.cfg.LegacyLaneWhitelist); err != nil {
+		return nil, err
+	}
+	policy, isDefault, err := s.effectivePolicy(ctx, lane)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := s.releaseSvc.releaseRepo.FindAll(ctx, "", lane)
+	if err != nil {
+		return nil, err
+	}
+	status := &LaneSleepStatus{Lane: lane, Policy: policy, IsDefault: isDefault, Releases: len(releases)}
+	if last, ok := lastActivity(releases); ok {
+		status.LastActivity = &last
+	}
+	for _, r := range releases {
+		if r.Status == domain.ReleaseStatusSleeping {
+			status.Sleeping = true
+			break
+		}
+	}
+	return status, nil
+}
+
+func (s *LaneSleepService) SetPolicy(ctx context.Context, lane string, req SetLaneSleepPolicyRequest) (*domain.LaneSleepPolicy, error) {
+	if err := domain.CanLaneSleep(lane, s.cfg.LegacyLaneWhitelist); err != nil {
+		return nil, err
+	}
+	policy := &domain.LaneSleepPolicy{
+		Lane:      lane,
+		IdleHours: req.IdleHours,
+		Schedule:  req.Schedule,
+		UpdatedAt: s.now(),
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.policyRepo.Upsert(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeletePolicy 删除 lane 的单独配置，之后回到默认策略。
+func (s *LaneSleepService) DeletePolicy(ctx context.Context, lane string) error {
+	return s.policyRepo.Delete(ctx, lane)
+}
+
+// Sleep 立即休眠 lane。
+func (s *LaneSleepService) Sleep(ctx context.Context, lane string) ([]*domain.Release, error) {
+	return s.releaseSvc.SleepLane(ctx, lane, "manual")
+}
+
+// Wake 唤醒 lane，返回被唤醒的 release 及对应的后台操作。
+func (s *LaneSleepService) Wake(ctx context.Context, lane string) ([]*domain.Release, []*domain.ReleaseOperation, error) {
+	return s.releaseSvc.WakeLane(ctx, lane)
+}
+
+// Start 启动自动休眠检查循环，ctx 取消时退出。
+func (s *LaneSleepService) Start(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+	slog.Info("lane sleeper started", "interval", s.cfg.Interval, "default_idle_hours", s.cfg.DefaultIdleHours)
+
+	s.checkAndLog(ctx)
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("lane sleeper stopped")
+			return
+		case <-ticker.C:
+			s.checkAndLog(ctx)
+		}
+	}
+}
+
+func (s *LaneSleepService) checkAndLog(ctx context.Context) {
+	if err := s.CheckOnce(ctx); err != nil {
+		slog.Error("LaneSleepService: check failed", "error", err)
+	}
+}
+
+// CheckOnce 按策略休眠所有满足条件的 lane。有 release 正在部署的 lane 本轮跳过。
+func (s *LaneSleepService) CheckOnce(ctx context.Context) error {
+	releases, err := s.releaseSvc.releaseRepo.FindAll(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	policies, err := s.policyRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	byLane := make(map[string]*domain.LaneSleepPolicy, len(policies))
+	for _, p := range policies {
+		byLane[p.Lane] = p
+	}
+
+	lanes := make(map[string][]*domain.Release)
+	var order []string
+	for _, r := range releases {
+		if _, ok := lanes[r.Lane]; !ok {
+			order = append(order, r.Lane)
+		}
+		lanes[r.Lane] = append(lanes[r.Lane], r)
+	}
+
+	now := s.now()
+	for _, lane := range order {
+		if domain.CanLaneSleep(lane, s.cfg.LegacyLaneWhitelist) != nil {
+			continue
+		}
+		laneReleases := lanes[lane]
+		if !hasAwakeReleases(laneReleases) {
+			continue
+		}
+		policy, ok := byLane[lane]
+		if !ok {
+			policy = s.defaultPolicy(lane)
+		}
+		last, _ := lastActivity(laneReleases)
+		sleep, reason := policy.ShouldSleep(last, now, s.cfg.Location)
+		if !sleep {
+			continue
+		}
+		if _, err := s.releaseSvc.SleepLane(ctx, lane, reason); err != nil {
+			slog.Error("LaneSleepService: failed to sleep lane", "lane", lane, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *LaneSleepService) effectivePolicy(ctx context.Context, lane string) (*domain.LaneSleepPolicy, bool, error) {
+	policy, err := s.policyRepo.FindByLane(ctx, lane)
+	if errors.Is(err, domain.ErrLaneSleepPolicyNotFound) {
+		return s.defaultPolicy(lane), true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return policy, false, nil
+}
+
+func (s *LaneSleepService) defaultPolicy(lane string) *domain.LaneSleepPolicy {
+	return &domain.LaneSleepPolicy{Lane: lane, IdleHours: s.cfg.DefaultIdleHours}
+}
+
+// hasAwakeReleases 报告 lane 是否有可休眠的 release：全部已休眠或有部署在进行时都返回 false。
+func hasAwakeReleases(releases []*domain.Release) bool {
+	awake := false
+	for _, r := range releases {
+		switch r.Status {
+		case domain.ReleaseStatusPending:
+			return false
+		case domain.ReleaseStatusDeployed, domain.ReleaseStatusFailed:
+			awake = true
+		}
+	}
+	return awake
+}
+
+// lastActivity 返回 lane 上 release 最近一次变更时间（部署、唤醒都会刷新 UpdatedAt）。
+func lastActivity(releases []*domain.Release) (time.Time, bool) {
+	var last time.Time
+	for _, r := range releases {
+		if r.UpdatedAt.After(last) {
+			last = r.UpdatedAt
+		}
+	}
+	return last, !last.IsZero()
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// laneReleaseRepo 是线程安全、按 lane 过滤 FindAll 的内存实现，存副本避免和后台 goroutine 共享。
+type laneReleaseRepo struct {
+	mu       sync.Mutex
+	releases map[string]domain.Release
+}
+
+func newLaneReleaseRepo(releases ...*domain.Release) *laneReleaseRepo {
+	r := &laneReleaseRepo{releases: make(map[string]domain.Release)}
+	for _, rel := range releases {
+		r.releases[rel.ID] = *rel
+	}
+	return r
+}
+
+func (r *laneReleaseRepo) Save(_ context.Context, rel *domain.Release) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.releases[rel.ID] = *rel
+	return nil
+}
+func (r *laneReleaseRepo) Update(ctx context.Context, rel *domain.Release) error {
+	return r.Save(ctx, rel)
+}
+func (r *laneReleaseRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.releases, id)
+	return nil
+}
+func (r *laneReleaseRepo) FindByID(_ context.Context, id string) (*domain.Release, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rel, ok := r.releases[id]
+	if !ok {
+		return nil, domain.ErrReleaseNotFound
+	}
+	return &rel, nil
+}
+func (r *laneReleaseRepo) FindByAppAndLane(_ context.Context, appName, lane string) (*domain.Release, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rel := range r.releases {
+		if rel.AppName == appName && rel.Lane == lane {
+			return &rel, nil
+		}
+	}
+	return nil, domain.ErrReleaseNotFound
+}
+func (r *laneReleaseRepo) FindAll(_ context.Context, appName, lane string) ([]*domain.Release, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*domain.Release
+	for _, rel := range r.releases {
+		if (appName == "" || rel.AppName == appName) && (lane == "" || rel.Lane == lane) {
+			cp := rel
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+// scaleDeployer 记录每次 Scale 的目标副本数，缩容时报告线上原有 liveReplicas 个副本。
+type scaleDeployer struct {
+	stubDeployer
+	mu           sync.Mutex
+	liveReplicas int32
+	scaled       map[string][]int32
+}
+
+func (d *scaleDeployer) Scale(_ context.Context, release *domain.Release, replicas int32) (int32, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.scaled[release.ResourceName()] = append(d.scaled[release.ResourceName()], replicas)
+	return d.liveReplicas, nil
+}
+
+func (d *scaleDeployer) scalesOf(name string) []int32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]int32(nil), d.scaled[name]...)
+}
+
+type stubLaneSleepPolicyRepo struct {
+	policies map[string]*domain.LaneSleepPolicy
+}
+
+func (r *stubLaneSleepPolicyRepo) Upsert(_ context.Context, p *domain.LaneSleepPolicy) error {
+	r.policies[p.Lane] = p
+	return nil
+}
+func (r *stubLaneSleepPolicyRepo) FindByLane(_ context.Context, lane string) (*domain.LaneSleepPolicy, error) {
+	if p, ok := r.policies[lane]; ok {
+		return p, nil
+	}
+	return nil, domain.ErrLaneSleepPolicyNotFound
+}
+func (r *stubLaneSleepPolicyRepo) FindAll(_ context.Context) ([]*domain.LaneSleepPolicy, error) {
+	var out []*domain.LaneSleepPolicy
+	for _, p := range r.policies {
+		out = append(out, p)
+	}
+	return out, nil
+}
+func (r *stubLaneSleepPolicyRepo) Delete(_ context.Context, lane string) error {
+	delete(r.policies, lane)
+	return nil
+}
+
+type laneSleepFixture struct {
+	svc         *LaneSleepService
+	releaseSvc  *ReleaseService
+	releaseRepo *laneReleaseRepo
+	opRepo      *stubOperationRepo
+	deployer    *scaleDeployer
+	policies    *stubLaneSleepPolicyRepo
+}
+
+func newLaneSleepFixture(releases ...*domain.Release) *laneSleepFixture {
+	apps := &multiAppRepo{byName: map[string]*domain.App{
+		"api":    {Name: "api", ImageRepoName: "api", Port: 8080},
+		"worker": {Name: "worker", ImageRepoName: "api"},
+	}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "api", Registry: "harbor.local/inner-bot/api"}}
+	releaseRepo := newLaneReleaseRepo(releases...)
+	opRepo := newStubOperationRepo()
+	deployer := &scaleDeployer{liveReplicas: 3, scaled: make(map[string][]int32)}
+	releaseSvc := NewReleaseService(apps, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, opRepo, deployer, nil, ReleaseServiceConfig{})
+	policies := &stubLaneSleepPolicyRepo{policies: make(map[string]*domain.LaneSleepPolicy)}
+	svc := NewLaneSleepService(releaseSvc, policies, LaneSleepServiceConfig{DefaultIdleHours: 24, Location: time.UTC, Interval: time.Minute})
+	return &laneSleepFixture{svc: svc, releaseSvc: releaseSvc, releaseRepo: releaseRepo, opRepo: opRepo, deployer: deployer, policies: policies}
+}
+
+func deployedRelease(id, app, lane string, updatedAt time.Time) *domain.Release {
+	return &domain.Release{
+		ID: id, AppName: app, Lane: lane, Image: "harbor.local/inner-bot/api:1.0.0.1", Replicas: 1,
+		Status: domain.ReleaseStatusDeployed, DeployName: app + "-" + lane, UpdatedAt: updatedAt,
+	}
+}
+
+func TestSleepAndWakeLane(t *testing.T) {
+	f := newLaneSleepFixture(
+		deployedRelease("r1", "api", "coe-feat", time.Now()),
+		deployedRelease("r2", "worker", "coe-feat", time.Now()),
+		deployedRelease("r3", "api", "coe-other", time.Now()),
+	)
+	ctx := context.Background()
+
+	slept, err := f.svc.Sleep(ctx, "coe-feat")
+	if err != nil {
+		t.Fatalf("Sleep: %v", err)
+	}
+	if len(slept) != 2 {
+		t.Fatalf("expected 2 releases put to sleep, got %d", len(slept))
+	}
+	for _, id := range []string{"r1", "r2"} {
+		rel, _ := f.releaseRepo.FindByID(ctx, id)
+		if rel.Status != domain.ReleaseStatusSleeping || rel.SleptReplicas != 3 {
+			t.Errorf("%s = %q / slept %d, want sleeping / 3", id, rel.Status, rel.SleptReplicas)
+		}
+	}
+	if other, _ := f.releaseRepo.FindByID(ctx, "r3"); other.Status != domain.ReleaseStatusDeployed {
+		t.Errorf("other lane must not sleep, got %q", other.Status)
+	}
+	if got := f.deployer.scalesOf("api-coe-feat"); len(got) != 1 || got[0] != 0 {
+		t.Errorf("scales = %v, want [0]", got)
+	}
+
+	woken, ops, err := f.svc.Wake(ctx, "coe-feat")
+	if err != nil {
+		t.Fatalf("Wake: %v", err)
+	}
+	if len(woken) != 2 || len(ops) != 2 {
+		t.Fatalf("expected 2 wake operations, got %d", len(ops))
+	}
+	for _, op := range ops {
+		if op.Type != domain.ReleaseOperationWake {
+			t.Errorf("op type = %q, want wake", op.Type)
+		}
+		if done := waitOperationDone(t, f.opRepo, op.ID); done.Status != domain.ReleaseOperationSucceeded {
+			t.Fatalf("wake op = %q / %q", done.Status, done.Message)
+		}
+	}
+	rel, _ := f.releaseRepo.FindByID(ctx, "r1")
+	if rel.Status != domain.ReleaseStatusDeployed || rel.SleptReplicas != 0 {
+		t.Errorf("woken release = %q / slept %d", rel.Status, rel.SleptReplicas)
+	}
+	if got := f.deployer.scalesOf("api-coe-feat"); len(got) != 2 || got[1] != 3 {
+		t.Errorf("scales = %v, want [0 3]", got)
+	}
+}
+
+func TestSleepLane_RejectsProdLane(t *testing.T) {
+	f := newLaneSleepFixture(deployedRelease("r1", "api", "prod", time.Now()))
+	if _, err := f.svc.Sleep(context.Background(), "prod"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for prod lane, got %v", err)
+	}
+}
+
+func TestDeployWakesSleepingLane(t *testing.T) {
+	f := newLaneSleepFixture(
+		deployedRelease("r1", "api", "coe-feat", time.Now()),
+		deployedRelease("r2", "worker", "coe-feat", time.Now()),
+	)
+	ctx := context.Background()
+	if _, err := f.svc.Sleep(ctx, "coe-feat"); err != nil {
+		t.Fatalf("Sleep: %v", err)
+	}
+
+	rel, err := f.releaseSvc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "api", Lane: "coe-feat", ImageTag: "1.0.0.2"})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateRelease: %v", err)
+	}
+	if rel.Status != domain.ReleaseStatusDeployed || rel.SleptReplicas != 0 {
+		t.Errorf("deployed release = %q / slept %d", rel.Status, rel.SleptReplicas)
+	}
+
+	// 同 lane 的 worker 被后台唤醒
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		worker, _ := f.releaseRepo.FindByID(ctx, "r2")
+		if worker.Status == domain.ReleaseStatusDeployed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sibling release not woken, status %q", worker.Status)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if got := f.deployer.scalesOf("worker-coe-feat"); len(got) != 2 || got[1] != 3 {
+		t.Errorf("worker scales = %v, want [0 3]", got)
+	}
+}
+
+func TestCheckOnce_SleepsIdleLanes(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	f := newLaneSleepFixture(
+		deployedRelease("idle", "api", "coe-idle", now.Add(-25*time.Hour)),
+		deployedRelease("busy", "api", "coe-busy", now.Add(-time.Hour)),
+		deployedRelease("optout", "api", "ppe-keep", now.Add(-72*time.Hour)),
+		deployedRelease("prod", "api", "prod", now.Add(-72*time.Hour)),
+		deployedRelease("night", "api", "coe-night", now.Add(-2*time.Hour)),
+	)
+	f.svc.now = func() time.Time { return now }
+	f.policies.policies["ppe-keep"] = &domain.LaneSleepPolicy{Lane: "ppe-keep"}
+	f.policies.policies["coe-night"] = &domain.LaneSleepPolicy{Lane: "coe-night", Schedule: "11:00-13:00"}
+
+	if err := f.svc.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce: %v", err)
+	}
+	want := map[string]domain.ReleaseStatus{
+		"idle":   domain.ReleaseStatusSleeping,
+		"busy":   domain.ReleaseStatusDeployed,
+		"optout": domain.ReleaseStatusDeployed,
+		"prod":   domain.ReleaseStatusDeployed,
+		"night":  domain.ReleaseStatusSleeping,
+	}
+	for id, status := range want {
+		rel, _ := f.releaseRepo.FindByID(context.Background(), id)
+		if rel.Status != status {
+			t.Errorf("%s status = %q, want %q", id, rel.Status, status)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/google/uuid"
+)
+
+// instanceID 标识当前 paas-engine 进程，作为租约持有者写进流程记录：
+// 主机名（Pod 名）便于排查，随机后缀区分同一个 Pod 里重启前后的进程。
+var instanceID = func() string {
+	host, _ := os.Hostname()
+	return host + "-" + uuid.New().String()[:8]
+}()
+
+// leaseRenewInterval 是续约间隔，测试里改小。
+var leaseRenewInterval = domain.LeaseRenewInterval
+
+// holdLease 在后台定期调用 renew 续约，直到返回的 stop 被调用。续约发现租约已被其他实例接管
+// 时调用 lost 并停止续约；DB 暂时不可用只记日志，租约过期前恢复即可。
+func holdLease(kind, id string, renew func(ctx context.Context, now time.Time) (bool, error), lost func()) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(leaseRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			held, err := renew(ctx, time.Now())
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				slog.Error("failed to renew lease", "kind", kind, "id", id, "error", err)
+				continue
+			}
+			if !held {
+				slog.Warn("lease taken over by another instance", "kind", kind, "id", id)
+				lost()
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// logPollInterval 是没有可跟随的 Pod 时重新读取日志、检查是否结束的间隔。
+var logPollInterval = 2 * time.Second
+
+// LogLine 是流式日志的一行。Offset 是它在完整日志中的行号（从 0 开始），断线后从 Offset+1 续传。
+type LogLine struct {
+	Offset int    `json:"offset"`
+	Text   string `json:"text"`
+}
+
+// logSource 描述一份可跟随的日志：有 Pod 时跟随 Pod 日志，Pod 不在了读历史日志（Loki → DB）。
+type logSource struct {
+	follow   func(ctx context.Context) (io.ReadCloser, error) // 跟随 Pod 日志，没有 Pod 时返回 nil
+	history  func(ctx context.Context) (string, error)        // Pod 不在时的完整日志
+	finished func(ctx context.Context) (bool, error)          // 构建 / job 是否已结束
+}
+
+// streamLogs 从第 offset 行开始把日志逐行交给 emit，直到构建 / job 结束且日志读完、ctx 取消或 emit 出错。
+// Pod 日志和历史日志都从第一行开始，按行号跳过已发送的部分，两者切换时不重复也不丢行。
+func streamLogs(ctx context.Context, src logSource, offset int, emit func(LogLine) error) error {
+	for {
+		// 先判断是否结束再读：结束后读到的一定是完整日志
+		finished, err := src.finished(ctx)
+		if err != nil {
+			return err
+		}
+		if offset, err = src.read(ctx, offset, emit); err != nil {
+			return err
+		}
+		if finished {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// read 读一遍当前可得的日志，发送 offset 之后的行，返回下一个待发送的行号。
+func (src logSource) read(ctx context.Context, offset int, emit func(LogLine) error) (int, error) {
+	if src.follow != nil {
+		stream, err := src.follow(ctx)
+		if err != nil {
+			// 容器还没启动等情况，退回历史日志，下一轮再试
+			slog.Debug("follow pod logs failed, reading history", "error", err)
+		} else if stream != nil {
+			defer stream.Close()
+			return emitLines(stream, offset, emit)
+		}
+	}
+	logs, err := src.history(ctx)
+	if err != nil {
+		return offset, err
+	}
+	return emitLines(strings.NewReader(logs), offset, emit)
+}
+
+func emitLines(r io.Reader, offset int, emit func(LogLine) error) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		if n >= offset {
+			if err := emit(LogLine{Offset: n, Text: scanner.Text()}); err != nil {
+				return n, err
+			}
+		}
+		n++
+	}
+	// Pod 被删除或 ctx 取消时日志流会中断，已发送的行照常计入，下一轮从断点继续
+	return max(n, offset), nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+func collectLines(t *testing.T, follow func(emit func(LogLine) error) error) []LogLine {
+	t.Helper()
+	var lines []LogLine
+	if err := follow(func(line LogLine) error {
+		lines = append(lines, line)
+		return nil
+	}); err != nil {
+		t.Fatalf("follow error = %v", err)
+	}
+	return lines
+}
+
+func TestStreamLogs_PodThenHistory(t *testing.T) {
+	logPollInterval = time.Millisecond
+	defer func() { logPollInterval = 2 * time.Second }()
+
+	// 第一轮 Pod 在，跟随到容器退出；之后 Pod 被清理，从历史日志接着读
+	podGone := false
+	checks := 0
+	src := logSource{
+		follow: func(context.Context) (io.ReadCloser, error) {
+			if podGone {
+				return nil, nil
+			}
+			podGone = true
+			return io.NopCloser(strings.NewReader("step 1\nstep 2\n")), nil
+		},
+		history: func(context.Context) (string, error) { return "step 1\nstep 2\npushed\n", nil },
+		finished: func(context.Context) (bool, error) {
+			checks++
+			return checks > 1, nil
+		},
+	}
+
+	for _, tt := range []struct {
+		offset int
+		want   []string
+	}{
+		{offset: 0, want: []string{"step 1", "step 2", "pushed"}},
+		{offset: 2, want: []string{"pushed"}},
+	} {
+		podGone, checks = false, 0
+		lines := collectLines(t, func(emit func(LogLine) error) error {
+			return streamLogs(context.Background(), src, tt.offset, emit)
+		})
+		if len(lines) != len(tt.want) {
+			t.Fatalf("offset %d: lines = %+v, want %v", tt.offset, lines, tt.want)
+		}
+		for i, line := range lines {
+			if line.Text != tt.want[i] || line.Offset != tt.offset+i {
+				t.Errorf("offset %d: line %d = %+v, want %q at %d", tt.offset, i, line, tt.want[i], tt.offset+i)
+			}
+		}
+	}
+}
+
+func TestFollowBuildLogs(t *testing.T) {
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b1": {ID: "b1", ImageRepoName: "agent-service", Status: domain.BuildStatusSucceeded, Log: "stored log"},
+	}}
+	executor := &stubBuildExecutor{podLogs: "INFO building\nINFO pushed\n"}
+	svc := NewBuildService(newTestImageRepoRepo("agent-service", ""), buildRepo, executor, nil, BuildServiceConfig{})
+	ctx := context.Background()
+
+	lines := collectLines(t, func(emit func(LogLine) error) error {
+		return svc.FollowBuildLogs(ctx, "agent-service", "b1", 0, emit)
+	})
+	if len(lines) != 2 || lines[1].Text != "INFO pushed" {
+		t.Errorf("lines = %+v, want pod logs", lines)
+	}
+
+	// Pod 已清理、没有 Loki：读存储的日志
+	executor.podLogs = ""
+	lines = collectLines(t, func(emit func(LogLine) error) error {
+		return svc.FollowBuildLogs(ctx, "agent-service", "b1", 0, emit)
+	})
+	if len(lines) != 1 || lines[0].Text != "stored log" {
+		t.Errorf("lines = %+v, want stored log", lines)
+	}
+
+	err := svc.FollowBuildLogs(ctx, "agent-service", "missing", 0, func(LogLine) error { return nil })
+	if !errors.Is(err, domain.ErrBuildNotFound) {
+		t.Errorf("FollowBuildLogs() of missing build error = %v, want ErrBuildNotFound", err)
+	}
+}
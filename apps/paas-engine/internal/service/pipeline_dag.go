@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// serviceStages 是每个服务在 DAG 中依次经过的阶段，也是 run 的前三个 stage。
+var serviceStages = []domain.StageType{domain.StageUnitTest, domain.StageBuild, domain.StageDeploy}
+
+// serviceStep 执行单个服务在某个 stage 的 job（job.Name 即服务名），自己负责把结果写回 job。
+// 接管上个进程没跑完的 run 时 job 是已记录的那条，step 据此接着等，而不是重新开始。
+// prev 是该服务在上一个 stage 的 job（第一个 stage 为 nil），部署据此拿到本次 run 构建出的镜像。
+type serviceStep func(ctx context.Context, run *domain.PipelineRun, job, prev *domain.JobRun) error
+
+// stageJobs 是 run 已记录的 job，按 stage ID + job 名索引。
+type stageJobs map[string]*domain.JobRun
+
+func (j stageJobs) get(stage *domain.StageRun, name string) *domain.JobRun {
+	return j[stage.ID+"/"+name]
+}
+
+// loadStageJobs 读出 stages 下已记录的 job。
+func (s *PipelineService) loadStageJobs(ctx context.Context, stages []*domain.StageRun) stageJobs {
+	jobs := make(stageJobs)
+	for _, stage := range stages {
+		list, err := s.pipelineRepo.FindJobsByStageID(ctx, stage.ID)
+		if err != nil {
+			slog.Warn("load stage jobs failed", "stage_id", stage.ID, "error", err)
+			continue
+		}
+		for i := range list {
+			jobs[stage.ID+"/"+list[i].Name] = &list[i]
+		}
+	}
+	return jobs
+}
+
+// errRunCancelled 表示 run 在执行中被取消，之后的 job 不再启动。
+var errRunCancelled = errors.New("pipeline run cancelled")
+
+// stageError 记录服务在哪个阶段失败。
+type stageError struct {
+	stage domain.StageType
+	err   error
+}
+
+func (e *stageError) Error() string {
+	return fmt.Sprintf("stage %s failed: %s", e.stage, e.err)
+}
+
+func (e *stageError) Unwrap() error { return e.err }
+
+// runServiceDAG 按服务依次执行 steps（与 stages 一一对应，即 unit-test → build → deploy）：每个服务自己的单测通过就开始构建，
+// 不用等其他服务；部署还要等 pipeline.yml 中 deploy_after 声明的、同一 run 里的服务先部署成功。
+// 同时执行的 job 不超过 MaxParallel，等依赖时不占名额。某个服务失败不影响无关服务继续，
+// 它后续的阶段和依赖它部署的服务记为 cancelled。jobs 中已结束的 job 不再执行，按记录的结果继续。
+// 返回按 run.Services 顺序的第一个失败。
+func (s *PipelineService) runServiceDAG(ctx context.Context, run *domain.PipelineRun, stages []*domain.StageRun,
+	jobs stageJobs, steps []serviceStep) error {
+	index := make(map[string]int, len(run.Services))
+	deployed := make([]chan struct{}, len(run.Services)) // 服务的链路结束（成功或失败）时关闭
+	for i, svc := range run.Services {
+		index[svc] = i
+		deployed[i] = make(chan struct{})
+	}
+	errs := make([]error, len(run.Services))
+
+	// waitDeps 等 deploy_after 中的服务结束，任一没有部署成功则返回错误
+	waitDeps := func(svc string) error {
+		testCfg, _ := run.Config.Service(svc)
+		for _, dep := range testCfg.DeployAfter {
+			i, ok := index[dep]
+			if !ok {
+				continue // 不在本次 run 里，lane 上用现有 release 或落回 prod
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deployed[i]:
+			}
+			if errs[i] != nil {
+				return fmt.Errorf("dependency %s was not deployed", dep)
+			}
+		}
+		return nil
+	}
+
+	sem := make(chan struct{}, s.cfg.MaxParallel)
+	var wg sync.WaitGroup
+	for i, svcName := range run.Services {
+		wg.Add(1)
+		go func(i int, svc string) {
+			defer wg.Done()
+			defer close(deployed[i])
+			errs[i] = s.runServiceChain(ctx, run, stages, jobs, steps, svc, sem, waitDeps)
+		}(i, svcName)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runServiceChain 依次执行单个服务的各阶段。无改动的服务在每个 stage 记一条 skipped job。
+func (s *PipelineService) runServiceChain(ctx context.Context, run *domain.PipelineRun, stages []*domain.StageRun,
+	jobs stageJobs, steps []serviceStep, svc string, sem chan struct{}, waitDeps func(string) error) error {
+	if run.Skips(svc) {
+		for _, stage := range stages {
+			s.endJob(ctx, s.stageJob(ctx, jobs, stage, svc, string(stage.Stage)), domain.PipelineRunSkipped, domain.SkippedNoChangesLog)
+		}
+		return nil
+	}
+
+	var prev *domain.JobRun
+	for i, step := range steps {
+		stage := stages[i]
+		job, ran, err := s.runStep(ctx, run, stage, jobs.get(stage, svc), prev, svc, sem, waitDeps, step)
+		prev = job
+		if errors.Is(err, errRunCancelled) || ctx.Err() != nil {
+			return err // 执行被停止，job 状态由取消方收尾
+		}
+		if err == nil {
+			continue
+		}
+
+		// 没有执行的阶段记为 cancelled，每个 stage 里都能看到这个服务
+		reason := "not run: " + err.Error()
+		rest := stages[i:]
+		if ran {
+			reason = fmt.Sprintf("not run: %s failed", stage.Stage)
+			rest = stages[i+1:]
+		}
+		for _, st := range rest {
+			s.endJob(ctx, s.stageJob(ctx, jobs, st, svc, string(st.Stage)), domain.PipelineRunCancelled, reason)
+		}
+		return &stageError{stage: stage.Stage, err: err}
+	}
+	return nil
+}
+
+// runStep 等部署依赖、拿到并发名额后执行一个阶段，返回这个阶段的 job。ran 表示 step 已执行（已记录自己的 job）。
+// job 是上个进程记录的 job，已结束时直接返回它的结果。
+func (s *PipelineService) runStep(ctx context.Context, run *domain.PipelineRun, stage *domain.StageRun, job, prev *domain.JobRun,
+	svc string, sem chan struct{}, waitDeps func(string) error, step serviceStep) (_ *domain.JobRun, ran bool, err error) {
+	if job != nil && job.Status.IsTerminal() {
+		return job, true, jobResult(job)
+	}
+	if stage.Stage == domain.StageDeploy {
+		if err := waitDeps(svc); err != nil {
+			return job, false, err
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return job, false, ctx.Err()
+	case sem <- struct{}{}:
+	}
+	defer func() { <-sem }()
+	if s.runCancelled(ctx, run.ID) {
+		return job, false, errRunCancelled
+	}
+	if job == nil {
+		job = s.saveJob(ctx, stage, svc, string(stage.Stage))
+	}
+	return job, true, step(ctx, run, job, prev)
+}
+
+// stageJob 返回 stage 下名为 name 的 job，还没有时落库一条 pending 的。
+func (s *PipelineService) stageJob(ctx context.Context, jobs stageJobs, stage *domain.StageRun, name, jobType string) *domain.JobRun {
+	if job := jobs.get(stage, name); job != nil {
+		return job
+	}
+	return s.saveJob(ctx, stage, name, jobType)
+}
+
+// endJob 把还没结束的 job 记为 status，已结束的不动。
+func (s *PipelineService) endJob(ctx context.Context, job *domain.JobRun, status domain.PipelineRunStatus, log string) {
+	if job.Status.IsTerminal() {
+		return
+	}
+	job.Status = status
+	job.Log = log
+	job.UpdatedAt = time.Now()
+	_ = s.pipelineRepo.UpdateJob(ctx, job)
+}
+
+// jobResult 把已结束的 job 转成 step 的返回值：成功或跳过为 nil。
+func jobResult(job *domain.JobRun) error {
+	switch job.Status {
+	case domain.PipelineRunSucceeded, domain.PipelineRunSkipped:
+		return nil
+	case domain.PipelineRunFailed:
+		return fmt.Errorf("job failed: %s", job.Log)
+	default:
+		return fmt.Errorf("job %s: %s", job.Status, job.Log)
+	}
+}
+
+// finishStage 按 stage 下各服务 job 的结果汇总 stage 状态：有失败则失败，有未执行的则 cancelled，
+// 全部因无改动跳过则 skipped，否则成功。
+func (s *PipelineService) finishStage(ctx context.Context, stage *domain.StageRun) {
+	jobs, err := s.pipelineRepo.FindJobsByStageID(ctx, stage.ID)
+	if err != nil {
+		slog.Warn("finish stage: failed to list jobs", "stage_id", stage.ID, "error", err)
+	}
+	var failed, cancelled []string
+	skipped := len(jobs) > 0
+	for _, job := range jobs {
+		switch job.Status {
+		case domain.PipelineRunFailed:
+			failed = append(failed, job.Name)
+		case domain.PipelineRunCancelled:
+			cancelled = append(cancelled, job.Name)
+		}
+		if job.Status != domain.PipelineRunSkipped {
+			skipped = false
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		stage.Status = domain.PipelineRunFailed
+		stage.Message = "failed: " + strings.Join(failed, ", ")
+	case len(cancelled) > 0:
+		stage.Status = domain.PipelineRunCancelled
+		stage.Message = "not run: " + strings.Join(cancelled, ", ")
+	case skipped:
+		stage.Status = domain.PipelineRunSkipped
+		stage.Message = domain.SkippedNoChangesLog
+	default:
+		stage.Status = domain.PipelineRunSucceeded
+	}
+	stage.UpdatedAt = time.Now()
+	_ = s.pipelineRepo.UpdateStage(ctx, stage)
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/google/uuid"
+)
+
+// dagRecorder 提供假的 serviceStep：记录执行顺序和并发数，按 fail 让指定的 "stage/svc" 失败。
+type dagRecorder struct {
+	repo    *stubPipelineRunRepo
+	fail    map[string]bool
+	hold    map[string]chan struct{} // "stage/svc" 执行时阻塞到 channel 关闭
+	mu      sync.Mutex
+	events  []string // "start stage/svc"、"end stage/svc"
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (d *dagRecorder) step(ctx context.Context, _ *domain.PipelineRun, job, _ *domain.JobRun) error {
+	key := job.JobType + "/" + job.Name
+	d.record("start " + key)
+	if n := d.running.Add(1); n > d.peak.Load() {
+		d.peak.Store(n)
+	}
+	if ch, ok := d.hold[key]; ok {
+		<-ch
+	}
+	time.Sleep(time.Millisecond)
+	d.running.Add(-1)
+
+	status := domain.PipelineRunSucceeded
+	var err error
+	if d.fail[key] {
+		status, err = domain.PipelineRunFailed, fmt.Errorf("%s failed", key)
+	}
+	job.Status = status
+	_ = d.repo.UpdateJob(ctx, job)
+	d.record("end " + key)
+	return err
+}
+
+func (d *dagRecorder) record(event string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+func (d *dagRecorder) index(event string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, e := range d.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func newDAGTestService(maxParallel int) (*PipelineService, *stubPipelineRunRepo, *dagRecorder) {
+	repo := newStubPipelineRunRepo()
+	svc := NewPipelineService(nil, repo, nil, nil, nil, nil, nil, nil, nil, nil, PipelineServiceConfig{MaxParallel: maxParallel})
+	return svc, repo, &dagRecorder{repo: repo, fail: map[string]bool{}, hold: map[string]chan struct{}{}}
+}
+
+// saveDAGStages 为 run 落库 serviceStages 对应的 stage。
+func saveDAGStages(svc *PipelineService, run *domain.PipelineRun) []*domain.StageRun {
+	var stages []*domain.StageRun
+	for seq, st := range serviceStages {
+		stages = append(stages, svc.saveStage(context.Background(), run, st, seq+1))
+	}
+	return stages
+}
+
+// runDAG 执行 run 的 DAG，stages 为 nil 时新建；stages 下已记录的 job 按接管处理。
+func runDAG(t *testing.T, svc *PipelineService, repo *stubPipelineRunRepo, rec *dagRecorder, run *domain.PipelineRun,
+	stages []*domain.StageRun) ([]*domain.StageRun, error) {
+	t.Helper()
+	_ = repo.Save(context.Background(), run)
+	if stages == nil {
+		stages = saveDAGStages(svc, run)
+	}
+	jobs := svc.loadStageJobs(context.Background(), stages)
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.runServiceDAG(context.Background(), run, stages, jobs, []serviceStep{rec.step, rec.step, rec.step})
+	}()
+	select {
+	case err := <-done:
+		for _, stage := range stages {
+			svc.finishStage(context.Background(), stage)
+		}
+		return stages, err
+	case <-time.After(5 * time.Second):
+		t.Fatal("runServiceDAG did not finish")
+		return nil, nil
+	}
+}
+
+func TestRunServiceDAG_PerServiceOrdering(t *testing.T) {
+	svc, repo, rec := newDAGTestService(4)
+	// lite-registry 的单测卡住时，channel-server 照样构建、部署；lane-sidecar 要等 lite-registry 部署完
+	release := make(chan struct{})
+	rec.hold["unit-test/lite-registry"] = release
+	go func() {
+		for rec.index("end deploy/channel-server") < 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+	}()
+	run := &domain.PipelineRun{
+		ID:       "run-1",
+		Services: []string{"lite-registry", "lane-sidecar", "channel-server", "tool-service"},
+		Config: &domain.PipelineConfig{Services: map[string]domain.ServiceTestConfig{
+			"lane-sidecar": {DeployAfter: []string{"lite-registry", "not-in-run"}},
+		}},
+		SkippedServices: []string{"tool-service"},
+	}
+
+	stages, err := runDAG(t, svc, repo, rec, run, nil)
+	if err != nil {
+		t.Fatalf("runServiceDAG() error = %v", err)
+	}
+	if rec.index("start deploy/lane-sidecar") < rec.index("end deploy/lite-registry") {
+		t.Errorf("lane-sidecar deployed before lite-registry: %v", rec.events)
+	}
+	if rec.index("start unit-test/tool-service") >= 0 {
+		t.Errorf("skipped service should not run: %v", rec.events)
+	}
+	for _, stage := range stages {
+		jobs := repo.jobsByName(stage.ID)
+		if len(jobs) != 4 || jobs["tool-service"].Status != domain.PipelineRunSkipped {
+			t.Errorf("stage %s jobs = %+v, want one per service", stage.Stage, jobs)
+		}
+		if stage.Status != domain.PipelineRunSucceeded {
+			t.Errorf("stage %s status = %s", stage.Stage, stage.Status)
+		}
+	}
+}
+
+func TestRunServiceDAG_FailureCancelsDownstream(t *testing.T) {
+	svc, repo, rec := newDAGTestService(4)
+	rec.fail["unit-test/lite-registry"] = true
+	run := &domain.PipelineRun{
+		ID:       "run-1",
+		Services: []string{"lite-registry", "lane-sidecar", "channel-server"},
+		Config: &domain.PipelineConfig{Services: map[string]domain.ServiceTestConfig{
+			"lane-sidecar": {DeployAfter: []string{"lite-registry"}},
+		}},
+	}
+
+	stages, err := runDAG(t, svc, repo, rec, run, nil)
+	var stageErr *stageError
+	if !errors.As(err, &stageErr) || stageErr.stage != domain.StageUnitTest {
+		t.Fatalf("runServiceDAG() error = %v, want unit-test failure", err)
+	}
+
+	// 无关的服务照常部署
+	if rec.index("end deploy/channel-server") < 0 {
+		t.Errorf("channel-server should still deploy: %v", rec.events)
+	}
+	unitTest, build, deploy := repo.jobsByName(stages[0].ID), repo.jobsByName(stages[1].ID), repo.jobsByName(stages[2].ID)
+	if unitTest["lite-registry"].Status != domain.PipelineRunFailed || build["lite-registry"].Status != domain.PipelineRunCancelled {
+		t.Errorf("lite-registry jobs = %+v / %+v", unitTest["lite-registry"], build["lite-registry"])
+	}
+	if build["lane-sidecar"].Status != domain.PipelineRunSucceeded {
+		t.Errorf("lane-sidecar build = %+v, want succeeded", build["lane-sidecar"])
+	}
+	if job := deploy["lane-sidecar"]; job.Status != domain.PipelineRunCancelled || !strings.Contains(job.Log, "dependency lite-registry was not deployed") {
+		t.Errorf("lane-sidecar deploy = %+v, want cancelled by dependency", job)
+	}
+	want := []domain.PipelineRunStatus{domain.PipelineRunFailed, domain.PipelineRunCancelled, domain.PipelineRunCancelled}
+	for i, stage := range stages {
+		if stage.Status != want[i] {
+			t.Errorf("stage %s status = %s, want %s", stage.Stage, stage.Status, want[i])
+		}
+	}
+}
+
+func TestRunServiceDAG_BoundedParallelism(t *testing.T) {
+	svc, repo, rec := newDAGTestService(2)
+	run := &domain.PipelineRun{ID: "run-1", Services: []string{"a", "b", "c", "d", "e"}}
+
+	if _, err := runDAG(t, svc, repo, rec, run, nil); err != nil {
+		t.Fatalf("runServiceDAG() error = %v", err)
+	}
+	if peak := rec.peak.Load(); peak > 2 {
+		t.Errorf("peak concurrent jobs = %d, want <= 2", peak)
+	}
+}
+
+func TestRunServiceDAG_StopsWhenCancelled(t *testing.T) {
+	svc, repo, rec := newDAGTestService(1)
+	run := &domain.PipelineRun{ID: "run-1", Services: []string{"a"}}
+	release := make(chan struct{})
+	rec.hold["unit-test/a"] = release
+	go func() {
+		for rec.index("start unit-test/a") < 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancelled := *run
+		cancelled.Status = domain.PipelineRunCancelled
+		_ = repo.Update(context.Background(), &cancelled)
+		close(release)
+	}()
+
+	if _, err := runDAG(t, svc, repo, rec, run, nil); !errors.Is(err, errRunCancelled) {
+		t.Fatalf("runServiceDAG() error = %v, want errRunCancelled", err)
+	}
+	if rec.index("start build/a") >= 0 {
+		t.Errorf("no job should start after the run is cancelled: %v", rec.events)
+	}
+}
+
+func TestRunServiceDAG_ResumesRecordedJobs(t *testing.T) {
+	svc, repo, rec := newDAGTestService(4)
+	run := &domain.PipelineRun{ID: "run-1", Services: []string{"a", "b"}}
+	stages := saveDAGStages(svc, run)
+	// 上个进程里 a 的单测已通过、构建在跑，b 的单测已失败
+	for _, job := range []domain.JobRun{
+		{StageRunID: stages[0].ID, Name: "a", Status: domain.PipelineRunSucceeded},
+		{StageRunID: stages[1].ID, Name: "a", Status: domain.PipelineRunRunning, RefID: "build-a"},
+		{StageRunID: stages[0].ID, Name: "b", Status: domain.PipelineRunFailed, Log: "exit 1"},
+	} {
+		job.ID = uuid.New().String()
+		job.JobType = string(stages[0].Stage)
+		if job.StageRunID == stages[1].ID {
+			job.JobType = string(stages[1].Stage)
+		}
+		_ = repo.SaveJob(context.Background(), &job)
+	}
+	buildA := repo.jobsByName(stages[1].ID)["a"]
+
+	_, err := runDAG(t, svc, repo, rec, run, stages)
+	var stageErr *stageError
+	if !errors.As(err, &stageErr) || stageErr.stage != domain.StageUnitTest || !strings.Contains(err.Error(), "exit 1") {
+		t.Fatalf("runServiceDAG() error = %v, want b's recorded unit-test failure", err)
+	}
+	if rec.index("start unit-test/a") >= 0 || rec.index("start unit-test/b") >= 0 {
+		t.Errorf("finished jobs should not run again: %v", rec.events)
+	}
+	if rec.index("end build/a") < 0 || rec.index("end deploy/a") < 0 {
+		t.Errorf("a should continue from its build: %v", rec.events)
+	}
+	if got := repo.jobsByName(stages[1].ID)["a"]; got.ID != buildA.ID || got.RefID != "build-a" || got.Status != domain.PipelineRunSucceeded {
+		t.Errorf("build job of a = %+v, want the recorded job %s finished", got, buildA.ID)
+	}
+	if got := repo.jobsByName(stages[1].ID)["b"]; got.Status != domain.PipelineRunCancelled {
+		t.Errorf("build job of b = %+v, want cancelled", got)
+	}
+	for _, stage := range stages {
+		if jobs, _ := repo.FindJobsByStageID(context.Background(), stage.ID); len(jobs) != 2 {
+			t.Errorf("stage %s has %d jobs, want one per service", stage.Stage, len(jobs))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// runStopTimeout 是取消 run 时等执行 goroutine 退出的上限。
+const runStopTimeout = 30 * time.Second
+
+// pipelineExecution 是 run 在本进程里的执行：cancel 让它停下，done 在执行 goroutine 退出后关闭。
+type pipelineExecution struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startRun 在后台执行 run。执行前先在 DB 里抢 run 的租约，同一个 run 在所有实例中只有一个执行者：
+// 已在本进程执行、或租约被其他实例持有时不启动并返回 false。执行期间定期续约，租约被接管时停止执行。
+func (s *PipelineService) startRun(ctx context.Context, run *domain.PipelineRun) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.running[run.ID]; ok {
+		return false
+	}
+	now := time.Now()
+	claimed, err := s.pipelineRepo.ClaimLease(ctx, run.ID, s.owner, now, domain.LeaseExpiredBefore(now))
+	if err != nil {
+		slog.Error("failed to claim pipeline run", "id", run.ID, "error", err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	run.Hold(s.owner, now)
+
+	execCtx, cancel := context.WithCancel(context.Background())
+	exec := &pipelineExecution{cancel: cancel, done: make(chan struct{})}
+	s.running[run.ID] = exec
+	go func() {
+		defer close(exec.done)
+		defer cancel()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, run.ID)
+			s.mu.Unlock()
+		}()
+		defer holdLease("pipeline_run", run.ID, func(ctx context.Context, now time.Time) (bool, error) {
+			return s.pipelineRepo.RenewLease(ctx, run.ID, s.owner, now)
+		}, cancel)()
+		s.executeRun(execCtx, run)
+	}()
+	return true
+}
+
+// stopRun 停止本进程里 run 的执行，等它退出后返回，之后不会再有写入。
+func (s *PipelineService) stopRun(runID string) {
+	s.mu.Lock()
+	exec, ok := s.running[runID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	exec.cancel()
+	select {
+	case <-exec.done:
+	case <-time.After(runStopTimeout):
+		slog.Warn("pipeline run did not stop in time", "id", runID, "timeout", runStopTimeout)
+	}
+}
+
+// ResumeRuns 接管执行者已退出的 run：租约过期说明执行它的实例（重启前的进程、蓝绿切换下线的
+// 旧实例）已不在续约。启动时和之后每隔 domain.LeaseTTL 调用一次；租约还在续的 run 由持有者继续执行，
+// 不动。已有的 stage 和 job 都在 DB 里，按记录接着执行即可（见 executeRun）；所属 CI 泳道已注销的
+// run 不再执行，收尾未结束的 job 并把 run 记为失败。
+func (s *PipelineService) ResumeRuns(ctx context.Context) error {
+	runs, err := s.pipelineRepo.FindUnfinished(ctx)
+	if err != nil {
+		return fmt.Errorf("find unfinished pipeline runs: %w", err)
+	}
+
+	for _, run := range runs {
+		if s.executing(run.ID) || !run.Lease.Expired(time.Now()) {
+			continue
+		}
+		if reason := s.resumeBlocker(ctx, run); reason != "" {
+			now := time.Now()
+			claimed, err := s.pipelineRepo.ClaimLease(ctx, run.ID, s.owner, now, domain.LeaseExpiredBefore(now))
+			if err != nil || !claimed {
+				continue // 其他实例先接管了
+			}
+			reason = "interrupted by paas-engine restart: " + reason
+			s.closeRunJobs(ctx, run.ID, domain.PipelineRunCancelled, reason)
+			s.failRun(ctx, run, reason)
+			slog.Warn("pipeline run not resumed", "id", run.ID, "lane", run.Lane, "reason", reason)
+			continue
+		}
+		previous := run.Owner
+		if s.startRun(ctx, run) {
+			slog.Info("resuming pipeline run", "id", run.ID, "lane", run.Lane, "previous_owner", previous)
+		}
+	}
+	return nil
+}
+
+// executing 判断 run 是否正在本进程里执行。
+func (s *PipelineService) executing(runID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.running[runID]
+	return ok
+}
+
+// resumeBlocker 返回 run 不能接管的原因，可以接管时返回空。
+func (s *PipelineService) resumeBlocker(ctx context.Context, run *domain.PipelineRun) string {
+	if run.CIConfigID == "" {
+		return ""
+	}
+	cfg, err := s.ciConfigRepo.FindByID(ctx, run.CIConfigID)
+	if err != nil {
+		return fmt.Sprintf("load ci config: %v", err)
+	}
+	if cfg.Status != "active" {
+		return fmt.Sprintf("lane %s CI was unregistered", cfg.Lane)
+	}
+	return ""
+}
+
+// closeRunJobs 把 run 下未结束的 job 和 stage 记为 status，删掉还在跑的测试 Job。reason 非空时写入 job 日志。
+func (s *PipelineService) closeRunJobs(ctx context.Context, runID string, status domain.PipelineRunStatus, reason string) {
+	stages, _ := s.pipelineRepo.FindStagesByRunID(ctx, runID)
+	for _, stage := range stages {
+		jobs, _ := s.pipelineRepo.FindJobsByStageID(ctx, stage.ID)
+		for _, job := range jobs {
+			if job.Status.IsTerminal() {
+				continue
+			}
+			if job.K8sJobName != "" && s.testExecutor != nil {
+				_ = s.testExecutor.Cancel(ctx, job.K8sJobName)
+			}
+			job.Status = status
+			if reason != "" {
+				job.Log = reason
+			}
+			job.UpdatedAt = time.Now()
+			_ = s.pipelineRepo.UpdateJob(ctx, &job)
+		}
+		if !stage.Status.IsTerminal() {
+			stage.Status = status
+			stage.UpdatedAt = time.Now()
+			_ = s.pipelineRepo.UpdateStage(ctx, &stage)
+		}
+	}
+}
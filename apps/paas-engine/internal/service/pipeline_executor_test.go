@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// newExecutorTestRun 返回一个 agent-service 单测会一直跑下去的 run。
+func newExecutorTestRun(id, ciConfigID string) *domain.PipelineRun {
+	return &domain.PipelineRun{
+		ID:         id,
+		CIConfigID: ciConfigID,
+		GitRef:     "feat/x",
+		Lane:       "coe-x",
+		Services:   []string{"agent-service"},
+		Status:     domain.PipelineRunPending,
+		Config: &domain.PipelineConfig{Services: map[string]domain.ServiceTestConfig{
+			"agent-service": {Runtime: "python", UnitTest: "pytest -x"},
+		}},
+	}
+}
+
+// waitSubmitted 等 executor 收到 n 个测试提交。
+func waitSubmitted(t *testing.T, executor *stubTestExecutor, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		executor.mu.Lock()
+		got := len(executor.submitted)
+		executor.mu.Unlock()
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("submitted %d test jobs, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCancelPipelineRun_StopsExecution(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	defer func() { pipelinePollInterval = 5 * time.Second }()
+
+	svc, repo, executor := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	executor.hang["pytest -x"] = true
+	run := newExecutorTestRun("run-1", "")
+	_ = repo.Save(context.Background(), run)
+
+	exec := *run
+	if !svc.startRun(context.Background(), &exec) {
+		t.Fatal("startRun() = false, want the run to start")
+	}
+	waitSubmitted(t, executor, 1)
+	again := *run
+	if svc.startRun(context.Background(), &again) {
+		t.Error("startRun() = true for a run that is already executing")
+	}
+
+	if err := svc.CancelPipelineRun(context.Background(), run.ID); err != nil {
+		t.Fatalf("CancelPipelineRun() error = %v", err)
+	}
+	if svc.executing(run.ID) {
+		t.Error("run is still executing after cancel")
+	}
+	got, _ := repo.FindByID(context.Background(), run.ID)
+	if got.Status != domain.PipelineRunCancelled {
+		t.Errorf("run status = %s, want cancelled", got.Status)
+	}
+	stages, _ := repo.FindStagesByRunID(context.Background(), run.ID)
+	for _, stage := range stages {
+		if stage.Status != domain.PipelineRunCancelled {
+			t.Errorf("stage %s status = %s, want cancelled", stage.Stage, stage.Status)
+		}
+	}
+	job := repo.jobsByName(stages[0].ID)["agent-service"]
+	if job.Status != domain.PipelineRunCancelled {
+		t.Errorf("unit-test job status = %s, want cancelled", job.Status)
+	}
+	if len(executor.cancelled) != 1 || executor.cancelled[0] != job.K8sJobName {
+		t.Errorf("cancelled K8s jobs = %v, want [%s]", executor.cancelled, job.K8sJobName)
+	}
+	if jobs, _ := repo.FindJobsByStageID(context.Background(), stages[1].ID); len(jobs) != 0 {
+		t.Errorf("build jobs = %+v, want none after cancel", jobs)
+	}
+}
+
+func TestResumeRuns(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	defer func() { pipelinePollInterval = 5 * time.Second }()
+
+	svc, repo, executor := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	executor.hang["pytest -x"] = true
+	svc.ciConfigRepo = &stubCIConfigRepo{configs: map[string]*domain.CIConfig{
+		"coe-x": {ID: "ci-x", Lane: "coe-x", Status: "active"},
+		"coe-y": {ID: "ci-y", Lane: "coe-y", Status: "archived"},
+	}}
+
+	// 两个 run 都停在单测：测试 Job 已提交、还在跑
+	interrupted := func(id, ciConfigID string) *domain.JobRun {
+		run := newExecutorTestRun(id, ciConfigID)
+		run.Status = domain.PipelineRunRunning
+		_ = repo.Save(context.Background(), run)
+		stage := svc.saveStage(context.Background(), run, domain.StageUnitTest, 1)
+		job := svc.saveJob(context.Background(), stage, "agent-service", string(domain.StageUnitTest))
+		job.Status = domain.PipelineRunRunning
+		job.K8sJobName = "ci-test-" + id
+		_ = repo.UpdateJob(context.Background(), job)
+		return job
+	}
+	resumedJob := interrupted("run-x", "ci-x")
+	orphanJob := interrupted("run-y", "ci-y")
+	// run-z 由另一个还在续约的实例执行，不接管
+	interrupted("run-z", "ci-x")
+	now := time.Now()
+	_, _ = repo.ClaimLease(context.Background(), "run-z", "live-instance", now, domain.LeaseExpiredBefore(now))
+	// run-x 的执行者已停止续约，租约过期
+	stale := now.Add(-2 * domain.LeaseTTL)
+	_, _ = repo.ClaimLease(context.Background(), "run-x", "old-instance", stale, domain.LeaseExpiredBefore(stale))
+
+	if err := svc.ResumeRuns(context.Background()); err != nil {
+		t.Fatalf("ResumeRuns() error = %v", err)
+	}
+
+	// 所属 CI 已注销的 run 不再执行
+	runY, _ := repo.FindByID(context.Background(), "run-y")
+	if runY.Status != domain.PipelineRunFailed || !strings.Contains(runY.Message, "lane coe-y CI was unregistered") {
+		t.Errorf("run-y = %s %q, want failed as unregistered", runY.Status, runY.Message)
+	}
+	if job, _ := repo.FindJobByID(context.Background(), orphanJob.ID); job.Status != domain.PipelineRunCancelled {
+		t.Errorf("run-y unit-test job status = %s, want cancelled", job.Status)
+	}
+	if svc.executing("run-y") {
+		t.Error("run-y should not be resumed")
+	}
+
+	// 接着等已提交的测试 Job，而不是新建 job
+	waitSubmitted(t, executor, 1)
+	if sub := executor.submitted[0]; sub.JobRunID != resumedJob.ID {
+		t.Errorf("resubmitted job run %s, want %s", sub.JobRunID, resumedJob.ID)
+	}
+	if !svc.executing("run-x") {
+		t.Error("run-x should be resumed")
+	}
+	stages, _ := repo.FindStagesByRunID(context.Background(), "run-x")
+	if len(stages) != 3 || stages[0].ID != resumedJob.StageRunID {
+		t.Errorf("run-x stages = %+v, want the recorded unit-test stage reused", stages)
+	}
+	if job, _ := repo.FindJobByID(context.Background(), resumedJob.ID); job.K8sJobName != "ci-test-run-x" || job.Status != domain.PipelineRunRunning {
+		t.Errorf("run-x unit-test job = %+v, want still running on its K8s job", job)
+	}
+	if jobs, _ := repo.FindJobsByStageID(context.Background(), resumedJob.StageRunID); len(jobs) != 1 {
+		t.Errorf("run-x unit-test jobs = %d, want 1", len(jobs))
+	}
+	if runX, _ := repo.FindByID(context.Background(), "run-x"); runX.Owner != svc.owner {
+		t.Errorf("run-x lease owner = %q, want %q", runX.Owner, svc.owner)
+	}
+	if runZ, _ := repo.FindByID(context.Background(), "run-z"); svc.executing("run-z") || runZ.Status != domain.PipelineRunRunning || runZ.Owner != "live-instance" {
+		t.Errorf("run-z = %s owned by %q, want left to its live owner", runZ.Status, runZ.Owner)
+	}
+
+	// 另一个实例周期性接管时，run-x 的租约由本实例持有，不会被重复执行
+	other := NewPipelineService(svc.ciConfigRepo, repo, executor, nil, svc.releaseSvc, svc.appRepo, svc.imageRepo, nil, nil, nil, svc.cfg)
+	other.owner = "other-instance"
+	if err := other.ResumeRuns(context.Background()); err != nil {
+		t.Fatalf("ResumeRuns() on another instance error = %v", err)
+	}
+	if other.executing("run-x") || other.executing("run-z") {
+		t.Error("another instance must not take over runs with live leases")
+	}
+
+	if err := svc.CancelPipelineRun(context.Background(), "run-x"); err != nil {
+		t.Fatalf("CancelPipelineRun() error = %v", err)
+	}
+	if len(executor.cancelled) != 2 {
+		t.Errorf("cancelled K8s jobs = %v, want both runs' test jobs", executor.cancelled)
+	}
+}
+
+func TestStartRun_StopsWhenLeaseTakenOver(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	leaseRenewInterval = time.Millisecond
+	defer func() {
+		pipelinePollInterval = 5 * time.Second
+		leaseRenewInterval = domain.LeaseRenewInterval
+	}()
+
+	svc, repo, executor := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	executor.hang["pytest -x"] = true
+	run := newExecutorTestRun("run-1", "")
+	_ = repo.Save(context.Background(), run)
+
+	exec := *run
+	if !svc.startRun(context.Background(), &exec) {
+		t.Fatal("startRun() = false, want the run to start")
+	}
+	waitSubmitted(t, executor, 1)
+
+	// 执行期间租约一直续着，其他实例抢不到
+	other := *run
+	svc2 := NewPipelineService(nil, repo, executor, nil, svc.releaseSvc, svc.appRepo, svc.imageRepo, nil, nil, nil, svc.cfg)
+	svc2.owner = "other-instance"
+	if svc2.startRun(context.Background(), &other) {
+		t.Fatal("another instance started a run whose lease is held")
+	}
+
+	// 租约被接管（例如本实例续约中断超过 TTL）：停止执行，不改 run 的状态
+	repo.mu.Lock()
+	repo.runs[run.ID].Hold("other-instance", time.Now())
+	repo.mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.executing(run.ID) {
+		if time.Now().After(deadline) {
+			t.Fatal("run is still executing after its lease was taken over")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got, _ := repo.FindByID(context.Background(), run.ID); got.Status != domain.PipelineRunRunning || got.Owner != "other-instance" {
+		t.Errorf("run = %s owned by %q, want still running under the new owner", got.Status, got.Owner)
+	}
+}
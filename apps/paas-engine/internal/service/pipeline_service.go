@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
 	"sync"
@@ -22,9 +24,35 @@ type PipelineService struct {
 	appRepo      port.AppRepository
 	imageRepo    port.ImageRepoRepository
 	logQuerier   port.LogQuerier
-	ciNamespace  string
+	gitComparer  port.GitComparer // nil 时不做变更检测，每次全量
+	configLoader port.PipelineConfigLoader // nil 时不读取 pipeline.yml，测试阶段全部跳过
+	cfg          PipelineServiceConfig
+	owner        string // 写进 run 租约的本实例标识
+
+	mu      sync.Mutex
+	running map[string]*pipelineExecution // run ID → 本进程里正在执行它的 goroutine
+}
+
+// PipelineServiceConfig 是 PipelineService 的运行参数。
+type PipelineServiceConfig struct {
+	CINamespace string // 测试 Job 所在 namespace，查 Loki 日志用
+	GatewayURL  string // E2E 测试经它访问 lane 上的服务
+	MaxParallel int    // 单个 run 同时执行的单测 / 构建 / 部署 job 数，<=0 时取 defaultPipelineParallelism
 }
 
+// defaultPipelineParallelism 是没有配置 MaxParallel 时单个 run 的并发 job 数。
+const defaultPipelineParallelism = 4
+
+const (
+	// testJobTimeout 是单测、e2e_test 单个测试 Job 的超时
+	testJobTimeout = 10 * time.Minute
+	// laneReadyTimeout 是 E2E 开始前等 lane 上的服务全部就绪的上限
+	laneReadyTimeout = 5 * time.Minute
+)
+
+// pipelinePollInterval 是等待测试 Job、lane 就绪时轮询 DB / Deployment 的间隔。
+var pipelinePollInterval = 5 * time.Second
+
 func NewPipelineService(
 	ciConfigRepo port.CIConfigRepository,
 	pipelineRepo port.PipelineRunRepository,
@@ -34,8 +62,13 @@ func NewPipelineService(
 	appRepo port.AppRepository,
 	imageRepo port.ImageRepoRepository,
 	logQuerier port.LogQuerier,
-	ciNamespace string,
+	gitComparer port.GitComparer,
+	configLoader port.PipelineConfigLoader,
+	cfg PipelineServiceConfig,
 ) *PipelineService {
+	if cfg.MaxParallel <= 0 {
+		cfg.MaxParallel = defaultPipelineParallelism
+	}
 	return &PipelineService{
 		ciConfigRepo: ciConfigRepo,
 		pipelineRepo: pipelineRepo,
@@ -45,7 +78,11 @@ func NewPipelineService(
 		appRepo:      appRepo,
 		imageRepo:    imageRepo,
 		logQuerier:   logQuerier,
-		ciNamespace:  ciNamespace,
+		gitComparer:  gitComparer,
+		configLoader: configLoader,
+		cfg:          cfg,
+		owner:        instanceID,
+		running:      make(map[string]*pipelineExecution),
 	}
 }
 
@@ -158,12 +195,34 @@ func (s *PipelineService) TriggerPipeline(ctx context.Context, lane string, req
 		return nil, err
 	}
 
-	// 异步执行 pipeline
-	go s.runPipeline(context.Background(), run, cfg)
+	// 异步执行 pipeline，执行时改的是副本
+	exec := *run
+	s.startRun(ctx, &exec)
 
 	return run, nil
 }
 
+// TriggerBranch 为推送到 branch 的 commit 触发 pipeline（webhook / 轮询共用）：按分支找到注册的 CI 泳道，
+// 同一 commit 只触发一次。分支没有注册 CI 时返回 ErrNotFound，main / master 不走特性分支 CI。
+func (s *PipelineService) TriggerBranch(ctx context.Context, branch, commitSHA string) (*domain.PipelineRun, error) {
+	if isMainBranch(branch) {
+		return nil, fmt.Errorf("%w: %s is not a feature branch", domain.ErrInvalidInput, branch)
+	}
+	if !domain.IsFullCommitSHA(commitSHA) {
+		return nil, fmt.Errorf("%w: invalid commit sha %q", domain.ErrInvalidInput, commitSHA)
+	}
+	cfg, err := s.ciConfigRepo.FindByBranch(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
+	return s.TriggerPipeline(ctx, cfg.Lane, TriggerPipelineRequest{CommitSHA: commitSHA})
+}
+
+// isMainBranch 判断是否为主干分支。
+func isMainBranch(branch string) bool {
+	return branch == "main" || branch == "master"
+}
+
 // GetPipelineRun 获取 pipeline run 详情（含嵌套 stages + jobs）。
 func (s *PipelineService) GetPipelineRun(ctx context.Context, id string) (*domain.PipelineRun, error) {
 	run, err := s.pipelineRepo.FindByID(ctx, id)
@@ -181,7 +240,7 @@ func (s *PipelineService) ListPipelineRuns(ctx context.Context, lane string, lim
 	return s.pipelineRepo.FindByLane(ctx, lane, limit)
 }
 
-// CancelPipelineRun 取消正在执行的 pipeline。
+// CancelPipelineRun 取消正在执行的 pipeline：先记下取消，停掉执行它的 goroutine，再收尾未结束的 job。
 func (s *PipelineService) CancelPipelineRun(ctx context.Context, id string) error {
 	run, err := s.pipelineRepo.FindByID(ctx, id)
 	if err != nil {
@@ -191,30 +250,14 @@ func (s *PipelineService) CancelPipelineRun(ctx context.Context, id string) erro
 		return fmt.Errorf("%w: pipeline run is already %s", domain.ErrCannotCancel, run.Status)
 	}
 
-	// 取消所有活跃 Job
-	stages, _ := s.pipelineRepo.FindStagesByRunID(ctx, id)
-	for _, stage := range stages {
-		jobs, _ := s.pipelineRepo.FindJobsByStageID(ctx, stage.ID)
-		for _, job := range jobs {
-			if !job.Status.IsTerminal() && job.K8sJobName != "" && s.testExecutor != nil {
-				_ = s.testExecutor.Cancel(ctx, job.K8sJobName)
-			}
-			if !job.Status.IsTerminal() {
-				job.Status = domain.PipelineRunCancelled
-				job.UpdatedAt = time.Now()
-				_ = s.pipelineRepo.UpdateJob(ctx, &job)
-			}
-		}
-		if !stage.Status.IsTerminal() {
-			stage.Status = domain.PipelineRunCancelled
-			stage.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateStage(ctx, &stage)
-		}
-	}
-
 	run.Status = domain.PipelineRunCancelled
 	run.UpdatedAt = time.Now()
-	return s.pipelineRepo.Update(ctx, run)
+	if err := s.pipelineRepo.Update(ctx, run); err != nil {
+		return err
+	}
+	s.stopRun(id)
+	s.closeRunJobs(ctx, id, domain.PipelineRunCancelled, "")
+	return nil
 }
 
 // GetJobLogs 获取指定 job 的日志。三级降级：Pod → Loki → DB。
@@ -225,7 +268,7 @@ func (s *PipelineService) GetJobLogs(ctx context.Context, jobRunID string) (stri
 	}
 
 	// 1. 尝试从 K8s Pod 读实时日志
-	if s.testExecutor != nil && job.JobType == string(domain.StageUnitTest) {
+	if s.testExecutor != nil && job.RunsTestPod() {
 		logs, err := s.testExecutor.GetLogs(ctx, job.ID)
 		if err == nil && logs != "" {
 			return logs, nil
@@ -235,13 +278,48 @@ func (s *PipelineService) GetJobLogs(ctx context.Context, jobRunID string) (stri
 		}
 	}
 
-	// 2. 尝试从 Loki 查询历史日志
-	if s.logQuerier != nil && s.ciNamespace != "" && job.JobType == string(domain.StageUnitTest) {
+	return s.jobHistoryLogs(ctx, job), nil
+}
+
+// FollowJobLogs 从第 offset 行开始逐行输出 job 日志直到 job 结束：测试 Pod 在时跟随 Pod 日志，
+// Pod 被清理后接着读 Loki / DB。
+func (s *PipelineService) FollowJobLogs(ctx context.Context, jobRunID string, offset int, emit func(LogLine) error) error {
+	job, err := s.pipelineRepo.FindJobByID(ctx, jobRunID)
+	if err != nil {
+		return err
+	}
+	src := logSource{
+		history: func(ctx context.Context) (string, error) {
+			return s.jobHistoryLogs(ctx, job), nil
+		},
+		finished: func(ctx context.Context) (bool, error) {
+			latest, err := s.pipelineRepo.FindJobByID(ctx, jobRunID)
+			if err != nil {
+				return false, err
+			}
+			job = latest
+			return job.Status.IsTerminal(), nil
+		},
+	}
+	if s.testExecutor != nil && job.RunsTestPod() {
+		src.follow = func(ctx context.Context) (io.ReadCloser, error) {
+			return s.testExecutor.FollowLogs(ctx, jobRunID)
+		}
+	}
+	return streamLogs(ctx, src, offset, emit)
+}
+
+// jobHistoryLogs 读取测试 Pod 已不在时的日志：先查 Loki，查不到返回 DB 中存储的日志。
+func (s *PipelineService) jobHistoryLogs(ctx context.Context, job *domain.JobRun) string {
+	if s.logQuerier != nil && s.cfg.CINamespace != "" && job.RunsTestPod() {
 		podPrefix := "ci-test-" + strings.ReplaceAll(job.ID, "-", "")[:24]
 		start := job.CreatedAt.Add(-1 * time.Minute)
 		end := job.UpdatedAt.Add(5 * time.Minute)
+		if now := time.Now(); !job.Status.IsTerminal() && now.After(end) {
+			end = now // job 还在进行，查到当前
+		}
 		query := port.AppLogQuery{
-			Namespace: s.ciNamespace,
+			Namespace: s.cfg.CINamespace,
 			Pod:       podPrefix,
 			Start:     start,
 			End:       end,
@@ -250,14 +328,12 @@ func (s *PipelineService) GetJobLogs(ctx context.Context, jobRunID string) (stri
 		}
 		logs, err := s.logQuerier.QueryAppLogs(ctx, query)
 		if err != nil {
-			slog.Warn("failed to get loki logs for ci job, falling back to db", "job_id", jobRunID, "error", err)
+			slog.Warn("failed to get loki logs for ci job, falling back to db", "job_id", job.ID, "error", err)
 		} else if logs != "" {
-			return logs, nil
+			return logs
 		}
 	}
-
-	// 3. 降级：返回 DB 中存储的日志
-	return job.Log, nil
+	return job.Log
 }
 
 // OnTestJobStatusChange 是 TestExecutor Informer 的 callback。
@@ -281,54 +357,95 @@ func (s *PipelineService) OnTestJobStatusChange(jobRunID string, status domain.P
 	}
 }
 
-// runPipeline 执行特性分支 pipeline：unit-test → build → deploy。
-func (s *PipelineService) runPipeline(ctx context.Context, run *domain.PipelineRun, _ *domain.CIConfig) {
-	slog.Info("pipeline started", "id", run.ID, "lane", run.Lane, "services", run.Services)
-
-	run.Status = domain.PipelineRunRunning
-	run.UpdatedAt = time.Now()
-	_ = s.pipelineRepo.Update(ctx, run)
+// executeRun 执行特性分支 pipeline：各服务按 unit-test → build → deploy 组成 DAG 执行（见 runServiceDAG），
+// 全部部署完成后跑整条 lane 的 e2e。run 已有 stage 时是接管上个进程没跑完的 run：沿用已记录的 stage 和 job，
+// 已结束的 job 按结果继续，测试 Job、构建按记录的 K8s Job 名 / 构建 ID 接着等。
+func (s *PipelineService) executeRun(ctx context.Context, run *domain.PipelineRun) {
+	recorded := make(map[domain.StageType]*domain.StageRun)
+	existing, err := s.pipelineRepo.FindStagesByRunID(ctx, run.ID)
+	if err != nil {
+		s.failRun(ctx, run, fmt.Sprintf("load stages: %v", err))
+		slog.Error("pipeline failed", "id", run.ID, "error", err)
+		return
+	}
+	for i := range existing {
+		recorded[existing[i].Stage] = &existing[i]
+	}
 
-	stages := []struct {
-		stage   domain.StageType
-		handler func(ctx context.Context, run *domain.PipelineRun, stage *domain.StageRun) error
-	}{
-		{domain.StageUnitTest, s.runUnitTestStage},
-		{domain.StageBuild, s.runBuildStage},
-		{domain.StageDeploy, s.runDeployStage},
-	}
-
-	for seq, st := range stages {
-		now := time.Now()
-		stage := &domain.StageRun{
-			ID:            uuid.New().String(),
-			PipelineRunID: run.ID,
-			Stage:         st.stage,
-			Seq:           seq + 1,
-			Status:        domain.PipelineRunRunning,
-			CreatedAt:     now,
-			UpdatedAt:     now,
-		}
-		_ = s.pipelineRepo.SaveStage(ctx, stage)
-
-		if err := st.handler(ctx, run, stage); err != nil {
-			stage.Status = domain.PipelineRunFailed
-			stage.Message = err.Error()
-			stage.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateStage(ctx, stage)
-
-			run.Status = domain.PipelineRunFailed
-			run.Message = fmt.Sprintf("stage %s failed: %s", st.stage, err.Error())
-			run.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.Update(ctx, run)
-			slog.Error("pipeline failed", "id", run.ID, "stage", st.stage, "error", err)
+	if len(existing) == 0 {
+		slog.Info("pipeline started", "id", run.ID, "lane", run.Lane, "services", run.Services)
+		run.Status = domain.PipelineRunRunning
+		s.detectChanges(ctx, run)
+		if err := s.loadPipelineConfig(ctx, run); err != nil {
+			s.failRun(ctx, run, err.Error())
+			slog.Error("pipeline failed", "id", run.ID, "error", err)
 			return
 		}
+		run.UpdatedAt = time.Now()
+		_ = s.pipelineRepo.Update(ctx, run)
+	} else {
+		slog.Info("pipeline resumed", "id", run.ID, "lane", run.Lane, "stages", len(existing))
+	}
+
+	// 每个 stage 记录各服务在该阶段的 job，stage 的起止覆盖 DAG 中所有服务的这一阶段
+	var stages []*domain.StageRun
+	for seq, st := range serviceStages {
+		stage, ok := recorded[st]
+		if !ok {
+			stage = s.saveStage(ctx, run, st, seq+1)
+		}
+		stages = append(stages, stage)
+	}
+	jobs := s.loadStageJobs(ctx, stages)
+	dagErr := s.runServiceDAG(ctx, run, stages, jobs, []serviceStep{s.runUnitTestJob, s.runBuildJob, s.runDeployJob})
+	if s.runCancelled(ctx, run.ID) {
+		slog.Info("pipeline cancelled", "id", run.ID, "lane", run.Lane)
+		return
+	}
+	if ctx.Err() != nil {
+		return // 租约被其他实例接管，由接管方接着执行
+	}
+	for _, stage := range stages {
+		s.finishStage(ctx, stage)
+	}
+	if dagErr != nil {
+		s.failRun(ctx, run, dagErr.Error())
+		slog.Error("pipeline failed", "id", run.ID, "error", dagErr)
+		return
+	}
 
-		stage.Status = domain.PipelineRunSucceeded
+	stage, ok := recorded[domain.StageE2E]
+	if !ok {
+		stage = s.saveStage(ctx, run, domain.StageE2E, len(stages)+1)
+	}
+	var e2eErr error
+	switch stage.Status {
+	case domain.PipelineRunSucceeded, domain.PipelineRunSkipped:
+	case domain.PipelineRunFailed:
+		e2eErr = errors.New(stage.Message)
+	default:
+		e2eErr = s.runE2EStage(ctx, run, stage, s.loadStageJobs(ctx, []*domain.StageRun{stage}))
+	}
+	if err := e2eErr; err != nil {
+		if ctx.Err() != nil || s.runCancelled(ctx, run.ID) {
+			return
+		}
+		stage.Status = domain.PipelineRunFailed
+		stage.Message = err.Error()
 		stage.UpdatedAt = time.Now()
 		_ = s.pipelineRepo.UpdateStage(ctx, stage)
+
+		s.failRun(ctx, run, fmt.Sprintf("stage %s failed: %s", domain.StageE2E, err.Error()))
+		slog.Error("pipeline failed", "id", run.ID, "stage", domain.StageE2E, "error", err)
+		return
+	}
+	stage.Status = domain.PipelineRunSucceeded
+	if len(run.SkippedServices) == len(run.Services) {
+		stage.Status = domain.PipelineRunSkipped
+		stage.Message = domain.SkippedNoChangesLog
 	}
+	stage.UpdatedAt = time.Now()
+	_ = s.pipelineRepo.UpdateStage(ctx, stage)
 
 	run.Status = domain.PipelineRunSucceeded
 	run.UpdatedAt = time.Now()
@@ -336,216 +453,427 @@ func (s *PipelineService) runPipeline(ctx context.Context, run *domain.PipelineR
 	slog.Info("pipeline succeeded", "id", run.ID, "lane", run.Lane)
 }
 
-// runUnitTestStage 并行跑注册服务的单测。
-func (s *PipelineService) runUnitTestStage(ctx context.Context, run *domain.PipelineRun, stage *domain.StageRun) error {
-	if s.testExecutor == nil {
-		slog.Warn("test executor not configured, skipping unit tests")
-		return nil
-	}
-
-	var wg sync.WaitGroup
-	errs := make(chan error, len(run.Services))
+// failRun 把 run 记为失败。
+func (s *PipelineService) failRun(ctx context.Context, run *domain.PipelineRun, message string) {
+	run.Status = domain.PipelineRunFailed
+	run.Message = message
+	run.UpdatedAt = time.Now()
+	_ = s.pipelineRepo.Update(ctx, run)
+}
 
-	for _, svcName := range run.Services {
-		wg.Add(1)
-		go func(svc string) {
-			defer wg.Done()
+// runCancelled 判断 run 是否已被 CancelPipelineRun 取消，取消后不再启动新的 job，也不覆盖已记录的状态。
+func (s *PipelineService) runCancelled(ctx context.Context, runID string) bool {
+	latest, err := s.pipelineRepo.FindByID(ctx, runID)
+	return err == nil && latest.Status == domain.PipelineRunCancelled
+}
 
-			now := time.Now()
-			job := &domain.JobRun{
-				ID:         uuid.New().String(),
-				StageRunID: stage.ID,
-				Name:       svc,
-				JobType:    string(domain.StageUnitTest),
-				Status:     domain.PipelineRunPending,
-				CreatedAt:  now,
-				UpdatedAt:  now,
-			}
-			_ = s.pipelineRepo.SaveJob(ctx, job)
-
-			// 确定 runtime 和命令（使用约定的默认值）
-			runtime, cmd := s.resolveUnitTestCommand(svc)
-			if cmd == "" {
-				job.Status = domain.PipelineRunSucceeded
-				job.Log = "no unit test configured, skipped"
-				job.UpdatedAt = time.Now()
-				_ = s.pipelineRepo.UpdateJob(ctx, job)
-				return
-			}
+// saveStage 落库一个 running 的 stage。
+func (s *PipelineService) saveStage(ctx context.Context, run *domain.PipelineRun, st domain.StageType, seq int) *domain.StageRun {
+	now := time.Now()
+	stage := &domain.StageRun{
+		ID:            uuid.New().String(),
+		PipelineRunID: run.ID,
+		Stage:         st,
+		Seq:           seq,
+		Status:        domain.PipelineRunRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	_ = s.pipelineRepo.SaveStage(ctx, stage)
+	return stage
+}
 
-			sub := &port.TestSubmission{
-				JobRunID: job.ID,
-				GitRepo:  s.resolveGitRepo(ctx, svc),
-				GitRef:   run.GitRef,
-				Runtime:  runtime,
-				Command:  cmd,
-			}
+// detectChanges 对比 lane 上次成功 run 的 commit，把没有改动、且 lane 上已有 release 的服务
+// 记入 run.SkippedServices。任何一步拿不准（manual 触发、没有成功过、compare 失败）都按全量跑。
+func (s *PipelineService) detectChanges(ctx context.Context, run *domain.PipelineRun) {
+	if s.gitComparer == nil || !domain.IsFullCommitSHA(run.CommitSHA) {
+		return
+	}
+	base, err := s.pipelineRepo.FindLatestSucceeded(ctx, run.Lane)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			slog.Warn("change detection: failed to find last successful run", "lane", run.Lane, "error", err)
+		}
+		return
+	}
 
-			jobName, err := s.testExecutor.Submit(ctx, sub)
+	changedByRepo := make(map[string][]string) // 同一仓库只 compare 一次
+	var skipped []string
+	for _, svc := range run.Services {
+		app, err := s.appRepo.FindByName(ctx, svc)
+		if err != nil || app.ImageRepoName == "" {
+			continue
+		}
+		imageRepo, err := s.imageRepo.FindByName(ctx, app.ImageRepoName)
+		if err != nil {
+			continue
+		}
+		// lane 上还没部署过（新加入 CIConfig 的服务）必须跑一遍
+		releases, err := s.releaseSvc.ListReleases(ctx, svc, run.Lane)
+		if err != nil || len(releases) == 0 {
+			continue
+		}
+		changed, ok := changedByRepo[imageRepo.GitRepo]
+		if !ok {
+			changed, err = s.gitComparer.ChangedFiles(ctx, imageRepo.GitRepo, base.CommitSHA, run.CommitSHA)
 			if err != nil {
-				job.Status = domain.PipelineRunFailed
-				job.Log = err.Error()
-				job.UpdatedAt = time.Now()
-				_ = s.pipelineRepo.UpdateJob(ctx, job)
-				errs <- fmt.Errorf("service %s unit test submit failed: %w", svc, err)
+				slog.Warn("change detection: compare failed, running all services",
+					"lane", run.Lane, "base", base.CommitSHA, "head", run.CommitSHA, "error", err)
 				return
 			}
+			changedByRepo[imageRepo.GitRepo] = changed
+		}
+		if !domain.PathsChanged(imageRepo.ChangePaths(), changed) {
+			skipped = append(skipped, svc)
+		}
+	}
 
-			job.K8sJobName = jobName
-			job.Status = domain.PipelineRunRunning
-			job.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateJob(ctx, job)
+	run.BaseCommitSHA = base.CommitSHA
+	run.SkippedServices = skipped
+	if len(skipped) > 0 {
+		slog.Info("change detection: skipping unchanged services", "id", run.ID, "lane", run.Lane,
+			"base", base.CommitSHA, "skipped", skipped)
+	}
+}
 
-			// 等待 Job 完成（轮询 DB 状态，由 Informer callback 更新）
-			if err := s.waitForJobCompletion(ctx, job.ID, 10*time.Minute); err != nil {
-				errs <- fmt.Errorf("service %s unit test: %w", svc, err)
-				return
-			}
-		}(svcName)
+// loadPipelineConfig 读取 run 所在 commit 的 pipeline.yml，校验后快照到 run 上，之后的测试阶段只看快照。
+// 仓库里没有 pipeline.yml 时不跑测试；读取失败或配置不合法时返回错误，run 在任何阶段开始前失败。
+func (s *PipelineService) loadPipelineConfig(ctx context.Context, run *domain.PipelineRun) error {
+	if s.configLoader == nil {
+		return nil
+	}
+	gitRepo := s.runGitRepo(ctx, run)
+	if gitRepo == "" {
+		return nil
+	}
+	ref := run.CheckoutRef()
+	cfg, err := s.configLoader.LoadPipelineConfig(ctx, gitRepo, ref)
+	if errors.Is(err, domain.ErrNotFound) {
+		slog.Warn("pipeline config not found, skipping tests", "id", run.ID, "repo", gitRepo, "ref", ref)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load %s at %s: %w", domain.PipelineConfigFile, ref, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
+	run.Config = cfg
+	return nil
+}
 
-	wg.Wait()
-	close(errs)
+// runUnitTestJob 跑服务的单测，命令来自 pipeline.yml 快照，没有配置时记为成功并注明跳过。
+func (s *PipelineService) runUnitTestJob(ctx context.Context, run *domain.PipelineRun, job, _ *domain.JobRun) error {
+	svc := job.Name
+	testCfg, _ := run.Config.Service(svc)
+	if s.testExecutor == nil {
+		s.endJob(ctx, job, domain.PipelineRunSucceeded, "test executor not configured, skipped")
+		return nil
+	}
+	if testCfg.UnitTest == "" {
+		s.endJob(ctx, job, domain.PipelineRunSucceeded, "no unit_test configured in pipeline.yml, skipped")
+		return nil
+	}
 
-	for err := range errs {
+	sub := &port.TestSubmission{
+		GitRepo: s.resolveGitRepo(ctx, svc),
+		GitRef:  run.CheckoutRef(),
+		Runtime: testCfg.Runtime,
+		Command: testCfg.UnitTest,
+	}
+	if err := s.submitTestJob(ctx, job, sub, testJobTimeout); err != nil {
+		return fmt.Errorf("service %s unit test: %w", svc, err)
+	}
+	return nil
+}
+
+// runBuildJob 构建服务的镜像并等待完成。job 已关联构建（接管上个进程的 run）时直接等这个构建。
+func (s *PipelineService) runBuildJob(ctx context.Context, run *domain.PipelineRun, job, _ *domain.JobRun) error {
+	svc := job.Name
+	if job.RefID == "" {
+		// 查找 App 关联的 ImageRepo
+		app, err := s.appRepo.FindByName(ctx, svc)
 		if err != nil {
-			return err
+			s.endJob(ctx, job, domain.PipelineRunFailed, fmt.Sprintf("app %s not found: %v", svc, err))
+			return fmt.Errorf("service %s build: app not found", svc)
+		}
+
+		// 使用 BuildService 创建构建；CI lane 的构建排在人工 / prod 构建之后。
+		// 构建固定在 run 的 commit 上，GitRef 只用来决定 channel
+		priority := domain.BuildPriorityCI
+		if run.Lane == domain.DefaultLane {
+			priority = domain.BuildPriorityManual
+		}
+		commitSHA := ""
+		if domain.IsFullCommitSHA(run.CommitSHA) {
+			commitSHA = run.CommitSHA
+		}
+		build, err := s.buildSvc.CreateBuild(ctx, app.ImageRepoName, CreateBuildRequest{
+			GitRef:    run.GitRef,
+			CommitSHA: commitSHA,
+			Priority:  priority,
+		})
+		if err != nil {
+			s.endJob(ctx, job, domain.PipelineRunFailed, err.Error())
+			return fmt.Errorf("service %s build failed: %w", svc, err)
+		}
+
+		job.RefID = build.ID
+		job.Status = domain.PipelineRunRunning
+		job.UpdatedAt = time.Now()
+		_ = s.pipelineRepo.UpdateJob(ctx, job)
+	}
+
+	// 等待 Build 完成
+	if err := s.waitForBuildCompletion(ctx, job.RefID, 15*time.Minute); err != nil {
+		if ctx.Err() != nil {
+			return err // 执行被停止，job 由取消方收尾
 		}
+		s.endJob(ctx, job, domain.PipelineRunFailed, err.Error())
+		return fmt.Errorf("service %s build: %w", svc, err)
 	}
+
+	s.endJob(ctx, job, domain.PipelineRunSucceeded, job.Log)
 	return nil
 }
 
-// runBuildStage 并行构建注册服务的镜像。
-func (s *PipelineService) runBuildStage(ctx context.Context, run *domain.PipelineRun, stage *domain.StageRun) error {
-	var wg sync.WaitGroup
-	errs := make(chan error, len(run.Services))
+// runDeployJob 把本次 run 的 build job（prev）构建出的镜像部署到 run 的泳道，等 rollout 结束；rollout 失败时 job 失败。
+// 下发是幂等的，接管上个进程的 run 时重新部署一次即可。
+func (s *PipelineService) runDeployJob(ctx context.Context, run *domain.PipelineRun, job, prev *domain.JobRun) error {
+	svc := job.Name
+	job.Status = domain.PipelineRunRunning
+	job.UpdatedAt = time.Now()
+	_ = s.pipelineRepo.UpdateJob(ctx, job)
 
-	for _, svcName := range run.Services {
-		wg.Add(1)
-		go func(svc string) {
-			defer wg.Done()
+	app, err := s.appRepo.FindByName(ctx, svc)
+	if err != nil {
+		s.endJob(ctx, job, domain.PipelineRunFailed, err.Error())
+		return fmt.Errorf("deploy %s: %w", svc, err)
+	}
 
-			now := time.Now()
-			job := &domain.JobRun{
-				ID:         uuid.New().String(),
-				StageRunID: stage.ID,
-				Name:       svc,
-				JobType:    string(domain.StageBuild),
-				Status:     domain.PipelineRunPending,
-				CreatedAt:  now,
-				UpdatedAt:  now,
-			}
-			_ = s.pipelineRepo.SaveJob(ctx, job)
+	// 部署 build job 关联的那个构建，而不是 repo 最新的成功构建：并发的其他 run 可能刚构建了别的分支
+	if prev == nil || prev.RefID == "" {
+		s.endJob(ctx, job, domain.PipelineRunFailed, "build job has no build recorded")
+		return fmt.Errorf("deploy %s: build job has no build recorded", svc)
+	}
+	build, err := s.buildSvc.GetBuild(ctx, app.ImageRepoName, prev.RefID)
+	if err == nil && build.Status != domain.BuildStatusSucceeded {
+		err = fmt.Errorf("build %s is %s", build.ID, build.Status)
+	}
+	if err != nil {
+		s.endJob(ctx, job, domain.PipelineRunFailed, err.Error())
+		return fmt.Errorf("deploy %s: %w", svc, err)
+	}
+
+	release, err := s.releaseSvc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
+		AppName:  svc,
+		Lane:     run.Lane,
+		ImageTag: build.Version,
+	})
+	if err == nil && release.Status != domain.ReleaseStatusDeployed {
+		// 只有 deployed 才算部署完成：rollout 失败等情况下 release 照常返回，原因在 Message 里；
+		// 依赖它的服务不能接着部署
+		job.RefID = release.ID
+		err = fmt.Errorf("release %s is %s: %s", release.ID, release.Status, release.Message)
+	}
+	if err != nil {
+		s.endJob(ctx, job, domain.PipelineRunFailed, err.Error())
+		return fmt.Errorf("deploy %s: %w", svc, err)
+	}
 
-			// 查找 App 关联的 ImageRepo
-			app, err := s.appRepo.FindByName(ctx, svc)
-			if err != nil {
-				job.Status = domain.PipelineRunFailed
-				job.Log = fmt.Sprintf("app %s not found: %v", svc, err)
-				job.UpdatedAt = time.Now()
-				_ = s.pipelineRepo.UpdateJob(ctx, job)
-				errs <- fmt.Errorf("service %s build: app not found", svc)
-				return
-			}
+	job.RefID = release.ID
+	s.endJob(ctx, job, domain.PipelineRunSucceeded, job.Log)
+	return nil
+}
 
-			// 使用 BuildService 创建构建
-			build, err := s.buildSvc.CreateBuild(ctx, app.ImageRepoName, CreateBuildRequest{
-				GitRef: run.GitRef,
-			})
-			if err != nil {
-				job.Status = domain.PipelineRunFailed
-				job.Log = err.Error()
-				job.UpdatedAt = time.Now()
-				_ = s.pipelineRepo.UpdateJob(ctx, job)
-				errs <- fmt.Errorf("service %s build failed: %w", svc, err)
-				return
-			}
+// runE2EStage 等 lane 上的服务就绪后，并行跑各服务的 e2e_test（e2e-http），全部通过后再跑
+// lark_flow 飞书全链路测试（e2e-lark）。测试代码检出 run 的 commit，与部署的镜像一致；
+// 测试 Job 通过 api-gateway 访问被测 lane，环境变量见 domain.E2EEnvs。
+// jobs 是 stage 下已记录的 job：已结束的按结果计，没结束的测试 Job 接着等。
+func (s *PipelineService) runE2EStage(ctx context.Context, run *domain.PipelineRun, stage *domain.StageRun, jobs stageJobs) error {
+	if s.testExecutor == nil {
+		slog.Warn("test executor not configured, skipping e2e tests")
+		return nil
+	}
 
-			job.RefID = build.ID
-			job.Status = domain.PipelineRunRunning
-			job.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateJob(ctx, job)
-
-			// 等待 Build 完成
-			if err := s.waitForBuildCompletion(ctx, build.ID, 15*time.Minute); err != nil {
-				job.Status = domain.PipelineRunFailed
-				job.Log = err.Error()
-				job.UpdatedAt = time.Now()
-				_ = s.pipelineRepo.UpdateJob(ctx, job)
-				errs <- fmt.Errorf("service %s build: %w", svc, err)
-				return
-			}
+	var services []string
+	for _, svc := range run.Services {
+		if run.Skips(svc) {
+			s.endJob(ctx, s.stageJob(ctx, jobs, stage, svc, string(stage.Stage)), domain.PipelineRunSkipped, domain.SkippedNoChangesLog)
+			continue
+		}
+		if testCfg, _ := run.Config.Service(svc); testCfg.E2ETest == "" {
+			s.endJob(ctx, s.stageJob(ctx, jobs, stage, svc, domain.JobTypeE2EHTTP), domain.PipelineRunSucceeded,
+				"no e2e_test configured in pipeline.yml, skipped")
+			continue
+		}
+		services = append(services, svc)
+	}
+	// 全链路测试覆盖整条 lane，只要有服务重新部署就跑
+	var larkFlow *domain.LarkFlowConfig
+	if run.Config != nil && len(run.SkippedServices) < len(run.Services) {
+		larkFlow = run.Config.LarkFlow
+	}
+	if len(services) == 0 && larkFlow == nil {
+		return nil
+	}
 
-			job.Status = domain.PipelineRunSucceeded
-			job.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateJob(ctx, job)
-		}(svcName)
+	if err := s.waitForLaneReady(ctx, run, laneReadyTimeout); err != nil {
+		return err
 	}
 
+	var wg sync.WaitGroup
+	errs := make(chan error, len(services))
+	for _, svcName := range services {
+		wg.Add(1)
+		go func(svc string) {
+			defer wg.Done()
+			testCfg, _ := run.Config.Service(svc)
+			job := s.stageJob(ctx, jobs, stage, svc, domain.JobTypeE2EHTTP)
+			sub := &port.TestSubmission{
+				GitRepo: s.resolveGitRepo(ctx, svc),
+				GitRef:  run.CheckoutRef(),
+				Runtime: testCfg.Runtime,
+				Command: testCfg.E2ETest,
+				Envs:    domain.E2EEnvs(run.Lane, s.cfg.GatewayURL, testCfg.E2EEnv),
+			}
+			if err := s.submitTestJob(ctx, job, sub, testJobTimeout); err != nil {
+				errs <- fmt.Errorf("service %s e2e test: %w", svc, err)
+			}
+		}(svcName)
+	}
 	wg.Wait()
 	close(errs)
-
 	for err := range errs {
 		if err != nil {
 			return err
 		}
 	}
+
+	if larkFlow == nil {
+		return nil
+	}
+	job := s.stageJob(ctx, jobs, stage, "lark-flow", domain.JobTypeE2ELark)
+	sub := &port.TestSubmission{
+		GitRepo: s.runGitRepo(ctx, run),
+		GitRef:  run.CheckoutRef(),
+		Runtime: larkFlow.Runtime,
+		Command: larkFlow.Cmd,
+		Envs:    domain.E2EEnvs(run.Lane, s.cfg.GatewayURL, larkFlow.Env),
+	}
+	if err := s.submitTestJob(ctx, job, sub, larkFlow.TimeoutDuration()); err != nil {
+		return fmt.Errorf("lark flow: %w", err)
+	}
 	return nil
 }
 
-// runDeployStage 部署服务到注册泳道。
-func (s *PipelineService) runDeployStage(ctx context.Context, run *domain.PipelineRun, stage *domain.StageRun) error {
-	for _, svcName := range run.Services {
-		now := time.Now()
-		job := &domain.JobRun{
-			ID:         uuid.New().String(),
-			StageRunID: stage.ID,
-			Name:       svcName,
-			JobType:    string(domain.StageDeploy),
-			Status:     domain.PipelineRunRunning,
-			CreatedAt:  now,
-			UpdatedAt:  now,
-		}
-		_ = s.pipelineRepo.SaveJob(ctx, job)
+// waitForLaneReady 轮询 lane 上参与本次 run 的服务，直到 release 全部部署成功且副本就绪。
+// deploy 阶段只等了本次部署的服务，跳过的服务沿用 lane 上现有的 release，也要确认它们在线。
+func (s *PipelineService) waitForLaneReady(ctx context.Context, run *domain.PipelineRun, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(pipelinePollInterval)
+	defer ticker.Stop()
 
-		app, err := s.appRepo.FindByName(ctx, svcName)
+	for {
+		pending, err := s.unreadyReleases(ctx, run)
 		if err != nil {
-			job.Status = domain.PipelineRunFailed
-			job.Log = err.Error()
-			job.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateJob(ctx, job)
-			return fmt.Errorf("deploy %s: %w", svcName, err)
+			return err
 		}
-
-		// 查找刚构建的最新成功版本
-		latestBuild, err := s.buildSvc.GetLatestSuccessfulBuild(ctx, app.ImageRepoName)
-		if err != nil {
-			job.Status = domain.PipelineRunFailed
-			job.Log = err.Error()
-			job.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateJob(ctx, job)
-			return fmt.Errorf("deploy %s: no successful build found", svcName)
+		if len(pending) == 0 {
+			return nil
 		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("lane %s not ready after %s: %s", run.Lane, timeout, strings.Join(pending, ", "))
+		case <-ticker.C:
+		}
+	}
+}
 
-		release, err := s.releaseSvc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
-			AppName:  svcName,
-			Lane:     run.Lane,
-			ImageTag: latestBuild.Version,
-		})
+// unreadyReleases 返回 lane 上还没就绪的 Deployment。部署失败、lane 休眠时直接报错，不再等待。
+// 服务在 lane 上没有 release 时请求落回 prod，不需要等。
+func (s *PipelineService) unreadyReleases(ctx context.Context, run *domain.PipelineRun) ([]string, error) {
+	var pending []string
+	for _, svc := range run.Services {
+		releases, err := s.releaseSvc.ListReleases(ctx, svc, run.Lane)
 		if err != nil {
-			job.Status = domain.PipelineRunFailed
-			job.Log = err.Error()
-			job.UpdatedAt = time.Now()
-			_ = s.pipelineRepo.UpdateJob(ctx, job)
-			return fmt.Errorf("deploy %s: %w", svcName, err)
+			return nil, fmt.Errorf("list releases of %s: %w", svc, err)
 		}
+		for _, release := range releases {
+			switch release.Status {
+			case domain.ReleaseStatusFailed:
+				return nil, fmt.Errorf("release %s failed: %s", release.ResourceName(), release.Message)
+			case domain.ReleaseStatusSleeping:
+				return nil, fmt.Errorf("release %s is sleeping, wake lane %s first", release.ResourceName(), run.Lane)
+			case domain.ReleaseStatusPending:
+				pending = append(pending, release.ResourceName())
+				continue
+			}
+			status, err := s.releaseSvc.GetReleaseStatus(ctx, release.ID)
+			if err != nil {
+				slog.Warn("e2e: failed to get deployment status", "release", release.ResourceName(), "error", err)
+				pending = append(pending, release.ResourceName())
+				continue
+			}
+			if status.Desired == 0 || status.Ready < status.Desired {
+				pending = append(pending, fmt.Sprintf("%s (%d/%d ready)", release.ResourceName(), status.Ready, status.Desired))
+			}
+		}
+	}
+	return pending, nil
+}
 
-		job.RefID = release.ID
-		job.Status = domain.PipelineRunSucceeded
+// saveJob 落库一条 pending 的 job。
+func (s *PipelineService) saveJob(ctx context.Context, stage *domain.StageRun, name, jobType string) *domain.JobRun {
+	now := time.Now()
+	job := &domain.JobRun{
+		ID:         uuid.New().String(),
+		StageRunID: stage.ID,
+		Name:       name,
+		JobType:    jobType,
+		Status:     domain.PipelineRunPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	_ = s.pipelineRepo.SaveJob(ctx, job)
+	return job
+}
+
+// submitTestJob 通过 TestExecutor 起测试 Job 并等待结束（状态由 Informer callback 写回 DB）。
+// job 已结束时直接返回它的结果；已记录 K8s Job 名（接管上个进程的 run）时重新提交，TestExecutor 按 JobRunID
+// 复用已有的 K8s Job，只在它已被清理时重跑。超时时删掉 K8s Job 并把 job 记为失败；执行被停止时 job 由取消方收尾。
+func (s *PipelineService) submitTestJob(ctx context.Context, job *domain.JobRun, sub *port.TestSubmission, timeout time.Duration) error {
+	if job.Status.IsTerminal() {
+		return jobResult(job)
+	}
+	sub.JobRunID = job.ID
+	jobName, err := s.testExecutor.Submit(ctx, sub)
+	if err != nil {
+		s.endJob(ctx, job, domain.PipelineRunFailed, err.Error())
+		return fmt.Errorf("submit failed: %w", err)
+	}
+
+	if job.K8sJobName == "" {
+		job.K8sJobName = jobName
+		job.Status = domain.PipelineRunRunning
 		job.UpdatedAt = time.Now()
 		_ = s.pipelineRepo.UpdateJob(ctx, job)
 	}
-	return nil
+
+	waitErr := s.waitForJobCompletion(ctx, job.ID, timeout)
+	if waitErr == nil || ctx.Err() != nil {
+		return waitErr
+	}
+	if latest, err := s.pipelineRepo.FindJobByID(ctx, job.ID); err == nil && !latest.Status.IsTerminal() {
+		_ = s.testExecutor.Cancel(context.WithoutCancel(ctx), jobName)
+		latest.Status = domain.PipelineRunFailed
+		latest.Log = waitErr.Error()
+		latest.UpdatedAt = time.Now()
+		_ = s.pipelineRepo.UpdateJob(context.WithoutCancel(ctx), latest)
+	}
+	return waitErr
 }
 
 // fillRunDetails 填充 PipelineRun 的 stages 和 jobs。
@@ -577,26 +905,20 @@ func (s *PipelineService) resolveGitRepo(ctx context.Context, appName string) st
 	return repo.GitRepo
 }
 
-// resolveUnitTestCommand 根据服务名推断 runtime 和测试命令。
-func (s *PipelineService) resolveUnitTestCommand(svcName string) (runtime, cmd string) {
-	// 约定：按 app 已知信息推断
-	// 未来由 pipeline.yml 提供，Phase 0 使用硬编码默认值
-	knownServices := map[string][2]string{
-		"paas-engine":   {"go", "cd apps/paas-engine && go test ./... -v -count=1"},
-		"agent-service": {"python", "cd apps/agent-service && uv run pytest tests/ -v"},
-		"channel-server": {"bun", "cd apps/channel-server && bun test"},
-		"tool-service":  {"python", "cd apps/tool-service && uv run pytest tests/ -v"},
-	}
-	if info, ok := knownServices[svcName]; ok {
-		return info[0], info[1]
+// runGitRepo 返回 run 的 git 仓库：monorepo 下各服务相同，取第一个能解析出来的。
+func (s *PipelineService) runGitRepo(ctx context.Context, run *domain.PipelineRun) string {
+	for _, svc := range run.Services {
+		if gitRepo := s.resolveGitRepo(ctx, svc); gitRepo != "" {
+			return gitRepo
+		}
 	}
-	return "", ""
+	return ""
 }
 
 // waitForJobCompletion 轮询 DB 等待 JobRun 完成。
 func (s *PipelineService) waitForJobCompletion(ctx context.Context, jobID string, timeout time.Duration) error {
 	deadline := time.After(timeout)
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pipelinePollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -622,9 +944,13 @@ func (s *PipelineService) waitForJobCompletion(ctx context.Context, jobID string
 	}
 }
 
-// waitForBuildCompletion 轮询 DB 等待 Build 完成。
+// buildQueueTimeout 是流水线等构建出队的上限，队列卡死（如无 executor）时不至于一直挂着。
+const buildQueueTimeout = time.Hour
+
+// waitForBuildCompletion 轮询 DB 等待 Build 完成。timeout 从构建出队开始计时，排队时间单独受 buildQueueTimeout 限制。
 func (s *PipelineService) waitForBuildCompletion(ctx context.Context, buildID string, timeout time.Duration) error {
-	deadline := time.After(timeout)
+	queueDeadline := time.After(buildQueueTimeout) // 出队后置 nil
+	var deadline <-chan time.Time                  // 出队前为 nil，永不触发
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -634,11 +960,17 @@ func (s *PipelineService) waitForBuildCompletion(ctx context.Context, buildID st
 			return ctx.Err()
 		case <-deadline:
 			return fmt.Errorf("timeout waiting for build %s", buildID)
+		case <-queueDeadline:
+			return fmt.Errorf("build %s still queued after %s", buildID, buildQueueTimeout)
 		case <-ticker.C:
 			build, err := s.buildSvc.buildRepo.FindByID(ctx, buildID)
 			if err != nil {
 				return err
 			}
+			if deadline == nil && build.Status != domain.BuildStatusPending {
+				deadline = time.After(timeout)
+				queueDeadline = nil
+			}
 			switch build.Status {
 			case domain.BuildStatusSucceeded:
 				return nil
@@ -0,0 +1,442 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+// --- stubs ---
+
+type stubPipelineRunRepo struct {
+	mu     sync.Mutex
+	runs   map[string]*domain.PipelineRun
+	stages map[string]*domain.StageRun
+	jobs   map[string]*domain.JobRun
+}
+
+func newStubPipelineRunRepo() *stubPipelineRunRepo {
+	return &stubPipelineRunRepo{
+		runs:   make(map[string]*domain.PipelineRun),
+		stages: make(map[string]*domain.StageRun),
+		jobs:   make(map[string]*domain.JobRun),
+	}
+}
+
+func (r *stubPipelineRunRepo) Save(_ context.Context, run *domain.PipelineRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *run
+	r.runs[run.ID] = &cp
+	return nil
+}
+func (r *stubPipelineRunRepo) FindByID(_ context.Context, id string) (*domain.PipelineRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cp := *run
+	return &cp, nil
+}
+func (r *stubPipelineRunRepo) FindByLane(_ context.Context, _ string, _ int) ([]*domain.PipelineRun, error) {
+	return nil, nil
+}
+func (r *stubPipelineRunRepo) ExistsByCommitSHA(_ context.Context, _ string) (bool, error) {
+	return false, nil
+}
+func (r *stubPipelineRunRepo) FindLatestSucceeded(_ context.Context, _ string) (*domain.PipelineRun, error) {
+	return nil, domain.ErrNotFound
+}
+func (r *stubPipelineRunRepo) FindUnfinished(_ context.Context) ([]*domain.PipelineRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*domain.PipelineRun
+	for _, run := range r.runs {
+		if !run.Status.IsTerminal() {
+			cp := *run
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Update 和真实实现一样不写租约列。
+func (r *stubPipelineRunRepo) Update(_ context.Context, run *domain.PipelineRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *run
+	if prev, ok := r.runs[run.ID]; ok {
+		cp.Lease = prev.Lease
+	}
+	r.runs[run.ID] = &cp
+	return nil
+}
+func (r *stubPipelineRunRepo) ClaimLease(_ context.Context, id, owner string, now, expiredBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[id]
+	if !ok || run.Status.IsTerminal() ||
+		(run.Owner != "" && run.HeartbeatAt != nil && !run.HeartbeatAt.Before(expiredBefore)) {
+		return false, nil
+	}
+	run.Hold(owner, now)
+	return true, nil
+}
+func (r *stubPipelineRunRepo) RenewLease(_ context.Context, id, owner string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[id]
+	if !ok || run.Owner != owner {
+		return false, nil
+	}
+	run.HeartbeatAt = &now
+	return true, nil
+}
+func (r *stubPipelineRunRepo) SaveStage(_ context.Context, stage *domain.StageRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *stage
+	r.stages[stage.ID] = &cp
+	return nil
+}
+func (r *stubPipelineRunRepo) FindStagesByRunID(_ context.Context, runID string) ([]domain.StageRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.StageRun
+	for _, st := range r.stages {
+		if st.PipelineRunID == runID {
+			out = append(out, *st)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	return out, nil
+}
+func (r *stubPipelineRunRepo) UpdateStage(ctx context.Context, stage *domain.StageRun) error {
+	return r.SaveStage(ctx, stage)
+}
+func (r *stubPipelineRunRepo) SaveJob(_ context.Context, job *domain.JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *job
+	r.jobs[job.ID] = &cp
+	return nil
+}
+func (r *stubPipelineRunRepo) FindJobsByStageID(_ context.Context, stageID string) ([]domain.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.JobRun
+	for _, job := range r.jobs {
+		if job.StageRunID == stageID {
+			out = append(out, *job)
+		}
+	}
+	return out, nil
+}
+func (r *stubPipelineRunRepo) FindJobByID(_ context.Context, id string) (*domain.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cp := *job
+	return &cp, nil
+}
+func (r *stubPipelineRunRepo) UpdateJob(ctx context.Context, job *domain.JobRun) error {
+	return r.SaveJob(ctx, job)
+}
+
+// jobsByName 返回 stage 下的 job，按 job 名索引。
+func (r *stubPipelineRunRepo) jobsByName(stageID string) map[string]domain.JobRun {
+	jobs, _ := r.FindJobsByStageID(context.Background(), stageID)
+	out := make(map[string]domain.JobRun, len(jobs))
+	for _, job := range jobs {
+		out[job.Name] = job
+	}
+	return out
+}
+
+// stubTestExecutor 记录提交的测试，等 job 进入 running 后按命令是否在 fail 中回调结果，模拟 Informer。
+type stubTestExecutor struct {
+	mu        sync.Mutex
+	repo      *stubPipelineRunRepo
+	onStatus  port.TestStatusCallback
+	fail      map[string]bool // 按 Command 判定失败
+	hang      map[string]bool // 按 Command 判定一直不结束
+	submitted []port.TestSubmission
+	cancelled []string
+}
+
+func (e *stubTestExecutor) Submit(_ context.Context, sub *port.TestSubmission) (string, error) {
+	e.mu.Lock()
+	e.submitted = append(e.submitted, *sub)
+	e.mu.Unlock()
+
+	if e.hang[sub.Command] {
+		return "ci-test-" + sub.JobRunID, nil
+	}
+	status := domain.PipelineRunSucceeded
+	if e.fail[sub.Command] {
+		status = domain.PipelineRunFailed
+	}
+	go func() {
+		for {
+			job, err := e.repo.FindJobByID(context.Background(), sub.JobRunID)
+			if err == nil && job.Status == domain.PipelineRunRunning {
+				e.onStatus(sub.JobRunID, status, "exit "+string(status))
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	return "ci-test-" + sub.JobRunID, nil
+}
+func (e *stubTestExecutor) Cancel(_ context.Context, jobName string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancelled = append(e.cancelled, jobName)
+	return nil
+}
+func (e *stubTestExecutor) Watch(_ context.Context, _ port.TestStatusCallback) error { return nil }
+func (e *stubTestExecutor) GetLogs(_ context.Context, _ string) (string, error)      { return "", nil }
+func (e *stubTestExecutor) FollowLogs(_ context.Context, _ string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+// newE2ETestService 返回一个 lane 上部署了 agent-service 的 PipelineService。
+func newE2ETestService(releaseStatus domain.ReleaseStatus, deployStatus *domain.DeploymentStatus) (*PipelineService, *stubPipelineRunRepo, *stubTestExecutor) {
+	appRepo := &stubAppRepo{app: &domain.App{Name: "agent-service", ImageRepoName: "agent-service", Port: 8080}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "agent-service", GitRepo: "bezhai/chiwei-platform"}}
+	releaseRepo := newReleaseTestReleaseRepo()
+	_ = releaseRepo.Save(context.Background(), &domain.Release{
+		ID: "r1", AppName: "agent-service", Lane: "coe-x", Status: releaseStatus, DeployName: "agent-service-coe-x",
+	})
+	releaseSvc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, nil,
+		&stubDeployer{status: deployStatus}, nil, ReleaseServiceConfig{})
+
+	pipelineRepo := newStubPipelineRunRepo()
+	executor := &stubTestExecutor{repo: pipelineRepo, fail: map[string]bool{}, hang: map[string]bool{}}
+	svc := NewPipelineService(nil, pipelineRepo, executor, nil, releaseSvc, appRepo, imageRepoRepo, nil, nil, nil,
+		PipelineServiceConfig{CINamespace: "paas-builds", GatewayURL: "http://api-gateway.prod:8080"})
+	executor.onStatus = svc.OnTestJobStatusChange
+	return svc, pipelineRepo, executor
+}
+
+func newE2ETestRun() *domain.PipelineRun {
+	return &domain.PipelineRun{
+		ID:              "run-1",
+		GitRef:          "feat/x",
+		Lane:            "coe-x",
+		Services:        []string{"agent-service", "channel-server", "tool-service"},
+		SkippedServices: []string{"tool-service"},
+		Config: &domain.PipelineConfig{
+			Services: map[string]domain.ServiceTestConfig{
+				"agent-service":  {Runtime: "python", E2ETest: "pytest e2e/", E2EEnv: map[string]string{"PAAS_API": "http://paas-engine:8080"}},
+				"channel-server": {Runtime: "bun", UnitTest: "bun test"},
+			},
+			LarkFlow: &domain.LarkFlowConfig{Runtime: "bun", Cmd: "bun run test:e2e:lark", Timeout: "5m"},
+		},
+	}
+}
+
+// --- tests ---
+
+func TestRunE2EStage(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	defer func() { pipelinePollInterval = 5 * time.Second }()
+
+	svc, repo, executor := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	stage := &domain.StageRun{ID: "stage-e2e", Stage: domain.StageE2E}
+	run := newE2ETestRun()
+	run.CommitSHA = "0123456789abcdef0123456789abcdef01234567"
+
+	if err := svc.runE2EStage(context.Background(), run, stage, nil); err != nil {
+		t.Fatalf("runE2EStage() error = %v", err)
+	}
+
+	// 先跑服务的 e2e_test，再跑全链路
+	if len(executor.submitted) != 2 {
+		t.Fatalf("submitted %d jobs, want 2: %+v", len(executor.submitted), executor.submitted)
+	}
+	http, lark := executor.submitted[0], executor.submitted[1]
+	// 测试代码检出 run 的 commit，而不是可能已被推进的分支
+	if http.Command != "pytest e2e/" || http.GitRepo != "bezhai/chiwei-platform" || http.GitRef != run.CommitSHA {
+		t.Errorf("e2e-http submission = %+v", http)
+	}
+	for k, v := range map[string]string{"LANE": "coe-x", "GATEWAY_URL": "http://api-gateway.prod:8080", "LANE_HEADER": "x-lane", "PAAS_API": "http://paas-engine:8080"} {
+		if http.Envs[k] != v {
+			t.Errorf("e2e-http env %s = %q, want %q", k, http.Envs[k], v)
+		}
+	}
+	if lark.Command != "bun run test:e2e:lark" || lark.Envs["LANE"] != "coe-x" || lark.GitRef != run.CommitSHA {
+		t.Errorf("e2e-lark submission = %+v", lark)
+	}
+
+	jobs := repo.jobsByName(stage.ID)
+	for name, want := range map[string]struct {
+		jobType string
+		status  domain.PipelineRunStatus
+	}{
+		"agent-service":  {domain.JobTypeE2EHTTP, domain.PipelineRunSucceeded},
+		"channel-server": {domain.JobTypeE2EHTTP, domain.PipelineRunSucceeded}, // 没配置 e2e_test
+		"tool-service":   {string(domain.StageE2E), domain.PipelineRunSkipped},
+		"lark-flow":      {domain.JobTypeE2ELark, domain.PipelineRunSucceeded},
+	} {
+		job := jobs[name]
+		if job.JobType != want.jobType || job.Status != want.status {
+			t.Errorf("job %s = %s/%s, want %s/%s", name, job.JobType, job.Status, want.jobType, want.status)
+		}
+	}
+}
+
+func TestRunE2EStage_FailureGatesLarkFlow(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	defer func() { pipelinePollInterval = 5 * time.Second }()
+
+	svc, repo, executor := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	executor.fail["pytest e2e/"] = true
+	stage := &domain.StageRun{ID: "stage-e2e", Stage: domain.StageE2E}
+
+	err := svc.runE2EStage(context.Background(), newE2ETestRun(), stage, nil)
+	if err == nil || !strings.Contains(err.Error(), "agent-service e2e test") {
+		t.Fatalf("runE2EStage() error = %v, want agent-service failure", err)
+	}
+	if len(executor.submitted) != 1 {
+		t.Errorf("lark flow should not run after e2e-http failed, submitted %+v", executor.submitted)
+	}
+	if job := repo.jobsByName(stage.ID)["agent-service"]; job.Status != domain.PipelineRunFailed {
+		t.Errorf("agent-service job status = %s, want failed", job.Status)
+	}
+}
+
+func TestRunE2EStage_WaitsForLane(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	defer func() { pipelinePollInterval = 5 * time.Second }()
+
+	// 部署失败的 release 直接让 stage 失败，不提交测试
+	svc, _, executor := newE2ETestService(domain.ReleaseStatusFailed, nil)
+	err := svc.runE2EStage(context.Background(), newE2ETestRun(), &domain.StageRun{ID: "s1", Stage: domain.StageE2E}, nil)
+	if err == nil || !strings.Contains(err.Error(), "agent-service-coe-x failed") {
+		t.Errorf("runE2EStage() error = %v, want release failure", err)
+	}
+	if len(executor.submitted) != 0 {
+		t.Errorf("no test should be submitted before the lane is ready, got %+v", executor.submitted)
+	}
+
+	// 副本一直没就绪：超时
+	svc, _, _ = newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 2, Ready: 1})
+	err = svc.waitForLaneReady(context.Background(), newE2ETestRun(), 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "agent-service-coe-x (1/2 ready)") {
+		t.Errorf("waitForLaneReady() error = %v, want timeout listing the deployment", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := svc.waitForLaneReady(ctx, newE2ETestRun(), time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitForLaneReady() with cancelled ctx error = %v", err)
+	}
+}
+
+func TestRunUnitTestJob_ChecksOutRunCommit(t *testing.T) {
+	pipelinePollInterval = time.Millisecond
+	defer func() { pipelinePollInterval = 5 * time.Second }()
+
+	svc, _, executor := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	stage := &domain.StageRun{ID: "stage-unit", Stage: domain.StageUnitTest}
+	run := newE2ETestRun()
+	run.CommitSHA = "0123456789abcdef0123456789abcdef01234567"
+
+	job := svc.saveJob(context.Background(), stage, "channel-server", string(domain.StageUnitTest))
+	if err := svc.runUnitTestJob(context.Background(), run, job, nil); err != nil {
+		t.Fatalf("runUnitTestJob() error = %v", err)
+	}
+	// 分支可能在 run 执行期间被推进，测试固定跑 run 的 commit
+	if len(executor.submitted) != 1 || executor.submitted[0].GitRef != run.CommitSHA {
+		t.Errorf("unit test submissions = %+v, want checkout of %s", executor.submitted, run.CommitSHA)
+	}
+}
+
+func TestRunDeployJob_DeploysTheRunsBuild(t *testing.T) {
+	svc, repo, _ := newE2ETestService(domain.ReleaseStatusDeployed, &domain.DeploymentStatus{Desired: 1, Ready: 1})
+	now := time.Now()
+	// b-other 是其他 run 之后构建的，虽然更新也不能部署
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b-run":     {ID: "b-run", ImageRepoName: "agent-service", Version: "1.0.0.5", Status: domain.BuildStatusSucceeded, CreatedAt: now.Add(-time.Minute)},
+		"b-other":   {ID: "b-other", ImageRepoName: "agent-service", Version: "1.0.0.6", Status: domain.BuildStatusSucceeded, CreatedAt: now},
+		"b-pending": {ID: "b-pending", ImageRepoName: "agent-service", Version: "1.0.0.7", Status: domain.BuildStatusPending, CreatedAt: now},
+	}}
+	svc.buildSvc = NewBuildService(nil, buildRepo, nil, nil, BuildServiceConfig{})
+	run := newE2ETestRun()
+	stage := &domain.StageRun{ID: "stage-deploy", Stage: domain.StageDeploy}
+
+	job := svc.saveJob(context.Background(), stage, "agent-service", string(domain.StageDeploy))
+	if err := svc.runDeployJob(context.Background(), run, job, &domain.JobRun{RefID: "b-run"}); err != nil {
+		t.Fatalf("runDeployJob() error = %v", err)
+	}
+	release, err := svc.releaseSvc.releaseRepo.FindByID(context.Background(), job.RefID)
+	if err != nil {
+		t.Fatalf("release %q not found: %v", job.RefID, err)
+	}
+	if !strings.HasSuffix(release.Image, ":1.0.0.5") {
+		t.Errorf("deployed image = %q, want the run's build 1.0.0.5", release.Image)
+	}
+
+	// build job 没有记录构建或构建没成功：不部署
+	for _, prev := range []*domain.JobRun{nil, {}, {RefID: "b-pending"}} {
+		job := svc.saveJob(context.Background(), stage, "agent-service", string(domain.StageDeploy))
+		if err := svc.runDeployJob(context.Background(), run, job, prev); err == nil {
+			t.Errorf("runDeployJob(prev=%+v) should fail", prev)
+		}
+		if got := repo.jobs[job.ID]; got.Status != domain.PipelineRunFailed {
+			t.Errorf("deploy job status = %s, want failed", got.Status)
+		}
+	}
+
+	// rollout 没有到 deployed：job 失败并记下 release
+	svc.releaseSvc.deployer = &stubDeployer{deployErr: errors.New("progress deadline exceeded")}
+	job = svc.saveJob(context.Background(), stage, "agent-service", string(domain.StageDeploy))
+	if err := svc.runDeployJob(context.Background(), run, job, &domain.JobRun{RefID: "b-run"}); err == nil {
+		t.Error("runDeployJob() with failed rollout should fail")
+	}
+	if got := repo.jobs[job.ID]; got.Status != domain.PipelineRunFailed || got.RefID == "" {
+		t.Errorf("deploy job = %+v, want failed with release ref", got)
+	}
+}
+
+func TestTriggerBranch_Rejects(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	repo := newStubPipelineRunRepo()
+	ciConfigs := &stubCIConfigRepo{configs: map[string]*domain.CIConfig{
+		"coe-x": {ID: "ci-x", Lane: "coe-x", Branch: "feat/x", Status: "archived"},
+	}}
+	svc := NewPipelineService(ciConfigs, repo, nil, nil, nil, nil, nil, nil, nil, nil, PipelineServiceConfig{})
+
+	cases := []struct {
+		branch, sha string
+		want        error
+	}{
+		{"main", sha, domain.ErrInvalidInput},
+		{"feat/x", "abc123", domain.ErrInvalidInput},
+		{"feat/x", sha, domain.ErrNotFound}, // 注销的 CI 不再触发
+		{"feat/unknown", sha, domain.ErrNotFound},
+	}
+	for _, tc := range cases {
+		if _, err := svc.TriggerBranch(context.Background(), tc.branch, tc.sha); !errors.Is(err, tc.want) {
+			t.Errorf("TriggerBranch(%s, %s) error = %v, want %v", tc.branch, tc.sha, err, tc.want)
+		}
+	}
+	if runs, _ := repo.FindUnfinished(context.Background()); len(runs) != 0 {
+		t.Errorf("created runs %+v, want none", runs)
+	}
+}
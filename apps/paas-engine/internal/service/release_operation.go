@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/metrics"
+	"github.com/google/uuid"
+)
+
+var (
+	errOperationSuperseded = fmt.Errorf("%w by a newer operation", domain.ErrOperationSuperseded)
+	errReleaseDeleted      = fmt.Errorf("%w: deleted while operation was running", domain.ErrReleaseNotFound)
+	errOperationTakenOver  = fmt.Errorf("%w: taken over by another paas-engine instance", domain.ErrOperationSuperseded)
+)
+
+// deployPlan 是一次部署的完整输入：待写回的 release 行 + 下发所需的 App 和 bundle envs。
+type deployPlan struct {
+	release    *domain.Release
+	app        *domain.App
+	bundleEnvs map[string]string
+	isNew      bool // release 行尚未落库
+	// wakeReplicas > 0 表示 lane 唤醒：只把副本数恢复到该值，不重新下发 spec
+	wakeReplicas int32
+	// reason 写入本次 revision，如漏洞扫描门禁的豁免说明
+	reason string
+}
+
+type runningOperation struct {
+	opID   string
+	cancel context.CancelCauseFunc
+}
+
+// runOperation 落库 pending 状态和操作记录后执行部署，等到结果写回再返回。
+// 部署不受 ctx 取消影响：调用方不再等待（如 pipeline run 被取消）时返回 ctx 的错误，
+// 操作在后台照常执行完，不会因为调用方走开而把 release 记为失败。
+// 操作被顶替或接管时返回 domain.ErrOperationSuperseded，返回的 release 一定已经部署完或失败。
+func (s *ReleaseService) runOperation(ctx context.Context, plan *deployPlan, opType domain.ReleaseOperationType, reason string) (*domain.Release, error) {
+	op, err := s.beginOperation(ctx, plan, opType, reason)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.executeOperation(context.WithoutCancel(ctx), plan, op)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+		return plan.release, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("stopped waiting for operation %s on release %s, it continues in the background: %w",
+			op.ID, plan.release.ID, context.Cause(ctx))
+	}
+}
+
+// startOperation 落库 pending 状态和操作记录后立即返回，部署在后台执行。
+// 后台 goroutine 持有 release 和操作的独立副本，返回给调用方的对象不会被并发改写。
+func (s *ReleaseService) startOperation(ctx context.Context, plan *deployPlan, opType domain.ReleaseOperationType, reason string) (*domain.Release, *domain.ReleaseOperation, error) {
+	op, err := s.beginOperation(ctx, plan, opType, reason)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := plan.release
+	bgRelease := *release
+	bgPlan := *plan
+	bgPlan.release = &bgRelease
+	bgOp := *op
+	bgOp.Events = append([]domain.ReleaseOperationEvent{}, op.Events...)
+
+	go func() {
+		if err := s.executeOperation(context.Background(), &bgPlan, &bgOp); persistFailed(err) {
+			slog.Error("startOperation: failed to persist release", "release_id", release.ID, "op_id", op.ID, "error", err)
+		}
+	}()
+	return release, op, nil
+}
+
+func (s *ReleaseService) beginOperation(ctx context.Context, plan *deployPlan, opType domain.ReleaseOperationType, reason string) (*domain.ReleaseOperation, error) {
+	release := plan.release
+	release.Status = domain.ReleaseStatusPending
+	if opType != domain.ReleaseOperationWake {
+		// 任何新部署都意味着 lane 不再休眠；唤醒操作保留 SleptReplicas 供重启后接管
+		release.SleptReplicas = 0
+	}
+	if plan.isNew {
+		if err := s.releaseRepo.Save(ctx, release); err != nil {
+			return nil, err
+		}
+		plan.isNew = false
+	} else {
+		if err := s.releaseRepo.Update(ctx, release); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	op := &domain.ReleaseOperation{
+		ID:        uuid.New().String(),
+		ReleaseID: release.ID,
+		AppName:   release.AppName,
+		Lane:      release.Lane,
+		Type:      opType,
+		Image:     release.Image,
+		Reason:    reason,
+		Status:    domain.ReleaseOperationRunning,
+		Events:    []domain.ReleaseOperationEvent{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	op.Hold(s.owner, now)
+	if s.operationRepo != nil {
+		if err := s.operationRepo.Save(ctx, op); err != nil {
+			return nil, err
+		}
+	}
+	if opType != domain.ReleaseOperationWake {
+		s.wakeLaneSiblings(ctx, release)
+	}
+	return op, nil
+}
+
+// executeOperation 下发并等待 rollout，把结果写回 release 行和操作记录。
+// 被新操作顶替或 release 被删除时只结束操作，不再碰 release 行；操作被其他实例接管
+// （本实例续约中断超过租约期）时操作和 release 行都交给接管者写。
+// 部署本身的失败记在 release.Status / Message 上；返回错误表示 release 行没有由本操作写回：
+// 被顶替或接管（domain.ErrOperationSuperseded）、release 已删除，或写回失败。
+func (s *ReleaseService) executeOperation(ctx context.Context, plan *deployPlan, op *domain.ReleaseOperation) error {
+	ctx, done := s.trackOperation(ctx, op)
+	defer done()
+	defer s.holdOperationLease(op)()
+
+	err := s.rollout(ctx, plan, op)
+	cause := context.Cause(ctx)
+	if err != nil && errors.Is(cause, errOperationTakenOver) {
+		slog.Warn("release operation taken over by another instance", "op_id", op.ID, "release_id", op.ReleaseID)
+		return cause
+	}
+	if err != nil && (errors.Is(cause, errOperationSuperseded) || errors.Is(cause, errReleaseDeleted)) {
+		s.finishOperation(op, cause)
+		return cause
+	}
+
+	release := plan.release
+	if err != nil {
+		release.Status = domain.ReleaseStatusFailed
+		release.Message = err.Error()
+	} else {
+		release.Status = domain.ReleaseStatusDeployed
+		release.Message = ""
+		release.SleptReplicas = 0
+	}
+	// 写回用独立 ctx：调用方 ctx 取消时也要把最终状态落库
+	bg := context.Background()
+	if uErr := s.releaseRepo.Update(bg, release); uErr != nil {
+		s.finishOperation(op, fmt.Errorf("persist release: %w", uErr))
+		return uErr
+	}
+	// 唤醒只恢复副本数，spec 没变，不算一次发布
+	if release.Status == domain.ReleaseStatusDeployed && plan.wakeReplicas == 0 {
+		metrics.ReleasesTotal.WithLabelValues(release.Lane).Inc()
+		s.recordRevision(bg, release, plan.bundleEnvs, op.Reason)
+	}
+	s.finishOperation(op, err)
+	return nil
+}
+
+// rollout 下发 K8s 资源并等待就绪，进度逐条追加到操作事件里。
+// 未配置 deployer（本地/测试）时直接视为部署成功。
+func (s *ReleaseService) rollout(ctx context.Context, plan *deployPlan, op *domain.ReleaseOperation) error {
+	if s.deployer == nil {
+		return nil
+	}
+	release := plan.release
+	if plan.wakeReplicas > 0 {
+		if _, err := s.deployer.Scale(ctx, release, plan.wakeReplicas); err != nil {
+			return err
+		}
+		s.appendOperationEvent(op, domain.ReleaseOperationEvent{
+			Type:    domain.OperationEventApplied,
+			Message: fmt.Sprintf("scaled %s to %d replicas", release.DeployName, plan.wakeReplicas),
+		})
+	} else {
+		if err := s.deployer.Apply(ctx, release, plan.app, plan.bundleEnvs); err != nil {
+			return err
+		}
+		s.appendOperationEvent(op, domain.ReleaseOperationEvent{
+			Type:    domain.OperationEventApplied,
+			Message: fmt.Sprintf("applied %s with image %s", release.DeployName, release.Image),
+		})
+	}
+	return s.deployer.WaitForRollout(ctx, release.DeployName, func(ev domain.ReleaseOperationEvent) {
+		s.appendOperationEvent(op, ev)
+	})
+}
+
+// trackOperation 登记 release 上正在运行的操作，并取消同一 release 上更早的操作。
+func (s *ReleaseService) trackOperation(ctx context.Context, op *domain.ReleaseOperation) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	s.mu.Lock()
+	if prev, ok := s.running[op.ReleaseID]; ok {
+		prev.cancel(errOperationSuperseded)
+	}
+	s.running[op.ReleaseID] = &runningOperation{opID: op.ID, cancel: cancel}
+	s.mu.Unlock()
+
+	return ctx, func() {
+		s.mu.Lock()
+		if cur, ok := s.running[op.ReleaseID]; ok && cur.opID == op.ID {
+			delete(s.running, op.ReleaseID)
+		}
+		s.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// holdOperationLease 在操作执行期间续约，租约被其他实例接管时取消本实例的执行。
+// 返回的函数停止续约；未配置 operationRepo 时没有租约可续。
+func (s *ReleaseService) holdOperationLease(op *domain.ReleaseOperation) func() {
+	if s.operationRepo == nil {
+		return func() {}
+	}
+	return holdLease("release operation", op.ID, func(ctx context.Context, now time.Time) (bool, error) {
+		return s.operationRepo.RenewLease(ctx, op.ID, s.owner, now)
+	}, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if cur, ok := s.running[op.ReleaseID]; ok && cur.opID == op.ID {
+			cur.cancel(errOperationTakenOver)
+		}
+	})
+}
+
+// cancelRunning 取消 release 上正在运行的操作（如果有）。
+func (s *ReleaseService) cancelRunning(releaseID string, cause error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.running[releaseID]; ok {
+		cur.cancel(cause)
+	}
+}
+
+// appendOperationEvent 追加一条进度事件并落库。事件写失败不影响部署本身，只记日志。
+func (s *ReleaseService) appendOperationEvent(op *domain.ReleaseOperation, ev domain.ReleaseOperationEvent) {
+	now := time.Now()
+	if ev.Time.IsZero() {
+		ev.Time = now
+	}
+	op.Events = append(op.Events, ev)
+	op.UpdatedAt = now
+	if s.operationRepo == nil {
+		return
+	}
+	if err := s.operationRepo.Update(context.Background(), op); err != nil {
+		slog.Error("appendOperationEvent: failed to update operation", "op_id", op.ID, "error", err)
+	}
+}
+
+// finishOperation 按 err 把操作标记为 succeeded / failed，并追加终态事件。
+func (s *ReleaseService) finishOperation(op *domain.ReleaseOperation, err error) {
+	now := time.Now()
+	op.FinishedAt = &now
+	if err != nil {
+		op.Status = domain.ReleaseOperationFailed
+		op.Message = err.Error()
+		s.appendOperationEvent(op, domain.ReleaseOperationEvent{Time: now, Type: domain.OperationEventFailed, Message: err.Error()})
+		return
+	}
+	op.Status = domain.ReleaseOperationSucceeded
+	op.Message = ""
+	s.appendOperationEvent(op, domain.ReleaseOperationEvent{Time: now, Type: domain.OperationEventSucceeded})
+}
+
+// GetReleaseOperation 查询某个 release 上的一次部署操作及其进度事件。
+func (s *ReleaseService) GetReleaseOperation(ctx context.Context, releaseID, opID string) (*domain.ReleaseOperation, error) {
+	if s.operationRepo == nil {
+		return nil, fmt.Errorf("release operations not configured")
+	}
+	op, err := s.operationRepo.FindByID(ctx, opID)
+	if err != nil {
+		return nil, err
+	}
+	if op.ReleaseID != releaseID {
+		return nil, domain.ErrReleaseOperationNotFound
+	}
+	return op, nil
+}
+
+// ResumeInterrupted 接管执行实例已退出（租约过期）的部署操作，例如重启或蓝绿切换打断的部署。
+// Release 行在操作开始前就已写入目标状态，下发又是幂等的，所以按当前 release 行重新下发
+// 并等 rollout 即可；release 已被删除或无法重建下发参数时把操作标记为 failed。同一 release
+// 上有多条 running 操作时只有最新一条可能被接管，其余视为被顶替。每条操作都要先抢到租约，
+// 多个实例同时接管时只有一个执行。
+func (s *ReleaseService) ResumeInterrupted(ctx context.Context) error {
+	if s.operationRepo == nil {
+		return nil
+	}
+	ops, err := s.operationRepo.FindRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("find running operations: %w", err)
+	}
+
+	latest := make(map[string]*domain.ReleaseOperation)
+	for _, op := range ops {
+		if prev, ok := latest[op.ReleaseID]; !ok || op.CreatedAt.After(prev.CreatedAt) {
+			latest[op.ReleaseID] = op
+		}
+	}
+
+	for _, op := range ops {
+		if s.executing(op) {
+			continue
+		}
+		previous := op.Owner
+		now := time.Now()
+		if !op.Expired(now) {
+			continue
+		}
+		claimed, err := s.operationRepo.ClaimLease(ctx, op.ID, s.owner, now, domain.LeaseExpiredBefore(now))
+		if err != nil {
+			slog.Error("failed to claim interrupted release operation", "op_id", op.ID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		op.Hold(s.owner, now)
+		if latest[op.ReleaseID] != op {
+			s.finishOperation(op, errOperationSuperseded)
+			continue
+		}
+
+		releaseID := op.ReleaseID
+		plan, err := s.planResume(ctx, op)
+		if err != nil {
+			s.finishOperation(op, fmt.Errorf("cannot resume interrupted operation: %w", err))
+			continue
+		}
+		s.appendOperationEvent(op, domain.ReleaseOperationEvent{
+			Type:    domain.OperationEventResumed,
+			Message: resumedMessage(previous),
+		})
+		slog.Info("resuming release operation", "op_id", op.ID, "release_id", releaseID, "previous_owner", previous)
+		go func() {
+			if err := s.executeOperation(context.Background(), plan, op); persistFailed(err) {
+				slog.Error("ResumeInterrupted: failed to persist release", "release_id", releaseID, "op_id", op.ID, "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// persistFailed 报告后台执行的操作是否因为写回失败而丢了结果；被顶替、接管或 release 被删除是正常结束。
+func persistFailed(err error) bool {
+	return err != nil && !errors.Is(err, domain.ErrOperationSuperseded) && !errors.Is(err, domain.ErrNotFound)
+}
+
+// executing 报告 op 是否正在本实例里执行。
+func (s *ReleaseService) executing(op *domain.ReleaseOperation) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.running[op.ReleaseID]
+	return ok && cur.opID == op.ID
+}
+
+func resumedMessage(previousOwner string) string {
+	if previousOwner == "" {
+		return "resumed after paas-engine restart"
+	}
+	return "resumed from paas-engine instance " + previousOwner
+}
+
+func (s *ReleaseService) planResume(ctx context.Context, op *domain.ReleaseOperation) (*deployPlan, error) {
+	release, err := s.releaseRepo.FindByID(ctx, op.ReleaseID)
+	if err != nil {
+		return nil, err
+	}
+	app, err := s.appRepo.FindByName(ctx, release.AppName)
+	if err != nil {
+		return nil, err
+	}
+	var bundleEnvs map[string]string
+	if s.configBundleSvc != nil && len(app.ConfigBundles) > 0 {
+		bundleEnvs, err = s.configBundleSvc.ResolveBundleEnvs(ctx, app, release.Lane)
+		if err != nil {
+			return nil, fmt.Errorf("resolve config bundles: %w", err)
+		}
+	}
+	release.DeployName = release.ResourceName()
+	plan := &deployPlan{release: release, app: app, bundleEnvs: bundleEnvs}
+	if op.Type == domain.ReleaseOperationWake {
+		plan.wakeReplicas = wakeReplicas(release)
+	}
+	return plan, nil
+}
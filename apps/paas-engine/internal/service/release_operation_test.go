@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+	"github.com/chiwei-platform/paas-engine/internal/port"
+)
+
+// stubOperationRepo 是线程安全的内存实现，存副本避免和后台 goroutine 共享结构体。
+type stubOperationRepo struct {
+	mu  sync.Mutex
+	ops map[string]domain.ReleaseOperation
+}
+
+func newStubOperationRepo() *stubOperationRepo {
+	return &stubOperationRepo{ops: make(map[string]domain.ReleaseOperation)}
+}
+
+func (r *stubOperationRepo) put(op *domain.ReleaseOperation) {
+	cp := *op
+	cp.Events = append([]domain.ReleaseOperationEvent(nil), op.Events...)
+	r.ops[op.ID] = cp
+}
+
+func (r *stubOperationRepo) Save(_ context.Context, op *domain.ReleaseOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(op)
+	return nil
+}
+
+// Update 和真实实现一样不写租约列。
+func (r *stubOperationRepo) Update(_ context.Context, op *domain.ReleaseOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lease := r.ops[op.ID].Lease
+	r.put(op)
+	cp := r.ops[op.ID]
+	cp.Lease = lease
+	r.ops[op.ID] = cp
+	return nil
+}
+
+func (r *stubOperationRepo) ClaimLease(_ context.Context, id, owner string, now, expiredBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op, ok := r.ops[id]
+	if !ok || op.Status != domain.ReleaseOperationRunning ||
+		(op.Owner != "" && op.HeartbeatAt != nil && !op.HeartbeatAt.Before(expiredBefore)) {
+		return false, nil
+	}
+	op.Hold(owner, now)
+	r.ops[id] = op
+	return true, nil
+}
+
+func (r *stubOperationRepo) RenewLease(_ context.Context, id, owner string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op, ok := r.ops[id]
+	if !ok || op.Owner != owner {
+		return false, nil
+	}
+	op.HeartbeatAt = &now
+	r.ops[id] = op
+	return true, nil
+}
+
+func (r *stubOperationRepo) FindByID(_ context.Context, id string) (*domain.ReleaseOperation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op, ok := r.ops[id]
+	if !ok {
+		return nil, domain.ErrReleaseOperationNotFound
+	}
+	return &op, nil
+}
+
+func (r *stubOperationRepo) FindRunning(_ context.Context) ([]*domain.ReleaseOperation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*domain.ReleaseOperation
+	for _, op := range r.ops {
+		if op.Status == domain.ReleaseOperationRunning {
+			cp := op
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+// blockingDeployer 的 WaitForRollout 一直阻塞到 ctx 取消或 release 被关闭。
+type blockingDeployer struct {
+	stubDeployer
+	release chan struct{}
+}
+
+func (d *blockingDeployer) WaitForRollout(ctx context.Context, _ string, _ func(domain.ReleaseOperationEvent)) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.release:
+		return nil
+	}
+}
+
+func newOperationTestService(deployer port.Deployer) (*ReleaseService, *stubOperationRepo, *releaseTestReleaseRepo) {
+	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp", ImageRepoName: "myapp", Port: 8080}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "myapp", Registry: "harbor.local/inner-bot/myapp"}}
+	releaseRepo := newReleaseTestReleaseRepo()
+	opRepo := newStubOperationRepo()
+	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, opRepo, deployer, nil, ReleaseServiceConfig{})
+	return svc, opRepo, releaseRepo
+}
+
+func waitOperationDone(t *testing.T, repo *stubOperationRepo, id string) *domain.ReleaseOperation {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		op, err := repo.FindByID(context.Background(), id)
+		if err == nil && op.IsTerminal() {
+			return op
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("operation %s did not finish in time", id)
+	return nil
+}
+
+func eventTypes(op *domain.ReleaseOperation) []string {
+	types := make([]string, 0, len(op.Events))
+	for _, ev := range op.Events {
+		types = append(types, ev.Type)
+	}
+	return types
+}
+
+func TestStartCreateOrUpdateRelease_RunsInBackground(t *testing.T) {
+	svc, opRepo, releaseRepo := newOperationTestService(&stubDeployer{})
+	ctx := context.Background()
+
+	rel, op, err := svc.StartCreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v1"})
+	if err != nil {
+		t.Fatalf("StartCreateOrUpdateRelease: %v", err)
+	}
+	if rel.Status != domain.ReleaseStatusPending || op.Status != domain.ReleaseOperationRunning {
+		t.Errorf("expected pending release and running op, got %q / %q", rel.Status, op.Status)
+	}
+	if op.ReleaseID != rel.ID || op.Type != domain.ReleaseOperationDeploy {
+		t.Errorf("unexpected op: %+v", op)
+	}
+
+	done := waitOperationDone(t, opRepo, op.ID)
+	if done.Status != domain.ReleaseOperationSucceeded {
+		t.Fatalf("op status = %q, message = %q", done.Status, done.Message)
+	}
+	want := []string{domain.OperationEventApplied, domain.OperationEventPodsReady, domain.OperationEventSucceeded}
+	if got := eventTypes(done); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	stored, _ := releaseRepo.FindByID(ctx, rel.ID)
+	if stored.Status != domain.ReleaseStatusDeployed {
+		t.Errorf("release status = %q, want deployed", stored.Status)
+	}
+
+	got, err := svc.GetReleaseOperation(ctx, rel.ID, op.ID)
+	if err != nil || got.ID != op.ID {
+		t.Errorf("GetReleaseOperation: %v", err)
+	}
+	if _, err := svc.GetReleaseOperation(ctx, "other-release", op.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("op from another release should be not found, got %v", err)
+	}
+}
+
+func TestStartCreateOrUpdateRelease_FailureRecordedOnOperation(t *testing.T) {
+	svc, opRepo, releaseRepo := newOperationTestService(&stubDeployer{deployErr: errors.New("wait for rollout: pod crashed")})
+
+	rel, op, err := svc.StartCreateOrUpdateRelease(context.Background(), CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v1"})
+	if err != nil {
+		t.Fatalf("StartCreateOrUpdateRelease: %v", err)
+	}
+	done := waitOperationDone(t, opRepo, op.ID)
+	if done.Status != domain.ReleaseOperationFailed || done.Message != "wait for rollout: pod crashed" {
+		t.Errorf("op = %q / %q", done.Status, done.Message)
+	}
+	if last := done.Events[len(done.Events)-1]; last.Type != domain.OperationEventFailed {
+		t.Errorf("last event = %q, want failed", last.Type)
+	}
+	stored, _ := releaseRepo.FindByID(context.Background(), rel.ID)
+	if stored.Status != domain.ReleaseStatusFailed || stored.Message != "wait for rollout: pod crashed" {
+		t.Errorf("release = %q / %q", stored.Status, stored.Message)
+	}
+}
+
+func TestStartCreateOrUpdateRelease_NewerOperationSupersedesOlder(t *testing.T) {
+	deployer := &blockingDeployer{release: make(chan struct{})}
+	svc, opRepo, _ := newOperationTestService(deployer)
+	ctx := context.Background()
+
+	_, first, err := svc.StartCreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v1"})
+	if err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	// 等第一个操作进入 rollout 等待
+	deadline := time.Now().Add(time.Second)
+	for {
+		op, _ := opRepo.FindByID(ctx, first.ID)
+		if len(op.Events) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	_, second, err := svc.StartCreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v2"})
+	if err != nil {
+		t.Fatalf("second: %v", err)
+	}
+	done := waitOperationDone(t, opRepo, first.ID)
+	if done.Status != domain.ReleaseOperationFailed || done.Message != errOperationSuperseded.Error() {
+		t.Errorf("first op = %q / %q, want superseded", done.Status, done.Message)
+	}
+
+	close(deployer.release)
+	if op := waitOperationDone(t, opRepo, second.ID); op.Status != domain.ReleaseOperationSucceeded {
+		t.Errorf("second op = %q / %q", op.Status, op.Message)
+	}
+}
+
+// waitRollingOut 等 release 上唯一一条 running 操作开始等 rollout，返回它。
+func waitRollingOut(t *testing.T, repo *stubOperationRepo) *domain.ReleaseOperation {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		ops, _ := repo.FindRunning(context.Background())
+		if len(ops) == 1 && len(ops[0].Events) > 0 {
+			return ops[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no operation is waiting for rollout")
+	return nil
+}
+
+func TestCreateOrUpdateRelease_SupersededIsNotReportedAsDeployed(t *testing.T) {
+	// 同步调用方（pipeline 部署、金丝雀 promote）不能把被顶替或被接管的部署当成功
+	for _, cause := range []error{errOperationSuperseded, errOperationTakenOver} {
+		deployer := &blockingDeployer{release: make(chan struct{})}
+		svc, opRepo, releaseRepo := newOperationTestService(deployer)
+
+		type result struct {
+			release *domain.Release
+			err     error
+		}
+		got := make(chan result, 1)
+		go func() {
+			rel, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v1"})
+			got <- result{rel, err}
+		}()
+		op := waitRollingOut(t, opRepo)
+		svc.cancelRunning(op.ReleaseID, cause)
+
+		if r := <-got; !errors.Is(r.err, domain.ErrOperationSuperseded) || r.release != nil {
+			t.Errorf("%v: sync deploy = %+v / %v, want ErrOperationSuperseded", cause, r.release, r.err)
+		}
+		if stored, _ := releaseRepo.FindByID(context.Background(), op.ReleaseID); stored.Status != domain.ReleaseStatusPending {
+			t.Errorf("%v: release status = %q, want left pending for the newer operation", cause, stored.Status)
+		}
+	}
+}
+
+func TestCreateOrUpdateRelease_CallerCancelDoesNotFailRelease(t *testing.T) {
+	deployer := &blockingDeployer{release: make(chan struct{})}
+	svc, opRepo, releaseRepo := newOperationTestService(deployer)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v1"})
+		errc <- err
+	}()
+	op := waitRollingOut(t, opRepo)
+
+	// 调用方不再等待（如 pipeline run 被取消），rollout 在后台照常完成
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateOrUpdateRelease() after cancel error = %v, want context.Canceled", err)
+	}
+	close(deployer.release)
+	if done := waitOperationDone(t, opRepo, op.ID); done.Status != domain.ReleaseOperationSucceeded {
+		t.Errorf("op = %q / %q, want succeeded", done.Status, done.Message)
+	}
+	if stored, _ := releaseRepo.FindByID(context.Background(), op.ReleaseID); stored.Status != domain.ReleaseStatusDeployed {
+		t.Errorf("release status = %q, want deployed", stored.Status)
+	}
+}
+
+func TestResumeInterrupted(t *testing.T) {
+	svc, opRepo, releaseRepo := newOperationTestService(&stubDeployer{})
+	ctx := context.Background()
+
+	pending := &domain.Release{ID: "r1", AppName: "myapp", Lane: "prod", Image: "harbor.local/inner-bot/myapp:v3",
+		Replicas: 1, Status: domain.ReleaseStatusPending}
+	_ = releaseRepo.Save(ctx, pending)
+	now := time.Now()
+	_ = opRepo.Save(ctx, &domain.ReleaseOperation{ID: "op-old", ReleaseID: "r1", Status: domain.ReleaseOperationRunning, CreatedAt: now.Add(-time.Minute)})
+	_ = opRepo.Save(ctx, &domain.ReleaseOperation{ID: "op-new", ReleaseID: "r1", Status: domain.ReleaseOperationRunning, CreatedAt: now})
+	_ = opRepo.Save(ctx, &domain.ReleaseOperation{ID: "op-gone", ReleaseID: "deleted", Status: domain.ReleaseOperationRunning, CreatedAt: now})
+	// 另一个实例还在执行的操作（蓝绿切换期间的旧实例）
+	_ = releaseRepo.Save(ctx, &domain.Release{ID: "r2", AppName: "myapp", Lane: "prod", Status: domain.ReleaseStatusPending})
+	_ = opRepo.Save(ctx, &domain.ReleaseOperation{ID: "op-live", ReleaseID: "r2", Status: domain.ReleaseOperationRunning,
+		CreatedAt: now, Lease: domain.Lease{Owner: "other-instance", HeartbeatAt: &now}})
+
+	if err := svc.ResumeInterrupted(ctx); err != nil {
+		t.Fatalf("ResumeInterrupted: %v", err)
+	}
+	// 第二个实例同时接管，已被抢走的操作不会再执行一遍
+	other := NewReleaseService(svc.appRepo, svc.imageRepoRepo, svc.buildRepo, releaseRepo, nil, opRepo, &stubDeployer{}, nil, ReleaseServiceConfig{})
+	other.owner = "new-instance"
+	if err := other.ResumeInterrupted(ctx); err != nil {
+		t.Fatalf("ResumeInterrupted on second instance: %v", err)
+	}
+
+	resumed := waitOperationDone(t, opRepo, "op-new")
+	if resumed.Status != domain.ReleaseOperationSucceeded || resumed.Events[0].Type != domain.OperationEventResumed {
+		t.Errorf("op-new = %q, events %v", resumed.Status, eventTypes(resumed))
+	}
+	if resumed.Owner != svc.owner || resumed.Events[1].Type == domain.OperationEventResumed {
+		t.Errorf("op-new owner = %q, events %v; want resumed once by the first instance", resumed.Owner, eventTypes(resumed))
+	}
+	if op, _ := opRepo.FindByID(ctx, "op-live"); op.Status != domain.ReleaseOperationRunning || op.Owner != "other-instance" || len(op.Events) != 0 {
+		t.Errorf("op-live was taken over: %+v", op)
+	}
+	if op, _ := opRepo.FindByID(ctx, "op-old"); op.Status != domain.ReleaseOperationFailed {
+		t.Errorf("op-old should be superseded, got %q", op.Status)
+	}
+	if op, _ := opRepo.FindByID(ctx, "op-gone"); op.Status != domain.ReleaseOperationFailed {
+		t.Errorf("op for deleted release should fail, got %q", op.Status)
+	}
+	stored, _ := releaseRepo.FindByID(ctx, "r1")
+	if stored.Status != domain.ReleaseStatusDeployed {
+		t.Errorf("release status = %q, want deployed", stored.Status)
+	}
+}
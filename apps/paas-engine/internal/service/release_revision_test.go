@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// stubRevisionRepo 按 release 维护 revision 列表，Save 时分配 max+1。
+type stubRevisionRepo struct {
+	revs map[string][]*domain.ReleaseRevision
+}
+
+func newStubRevisionRepo() *stubRevisionRepo {
+	return &stubRevisionRepo{revs: make(map[string][]*domain.ReleaseRevision)}
+}
+
+func (r *stubRevisionRepo) Save(_ context.Context, rev *domain.ReleaseRevision) error {
+	rev.Revision = int64(len(r.revs[rev.ReleaseID])) + 1
+	cp := *rev
+	r.revs[rev.ReleaseID] = append(r.revs[rev.ReleaseID], &cp)
+	return nil
+}
+
+func (r *stubRevisionRepo) FindByRelease(_ context.Context, releaseID string, limit int) ([]*domain.ReleaseRevision, error) {
+	list := r.revs[releaseID]
+	out := make([]*domain.ReleaseRevision, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+		out = append(out, list[i])
+	}
+	return out, nil
+}
+
+func (r *stubRevisionRepo) FindByReleaseAndRevision(_ context.Context, releaseID string, revision int64) (*domain.ReleaseRevision, error) {
+	for _, rev := range r.revs[releaseID] {
+		if rev.Revision == revision {
+			return rev, nil
+		}
+	}
+	return nil, domain.ErrReleaseRevisionNotFound
+}
+
+func (r *stubRevisionRepo) ListImages(_ context.Context) ([]string, error) {
+	var images []string
+	for _, list := range r.revs {
+		for _, rev := range list {
+			if !slices.Contains(images, rev.Image) {
+				images = append(images, rev.Image)
+			}
+		}
+	}
+	return images, nil
+}
+
+func newRevisionTestService(deployer *stubDeployer) (*ReleaseService, *stubRevisionRepo) {
+	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp", ImageRepoName: "myapp", Port: 8080}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "myapp", Registry: "harbor.local/inner-bot/myapp"}}
+	revRepo := newStubRevisionRepo()
+	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, newReleaseTestReleaseRepo(), revRepo, nil, deployer, nil, ReleaseServiceConfig{})
+	return svc, revRepo
+}
+
+func TestCreateRelease_RecordsRevisionOnSuccess(t *testing.T) {
+	svc, revRepo := newRevisionTestService(&stubDeployer{})
+	ctx := context.Background()
+
+	rel, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "v1", Replicas: 2,
+		Envs: map[string]string{"A": "1"}, Version: "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "v2",
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	revs, err := svc.ListReleaseRevisions(ctx, rel.ID, 20)
+	if err != nil {
+		t.Fatalf("list revisions: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(revs))
+	}
+	if revs[0].Revision != 2 || revs[0].Image != "harbor.local/inner-bot/myapp:v2" {
+		t.Errorf("newest revision = %d %q", revs[0].Revision, revs[0].Image)
+	}
+	first := revRepo.revs[rel.ID][0]
+	if first.Image != "harbor.local/inner-bot/myapp:v1" || first.Replicas != 2 || first.Version != "1.0.0" || first.Envs["A"] != "1" {
+		t.Errorf("revision 1 not preserved: %+v", first)
+	}
+}
+
+func TestCreateRelease_FailedDeployRecordsNoRevision(t *testing.T) {
+	svc, revRepo := newRevisionTestService(&stubDeployer{deployErr: errors.New("rollout timeout")})
+
+	rel, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "bad",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if n := len(revRepo.revs[rel.ID]); n != 0 {
+		t.Errorf("failed deploy should not record a revision, got %d", n)
+	}
+}
+
+func TestRollbackRelease_RedeploysStoredRevision(t *testing.T) {
+	svc, revRepo := newRevisionTestService(&stubDeployer{})
+	ctx := context.Background()
+
+	rel, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "v1", Replicas: 3, Envs: map[string]string{"A": "1"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{
+		AppName: "myapp", Lane: "prod", ImageTag: "v2", Envs: map[string]string{"A": "2"},
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := svc.RollbackRelease(ctx, rel.ID, 1)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if got.Image != "harbor.local/inner-bot/myapp:v1" || got.Replicas != 3 || got.Envs["A"] != "1" {
+		t.Errorf("release not restored to revision 1: %+v", got)
+	}
+	if got.Status != domain.ReleaseStatusDeployed {
+		t.Errorf("status = %q", got.Status)
+	}
+
+	// 回滚写入新 revision 3，而不是把号倒回 1
+	revs := revRepo.revs[rel.ID]
+	if len(revs) != 3 {
+		t.Fatalf("expected 3 revisions after rollback, got %d", len(revs))
+	}
+	if revs[2].Revision != 3 || revs[2].Image != "harbor.local/inner-bot/myapp:v1" {
+		t.Errorf("rollback revision = %d %q", revs[2].Revision, revs[2].Image)
+	}
+	if revs[2].Reason != "rollback to revision 1" {
+		t.Errorf("rollback reason = %q", revs[2].Reason)
+	}
+}
+
+func TestRollbackRelease_UnknownRevision(t *testing.T) {
+	svc, _ := newRevisionTestService(&stubDeployer{})
+	ctx := context.Background()
+
+	rel, err := svc.CreateOrUpdateRelease(ctx, CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "v1"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.RollbackRelease(ctx, rel.ID, 9); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := svc.RollbackRelease(ctx, rel.ID, 0); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for revision 0, got %v", err)
+	}
+}
+
+func TestHashBundleEnvs_StableAndOrderIndependent(t *testing.T) {
+	a := hashBundleEnvs(map[string]string{"X": "1", "Y": "2"})
+	b := hashBundleEnvs(map[string]string{"Y": "2", "X": "1"})
+	if a == "" || a != b {
+		t.Errorf("hash not stable: %q vs %q", a, b)
+	}
+	if c := hashBundleEnvs(map[string]string{"X": "1", "Y": "3"}); c == a {
+		t.Error("different values should hash differently")
+	}
+	if hashBundleEnvs(nil) != "" {
+		t.Error("empty bundle envs should hash to empty string")
+	}
+}
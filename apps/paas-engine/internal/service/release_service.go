@@ -2,13 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chiwei-platform/paas-engine/internal/domain"
-	"github.com/chiwei-platform/paas-engine/internal/metrics"
 	"github.com/chiwei-platform/paas-engine/internal/port"
 	"github.com/google/uuid"
 )
@@ -26,9 +31,18 @@ type ReleaseService struct {
 	imageRepoRepo   port.ImageRepoRepository
 	buildRepo       port.BuildRepository
 	releaseRepo     port.ReleaseRepository
+	revisionRepo    port.ReleaseRevisionRepository
+	operationRepo   port.ReleaseOperationRepository
 	deployer        port.Deployer
 	configBundleSvc *ConfigBundleService
 	cfg             ReleaseServiceConfig
+	// owner 是本实例的租约持有者 ID，测试里模拟多实例时改写。
+	owner string
+
+	// running 记录每个 release 上正在执行的部署操作（release id -> 操作），
+	// 同一 release 上新操作开始时取消旧的
+	mu      sync.Mutex
+	running map[string]*runningOperation
 }
 
 func NewReleaseService(
@@ -36,6 +50,8 @@ func NewReleaseService(
 	imageRepoRepo port.ImageRepoRepository,
 	buildRepo port.BuildRepository,
 	releaseRepo port.ReleaseRepository,
+	revisionRepo port.ReleaseRevisionRepository,
+	operationRepo port.ReleaseOperationRepository,
 	deployer port.Deployer,
 	configBundleSvc *ConfigBundleService,
 	cfg ReleaseServiceConfig,
@@ -45,9 +61,13 @@ func NewReleaseService(
 		imageRepoRepo:   imageRepoRepo,
 		buildRepo:       buildRepo,
 		releaseRepo:     releaseRepo,
+		revisionRepo:    revisionRepo,
+		operationRepo:   operationRepo,
 		deployer:        deployer,
 		configBundleSvc: configBundleSvc,
 		cfg:             cfg,
+		owner:           instanceID,
+		running:         make(map[string]*runningOperation),
 	}
 }
 
@@ -58,9 +78,36 @@ type CreateReleaseRequest struct {
 	Replicas int32             `json:"replicas"`
 	Envs     map[string]string `json:"envs"`
 	Version  string            `json:"version"` // 自定义版本标识，可选
+	// Resources / Probes 覆盖 App 上的默认值，可选
+	Resources *domain.ResourceSpec `json:"resources"`
+	Probes    *domain.HealthProbes `json:"probes"`
+	// Autoscaling 非空时创建 HPA，副本数由 HPA 管理，可选
+	Autoscaling *domain.AutoscalingSpec `json:"autoscaling"`
+	// ScanOverrideReason 豁免漏洞扫描门禁的原因，记入 revision，可选
+	ScanOverrideReason string `json:"scan_override_reason,omitempty"`
 }
 
+// CreateOrUpdateRelease 同步部署：等 rollout 结束（成功或失败）才返回。
+// 流水线、金丝雀等本身就在后台跑的流程用它；HTTP 入口走 StartCreateOrUpdateRelease。
 func (s *ReleaseService) CreateOrUpdateRelease(ctx context.Context, req CreateReleaseRequest) (*domain.Release, error) {
+	plan, err := s.planCreateOrUpdate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.runOperation(ctx, plan, domain.ReleaseOperationDeploy, plan.reason)
+}
+
+// StartCreateOrUpdateRelease 校验通过后把 release 以 pending 落库并立即返回，
+// 下发和等待 rollout 在后台执行，进度通过返回的 operation 查询。
+func (s *ReleaseService) StartCreateOrUpdateRelease(ctx context.Context, req CreateReleaseRequest) (*domain.Release, *domain.ReleaseOperation, error) {
+	plan, err := s.planCreateOrUpdate(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.startOperation(ctx, plan, domain.ReleaseOperationDeploy, plan.reason)
+}
+
+func (s *ReleaseService) planCreateOrUpdate(ctx context.Context, req CreateReleaseRequest) (*deployPlan, error) {
 	// lane 命名前缀强制校验（fail-closed）
 	// spec: docs/superpowers/specs/2026-05-11-dev-workflow-v2-test-env-isolation-design.md §lane 分类用命名前缀
 	lane := req.Lane
@@ -98,34 +145,14 @@ func (s *ReleaseService) CreateOrUpdateRelease(ctx context.Context, req CreateRe
 		}
 	}
 
-	// 通过 App → ImageRepo 拼完整镜像地址
-	var fullImage string
-	if app.ImageRepoName != "" {
-		imageRepo, err := s.imageRepoRepo.FindByName(ctx, app.ImageRepoName)
-		if err != nil {
-			return nil, err
-		}
-		fullImage = imageRepo.FullImageRef(req.ImageTag)
+	fullImage, version, err := s.resolveImage(ctx, app, req.ImageTag, lane, req.Version)
+	if err != nil {
+		return nil, err
 	}
-
-	// prod 泳道禁止 test channel 镜像
-	if lane == domain.DefaultLane && fullImage != "" {
-		build, err := s.buildRepo.FindByImageTag(ctx, fullImage)
-		if err == nil {
-			if build.Channel == domain.ChannelTest {
-				return nil, fmt.Errorf("%w: 测试版本 %s (channel=%s, git_ref=%s) 不允许部署到 prod 泳道",
-					domain.ErrInvalidInput, build.Version, build.Channel, build.GitRef)
-			}
-			// 向后兼容：旧 Build 无 channel，fallback 到检查 GitRef
-			if build.Channel == "" && build.GitRef != "main" {
-				return nil, fmt.Errorf("%w (got %q)", domain.ErrNonMainProdDeploy, build.GitRef)
-			}
-			// 自动继承 Build 版本到 Release
-			if req.Version == "" && build.Version != "" {
-				req.Version = build.Version
-			}
-		}
-		// build not found → 外部镜像，放行
+	req.Version = version
+	gateReason, err := s.checkScanGate(ctx, app, fullImage, lane, req.ScanOverrideReason)
+	if err != nil {
+		return nil, err
 	}
 
 	if req.Replicas <= 0 {
@@ -145,24 +172,37 @@ func (s *ReleaseService) CreateOrUpdateRelease(ctx context.Context, req CreateRe
 		existing.Replicas = req.Replicas
 		existing.Envs = req.Envs
 		existing.Version = req.Version
+		existing.Resources = req.Resources
+		existing.Probes = req.Probes
+		existing.Autoscaling = req.Autoscaling
 		existing.Status = domain.ReleaseStatusPending
 		existing.UpdatedAt = now
 		release = existing
 	} else {
 		release = &domain.Release{
-			ID:        uuid.New().String(),
-			AppName:   req.AppName,
-			Lane:      lane,
-			Image:     fullImage,
-			Replicas:  req.Replicas,
-			Envs:      req.Envs,
-			Version:   req.Version,
-			Status:    domain.ReleaseStatusPending,
-			CreatedAt: now,
-			UpdatedAt: now,
+			ID:          uuid.New().String(),
+			AppName:     req.AppName,
+			Lane:        lane,
+			Image:       fullImage,
+			Replicas:    req.Replicas,
+			Envs:        req.Envs,
+			Version:     req.Version,
+			Resources:   req.Resources,
+			Probes:      req.Probes,
+			Autoscaling: req.Autoscaling,
+			Status:      domain.ReleaseStatusPending,
+			CreatedAt:   now,
+			UpdatedAt:   now,
 		}
 	}
 	release.DeployName = release.ResourceName()
+	if err := s.applyBuildProvenance(ctx, release); err != nil {
+		return nil, err
+	}
+
+	if err := validateWorkload(app, release); err != nil {
+		return nil, err
+	}
 
 	// RequiredKeys 校验：根据 lane class 强制完整 override
 	// spec: docs/superpowers/specs/2026-05-11-dev-workflow-v2-phase-2-design.md §Fail-closed 部署校验
@@ -188,35 +228,138 @@ func (s *ReleaseService) CreateOrUpdateRelease(ctx context.Context, req CreateRe
 		}
 	}
 
-	// 下发 K8s 资源
-	if s.deployer != nil {
-		if err := s.deployer.Deploy(ctx, release, app, bundleEnvs); err != nil {
-			release.Status = domain.ReleaseStatusFailed
-			release.Message = err.Error()
-		} else {
-			release.Status = domain.ReleaseStatusDeployed
-			release.Message = ""
+	return &deployPlan{release: release, app: app, bundleEnvs: bundleEnvs, isNew: existing == nil, reason: gateReason}, nil
+}
+
+// resolveImage 通过 App → ImageRepo 拼完整镜像地址，并对 prod 泳道执行镜像门禁。
+// version 为空时继承 Build 上的版本号，返回最终使用的 version。
+func (s *ReleaseService) resolveImage(ctx context.Context, app *domain.App, imageTag, lane, version string) (string, string, error) {
+	// 通过 App → ImageRepo 拼完整镜像地址
+	var fullImage string
+	if app.ImageRepoName != "" {
+		imageRepo, err := s.imageRepoRepo.FindByName(ctx, app.ImageRepoName)
+		if err != nil {
+			return "", "", err
 		}
-	} else {
-		release.Status = domain.ReleaseStatusDeployed
-		release.Message = ""
+		fullImage = imageRepo.FullImageRef(imageTag)
 	}
 
-	if existing != nil {
-		if err := s.releaseRepo.Update(ctx, release); err != nil {
-			return nil, err
+	if fullImage == "" || s.buildRepo == nil {
+		return fullImage, version, nil
+	}
+	build, err := s.buildRepo.FindByImageTag(ctx, fullImage)
+	if err != nil {
+		// build not found → 外部镜像，放行
+		return fullImage, version, nil
+	}
+	if build.PrunedAt != nil {
+		return "", "", fmt.Errorf("%w: 镜像 %s 已按保留策略从 registry 删除", domain.ErrInvalidInput, fullImage)
+	}
+
+	// prod 泳道禁止 test channel 镜像
+	if lane == domain.DefaultLane {
+		if build.Channel == domain.ChannelTest {
+			return "", "", fmt.Errorf("%w: 测试版本 %s (channel=%s, git_ref=%s) 不允许部署到 prod 泳道",
+				domain.ErrInvalidInput, build.Version, build.Channel, build.GitRef)
 		}
-	} else {
-		if err := s.releaseRepo.Save(ctx, release); err != nil {
-			return nil, err
+		// 向后兼容：旧 Build 无 channel，fallback 到检查 GitRef
+		if build.Channel == "" && build.GitRef != "main" {
+			return "", "", fmt.Errorf("%w (got %q)", domain.ErrNonMainProdDeploy, build.GitRef)
+		}
+		// 自动继承 Build 版本到 Release
+		if version == "" && build.Version != "" {
+			version = build.Version
 		}
 	}
+	return fullImage, version, nil
+}
+
+// checkScanGate 对 prod 类 lane 执行 ImageRepo 的漏洞扫描门禁：镜像没有通过扫描时，没有豁免原因就拒绝，
+// 有豁免原因则放行并返回写入 revision 的说明。外部镜像（没有 Build 记录）不拦截。
+func (s *ReleaseService) checkScanGate(ctx context.Context, app *domain.App, image, lane, overrideReason string) (string, error) {
+	if app.ImageRepoName == "" || image == "" || s.buildRepo == nil {
+		return "", nil
+	}
+	if class, err := domain.ClassifyLane(lane, s.cfg.LegacyLaneWhitelist); err != nil || class != domain.LaneClassProd {
+		return "", nil
+	}
+	imageRepo, err := s.imageRepoRepo.FindByName(ctx, app.ImageRepoName)
+	if err != nil {
+		return "", err
+	}
+	if !imageRepo.ScanPolicy.Gates() {
+		return "", nil
+	}
+	build, err := s.buildRepo.FindByImageTag(ctx, image)
+	if err != nil {
+		return "", nil
+	}
+	violation := imageRepo.ScanPolicy.Violation(build.Scan)
+	if violation == "" {
+		return "", nil
+	}
+	overrideReason = strings.TrimSpace(overrideReason)
+	if overrideReason == "" {
+		return "", fmt.Errorf("%w: 镜像 %s 未通过漏洞扫描门禁（%s），确需发布请填写 scan_override_reason",
+			domain.ErrInvalidInput, image, violation)
+	}
+	slog.Warn("scan gate overridden", "app", app.Name, "lane", lane, "image", image,
+		"violation", violation, "reason", overrideReason)
+	return fmt.Sprintf("scan gate overridden (%s): %s", violation, overrideReason), nil
+}
 
-	if release.Status == domain.ReleaseStatusDeployed {
-		metrics.ReleasesTotal.WithLabelValues(release.Lane).Inc()
+// applyBuildProvenance 按镜像地址找到产出它的 Build，记下 commit，构建成功且拿到了 digest
+// 时按 digest 部署。外部镜像（没有 Build 记录）清空这两项，按 tag 部署。
+func (s *ReleaseService) applyBuildProvenance(ctx context.Context, release *domain.Release) error {
+	release.CommitSHA, release.ImageDigest = "", ""
+	if s.buildRepo == nil || release.Image == "" {
+		return nil
+	}
+	build, err := s.buildRepo.FindByImageTag(ctx, release.Image)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
 	}
+	if err != nil {
+		return err
+	}
+	release.CommitSHA = build.CommitSHA
+	if build.Status == domain.BuildStatusSucceeded {
+		release.ImageDigest = build.Digest
+	}
+	return nil
+}
 
-	return release, nil
+// LaneCommit 描述某条 lane 上一个 app 当前部署的镜像来自哪个 commit。
+type LaneCommit struct {
+	App         string               `json:"app"`
+	Image       string               `json:"image"`
+	Version     string               `json:"version,omitempty"`
+	CommitSHA   string               `json:"commit_sha,omitempty"` // 外部镜像或旧构建没有记录时为空
+	ImageDigest string               `json:"image_digest,omitempty"`
+	Status      domain.ReleaseStatus `json:"status"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// ListLaneCommits 返回 lane 上每个 release 正在运行的 commit，按 app 名排序。
+func (s *ReleaseService) ListLaneCommits(ctx context.Context, lane string) ([]LaneCommit, error) {
+	releases, err := s.releaseRepo.FindAll(ctx, "", lane)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]LaneCommit, 0, len(releases))
+	for _, r := range releases {
+		commits = append(commits, LaneCommit{
+			App:         r.AppName,
+			Image:       r.DeployImage(),
+			Version:     r.Version,
+			CommitSHA:   r.CommitSHA,
+			ImageDigest: r.ImageDigest,
+			Status:      r.Status,
+			UpdatedAt:   r.UpdatedAt,
+		})
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].App < commits[j].App })
+	return commits, nil
 }
 
 func (s *ReleaseService) GetRelease(ctx context.Context, id string) (*domain.Release, error) {
@@ -231,14 +374,37 @@ func (s *ReleaseService) GetReleaseStatus(ctx context.Context, id string) (*doma
 	if s.deployer == nil {
 		return nil, fmt.Errorf("deployer not configured")
 	}
-	return s.deployer.GetDeploymentStatus(ctx, release.DeployName)
+	status, err := s.deployer.GetDeploymentStatus(ctx, release.DeployName)
+	if err != nil {
+		return nil, err
+	}
+	status.Sleeping = release.Status == domain.ReleaseStatusSleeping
+	return status, nil
 }
 
 func (s *ReleaseService) ListReleases(ctx context.Context, appName, lane string) ([]*domain.Release, error) {
 	return s.releaseRepo.FindAll(ctx, appName, lane)
 }
 
+// UpdateRelease 按字段 PATCH release 并同步部署。
 func (s *ReleaseService) UpdateRelease(ctx context.Context, id string, body []byte) (*domain.Release, error) {
+	plan, err := s.planUpdate(ctx, id, body)
+	if err != nil {
+		return nil, err
+	}
+	return s.runOperation(ctx, plan, domain.ReleaseOperationUpdate, plan.reason)
+}
+
+// StartUpdateRelease 是 UpdateRelease 的异步版本。
+func (s *ReleaseService) StartUpdateRelease(ctx context.Context, id string, body []byte) (*domain.Release, *domain.ReleaseOperation, error) {
+	plan, err := s.planUpdate(ctx, id, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.startOperation(ctx, plan, domain.ReleaseOperationUpdate, plan.reason)
+}
+
+func (s *ReleaseService) planUpdate(ctx context.Context, id string, body []byte) (*deployPlan, error) {
 	release, err := s.releaseRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -254,7 +420,8 @@ func (s *ReleaseService) UpdateRelease(ctx context.Context, id string, body []by
 		return nil, err
 	}
 
-	// image_tag 特殊处理：出现则通过 App → ImageRepo 重建完整镜像地址
+	// image_tag 特殊处理：出现则通过 App → ImageRepo 重建完整镜像地址，并过漏洞扫描门禁
+	var gateReason string
 	if raw, ok := fields["image_tag"]; ok {
 		var tag string
 		if err := json.Unmarshal(raw, &tag); err != nil {
@@ -267,6 +434,16 @@ func (s *ReleaseService) UpdateRelease(ctx context.Context, id string, body []by
 			}
 			release.Image = imageRepo.FullImageRef(tag)
 		}
+		if err := s.applyBuildProvenance(ctx, release); err != nil {
+			return nil, err
+		}
+		var override string
+		if err := ApplyField(fields, "scan_override_reason", &override); err != nil {
+			return nil, domain.ErrInvalidInput
+		}
+		if gateReason, err = s.checkScanGate(ctx, app, release.Image, release.Lane, override); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := ApplyField(fields, "replicas", &release.Replicas); err != nil {
@@ -279,6 +456,19 @@ func (s *ReleaseService) UpdateRelease(ctx context.Context, id string, body []by
 		return nil, domain.ErrInvalidInput
 	}
 
+	if err := ApplyField(fields, "resources", &release.Resources); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "probes", &release.Probes); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := ApplyField(fields, "autoscaling", &release.Autoscaling); err != nil {
+		return nil, domain.ErrInvalidInput
+	}
+	if err := validateWorkload(app, release); err != nil {
+		return nil, err
+	}
+
 	// Map 字段：按 key 合并
 	release.Envs, err = MergeEnvs(release.Envs, fields["envs"])
 	if err != nil {
@@ -298,28 +488,148 @@ func (s *ReleaseService) UpdateRelease(ctx context.Context, id string, body []by
 		}
 	}
 
-	if s.deployer != nil {
-		if err := s.deployer.Deploy(ctx, release, app, bundleEnvs); err != nil {
-			release.Status = domain.ReleaseStatusFailed
-			release.Message = err.Error()
-		} else {
-			release.Status = domain.ReleaseStatusDeployed
-			release.Message = ""
-		}
-	} else {
-		release.Status = domain.ReleaseStatusDeployed
-		release.Message = ""
+	return &deployPlan{release: release, app: app, bundleEnvs: bundleEnvs, reason: gateReason}, nil
+}
+
+// validateWorkload 校验 App 默认值叠加 Release 覆盖之后实际下发的 resources / probes / autoscaling。
+func validateWorkload(app *domain.App, release *domain.Release) error {
+	resources := domain.EffectiveResources(app, release)
+	if err := domain.ValidateResources(resources); err != nil {
+		return err
+	}
+	if err := domain.ValidateProbes(domain.EffectiveProbes(app, release), app.Port); err != nil {
+		return err
 	}
+	return domain.ValidateAutoscaling(release.Autoscaling, resources)
+}
 
-	if err := s.releaseRepo.Update(ctx, release); err != nil {
+// recordRevision 为一次成功部署追加一条 revision。
+// 历史写失败不影响已经完成的部署，只记日志。
+func (s *ReleaseService) recordRevision(ctx context.Context, release *domain.Release, bundleEnvs map[string]string, reason string) *domain.ReleaseRevision {
+	if s.revisionRepo == nil {
+		return nil
+	}
+	rev := &domain.ReleaseRevision{
+		ReleaseID:   release.ID,
+		AppName:     release.AppName,
+		Lane:        release.Lane,
+		Image:       release.Image,
+		Version:     release.Version,
+		Envs:        release.Envs,
+		Replicas:    release.Replicas,
+		Resources:   release.Resources,
+		Probes:      release.Probes,
+		Autoscaling: release.Autoscaling,
+		CommitSHA:   release.CommitSHA,
+		ImageDigest: release.ImageDigest,
+		BundleHash:  hashBundleEnvs(bundleEnvs),
+		Reason:      reason,
+		CreatedAt:   release.UpdatedAt,
+	}
+	if err := s.revisionRepo.Save(ctx, rev); err != nil {
+		slog.Error("recordRevision: failed to save revision", "release_id", release.ID, "error", err)
+		return nil
+	}
+	return rev
+}
+
+// hashBundleEnvs 对解析后的 bundle envs 做稳定摘要（按 key 排序），空返回 ""。
+func hashBundleEnvs(envs map[string]string) string {
+	if len(envs) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(envs))
+	for k := range envs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{'='})
+		h.Write([]byte(envs[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ListReleaseRevisions 按 revision 倒序返回某个 release 最近 limit 条部署历史。
+func (s *ReleaseService) ListReleaseRevisions(ctx context.Context, id string, limit int) ([]*domain.ReleaseRevision, error) {
+	if s.revisionRepo == nil {
+		return nil, fmt.Errorf("release revisions not configured")
+	}
+	if _, err := s.releaseRepo.FindByID(ctx, id); err != nil {
 		return nil, err
 	}
+	return s.revisionRepo.FindByRelease(ctx, id, limit)
+}
 
-	if release.Status == domain.ReleaseStatusDeployed {
-		metrics.ReleasesTotal.WithLabelValues(release.Lane).Inc()
+// RollbackRelease 把 release 重新部署为第 revision 条历史的 image / version / envs / replicas。
+// 和 gateway 快照回滚一样，回滚本身会写入一条新的（更大的）revision，而不是把号倒回去。
+// ConfigBundle 按当前内容重新解析（revision 只存 hash，不存密钥明文）。
+func (s *ReleaseService) RollbackRelease(ctx context.Context, id string, revision int64) (*domain.Release, error) {
+	plan, err := s.planRollback(ctx, id, revision)
+	if err != nil {
+		return nil, err
 	}
+	return s.runOperation(ctx, plan, domain.ReleaseOperationRollback, rollbackReason(revision))
+}
+
+// StartRollbackRelease 是 RollbackRelease 的异步版本。
+func (s *ReleaseService) StartRollbackRelease(ctx context.Context, id string, revision int64) (*domain.Release, *domain.ReleaseOperation, error) {
+	plan, err := s.planRollback(ctx, id, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.startOperation(ctx, plan, domain.ReleaseOperationRollback, rollbackReason(revision))
+}
 
-	return release, nil
+func rollbackReason(revision int64) string {
+	return fmt.Sprintf("rollback to revision %d", revision)
+}
+
+func (s *ReleaseService) planRollback(ctx context.Context, id string, revision int64) (*deployPlan, error) {
+	if s.revisionRepo == nil {
+		return nil, fmt.Errorf("release revisions not configured")
+	}
+	if revision <= 0 {
+		return nil, fmt.Errorf("%w: revision must be positive", domain.ErrInvalidInput)
+	}
+	release, err := s.releaseRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.revisionRepo.FindByReleaseAndRevision(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	app, err := s.appRepo.FindByName(ctx, release.AppName)
+	if err != nil {
+		return nil, err
+	}
+
+	release.Image = target.Image
+	release.CommitSHA = target.CommitSHA
+	release.ImageDigest = target.ImageDigest
+	release.Version = target.Version
+	release.Envs = target.Envs
+	release.Replicas = target.Replicas
+	release.Resources = target.Resources
+	release.Probes = target.Probes
+	release.Autoscaling = target.Autoscaling
+	release.Status = domain.ReleaseStatusPending
+	release.UpdatedAt = time.Now()
+	release.DeployName = release.ResourceName()
+
+	var bundleEnvs map[string]string
+	if s.configBundleSvc != nil && len(app.ConfigBundles) > 0 {
+		bundleEnvs, err = s.configBundleSvc.ResolveBundleEnvs(ctx, app, release.Lane)
+		if err != nil {
+			return nil, fmt.Errorf("resolve config bundles: %w", err)
+		}
+	}
+
+	return &deployPlan{release: release, app: app, bundleEnvs: bundleEnvs}, nil
 }
 
 func (s *ReleaseService) DeleteReleaseByAppAndLane(ctx context.Context, appName, lane string) error {
@@ -330,6 +640,25 @@ func (s *ReleaseService) DeleteReleaseByAppAndLane(ctx context.Context, appName,
 	return s.deleteRelease(ctx, release)
 }
 
+// DeleteLaneReleases 删除 lane 上的全部 release（含 K8s 资源），返回已删除的 app 名。
+// 单个 release 删除失败不中断，错误合并返回。
+func (s *ReleaseService) DeleteLaneReleases(ctx context.Context, lane string) ([]string, error) {
+	releases, err := s.releaseRepo.FindAll(ctx, "", lane)
+	if err != nil {
+		return nil, err
+	}
+	deleted := []string{}
+	var errs []error
+	for _, release := range releases {
+		if err := s.deleteRelease(ctx, release); err != nil {
+			errs = append(errs, fmt.Errorf("release %s: %w", release.ResourceName(), err))
+			continue
+		}
+		deleted = append(deleted, release.AppName)
+	}
+	return deleted, errors.Join(errs...)
+}
+
 func (s *ReleaseService) DeleteRelease(ctx context.Context, id string) error {
 	release, err := s.releaseRepo.FindByID(ctx, id)
 	if err != nil {
@@ -339,6 +668,9 @@ func (s *ReleaseService) DeleteRelease(ctx context.Context, id string) error {
 }
 
 func (s *ReleaseService) deleteRelease(ctx context.Context, release *domain.Release) error {
+	// 还在等 rollout 的操作不能在删除之后把 release 行写回来
+	s.cancelRunning(release.ID, errReleaseDeleted)
+
 	if s.deployer != nil {
 		// 查询该 app 是否还有其他 release
 		others, err := s.releaseRepo.FindAll(ctx, release.AppName, "")
@@ -31,6 +31,8 @@ func newReleaseSvcWithBundles(t *testing.T, apps []*domain.App, bundles []*domai
 		&stubImageRepoRepo{repo: &domain.ImageRepo{Name: "agent-service", Registry: "harbor.local/inner-bot/agent-service"}},
 		&stubBuildRepo{},
 		newReleaseTestReleaseRepo(),
+		nil,
+		nil,
 		&stubDeployer{},
 		configBundleSvc,
 		ReleaseServiceConfig{},
@@ -108,8 +110,14 @@ func (r *releaseTestReleaseRepo) FindByAppAndLane(_ context.Context, appName, la
 	}
 	return nil, domain.ErrReleaseNotFound
 }
-func (r *releaseTestReleaseRepo) FindAll(_ context.Context, _, _ string) ([]*domain.Release, error) {
-	return nil, nil
+func (r *releaseTestReleaseRepo) FindAll(_ context.Context, appName, lane string) ([]*domain.Release, error) {
+	var out []*domain.Release
+	for _, rel := range r.releases {
+		if (appName == "" || rel.AppName == appName) && (lane == "" || rel.Lane == lane) {
+			out = append(out, rel)
+		}
+	}
+	return out, nil
 }
 
 type stubDeployer struct {
@@ -117,9 +125,21 @@ type stubDeployer struct {
 	status    *domain.DeploymentStatus
 }
 
-func (s *stubDeployer) Deploy(_ context.Context, _ *domain.Release, _ *domain.App, _ map[string]string) error {
+func (s *stubDeployer) Apply(_ context.Context, _ *domain.Release, _ *domain.App, _ map[string]string) error {
+	return nil
+}
+func (s *stubDeployer) WaitForRollout(_ context.Context, _ string, onEvent func(domain.ReleaseOperationEvent)) error {
+	if onEvent != nil && s.deployErr == nil {
+		onEvent(domain.ReleaseOperationEvent{Type: domain.OperationEventPodsReady, Message: "pods ready 1/1"})
+	}
 	return s.deployErr
 }
+func (s *stubDeployer) DiffDeployment(_ context.Context, _ *domain.Release, _ *domain.App, _ map[string]string) ([]domain.DriftItem, error) {
+	return nil, nil
+}
+func (s *stubDeployer) Scale(_ context.Context, release *domain.Release, _ int32) (int32, error) {
+	return release.Replicas, nil
+}
 func (s *stubDeployer) Delete(_ context.Context, _ *domain.Release, _ bool) error { return nil }
 func (s *stubDeployer) GetDeploymentStatus(_ context.Context, name string) (*domain.DeploymentStatus, error) {
 	if s.status != nil {
@@ -149,7 +169,7 @@ func TestCreateRelease_DeployFailure_SetsMessage(t *testing.T) {
 		deployErr: errors.New("wait for rollout: deployment myapp-prod failed: pod myapp-prod-abc is in CrashLoopBackOff: exit code 1"),
 	}
 
-	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, deployer, nil, ReleaseServiceConfig{})
+	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, nil, deployer, nil, ReleaseServiceConfig{})
 
 	release, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
 		AppName:  "myapp",
@@ -184,7 +204,7 @@ func TestCreateRelease_DeploySuccess_ClearsMessage(t *testing.T) {
 	releaseRepo := newReleaseTestReleaseRepo()
 	deployer := &stubDeployer{}
 
-	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, deployer, nil, ReleaseServiceConfig{})
+	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, nil, deployer, nil, ReleaseServiceConfig{})
 
 	release, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
 		AppName:  "myapp",
@@ -203,6 +223,48 @@ func TestCreateRelease_DeploySuccess_ClearsMessage(t *testing.T) {
 	}
 }
 
+// TestCreateRelease_DeploysByBuildDigest 验证 release 从产出镜像的 Build 继承 commit 和 digest，
+// 并能按 lane 查到正在运行的 commit。
+func TestCreateRelease_DeploysByBuildDigest(t *testing.T) {
+	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp", ImageRepoName: "myapp", Port: 8080}}
+	imageRepoRepo := &stubImageRepoRepo{repo: &domain.ImageRepo{Name: "myapp", Registry: "harbor.local/inner-bot/myapp"}}
+	buildRepo := &stubBuildRepo{builds: map[string]*domain.Build{
+		"b1": {
+			ID: "b1", ImageTag: "harbor.local/inner-bot/myapp:1.0.0.3", GitRef: "main", Channel: domain.ChannelStable,
+			Status: domain.BuildStatusSucceeded, CommitSHA: "3f2a9c1d4b5e6f708192a3b4c5d6e7f809162738", Digest: "sha256:0123abcd",
+		},
+	}}
+	svc := NewReleaseService(appRepo, imageRepoRepo, buildRepo, newLaneReleaseRepo(), nil, nil, &stubDeployer{}, nil, ReleaseServiceConfig{})
+
+	release, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "1.0.0.3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if release.CommitSHA != "3f2a9c1d4b5e6f708192a3b4c5d6e7f809162738" || release.ImageDigest != "sha256:0123abcd" {
+		t.Errorf("provenance = %q / %q", release.CommitSHA, release.ImageDigest)
+	}
+	if got := release.DeployImage(); got != "harbor.local/inner-bot/myapp:1.0.0.3@sha256:0123abcd" {
+		t.Errorf("DeployImage() = %q", got)
+	}
+
+	// 换成外部镜像：清空溯源，按 tag 部署
+	release, err = svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{AppName: "myapp", Lane: "prod", ImageTag: "hotfix"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if release.CommitSHA != "" || release.DeployImage() != "harbor.local/inner-bot/myapp:hotfix" {
+		t.Errorf("external image should deploy by tag, got %q (commit %q)", release.DeployImage(), release.CommitSHA)
+	}
+
+	commits, err := svc.ListLaneCommits(context.Background(), "prod")
+	if err != nil {
+		t.Fatalf("ListLaneCommits() error = %v", err)
+	}
+	if len(commits) != 1 || commits[0].App != "myapp" || commits[0].Image != "harbor.local/inner-bot/myapp:hotfix" {
+		t.Errorf("commits = %+v", commits)
+	}
+}
+
 func TestGetReleaseStatus(t *testing.T) {
 	appRepo := &stubAppRepo{app: &domain.App{Name: "myapp"}}
 	releaseRepo := newReleaseTestReleaseRepo()
@@ -217,7 +279,7 @@ func TestGetReleaseStatus(t *testing.T) {
 	}
 	deployer := &stubDeployer{status: expectedStatus}
 
-	svc := NewReleaseService(appRepo, nil, nil, releaseRepo, deployer, nil, ReleaseServiceConfig{})
+	svc := NewReleaseService(appRepo, nil, nil, releaseRepo, nil, nil, deployer, nil, ReleaseServiceConfig{})
 
 	// 先存一个 release
 	rel := &domain.Release{ID: "r1", AppName: "myapp", Lane: "prod", DeployName: "myapp-prod"}
@@ -248,7 +310,7 @@ func TestCreateOrUpdateRelease_RejectsBadLaneName(t *testing.T) {
 	releaseRepo := newReleaseTestReleaseRepo()
 	deployer := &stubDeployer{}
 
-	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, deployer, nil, ReleaseServiceConfig{})
+	svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, nil, deployer, nil, ReleaseServiceConfig{})
 
 	_, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
 		AppName:  "agent-service",
@@ -280,7 +342,7 @@ func TestCreateOrUpdateRelease_AcceptsValidLanes(t *testing.T) {
 			releaseRepo := newReleaseTestReleaseRepo()
 			deployer := &stubDeployer{}
 
-			svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, deployer, nil, ReleaseServiceConfig{})
+			svc := NewReleaseService(appRepo, imageRepoRepo, &stubBuildRepo{}, releaseRepo, nil, nil, deployer, nil, ReleaseServiceConfig{})
 
 			_, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
 				AppName:  "agent-service",
@@ -297,7 +359,7 @@ func TestCreateOrUpdateRelease_AcceptsValidLanes(t *testing.T) {
 func TestGetReleaseStatus_NotFound(t *testing.T) {
 	releaseRepo := newReleaseTestReleaseRepo()
 	deployer := &stubDeployer{}
-	svc := NewReleaseService(nil, nil, nil, releaseRepo, deployer, nil, ReleaseServiceConfig{})
+	svc := NewReleaseService(nil, nil, nil, releaseRepo, nil, nil, deployer, nil, ReleaseServiceConfig{})
 
 	_, err := svc.GetReleaseStatus(context.Background(), "nonexistent")
 	if !errors.Is(err, domain.ErrReleaseNotFound) {
@@ -412,3 +474,22 @@ func TestCreateOrUpdateRelease_AppWithoutAllowedLaneClasses_AllowsAll(t *testing
 		t.Fatalf("nil AllowedLaneClasses should allow all: %v", err)
 	}
 }
+
+func TestCreateOrUpdateRelease_RejectsInvalidResourceOverride(t *testing.T) {
+	appRepo := &stubAppRepo{app: &domain.App{
+		Name:      "myapp",
+		Port:      8080,
+		Resources: &domain.ResourceSpec{MemoryLimit: "512Mi"},
+	}}
+	svc := NewReleaseService(appRepo, nil, &stubBuildRepo{}, newReleaseTestReleaseRepo(), nil, nil, &stubDeployer{}, nil, ReleaseServiceConfig{})
+
+	// 覆盖后的 request 超过 App 上的 limit
+	_, err := svc.CreateOrUpdateRelease(context.Background(), CreateReleaseRequest{
+		AppName:   "myapp",
+		Lane:      "prod",
+		Resources: &domain.ResourceSpec{MemoryRequest: "1Gi"},
+	})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
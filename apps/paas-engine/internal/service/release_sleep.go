@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chiwei-platform/paas-engine/internal/domain"
+)
+
+// SleepLane 把 lane 上的 release 缩到 0 副本并标记为 sleeping，记住缩容前的副本数。
+// 部署中（pending 或有后台操作）的 release 跳过，单个 release 失败只记日志。
+// 返回本次被休眠的 release。
+func (s *ReleaseService) SleepLane(ctx context.Context, lane, reason string) ([]*domain.Release, error) {
+	if err := domain.CanLaneSleep(lane, s.cfg.LegacyLaneWhitelist); err != nil {
+		return nil, err
+	}
+	releases, err := s.releaseRepo.FindAll(ctx, "", lane)
+	if err != nil {
+		return nil, err
+	}
+
+	slept := []*domain.Release{}
+	for _, release := range releases {
+		if release.Status == domain.ReleaseStatusSleeping || release.Status == domain.ReleaseStatusPending || s.isRunning(release.ID) {
+			continue
+		}
+		previous := release.Replicas
+		if s.deployer != nil {
+			previous, err = s.deployer.Scale(ctx, release, 0)
+			if err != nil {
+				slog.Error("SleepLane: failed to scale down release", "release_id", release.ID, "lane", lane, "error", err)
+				continue
+			}
+		}
+		if previous <= 0 {
+			previous = release.Replicas
+		}
+		release.SleptReplicas = previous
+		release.Status = domain.ReleaseStatusSleeping
+		release.Message = "sleeping: " + reason
+		release.UpdatedAt = time.Now()
+		if err := s.releaseRepo.Update(ctx, release); err != nil {
+			slog.Error("SleepLane: failed to persist release", "release_id", release.ID, "lane", lane, "error", err)
+			continue
+		}
+		slept = append(slept, release)
+	}
+	if len(slept) > 0 {
+		slog.Info("lane put to sleep", "lane", lane, "releases", len(slept), "reason", reason)
+	}
+	return slept, nil
+}
+
+// WakeLane 为 lane 上每个 sleeping 的 release 启动一次唤醒操作（恢复副本数并等 rollout），
+// 立即返回，进度通过各自的 operation 查询。lane 没有休眠的 release 时返回空列表。
+func (s *ReleaseService) WakeLane(ctx context.Context, lane string) ([]*domain.Release, []*domain.ReleaseOperation, error) {
+	releases, err := s.releaseRepo.FindAll(ctx, "", lane)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.wakeReleases(ctx, releases, "")
+}
+
+// wakeLaneSiblings 在 lane 上有新部署时唤醒同 lane 其他休眠的 release。失败只记日志，不影响部署本身。
+func (s *ReleaseService) wakeLaneSiblings(ctx context.Context, release *domain.Release) {
+	siblings, err := s.releaseRepo.FindAll(ctx, "", release.Lane)
+	if err != nil {
+		slog.Error("wakeLaneSiblings: failed to list lane releases", "lane", release.Lane, "error", err)
+		return
+	}
+	woken, _, err := s.wakeReleases(ctx, siblings, release.ID)
+	if err != nil {
+		slog.Error("wakeLaneSiblings: failed to wake lane", "lane", release.Lane, "error", err)
+	}
+	if len(woken) > 0 {
+		slog.Info("lane woken by deploy", "lane", release.Lane, "release_id", release.ID, "woken", len(woken))
+	}
+}
+
+func (s *ReleaseService) wakeReleases(ctx context.Context, releases []*domain.Release, skipID string) ([]*domain.Release, []*domain.ReleaseOperation, error) {
+	woken := []*domain.Release{}
+	ops := []*domain.ReleaseOperation{}
+	for _, release := range releases {
+		if release.ID == skipID || release.Status != domain.ReleaseStatusSleeping {
+			continue
+		}
+		app, err := s.appRepo.FindByName(ctx, release.AppName)
+		if err != nil {
+			return woken, ops, fmt.Errorf("wake %s: %w", release.ResourceName(), err)
+		}
+		release.UpdatedAt = time.Now()
+		release.DeployName = release.ResourceName()
+		plan := &deployPlan{release: release, app: app, wakeReplicas: wakeReplicas(release)}
+		r, op, err := s.startOperation(ctx, plan, domain.ReleaseOperationWake, "")
+		if err != nil {
+			return woken, ops, fmt.Errorf("wake %s: %w", release.ResourceName(), err)
+		}
+		woken = append(woken, r)
+		ops = append(ops, op)
+	}
+	return woken, ops, nil
+}
+
+// wakeReplicas 是唤醒时恢复的副本数：休眠前的线上值，缺失时退回 release 配置。
+func wakeReplicas(release *domain.Release) int32 {
+	if release.SleptReplicas > 0 {
+		return release.SleptReplicas
+	}
+	if release.Replicas > 0 {
+		return release.Replicas
+	}
+	return 1
+}
+
+// isRunning 报告 release 上是否有后台部署操作在执行。
+func (s *ReleaseService) isRunning(releaseID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.running[releaseID]
+	return ok
+}
@@ -0,0 +1,34 @@
+#!/bin/sh
+# 等待一次异步 release 操作结束并打印进度事件。
+# 用法: wait-release-op.sh '<POST/PUT /api/paas/releases 的响应 JSON>'
+# 依赖环境变量 PAAS_API / PAAS_TOKEN，X_LANE 可选（路由到指定泳道的 paas-engine）。
+set -e
+
+RESP="$1"
+OP_PATH=$(printf '%s' "$RESP" | python3 -c "import sys,json; d=json.load(sys.stdin)['data']; print(d['id']+'/operations/'+d['operation_id'])")
+SEEN=0
+while true; do
+	# paas-engine 自部署期间接口会短暂不可用，查询失败就重试
+	if [ -n "$X_LANE" ]; then
+		OP=$(curl -sf "$PAAS_API/api/paas/releases/$OP_PATH" -H "X-API-Key: $PAAS_TOKEN" -H "x-lane: $X_LANE") || { sleep 3; continue; }
+	else
+		OP=$(curl -sf "$PAAS_API/api/paas/releases/$OP_PATH" -H "X-API-Key: $PAAS_TOKEN") || { sleep 3; continue; }
+	fi
+	# 只打印新增事件，最后一行输出操作状态
+	OUT=$(printf '%s' "$OP" | python3 -c "
+import sys,json
+d=json.load(sys.stdin)['data']
+evs=d.get('events') or []
+for e in evs[$SEEN:]:
+    print('    ' + e['type'] + (': ' + e['message'] if e.get('message') else ''))
+print(len(evs), d['status'])
+")
+	printf '%s\n' "$OUT" | sed '$d'
+	SEEN=$(printf '%s\n' "$OUT" | tail -n 1 | cut -d' ' -f1)
+	STATUS=$(printf '%s\n' "$OUT" | tail -n 1 | cut -d' ' -f2)
+	case "$STATUS" in
+		succeeded) exit 0 ;;
+		failed) echo ">>> 发布失败"; exit 1 ;;
+	esac
+	sleep 3
+done